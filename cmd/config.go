@@ -15,6 +15,8 @@ type Config struct {
 	Model    string `yaml:"model"`
 	Verbose  bool   `yaml:"verbose"`
 	Output   string `yaml:"output"`
+	// Redact lists the patterns redacted from verbose request logs: email, card, phone or regular expressions
+	Redact []string `yaml:"redact"`
 
 	Providers struct {
 		OpenAI struct {
@@ -103,6 +105,9 @@ func loadEnvVars() {
 	if val := os.Getenv("GO_LLMS_OUTPUT"); val != "" {
 		config.Output = val
 	}
+	if val := os.Getenv("GO_LLMS_REDACT"); val != "" {
+		config.Redact = splitList(val)
+	}
 
 	// Provider-specific settings
 	loadProviderEnvVars("openai", "OPENAI")
@@ -181,19 +186,31 @@ func GetOptimizedProvider() (string, string, error) {
 
 	// If no model specified, get the default for the provider
 	if model == "" {
-		switch provider {
-		case "openai":
-			model = config.Providers.OpenAI.DefaultModel
-		case "anthropic":
-			model = config.Providers.Anthropic.DefaultModel
-		case "gemini":
-			model = config.Providers.Gemini.DefaultModel
-		}
-
-		if model == "" {
-			return "", "", fmt.Errorf("no model specified and no default model configured for provider %s", provider)
+		var err error
+		if model, err = GetDefaultModel(provider); err != nil {
+			return "", "", err
 		}
 	}
 
 	return provider, model, nil
 }
+
+// GetDefaultModel returns the configured default model of a provider
+func GetDefaultModel(provider string) (string, error) {
+	var model string
+	switch provider {
+	case "openai":
+		model = config.Providers.OpenAI.DefaultModel
+	case "anthropic":
+		model = config.Providers.Anthropic.DefaultModel
+	case "gemini":
+		model = config.Providers.Gemini.DefaultModel
+	case "mock":
+		model = "mock"
+	}
+
+	if model == "" {
+		return "", fmt.Errorf("no model specified and no default model configured for provider %s", provider)
+	}
+	return model, nil
+}
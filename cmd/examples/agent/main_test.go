@@ -67,6 +67,11 @@ func TestCachedAgentExample(t *testing.T) {
 		t.Logf("Cache stats: %v", stats)
 		t.Errorf("Expected at least 1 cache hit, got %d", stats["hits"].(int))
 	}
+	for _, key := range []string{"response_saving_p50_ms", "response_saving_p90_ms", "response_saving_p99_ms"} {
+		if _, ok := stats[key].(float64); !ok {
+			t.Errorf("Expected %s in cache stats, got %v", key, stats[key])
+		}
+	}
 }
 
 // TestMessageManagerExample tests the message manager functionality
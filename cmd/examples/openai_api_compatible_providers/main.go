@@ -78,6 +78,7 @@ func runOpenRouterExample() {
 		baseURLOption,
 		httpClientOption,
 		headersOption,
+		domain.NewOpenAICompatibilityOption("openrouter"),
 	)
 
 	// Use the provider to generate a response with messages (preferred for OpenRouter)
@@ -183,6 +184,7 @@ func runOllamaExample() {
 		ollamaModel,
 		ollamaBaseURLOption,
 		ollamaHTTPClientOption,
+		domain.NewOpenAICompatibilityOption("ollama"),
 	)
 
 	// Use the provider to generate a response
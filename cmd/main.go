@@ -5,12 +5,14 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
 	llmDomain "github.com/lexlapax/go-llms/pkg/llm/domain"
 	"github.com/lexlapax/go-llms/pkg/llm/provider"
+	"github.com/lexlapax/go-llms/pkg/util/httplog"
 )
 
 // Version information (set during build)
@@ -25,7 +27,8 @@ var (
 	configFile   = flag.String("c", "", "Config file location")
 	providerFlag = flag.String("p", "", "LLM provider to use")
 	modelFlag    = flag.String("m", "", "Model to use (overrides provider default)")
-	verbose      = flag.Bool("v", false, "Enable verbose output")
+	verbose      = flag.Bool("v", false, "Enable verbose output, including provider HTTP requests")
+	redact       = flag.String("redact", "", "Comma-separated patterns to redact from verbose request logs (email, card, phone or regular expressions)")
 	output       = flag.String("o", "text", "Output format (text or json)")
 	help         = flag.Bool("h", false, "Show help")
 	versionFlag  = flag.Bool("version", false, "Show version information")
@@ -41,6 +44,7 @@ Usage:
 Commands:
   chat        Interactive chat with an LLM
   complete    One-shot text completion
+  serve       Serve providers over an OpenAI-compatible HTTP API
   version     Show version information
 
 Options:
@@ -86,6 +90,9 @@ Options:
 	if *verbose {
 		config.Verbose = *verbose
 	}
+	if *redact != "" {
+		config.Redact = splitList(*redact)
+	}
 	if *output != "" {
 		config.Output = *output
 	}
@@ -100,6 +107,8 @@ Options:
 		runMinimalChat(ctx)
 	case "complete":
 		runMinimalComplete(ctx)
+	case "serve":
+		runServe(ctx)
 	default:
 		fmt.Fprintf(os.Stderr, "Error: Unknown command '%s'\n\n", command)
 		flag.Usage()
@@ -119,23 +128,52 @@ func createMinimalProvider() (llmDomain.Provider, error) {
 		return nil, err
 	}
 
+	return createNamedProvider(providerName, modelName)
+}
+
+// createNamedProvider creates a provider by name using the configured API key
+func createNamedProvider(providerName, modelName string) (llmDomain.Provider, error) {
+	if providerName == "mock" {
+		return provider.NewMockProvider(), nil
+	}
+
 	apiKey, err := GetOptimizedAPIKey(providerName)
 	if err != nil {
 		return nil, err
 	}
 
+	options, err := providerOptions()
+	if err != nil {
+		return nil, err
+	}
+
 	switch providerName {
 	case "openai":
-		return provider.NewOpenAIProvider(apiKey, modelName), nil
+		return provider.NewOpenAIProvider(apiKey, modelName, options...), nil
 	case "anthropic":
-		return provider.NewAnthropicProvider(apiKey, modelName), nil
+		return provider.NewAnthropicProvider(apiKey, modelName, options...), nil
 	case "gemini":
-		return provider.NewGeminiProvider(apiKey, modelName), nil
+		return provider.NewGeminiProvider(apiKey, modelName, options...), nil
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", providerName)
 	}
 }
 
+// providerOptions returns the options for all providers. With verbose output, HTTP requests
+// are logged to stderr with API keys and the configured patterns redacted.
+func providerOptions() ([]llmDomain.ProviderOption, error) {
+	if !config.Verbose {
+		return nil, nil
+	}
+
+	patterns, err := httplog.ParsePatterns(config.Redact)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction pattern: %w", err)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return []llmDomain.ProviderOption{llmDomain.NewRequestLoggingOption(logger, patterns...)}, nil
+}
+
 func runMinimalChat(ctx context.Context) {
 	// Parse chat-specific flags
 	chatFlags := flag.NewFlagSet("chat", flag.ExitOnError)
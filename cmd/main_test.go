@@ -3,6 +3,8 @@ package main
 import (
 	"os"
 	"testing"
+
+	llmDomain "github.com/lexlapax/go-llms/pkg/llm/domain"
 )
 
 func TestGetAPIKey(t *testing.T) {
@@ -135,3 +137,27 @@ func TestGetProvider(t *testing.T) {
 		}
 	})
 }
+
+func TestProviderOptions(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+
+	config = Config{}
+	if options, err := providerOptions(); err != nil || len(options) != 0 {
+		t.Errorf("Expected no options without verbose output, got %v (%v)", options, err)
+	}
+
+	config = Config{Verbose: true, Redact: []string{"email", `ACCT-\d+`}}
+	options, err := providerOptions()
+	if err != nil || len(options) != 1 {
+		t.Fatalf("Expected a request logging option, got %v (%v)", options, err)
+	}
+	if _, ok := options[0].(*llmDomain.RequestLoggingOption); !ok {
+		t.Errorf("Expected a RequestLoggingOption, got %T", options[0])
+	}
+
+	config = Config{Verbose: true, Redact: []string{"("}}
+	if _, err := providerOptions(); err == nil {
+		t.Errorf("Expected an error for an invalid pattern")
+	}
+}
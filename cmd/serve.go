@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/gateway"
+	"github.com/lexlapax/go-llms/pkg/llm/provider"
+)
+
+// fallbackModel is the model name serving all providers with fallback
+const fallbackModel = "go-llms"
+
+func runServe(ctx context.Context) {
+	// Parse serve-specific flags
+	serveFlags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := serveFlags.String("addr", "127.0.0.1:8080", "Address to listen on (listening beyond loopback requires API keys)")
+	providers := serveFlags.String("providers", "", "Comma-separated providers to serve (defaults to the configured provider)")
+	apiKeys := serveFlags.String("api-keys", "", "Comma-separated API keys clients must send as bearer tokens")
+
+	if err := serveFlags.Parse(flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing serve flags: %v\n", err)
+		os.Exit(1)
+	}
+
+	names := splitList(*providers)
+	if len(names) == 0 {
+		names = []string{config.Provider}
+	}
+
+	handler, err := newGatewayHandler(names)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating gateway: %v\n", err)
+		os.Exit(1)
+	}
+	keys := splitList(*apiKeys)
+	if len(keys) == 0 {
+		keys = splitList(os.Getenv("GO_LLMS_SERVE_API_KEYS"))
+	}
+	if len(keys) == 0 && !isLoopbackAddr(*addr) {
+		fmt.Fprintf(os.Stderr, "Error: refusing to serve on %s without API keys; set -api-keys or GO_LLMS_SERVE_API_KEYS, or listen on a loopback address\n", *addr)
+		os.Exit(1)
+	}
+	if len(keys) > 0 {
+		handler.WithAPIKeys(keys...)
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving %s on %s\n", strings.Join(handler.Models(), ", "), *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// newGatewayHandler serves each provider under its model name. With several providers,
+// they are also served together as the "go-llms" model, which falls back in order.
+func newGatewayHandler(names []string) (*gateway.Handler, error) {
+	handler := gateway.NewHandler()
+	weights := make([]provider.ProviderWeight, 0, len(names))
+
+	for _, name := range names {
+		// The model may be given as provider:model
+		providerName, modelName, _ := strings.Cut(name, ":")
+		if modelName == "" {
+			var err error
+			if modelName, err = GetDefaultModel(providerName); err != nil {
+				return nil, err
+			}
+		}
+
+		p, err := createNamedProvider(providerName, modelName)
+		if err != nil {
+			return nil, err
+		}
+		handler.Register(modelName, p)
+		weights = append(weights, provider.ProviderWeight{Provider: p, Weight: 1.0, Name: providerName})
+	}
+
+	if len(weights) > 1 {
+		handler.Register(fallbackModel, provider.NewMultiProvider(weights, provider.StrategyPrimary))
+	}
+	return handler, nil
+}
+
+// isLoopbackAddr reports whether a listen address only accepts local connections.
+// An empty host listens on every interface, so it is not loopback.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
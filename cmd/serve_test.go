@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewGatewayHandler(t *testing.T) {
+	handler, err := newGatewayHandler([]string{"mock", "mock:mock-2"})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+
+	expected := []string{"go-llms", "mock", "mock-2"}
+	if models := handler.Models(); !reflect.DeepEqual(models, expected) {
+		t.Errorf("Expected models %v, got %v", expected, models)
+	}
+
+	if _, err := newGatewayHandler([]string{"unknown"}); err == nil {
+		t.Errorf("Expected an error for an unknown provider")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if items := splitList(" a, ,b "); !reflect.DeepEqual(items, []string{"a", "b"}) {
+		t.Errorf("Unexpected items: %v", items)
+	}
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	for addr, expected := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"[::]:8080":      false,
+		"10.0.0.5:8080":  false,
+		"invalid":        false,
+	} {
+		if got := isLoopbackAddr(addr); got != expected {
+			t.Errorf("isLoopbackAddr(%q) = %v, expected %v", addr, got, expected)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	ldomain "github.com/lexlapax/go-llms/pkg/llm/domain"
 	sdomain "github.com/lexlapax/go-llms/pkg/schema/domain"
 	"github.com/lexlapax/go-llms/pkg/structured/processor"
+	"github.com/lexlapax/go-llms/pkg/util/tracing"
 )
 
 // UnoptimizedDefaultAgent is the original implementation of DefaultAgent
@@ -38,6 +39,12 @@ type DefaultAgent struct {
 	cachedToolNames []string
 	// Optimization: pre-allocate message buffer
 	messageBuffer []ldomain.Message
+
+	// tracer records spans of runs, iterations and tool executions
+	tracer *tracing.Tracer
+
+	// toolOutputPolicy checks and spotlights tool output before it enters the conversation
+	toolOutputPolicy *ToolOutputPolicy
 }
 
 // NewUnoptimizedAgent creates a new agent with an LLM provider using the unoptimized implementation
@@ -136,7 +143,16 @@ func (a *UnoptimizedDefaultAgent) RunWithSchema(ctx context.Context, input strin
 }
 
 // run is the internal implementation of Run and RunWithSchema for the optimized agent
-func (a *DefaultAgent) run(ctx context.Context, input string, schema *sdomain.Schema) (interface{}, error) {
+func (a *DefaultAgent) run(ctx context.Context, input string, schema *sdomain.Schema) (result interface{}, err error) {
+	ctx, runSpan := a.startRunSpan(ctx)
+	var iterationSpan *tracing.Span
+	defer func() {
+		iterationSpan.End()
+		runSpan.RecordError(err)
+		runSpan.End()
+	}()
+	runCtx := ctx
+
 	// Prepare the prompt
 	prompt := input
 	if schema != nil {
@@ -155,6 +171,10 @@ func (a *DefaultAgent) run(ctx context.Context, input string, schema *sdomain.Sc
 	var finalResponse interface{}
 	maxIterations := 10 // Prevent infinite loops
 	for i := 0; i < maxIterations; i++ {
+		// Each iteration's span ends when the next one starts or the run returns
+		iterationSpan.End()
+		ctx, iterationSpan = startIterationSpan(runCtx, i)
+
 		// Call hooks before generate
 		a.notifyBeforeGenerate(ctx, messages)
 
@@ -219,7 +239,7 @@ func (a *DefaultAgent) run(ctx context.Context, input string, schema *sdomain.Sc
 				a.notifyBeforeToolCall(ctx, toolName, params)
 
 				// Execute the tool
-				toolResult, toolErr := tool.Execute(ctx, params)
+				toolResult, toolErr := executeTool(ctx, tool, toolName, params)
 
 				// Call hooks after tool call
 				a.notifyAfterToolCall(ctx, toolName, toolResult, toolErr)
@@ -245,6 +265,12 @@ func (a *DefaultAgent) run(ctx context.Context, input string, schema *sdomain.Sc
 					}
 				}
 
+				// Check and spotlight the output before it enters the conversation
+				toolRespContent, err = a.toolOutputPolicy.Apply(ctx, toolName, params, toolRespContent)
+				if err != nil {
+					return nil, err
+				}
+
 				// Add this tool's result to the combined output
 				allToolsOutput.WriteString(fmt.Sprintf("Tool '%s' result: %s\n\n", toolName, toolRespContent))
 			}
@@ -297,7 +323,7 @@ func (a *DefaultAgent) run(ctx context.Context, input string, schema *sdomain.Sc
 		a.notifyBeforeToolCall(ctx, toolCall, params)
 
 		// Execute the tool
-		toolResult, toolErr := tool.Execute(ctx, params)
+		toolResult, toolErr := executeTool(ctx, tool, toolCall, params)
 
 		// Call hooks after tool call
 		a.notifyAfterToolCall(ctx, toolCall, toolResult, toolErr)
@@ -324,6 +350,12 @@ func (a *DefaultAgent) run(ctx context.Context, input string, schema *sdomain.Sc
 			}
 		}
 
+		// Check and spotlight the output before it enters the conversation
+		toolRespContent, err = a.toolOutputPolicy.Apply(ctx, toolCall, params, toolRespContent)
+		if err != nil {
+			return nil, err
+		}
+
 		// Add the assistant message and tool result to the conversation
 		messages = append(messages, ldomain.Message{
 			Role:    ldomain.RoleAssistant,
@@ -86,6 +86,13 @@ func (a *DefaultAgent) getToolsDescription() string {
 		}
 	}
 
+	// Explain how tool output is marked
+	if instructions := a.toolOutputPolicy.Instructions(); instructions != "" {
+		builder.WriteString("\n")
+		builder.WriteString(instructions)
+		builder.WriteString("\n")
+	}
+
 	// Cache the result for future calls
 	a.cachedToolsDescription = builder.String()
 	return a.cachedToolsDescription
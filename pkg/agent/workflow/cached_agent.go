@@ -15,6 +15,8 @@ import (
 	ldomain "github.com/lexlapax/go-llms/pkg/llm/domain"
 	sdomain "github.com/lexlapax/go-llms/pkg/schema/domain"
 	"github.com/lexlapax/go-llms/pkg/structured/processor"
+	"github.com/lexlapax/go-llms/pkg/util/metrics"
+	"github.com/lexlapax/go-llms/pkg/util/tracing"
 )
 
 // CachedAgent extends MultiAgent with response caching capabilities
@@ -35,6 +37,12 @@ type CachedAgent struct {
 
 	// Cache statistics for monitoring
 	cacheStats CacheStats
+
+	// Generation times of cached responses, the time a cache hit saves
+	responseTimes *metrics.Histogram
+
+	// registry receives the generation times too, in series shared by all agents
+	registry *metrics.Registry
 }
 
 // executeMultipleToolsParallel delegates to MultiAgent's implementation
@@ -105,6 +113,8 @@ func NewCachedAgent(provider ldomain.Provider) *CachedAgent {
 		responseCache: cache,
 		cacheConfig:   config,
 		cacheStats:    CacheStats{LastCacheCleanup: time.Now()},
+		responseTimes: metrics.NewHistogram("cached_agent_response_seconds", nil),
+		registry:      metrics.GetRegistry(),
 	}
 
 	// Embed the MultiAgent using pointer semantics
@@ -129,7 +139,16 @@ func (a *CachedAgent) RunWithSchema(ctx context.Context, input string, schema *s
 
 // run is the internal implementation of Run and RunWithSchema
 // This version includes response caching for improved performance
-func (a *CachedAgent) run(ctx context.Context, input string, schema *sdomain.Schema) (interface{}, error) {
+func (a *CachedAgent) run(ctx context.Context, input string, schema *sdomain.Schema) (result interface{}, err error) {
+	ctx, runSpan := a.startRunSpan(ctx)
+	var iterationSpan *tracing.Span
+	defer func() {
+		iterationSpan.End()
+		runSpan.RecordError(err)
+		runSpan.End()
+	}()
+	runCtx := ctx
+
 	// Prepare the prompt
 	prompt := input
 	if schema != nil {
@@ -165,6 +184,8 @@ func (a *CachedAgent) run(ctx context.Context, input string, schema *sdomain.Sch
 		if cacheHit {
 			// We found a cached response, use it
 			a.cacheStats.Hits++
+			runSpan.SetAttribute("agent.cache_hit", true)
+			ctx, iterationSpan = startIterationSpan(runCtx, 0)
 
 			// Process cached response for tool calls
 			toolCalls, multiParams, shouldCallMultipleTools := a.ExtractMultipleToolCalls(cachedResponse.Content)
@@ -218,7 +239,7 @@ func (a *CachedAgent) run(ctx context.Context, input string, schema *sdomain.Sch
 					a.notifyBeforeToolCall(ctx, toolCall, params)
 
 					// Execute the tool
-					toolResult, toolErr := tool.Execute(ctx, params)
+					toolResult, toolErr := executeTool(ctx, tool, toolCall, params)
 
 					// Call hooks after tool call
 					a.notifyAfterToolCall(ctx, toolCall, toolResult, toolErr)
@@ -243,6 +264,12 @@ func (a *CachedAgent) run(ctx context.Context, input string, schema *sdomain.Sch
 						}
 					}
 
+					// Check and spotlight the output before it enters the conversation
+					toolRespContent, err := a.toolOutputPolicy.Apply(ctx, toolCall, params, toolRespContent)
+					if err != nil {
+						return nil, err
+					}
+
 					// Add messages and generate a response
 					messages = append(messages, ldomain.Message{
 						Role:    ldomain.RoleAssistant,
@@ -290,6 +317,10 @@ func (a *CachedAgent) run(ctx context.Context, input string, schema *sdomain.Sch
 	var finalResponse interface{}
 	maxIterations := 10 // Prevent infinite loops
 	for i := 0; i < maxIterations; i++ {
+		// Each iteration's span ends when the next one starts or the run returns
+		iterationSpan.End()
+		ctx, iterationSpan = startIterationSpan(runCtx, i)
+
 		// Call hooks before generate
 		a.notifyBeforeGenerate(ctx, messages)
 
@@ -327,7 +358,13 @@ func (a *CachedAgent) run(ctx context.Context, input string, schema *sdomain.Sch
 				a.cacheStats.StoredResponses++
 
 				// Track time saved for future cache hits
-				a.cacheStats.AverageResponseSavingMs = (a.cacheStats.AverageResponseSavingMs + time.Since(startTime).Milliseconds()) / 2
+				duration := time.Since(startTime)
+				a.responseTimes.ObserveDuration(duration)
+				if a.registry != nil {
+					a.registry.GetOrCreateLabeledHistogram("cached_agent_response_seconds",
+						metrics.Labels{metrics.LabelModel: modelName}, nil).ObserveDuration(duration)
+				}
+				a.cacheStats.AverageResponseSavingMs = int64(a.responseTimes.GetMean() * 1000)
 			}
 		}
 
@@ -397,7 +434,7 @@ func (a *CachedAgent) run(ctx context.Context, input string, schema *sdomain.Sch
 		a.notifyBeforeToolCall(ctx, toolCall, params)
 
 		// Execute the tool
-		toolResult, toolErr := tool.Execute(ctx, params)
+		toolResult, toolErr := executeTool(ctx, tool, toolCall, params)
 
 		// Call hooks after tool call
 		a.notifyAfterToolCall(ctx, toolCall, toolResult, toolErr)
@@ -424,6 +461,12 @@ func (a *CachedAgent) run(ctx context.Context, input string, schema *sdomain.Sch
 			}
 		}
 
+		// Check and spotlight the output before it enters the conversation
+		toolRespContent, err := a.toolOutputPolicy.Apply(ctx, toolCall, params, toolRespContent)
+		if err != nil {
+			return nil, err
+		}
+
 		// Add the assistant message and tool result to the conversation
 		messages = append(messages, ldomain.Message{
 			Role:    ldomain.RoleAssistant,
@@ -506,6 +549,9 @@ func (a *CachedAgent) GetCacheStats() map[string]interface{} {
 	stats["fuzzy_match_successes"] = a.cacheStats.FuzzyMatchSuccesses
 	stats["fuzzy_match_failures"] = a.cacheStats.FuzzyMatchFailures
 	stats["avg_response_saving_ms"] = a.cacheStats.AverageResponseSavingMs
+	stats["response_saving_p50_ms"] = a.responseTimes.GetQuantile(0.5) * 1000
+	stats["response_saving_p90_ms"] = a.responseTimes.GetQuantile(0.9) * 1000
+	stats["response_saving_p99_ms"] = a.responseTimes.GetQuantile(0.99) * 1000
 	stats["last_cleanup"] = a.cacheStats.LastCacheCleanup.Format(time.RFC3339)
 
 	// Add cache configuration
@@ -526,6 +572,14 @@ func (a *CachedAgent) GetCacheStats() map[string]interface{} {
 	return stats
 }
 
+// WithMetricsRegistry sets the registry the generation times of cached responses are
+// exported to, as the cached_agent_response_seconds histogram labelled by model. Agents
+// share these series. It defaults to metrics.GetRegistry(); nil stops exporting.
+func (a *CachedAgent) WithMetricsRegistry(registry *metrics.Registry) *CachedAgent {
+	a.registry = registry
+	return a
+}
+
 // EnableCaching turns caching on or off
 func (a *CachedAgent) EnableCaching(enabled bool) {
 	a.cacheConfig.Enabled = enabled
@@ -546,6 +600,8 @@ func (a *CachedAgent) ClearCache() {
 	a.responseCache.Clear()
 	a.cacheStats.StoredResponses = 0
 	a.cacheStats.EvictedResponses = 0
+	a.cacheStats.AverageResponseSavingMs = 0
+	a.responseTimes.Reset()
 }
 
 // WithModel specifies which LLM model to use
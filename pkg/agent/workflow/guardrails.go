@@ -0,0 +1,14 @@
+package workflow
+
+import (
+	"github.com/lexlapax/go-llms/pkg/agent/domain"
+	"github.com/lexlapax/go-llms/pkg/llm/guardrails"
+)
+
+// WithGuardrails runs a guardrail pipeline on every LLM call of the agent: input guards
+// check the user messages and output guards check each response, including responses
+// requesting tool calls. A blocked call fails the run with a *guardrails.ViolationError.
+func (a *DefaultAgent) WithGuardrails(pipeline *guardrails.Pipeline) domain.Agent {
+	a.llmProvider = guardrails.NewGuardedProvider(a.llmProvider, pipeline)
+	return a
+}
@@ -0,0 +1,35 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	ldomain "github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/llm/guardrails"
+	"github.com/lexlapax/go-llms/pkg/llm/provider"
+)
+
+func TestAgentGuardrails(t *testing.T) {
+	var prompts []string
+	mockProvider := provider.NewMockProvider().WithGenerateMessageFunc(
+		func(ctx context.Context, messages []ldomain.Message, options ...ldomain.Option) (ldomain.Response, error) {
+			prompts = append(prompts, messages[len(messages)-1].Content[0].Text)
+			return ldomain.Response{Content: "The launch code is 0000"}, nil
+		})
+
+	agent := NewAgent(mockProvider)
+	agent.WithGuardrails(guardrails.NewPipeline().
+		Input(guardrails.NewPIIGuard(), guardrails.ActionRewrite).
+		Output(guardrails.NewDenylistGuard("launch code"), guardrails.ActionBlock))
+
+	_, err := agent.Run(context.Background(), "I'm bob@example.com, what's new?")
+	var violation *guardrails.ViolationError
+	if !errors.As(err, &violation) || violation.Stage != guardrails.StageOutput {
+		t.Fatalf("Expected an output ViolationError, got %v", err)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "I'm [EMAIL], what's new?") {
+		t.Errorf("Expected the prompt to be rewritten, got %q", prompts)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
 	ldomain "github.com/lexlapax/go-llms/pkg/llm/domain"
+	utilMetrics "github.com/lexlapax/go-llms/pkg/util/metrics"
 )
 
 func TestLoggingHook(t *testing.T) {
@@ -107,7 +109,8 @@ func TestLoggingHook(t *testing.T) {
 }
 
 func TestMetricsHook(t *testing.T) {
-	hook := NewMetricsHook()
+	registry := utilMetrics.NewRegistry()
+	hook := NewMetricsHook().WithMetricsRegistry(registry)
 	ctx := WithMetrics(context.Background())
 
 	// Test BeforeGenerate and AfterGenerate
@@ -136,6 +139,12 @@ func TestMetricsHook(t *testing.T) {
 		if metrics.AverageGenTimeMs < 10 {
 			t.Errorf("Expected generation time >= 10ms, got %.2f", metrics.AverageGenTimeMs)
 		}
+
+		// With a single observation every percentile is that observation
+		if metrics.GenTimeP50Ms != metrics.AverageGenTimeMs || metrics.GenTimeP99Ms != metrics.AverageGenTimeMs {
+			t.Errorf("Expected percentiles equal to the only generation time, got p50 %.2f, p99 %.2f",
+				metrics.GenTimeP50Ms, metrics.GenTimeP99Ms)
+		}
 	})
 
 	// Test BeforeToolCall and AfterToolCall
@@ -169,6 +178,10 @@ func TestMetricsHook(t *testing.T) {
 			if toolStats.AverageTimeMs < 15 {
 				t.Errorf("Expected tool execution time >= 15ms, got %.2f", toolStats.AverageTimeMs)
 			}
+
+			if toolStats.P90Ms < 15 || toolStats.FastestCallMs != toolStats.SlowestCallMs {
+				t.Errorf("Unexpected tool percentiles: %+v", toolStats)
+			}
 		}
 	})
 
@@ -189,6 +202,22 @@ func TestMetricsHook(t *testing.T) {
 		}
 	})
 
+	// Test the histograms are exported from the metrics registry
+	t.Run("PrometheusExport", func(t *testing.T) {
+		var out strings.Builder
+		if err := registry.WritePrometheus(&out, ""); err != nil {
+			t.Fatalf("WritePrometheus failed: %v", err)
+		}
+		for _, series := range []string{
+			"agent_generate_seconds_count 1\n",
+			`agent_tool_seconds_count{tool="weather"} 1` + "\n",
+		} {
+			if !strings.Contains(out.String(), series) {
+				t.Errorf("Expected %q in the Prometheus output", series)
+			}
+		}
+	})
+
 	// Test reset
 	t.Run("Reset", func(t *testing.T) {
 		hook.Reset()
@@ -199,6 +228,9 @@ func TestMetricsHook(t *testing.T) {
 			t.Errorf("Reset should zero all counts, got requests=%d, toolCalls=%d, errors=%d",
 				metrics.Requests, metrics.ToolCalls, metrics.ErrorCount)
 		}
+		if len(metrics.ToolStats) != 0 || metrics.GenTimeP50Ms != 0 {
+			t.Errorf("Reset should clear the timings, got %+v", metrics)
+		}
 	})
 }
 
@@ -0,0 +1,138 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// InjectionFinding describes signs of prompt injection in untrusted text
+type InjectionFinding struct {
+	// Score is the confidence that the text is an injection attempt, from 0 to 1
+	Score float64
+	// Reasons explain what was detected, without repeating the text
+	Reasons []string
+	// Matches are the suspicious passages, removed by InjectionStrip
+	Matches []string
+}
+
+// InjectionDetector classifies untrusted text. Detect returns nil when the text looks safe.
+type InjectionDetector interface {
+	Detect(ctx context.Context, text string) (*InjectionFinding, error)
+}
+
+// InjectionDetectorFunc adapts a function, such as a call to a classifier model, to an InjectionDetector
+type InjectionDetectorFunc func(ctx context.Context, text string) (*InjectionFinding, error)
+
+// Detect calls the function
+func (f InjectionDetectorFunc) Detect(ctx context.Context, text string) (*InjectionFinding, error) {
+	return f(ctx, text)
+}
+
+// injectionRule is a heuristic with the weight it adds to the score
+type injectionRule struct {
+	reason  string
+	pattern *regexp.Regexp
+	weight  float64
+}
+
+// sentenceRest extends a match to the end of its sentence, so stripping removes the whole
+// instruction. Periods only end a sentence before whitespace, not in names like example.com.
+const sentenceRest = `(?:[^.!?\n]|[.!?][^\s.!?])*[.!?]?`
+
+// defaultInjectionRules are phrases commonly used to hijack a model
+var defaultInjectionRules = []injectionRule{
+	{"overrides previous instructions", regexp.MustCompile(`(?i)\b(?:ignore|disregard|forget|override)\b[^.!?\n]{0,40}\b(?:previous|prior|above|earlier|preceding|all|any|your)\b[^.!?\n]{0,30}\b(?:instructions?|prompts?|rules|directions|guidelines)\b` + sentenceRest), 0.6},
+	{"issues new instructions", regexp.MustCompile(`(?i)(?:\bnew instructions\s*:|\byour (?:new|real|actual) (?:task|instructions?|goal) (?:is|are)\b|\bfrom now on,? you\b)` + sentenceRest), 0.4},
+	{"reassigns the model's role", regexp.MustCompile(`(?i)\b(?:you are now|pretend (?:to be|you are)|act as (?:an? )?(?:unrestricted|jailbroken|different)|enter (?:developer|god|dan) mode)\b` + sentenceRest), 0.3},
+	{"targets the system prompt", regexp.MustCompile(`(?i)\b(?:reveal|print|repeat|show|output)\b[^.!?\n]{0,30}\b(?:system prompt|your instructions|hidden instructions)\b` + sentenceRest), 0.4},
+	{"contains chat template markers", regexp.MustCompile(`(?i)<\|(?:im_start|im_end|system|user|assistant|endoftext)\|>|\[/?INST\]|<</?SYS>>|(?m:^\s*#{2,}\s*(?:system|instruction)s?\b.*$)`), 0.5},
+	{"impersonates a conversation role", regexp.MustCompile(`(?im)^\s*(?:system|assistant)\s*:.*$`), 0.3},
+	{"imitates the tool output delimiters", regexp.MustCompile(`(?i)</?tool_output\b[^>]*>`), 0.5},
+	{"asks to hide actions from the user", regexp.MustCompile(`(?i)\b(?:do not|don't|never)\s+(?:tell|inform|mention|reveal)\b[^.!?\n]{0,20}\b(?:the user|anyone)\b` + sentenceRest + `|\bwithout (?:telling|informing|notifying) the user\b` + sentenceRest), 0.4},
+	{"requests a tool call", regexp.MustCompile(`\{\s*"(?:tool|tool_calls)"\s*:`), 0.3},
+}
+
+// HeuristicDetector flags text matching phrases commonly used in prompt injections. The
+// score is the sum of the weights of the matching rules, capped at 1.
+type HeuristicDetector struct {
+	rules     []injectionRule
+	threshold float64
+}
+
+// NewHeuristicDetector creates a detector with the built-in rules and a threshold of 0.5
+func NewHeuristicDetector() *HeuristicDetector {
+	return &HeuristicDetector{
+		rules:     append([]injectionRule(nil), defaultInjectionRules...),
+		threshold: 0.5,
+	}
+}
+
+// WithThreshold sets the score from which text is reported
+func (d *HeuristicDetector) WithThreshold(threshold float64) *HeuristicDetector {
+	d.threshold = threshold
+	return d
+}
+
+// WithRule adds a rule; matches of the pattern add weight to the score
+func (d *HeuristicDetector) WithRule(reason string, pattern *regexp.Regexp, weight float64) *HeuristicDetector {
+	d.rules = append(d.rules, injectionRule{reason: reason, pattern: pattern, weight: weight})
+	return d
+}
+
+// Detect scores the text against the rules. JSON text, such as a marshaled tool result, is
+// checked on its decoded string values.
+func (d *HeuristicDetector) Detect(ctx context.Context, text string) (*InjectionFinding, error) {
+	text = readableText(text)
+
+	finding := &InjectionFinding{}
+	for _, rule := range d.rules {
+		matches := rule.pattern.FindAllString(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		finding.Score += rule.weight
+		finding.Reasons = append(finding.Reasons, rule.reason)
+		finding.Matches = append(finding.Matches, matches...)
+	}
+	if finding.Score > 1 {
+		finding.Score = 1
+	}
+	if finding.Score == 0 || finding.Score < d.threshold {
+		return nil, nil
+	}
+	return finding, nil
+}
+
+// readableText returns the string values of JSON text, one per line, or the text itself
+func readableText(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return text
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(trimmed), &value); err != nil {
+		return text
+	}
+	var values []string
+	collectStrings(value, &values)
+	return strings.Join(values, "\n")
+}
+
+// collectStrings appends the strings, including keys, of a decoded JSON value
+func collectStrings(value interface{}, values *[]string) {
+	switch v := value.(type) {
+	case string:
+		*values = append(*values, v)
+	case map[string]interface{}:
+		for key, item := range v {
+			*values = append(*values, key)
+			collectStrings(item, values)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectStrings(item, values)
+		}
+	}
+}
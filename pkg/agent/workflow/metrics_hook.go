@@ -6,27 +6,42 @@ import (
 	"time"
 
 	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/metrics"
 )
 
-// MetricsHook implements Hook for collecting metrics
+// MetricsHook implements Hook for collecting metrics. Hooks do not see streams; wrap the
+// agent's provider with provider.NewStreamMetricsProvider to record them.
 type MetricsHook struct {
 	mu            sync.Mutex
 	requests      int
 	toolCalls     int
 	errorCount    int
 	totalTokens   int
-	generateTimes []time.Duration
-	toolTimes     map[string][]time.Duration
+	generateTimes *metrics.Histogram
+	toolTimes     map[string]*metrics.Histogram
+	// registry receives the timings too, in series shared by all hooks
+	registry *metrics.Registry
 }
 
 // NewMetricsHook creates a new metrics hook
 func NewMetricsHook() *MetricsHook {
 	return &MetricsHook{
-		generateTimes: make([]time.Duration, 0),
-		toolTimes:     make(map[string][]time.Duration),
+		generateTimes: metrics.NewHistogram("agent_generate_seconds", nil),
+		toolTimes:     make(map[string]*metrics.Histogram),
+		registry:      metrics.GetRegistry(),
 	}
 }
 
+// WithMetricsRegistry sets the registry the timings are exported to, as the
+// agent_generate_seconds histogram and the agent_tool_seconds histogram labelled by tool.
+// Hooks share these series. It defaults to metrics.GetRegistry(); nil stops exporting.
+func (h *MetricsHook) WithMetricsRegistry(registry *metrics.Registry) *MetricsHook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.registry = registry
+	return h
+}
+
 // BeforeGenerate is called before generating a response
 func (h *MetricsHook) BeforeGenerate(ctx context.Context, messages []domain.Message) {
 	h.mu.Lock()
@@ -59,7 +74,10 @@ func (h *MetricsHook) AfterGenerate(ctx context.Context, response domain.Respons
 	startTime, ok := getMetricContextValue(ctx, "generateStartTime").(time.Time)
 	if ok {
 		duration := time.Since(startTime)
-		h.generateTimes = append(h.generateTimes, duration)
+		h.generateTimes.ObserveDuration(duration)
+		if h.registry != nil {
+			h.registry.GetOrCreateHistogram("agent_generate_seconds", nil).ObserveDuration(duration)
+		}
 	}
 }
 
@@ -83,11 +101,11 @@ func (h *MetricsHook) AfterToolCall(ctx context.Context, tool string, result int
 	startTime, ok := getMetricContextValue(ctx, "toolStartTime").(time.Time)
 	if ok {
 		duration := time.Since(startTime)
-
-		if _, exists := h.toolTimes[tool]; !exists {
-			h.toolTimes[tool] = make([]time.Duration, 0)
+		h.toolHistogram(tool).ObserveDuration(duration)
+		if h.registry != nil {
+			h.registry.GetOrCreateLabeledHistogram("agent_tool_seconds", metrics.Labels{metrics.LabelTool: tool}, nil).
+				ObserveDuration(duration)
 		}
-		h.toolTimes[tool] = append(h.toolTimes[tool], duration)
 	}
 }
 
@@ -98,6 +116,9 @@ type Metrics struct {
 	ErrorCount       int
 	TotalTokens      int
 	AverageGenTimeMs float64
+	GenTimeP50Ms     float64
+	GenTimeP90Ms     float64
+	GenTimeP99Ms     float64
 	ToolStats        map[string]ToolStats
 }
 
@@ -107,6 +128,9 @@ type ToolStats struct {
 	AverageTimeMs float64
 	FastestCallMs float64
 	SlowestCallMs float64
+	P50Ms         float64
+	P90Ms         float64
+	P99Ms         float64
 }
 
 // GetMetrics returns the collected metrics
@@ -122,41 +146,29 @@ func (h *MetricsHook) GetMetrics() Metrics {
 		ToolStats:   make(map[string]ToolStats),
 	}
 
-	// Calculate average generation time
-	if len(h.generateTimes) > 0 {
-		var total time.Duration
-		for _, t := range h.generateTimes {
-			total += t
-		}
-		metrics.AverageGenTimeMs = float64(total.Milliseconds()) / float64(len(h.generateTimes))
+	// Calculate generation time statistics
+	if h.generateTimes.GetCount() > 0 {
+		metrics.AverageGenTimeMs = h.generateTimes.GetMean() * 1000
+		metrics.GenTimeP50Ms = h.generateTimes.GetQuantile(0.5) * 1000
+		metrics.GenTimeP90Ms = h.generateTimes.GetQuantile(0.9) * 1000
+		metrics.GenTimeP99Ms = h.generateTimes.GetQuantile(0.99) * 1000
 	}
 
 	// Calculate tool statistics
 	for tool, times := range h.toolTimes {
-		if len(times) == 0 {
+		if times.GetCount() == 0 {
 			continue
 		}
 
-		stats := ToolStats{
-			Calls:         len(times),
-			FastestCallMs: float64(times[0].Milliseconds()),
-			SlowestCallMs: float64(times[0].Milliseconds()),
+		metrics.ToolStats[tool] = ToolStats{
+			Calls:         int(times.GetCount()),
+			AverageTimeMs: times.GetMean() * 1000,
+			FastestCallMs: times.GetMin() * 1000,
+			SlowestCallMs: times.GetMax() * 1000,
+			P50Ms:         times.GetQuantile(0.5) * 1000,
+			P90Ms:         times.GetQuantile(0.9) * 1000,
+			P99Ms:         times.GetQuantile(0.99) * 1000,
 		}
-
-		var total time.Duration
-		for _, t := range times {
-			total += t
-
-			if float64(t.Milliseconds()) < stats.FastestCallMs {
-				stats.FastestCallMs = float64(t.Milliseconds())
-			}
-			if float64(t.Milliseconds()) > stats.SlowestCallMs {
-				stats.SlowestCallMs = float64(t.Milliseconds())
-			}
-		}
-
-		stats.AverageTimeMs = float64(total.Milliseconds()) / float64(len(times))
-		metrics.ToolStats[tool] = stats
 	}
 
 	return metrics
@@ -171,8 +183,18 @@ func (h *MetricsHook) Reset() {
 	h.toolCalls = 0
 	h.errorCount = 0
 	h.totalTokens = 0
-	h.generateTimes = make([]time.Duration, 0)
-	h.toolTimes = make(map[string][]time.Duration)
+	h.generateTimes.Reset()
+	h.toolTimes = make(map[string]*metrics.Histogram)
+}
+
+// toolHistogram returns the duration histogram of a tool, creating it on first use
+func (h *MetricsHook) toolHistogram(tool string) *metrics.Histogram {
+	histogram, exists := h.toolTimes[tool]
+	if !exists {
+		histogram = metrics.NewHistogram("agent_tool_seconds", nil)
+		h.toolTimes[tool] = histogram
+	}
+	return histogram
 }
 
 // NotifyToolCall manually increments the tool call counter for testing purposes
@@ -182,10 +204,7 @@ func (h *MetricsHook) NotifyToolCall(tool string, _ interface{}) {
 	h.toolCalls++
 
 	// Also record a dummy tool time for completeness
-	if _, exists := h.toolTimes[tool]; !exists {
-		h.toolTimes[tool] = make([]time.Duration, 0)
-	}
-	h.toolTimes[tool] = append(h.toolTimes[tool], time.Millisecond)
+	h.toolHistogram(tool).ObserveDuration(time.Millisecond)
 }
 
 // Helper functions for storing metrics context values
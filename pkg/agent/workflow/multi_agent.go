@@ -13,6 +13,7 @@ import (
 	"github.com/lexlapax/go-llms/pkg/llm/provider"
 	sdomain "github.com/lexlapax/go-llms/pkg/schema/domain"
 	"github.com/lexlapax/go-llms/pkg/structured/processor"
+	"github.com/lexlapax/go-llms/pkg/util/tracing"
 )
 
 // MultiAgent extends DefaultAgent with optimizations for multi-provider scenarios
@@ -61,7 +62,15 @@ func (a *MultiAgent) RunWithSchema(ctx context.Context, input string, schema *sd
 
 // run is the internal implementation of Run and RunWithSchema
 // This version includes optimizations for multi-provider scenarios
-func (a *MultiAgent) run(ctx context.Context, input string, schema *sdomain.Schema) (interface{}, error) {
+func (a *MultiAgent) run(ctx context.Context, input string, schema *sdomain.Schema) (result interface{}, err error) {
+	ctx, runSpan := a.startRunSpan(ctx)
+	var iterationSpan *tracing.Span
+	defer func() {
+		iterationSpan.End()
+		runSpan.RecordError(err)
+		runSpan.End()
+	}()
+
 	// Prepare the prompt - same as DefaultAgent
 	prompt := input
 	if schema != nil {
@@ -88,7 +97,12 @@ func (a *MultiAgent) run(ctx context.Context, input string, schema *sdomain.Sche
 	// Main agent loop - continue until we have a result or error
 	var finalResponse interface{}
 	maxIterations := 10 // Prevent infinite loops
+	runCtx := ctx
 	for i := 0; i < maxIterations; i++ {
+		// Each iteration's span ends when the next one starts or the run returns
+		iterationSpan.End()
+		ctx, iterationSpan = startIterationSpan(runCtx, i)
+
 		// Call hooks before generate
 		a.notifyBeforeGenerate(ctx, messages)
 
@@ -181,7 +195,7 @@ func (a *MultiAgent) run(ctx context.Context, input string, schema *sdomain.Sche
 		a.notifyBeforeToolCall(ctx, toolCall, params)
 
 		// Execute the tool
-		toolResult, toolErr := tool.Execute(ctx, params)
+		toolResult, toolErr := executeTool(ctx, tool, toolCall, params)
 
 		// Call hooks after tool call
 		a.notifyAfterToolCall(ctx, toolCall, toolResult, toolErr)
@@ -208,6 +222,12 @@ func (a *MultiAgent) run(ctx context.Context, input string, schema *sdomain.Sche
 			}
 		}
 
+		// Check and spotlight the output before it enters the conversation
+		toolRespContent, err = a.toolOutputPolicy.Apply(ctx, toolCall, params, toolRespContent)
+		if err != nil {
+			return nil, err
+		}
+
 		// Add the assistant message and tool result to the conversation
 		messages = append(messages, ldomain.Message{
 			Role:    ldomain.RoleAssistant,
@@ -250,6 +270,8 @@ func (a *MultiAgent) executeMultipleToolsParallel(ctx context.Context, toolNames
 	resultsMutex := sync.Mutex{}
 	errorCount := 0
 	successCount := 0
+	// The first tool output rejected by the tool output policy stops the run
+	var rejectErr error
 
 	// Process each tool in parallel
 	for i, toolName := range toolNames {
@@ -274,7 +296,7 @@ func (a *MultiAgent) executeMultipleToolsParallel(ctx context.Context, toolNames
 			a.notifyBeforeToolCall(ctx, name, params)
 
 			// Execute the tool
-			toolResult, toolErr := tool.Execute(ctx, params)
+			toolResult, toolErr := executeTool(ctx, tool, name, params)
 
 			// Call hooks after tool call
 			a.notifyAfterToolCall(ctx, name, toolResult, toolErr)
@@ -307,6 +329,17 @@ func (a *MultiAgent) executeMultipleToolsParallel(ctx context.Context, toolNames
 				}
 			}
 
+			// Check and spotlight the output before it enters the conversation
+			toolRespContent, policyErr := a.toolOutputPolicy.Apply(ctx, name, params, toolRespContent)
+			if policyErr != nil {
+				resultsMutex.Lock()
+				if rejectErr == nil {
+					rejectErr = policyErr
+				}
+				resultsMutex.Unlock()
+				return
+			}
+
 			// Add this tool's result to the combined output
 			resultsMutex.Lock()
 			allToolsOutput.WriteString(fmt.Sprintf("Tool '%s' result: %s\n\n", name, toolRespContent))
@@ -317,6 +350,10 @@ func (a *MultiAgent) executeMultipleToolsParallel(ctx context.Context, toolNames
 	// Wait for all tools to complete
 	wg.Wait()
 
+	if rejectErr != nil {
+		return "", rejectErr
+	}
+
 	// If all tools failed, return an error
 	if errorCount == len(toolNames) {
 		return "", fmt.Errorf("all tools failed to execute")
@@ -340,7 +377,7 @@ func (a *MultiAgent) executeSingleTool(ctx context.Context, toolName string, par
 	a.notifyBeforeToolCall(ctx, toolName, params)
 
 	// Execute the tool
-	toolResult, toolErr := tool.Execute(ctx, params)
+	toolResult, toolErr := executeTool(ctx, tool, toolName, params)
 
 	// Call hooks after tool call
 	a.notifyAfterToolCall(ctx, toolName, toolResult, toolErr)
@@ -366,6 +403,12 @@ func (a *MultiAgent) executeSingleTool(ctx context.Context, toolName string, par
 		}
 	}
 
+	// Check and spotlight the output before it enters the conversation
+	toolRespContent, err := a.toolOutputPolicy.Apply(ctx, toolName, params, toolRespContent)
+	if err != nil {
+		return "", err
+	}
+
 	// Add this tool's result to the combined output
 	output.WriteString(fmt.Sprintf("Tool '%s' result: %s\n\n", toolName, toolRespContent))
 
@@ -209,7 +209,7 @@ func (e *ToolExecutor) executeTool(ctx context.Context, toolName string, params
 
 	// Execute the tool with timeout
 	startTime := time.Now()
-	toolResult, toolErr := tool.Execute(ctx, params)
+	toolResult, toolErr := executeTool(ctx, tool, toolName, params)
 	elapsed := time.Since(startTime)
 
 	// Create result
@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"context"
+
+	"github.com/lexlapax/go-llms/pkg/agent/domain"
+	"github.com/lexlapax/go-llms/pkg/util/tracing"
+)
+
+// WithTracer records a span for each run, loop iteration and tool execution of the agent.
+// Wrap the agent's provider with provider.NewTracedProvider to also record LLM calls.
+// Without a tracer, the agent still records spans when the run's context carries one.
+func (a *DefaultAgent) WithTracer(tracer *tracing.Tracer) domain.Agent {
+	a.tracer = tracer
+	return a
+}
+
+// startRunSpan starts the span of an agent run
+func (a *DefaultAgent) startRunSpan(ctx context.Context) (context.Context, *tracing.Span) {
+	if a.tracer != nil && tracing.TracerFromContext(ctx) == nil {
+		ctx = tracing.ContextWithTracer(ctx, a.tracer)
+	}
+	ctx, span := tracing.Start(ctx, "invoke_agent", tracing.SpanKindInternal)
+	span.SetAttribute("gen_ai.operation.name", "invoke_agent")
+	span.SetAttribute("agent.tools", len(a.tools))
+	if a.modelName != "" {
+		span.SetAttribute("gen_ai.request.model", a.modelName)
+	}
+	return ctx, span
+}
+
+// startIterationSpan starts the span of an agent loop iteration
+func startIterationSpan(ctx context.Context, iteration int) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, "agent.iteration", tracing.SpanKindInternal)
+	span.SetAttribute("agent.iteration", iteration)
+	return ctx, span
+}
+
+// executeTool executes a tool within a span
+func executeTool(ctx context.Context, tool domain.Tool, name string, params interface{}) (interface{}, error) {
+	ctx, span := tracing.Start(ctx, "execute_tool "+name, tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("gen_ai.operation.name", "execute_tool")
+	span.SetAttribute("gen_ai.tool.name", name)
+
+	result, err := tool.Execute(ctx, params)
+	span.RecordError(err)
+	return result, err
+}
@@ -0,0 +1,134 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/agent/tools"
+	ldomain "github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/llm/provider"
+	"github.com/lexlapax/go-llms/pkg/util/tracing"
+)
+
+// spanRecorder keeps exported spans in memory
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) ExportSpans(ctx context.Context, spans []tracing.SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *spanRecorder) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestAgentTracing(t *testing.T) {
+	responses := []ldomain.Response{
+		{Content: `{"tool": "echo", "params": {"text": "hi"}}`},
+		{Content: "Done"},
+	}
+	calls := 0
+	mockProvider := provider.NewMockProvider().WithGenerateMessageFunc(
+		func(ctx context.Context, messages []ldomain.Message, options ...ldomain.Option) (ldomain.Response, error) {
+			resp := responses[calls]
+			calls++
+			return resp, nil
+		})
+
+	recorder := &spanRecorder{}
+	tracer := tracing.NewTracer("agent-test", recorder)
+	agent := NewAgent(provider.NewTracedProvider(mockProvider, nil))
+	agent.WithTracer(tracer)
+	agent.AddTool(tools.NewTool("echo", "Echoes text", func(params struct {
+		Text string `json:"text"`
+	}) (string, error) {
+		return params.Text, nil
+	}, nil))
+
+	if _, err := agent.Run(context.Background(), "Say hi"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	byName := make(map[string][]tracing.SpanData)
+	for _, span := range recorder.spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	if len(byName["invoke_agent"]) != 1 || len(byName["agent.iteration"]) != 2 ||
+		len(byName["chat"]) != 2 || len(byName["execute_tool echo"]) != 1 {
+		t.Fatalf("Unexpected spans: %v", byName)
+	}
+
+	root := byName["invoke_agent"][0]
+	first := byName["agent.iteration"][0]
+	if first.ParentSpanID != root.SpanID {
+		t.Errorf("Expected iterations to be children of the run")
+	}
+	if tool := byName["execute_tool echo"][0]; tool.ParentSpanID != first.SpanID || tool.Attributes["gen_ai.tool.name"] != "echo" {
+		t.Errorf("Expected the tool span in the first iteration, got %+v", tool)
+	}
+	if chat := byName["chat"][0]; chat.ParentSpanID != first.SpanID || chat.Attributes["gen_ai.system"] != "mock" {
+		t.Errorf("Expected the provider span in the first iteration, got %+v", chat)
+	}
+}
+
+func TestCachedAgentTracing(t *testing.T) {
+	mockProvider := provider.NewMockProvider().WithGenerateMessageFunc(
+		func(ctx context.Context, messages []ldomain.Message, options ...ldomain.Option) (ldomain.Response, error) {
+			// Answer once the tool result is in the conversation
+			if last := messages[len(messages)-1]; strings.HasPrefix(last.Content[0].Text, "Tool ") {
+				return ldomain.Response{Content: "Done"}, nil
+			}
+			return ldomain.Response{Content: `{"tool": "echo", "params": {"text": "hi"}}`}, nil
+		})
+
+	recorder := &spanRecorder{}
+	tracer := tracing.NewTracer("agent-test", recorder)
+	agent := NewCachedAgent(mockProvider)
+	agent.WithTracer(tracer)
+	agent.AddTool(tools.NewTool("echo", "Echoes text", func(params struct {
+		Text string `json:"text"`
+	}) (string, error) {
+		return params.Text, nil
+	}, nil))
+
+	// The second run is served from the cache, but still executes the tool
+	for i := 0; i < 2; i++ {
+		if _, err := agent.Run(context.Background(), "Say hi"); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if agent.GetCacheStats()["hits"] != 1 {
+		t.Fatalf("Expected the second run to hit the cache, got %v", agent.GetCacheStats())
+	}
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	byName := make(map[string][]tracing.SpanData)
+	for _, span := range recorder.spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	if len(byName["invoke_agent"]) != 2 || len(byName["agent.iteration"]) != 3 || len(byName["execute_tool echo"]) != 2 {
+		t.Fatalf("Unexpected spans: %v", byName)
+	}
+
+	iterations := make(map[tracing.SpanID]bool)
+	for _, iteration := range byName["agent.iteration"] {
+		iterations[iteration.SpanID] = true
+	}
+	for _, tool := range byName["execute_tool echo"] {
+		if !iterations[tool.ParentSpanID] {
+			t.Errorf("Expected tool spans within an iteration, got %+v", tool)
+		}
+	}
+}
@@ -58,6 +58,9 @@ var (
 
 	// ErrUnsupportedContentType is returned when a provider doesn't support a specific content type.
 	ErrUnsupportedContentType = errors.New("content type not supported by provider")
+
+	// ErrMediaLimitExceeded is returned when media in a request exceeds the provider's size or count limits.
+	ErrMediaLimitExceeded = errors.New("media exceeds provider limits")
 )
 
 // ProviderError represents an error from an LLM provider with additional context.
@@ -204,3 +207,96 @@ func NewUnsupportedContentTypeError(provider string, contentType ContentType) *U
 func IsUnsupportedContentTypeError(err error) bool {
 	return errors.Is(err, ErrUnsupportedContentType)
 }
+
+// ContentFilterSource identifies what a content filter blocked
+type ContentFilterSource string
+
+const (
+	// ContentFilterPrompt means the input was blocked before generation
+	ContentFilterPrompt ContentFilterSource = "prompt"
+	// ContentFilterResponse means the generated output was blocked or refused
+	ContentFilterResponse ContentFilterSource = "response"
+)
+
+// SafetyRating is a provider's assessment of one harm category
+type SafetyRating struct {
+	// Category is the provider's category name (e.g., "HARM_CATEGORY_HARASSMENT", "hate")
+	Category string
+	// Probability is the likelihood reported by the provider (e.g., "HIGH"), if any
+	Probability string
+	// Severity is the severity reported by the provider (e.g., "medium"), if any
+	Severity string
+	// Blocked reports whether this category caused the block
+	Blocked bool
+}
+
+// ContentFilteredError is returned when a provider blocks a prompt or response for safety reasons,
+// or the model refuses to answer. It wraps ErrContentFiltered.
+type ContentFilteredError struct {
+	// Provider is the name of the LLM provider
+	Provider string
+	// Operation is the operation that failed
+	Operation string
+	// StatusCode is the HTTP status code if the block was reported as an HTTP error
+	StatusCode int
+	// Source reports whether the prompt or the response was blocked
+	Source ContentFilterSource
+	// Reason is the provider's block reason (e.g., "SAFETY", "content_filter", "refusal")
+	Reason string
+	// Message is the provider's error message or the model's refusal text
+	Message string
+	// Ratings holds the provider's per-category assessments
+	Ratings []SafetyRating
+}
+
+// Error implements the error interface
+func (e *ContentFilteredError) Error() string {
+	msg := fmt.Sprintf("%s %s: %s content filtered", e.Provider, e.Operation, e.Source)
+	if e.Reason != "" {
+		msg += fmt.Sprintf(" (%s)", e.Reason)
+	}
+	if categories := e.BlockedCategories(); len(categories) > 0 {
+		msg += fmt.Sprintf(" [%s]", strings.Join(categories, ", "))
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *ContentFilteredError) Unwrap() error {
+	return ErrContentFiltered
+}
+
+// BlockedCategories returns the categories that caused the block
+func (e *ContentFilteredError) BlockedCategories() []string {
+	var categories []string
+	for _, rating := range e.Ratings {
+		if rating.Blocked {
+			categories = append(categories, rating.Category)
+		}
+	}
+	return categories
+}
+
+// NewContentFilteredError creates a new ContentFilteredError
+func NewContentFilteredError(provider, operation string, source ContentFilterSource, reason, message string, ratings []SafetyRating) *ContentFilteredError {
+	return &ContentFilteredError{
+		Provider:  provider,
+		Operation: operation,
+		Source:    source,
+		Reason:    reason,
+		Message:   message,
+		Ratings:   ratings,
+	}
+}
+
+// AsContentFilteredError returns the ContentFilteredError in the error chain, if any
+func AsContentFilteredError(err error) (*ContentFilteredError, bool) {
+	var filtered *ContentFilteredError
+	if errors.As(err, &filtered) {
+		return filtered, true
+	}
+	return nil, false
+}
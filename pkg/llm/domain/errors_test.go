@@ -5,6 +5,7 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -66,3 +67,25 @@ func TestContentTypeSupport(t *testing.T) {
 	assert.True(t, IsUnsupportedContentTypeError(videoErr))
 	assert.True(t, IsUnsupportedContentTypeError(audioErr))
 }
+
+func TestContentFilteredError(t *testing.T) {
+	err := NewContentFilteredError("gemini", "GenerateMessage", ContentFilterPrompt, "SAFETY", "", []SafetyRating{
+		{Category: "HARM_CATEGORY_HARASSMENT", Probability: "HIGH", Blocked: true},
+		{Category: "HARM_CATEGORY_HATE_SPEECH", Probability: "NEGLIGIBLE"},
+	})
+
+	assert.True(t, IsContentFilteredError(err))
+	assert.True(t, errors.Is(err, ErrContentFiltered))
+	assert.Equal(t, []string{"HARM_CATEGORY_HARASSMENT"}, err.BlockedCategories())
+	assert.Contains(t, err.Error(), "prompt content filtered (SAFETY) [HARM_CATEGORY_HARASSMENT]")
+
+	// The structured error can be recovered from a wrapped error
+	wrapped := fmt.Errorf("generation failed: %w", err)
+	filtered, ok := AsContentFilteredError(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, "gemini", filtered.Provider)
+	assert.Len(t, filtered.Ratings, 2)
+
+	_, ok = AsContentFilteredError(errors.New("other"))
+	assert.False(t, ok)
+}
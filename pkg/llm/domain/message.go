@@ -5,6 +5,9 @@ package domain
 
 import (
 	"encoding/base64"
+	"math"
+	"strings"
+	"time"
 )
 
 // Role represents the role of a message sender
@@ -34,6 +37,8 @@ type SourceType string
 const (
 	SourceTypeBase64 SourceType = "base64"
 	SourceTypeURL    SourceType = "url"
+	// SourceTypeFile references a file uploaded with a provider's File API
+	SourceTypeFile SourceType = "file"
 )
 
 // SourceInfo represents the source of media content
@@ -42,6 +47,7 @@ type SourceInfo struct {
 	MediaType string     `json:"media_type,omitempty"` // MIME type
 	Data      string     `json:"data,omitempty"`       // Base64 encoded
 	URL       string     `json:"url,omitempty"`
+	FileID    string     `json:"file_id,omitempty"` // Provider file reference (file ID or URI)
 }
 
 // ImageContent represents an image in a message
@@ -52,8 +58,35 @@ type ImageContent struct {
 // FileContent represents a file in a message
 type FileContent struct {
 	FileName string `json:"file_name"`
-	FileData string `json:"file_data"` // Base64 encoded
-	MimeType string `json:"mime_type"` // MIME type
+	FileData string `json:"file_data"`         // Base64 encoded
+	MimeType string `json:"mime_type"`         // MIME type
+	FileID   string `json:"file_id,omitempty"` // Provider file reference, used instead of FileData
+}
+
+// UploadedFile describes a file uploaded with a provider's File API
+type UploadedFile struct {
+	// Provider is the name of the provider that stores the file
+	Provider string `json:"provider"`
+	// ID identifies the file for list and delete calls
+	ID string `json:"id"`
+	// URI is the file's URI, for providers that reference files by URI (Gemini)
+	URI string `json:"uri,omitempty"`
+	// Name is the file name given at upload
+	Name      string `json:"name,omitempty"`
+	MimeType  string `json:"mime_type,omitempty"`
+	SizeBytes int64  `json:"size_bytes,omitempty"`
+	// CreatedAt is when the file was uploaded
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the provider deletes the file, if it expires
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Reference returns the value used to reference the file in a message
+func (f UploadedFile) Reference() string {
+	if f.URI != "" {
+		return f.URI
+	}
+	return f.ID
 }
 
 // VideoContent represents a video in a message
@@ -240,6 +273,52 @@ func NewAudioMessage(role Role, audioData []byte, mimeType string, text string)
 	}
 }
 
+// NewUploadedFileMessage creates a message referencing an uploaded file and optional text.
+// The content type is chosen from the file's MIME type.
+func NewUploadedFileMessage(role Role, file UploadedFile, text string) Message {
+	parts := []ContentPart{UploadedFilePart(file)}
+
+	if text != "" {
+		parts = append(parts, ContentPart{
+			Type: ContentTypeText,
+			Text: text,
+		})
+	}
+
+	return Message{
+		Role:    role,
+		Content: parts,
+	}
+}
+
+// UploadedFilePart creates a content part referencing an uploaded file, with the content
+// type chosen from the file's MIME type
+func UploadedFilePart(file UploadedFile) ContentPart {
+	source := SourceInfo{
+		Type:      SourceTypeFile,
+		MediaType: file.MimeType,
+		FileID:    file.Reference(),
+	}
+
+	switch {
+	case strings.HasPrefix(file.MimeType, "image/"):
+		return ContentPart{Type: ContentTypeImage, Image: &ImageContent{Source: source}}
+	case strings.HasPrefix(file.MimeType, "video/"):
+		return ContentPart{Type: ContentTypeVideo, Video: &VideoContent{Source: source}}
+	case strings.HasPrefix(file.MimeType, "audio/"):
+		return ContentPart{Type: ContentTypeAudio, Audio: &AudioContent{Source: source}}
+	default:
+		return ContentPart{
+			Type: ContentTypeFile,
+			File: &FileContent{
+				FileName: file.Name,
+				MimeType: file.MimeType,
+				FileID:   file.Reference(),
+			},
+		}
+	}
+}
+
 // Token represents a token in a streamed response
 type Token struct {
 	Text     string `json:"text"`
@@ -249,6 +328,140 @@ type Token struct {
 // Response represents a complete response from an LLM
 type Response struct {
 	Content string `json:"content"`
+	// ID identifies the response at the provider. For the OpenAI Responses API it can be
+	// passed to WithPreviousResponseID to continue the conversation.
+	ID string `json:"id,omitempty"`
+	// Reasoning is the summary of the model's reasoning, for reasoning models that provide one
+	Reasoning string `json:"reasoning,omitempty"`
+	// Choices holds every candidate returned by the provider. It is only set when
+	// multiple candidates or log probabilities were requested; Content always holds
+	// the first choice.
+	Choices []Choice `json:"choices,omitempty"`
+	// SystemFingerprint identifies the backend configuration that produced the response.
+	// Seeded requests are only reproducible while the fingerprint stays the same.
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	// Citations link parts of Content to the source passages they are based on.
+	// They are only set when citations were requested with WithCitations, or for
+	// Gemini when the response is grounded with WithGoogleSearch.
+	Citations []Citation `json:"citations,omitempty"`
+	// Grounding describes the web searches the response is grounded in, if any
+	Grounding *Grounding `json:"grounding,omitempty"`
+}
+
+// Citation types describe how a citation locates the cited passage in its document
+const (
+	// CitationCharLocation locates the passage by character offsets in a text document
+	CitationCharLocation = "char_location"
+	// CitationPageLocation locates the passage by page numbers in a PDF document
+	CitationPageLocation = "page_location"
+	// CitationContentBlockLocation locates the passage by content block indices in a custom document
+	CitationContentBlockLocation = "content_block_location"
+	// CitationWebLocation identifies the passage only by the URL of its web page
+	CitationWebLocation = "web_location"
+)
+
+// Citation is a quoted passage of a source document that supports part of a response
+type Citation struct {
+	// Type describes the unit of Start and End (one of the Citation*Location constants)
+	Type string `json:"type"`
+	// CitedText is the quoted passage from the document
+	CitedText string `json:"cited_text"`
+	// DocumentIndex is the position of the cited document among the documents in the request.
+	// For grounded web citations it is the position of the source in Response.Grounding.Sources,
+	// and -1 for web citations without a grounding source.
+	DocumentIndex int `json:"document_index"`
+	// DocumentTitle is the title of the cited document, if it has one
+	DocumentTitle string `json:"document_title,omitempty"`
+	// URL is the address of a cited web page
+	URL string `json:"url,omitempty"`
+	// Start and End locate the passage in the document. Character and content block
+	// ranges are zero-based and exclusive of End; page ranges are one-based and exclusive of End.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// ResponseStart and ResponseEnd are the byte offsets of the supported text in Response.Content
+	ResponseStart int `json:"response_start"`
+	ResponseEnd   int `json:"response_end"`
+}
+
+// Grounding describes the web searches a response is grounded in
+type Grounding struct {
+	// Queries are the search queries the model ran
+	Queries []string `json:"queries,omitempty"`
+	// Sources are the web pages the response is based on
+	Sources []GroundingSource `json:"sources,omitempty"`
+	// SearchEntryPoint is HTML that renders the search suggestions. Google requires it to be
+	// displayed alongside responses grounded with Google Search.
+	SearchEntryPoint string `json:"search_entry_point,omitempty"`
+}
+
+// GroundingSource is a web page a grounded response is based on
+type GroundingSource struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+}
+
+// Choice is one candidate response
+type Choice struct {
+	Index        int            `json:"index"`
+	Content      string         `json:"content"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	Logprobs     []TokenLogprob `json:"logprobs,omitempty"`
+}
+
+// TokenLogprob is the log probability of a generated token
+type TokenLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	// TopLogprobs lists the most likely tokens at this position, when requested
+	TopLogprobs []TopLogprob `json:"top_logprobs,omitempty"`
+}
+
+// TopLogprob is an alternative token and its log probability
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+}
+
+// TotalLogprob returns the sum of the token log probabilities, i.e. the log probability of the whole choice
+func (c Choice) TotalLogprob() float64 {
+	total := 0.0
+	for _, lp := range c.Logprobs {
+		total += lp.Logprob
+	}
+	return total
+}
+
+// AverageLogprob returns the mean token log probability, which compares choices of different lengths.
+// It returns 0 if no log probabilities are available.
+func (c Choice) AverageLogprob() float64 {
+	if len(c.Logprobs) == 0 {
+		return 0
+	}
+	return c.TotalLogprob() / float64(len(c.Logprobs))
+}
+
+// Confidence returns the geometric mean token probability (0.0-1.0), or 0 if no log probabilities are available
+func (c Choice) Confidence() float64 {
+	if len(c.Logprobs) == 0 {
+		return 0
+	}
+	return math.Exp(c.AverageLogprob())
+}
+
+// BestChoice returns the choice with the highest average log probability. Without log
+// probabilities it returns the first choice; without choices it returns Content.
+func (r Response) BestChoice() Choice {
+	if len(r.Choices) == 0 {
+		return Choice{Content: r.Content}
+	}
+
+	best := r.Choices[0]
+	for _, choice := range r.Choices[1:] {
+		if len(choice.Logprobs) > 0 && (len(best.Logprobs) == 0 || choice.AverageLogprob() > best.AverageLogprob()) {
+			best = choice
+		}
+	}
+	return best
 }
 
 // ResponseStream represents a stream of tokens from an LLM
@@ -152,3 +152,60 @@ func TestURLBasedMessage(t *testing.T) {
 	assert.Equal(t, ContentTypeText, msg.Content[1].Type)
 	assert.Equal(t, text, msg.Content[1].Text)
 }
+
+func TestChoiceLogprobs(t *testing.T) {
+	choice := Choice{Logprobs: []TokenLogprob{{Token: "a", Logprob: -0.5}, {Token: "b", Logprob: -1.5}}}
+	assert.InDelta(t, -2.0, choice.TotalLogprob(), 1e-9)
+	assert.InDelta(t, -1.0, choice.AverageLogprob(), 1e-9)
+	assert.InDelta(t, 0.3679, choice.Confidence(), 1e-4)
+
+	empty := Choice{Content: "no logprobs"}
+	assert.Equal(t, 0.0, empty.AverageLogprob())
+	assert.Equal(t, 0.0, empty.Confidence())
+
+	// Without choices, the best choice is the content
+	assert.Equal(t, "text", Response{Content: "text"}.BestChoice().Content)
+
+	// Choices without log probabilities fall back to the first choice
+	response := Response{Content: "first", Choices: []Choice{{Index: 0, Content: "first"}, {Index: 1, Content: "second"}}}
+	assert.Equal(t, "first", response.BestChoice().Content)
+
+	response.Choices[1].Logprobs = []TokenLogprob{{Token: "second", Logprob: -0.1}}
+	assert.Equal(t, "second", response.BestChoice().Content)
+}
+
+func TestResponsePoolPutClearsResponse(t *testing.T) {
+	resp := &Response{
+		Content:   "answer",
+		ID:        "resp_1",
+		Reasoning: "summary",
+		Citations: []Citation{{Type: CitationCharLocation, CitedText: "quote"}},
+		Grounding: &Grounding{Queries: []string{"query"}},
+	}
+	NewResponsePool().Put(resp)
+
+	assert.Equal(t, Response{}, *resp)
+}
+
+func TestNewUploadedFileMessage(t *testing.T) {
+	video := UploadedFile{Provider: "gemini", ID: "files/abc", URI: "https://example.test/files/abc", MimeType: "video/mp4"}
+	msg := NewUploadedFileMessage(RoleUser, video, "What happens?")
+
+	assert.Equal(t, 2, len(msg.Content))
+	assert.Equal(t, ContentTypeVideo, msg.Content[0].Type)
+	assert.Equal(t, SourceTypeFile, msg.Content[0].Video.Source.Type)
+	assert.Equal(t, video.URI, msg.Content[0].Video.Source.FileID)
+	assert.Equal(t, "video/mp4", msg.Content[0].Video.Source.MediaType)
+
+	// Files without a URI are referenced by ID, and non-media types become file parts
+	pdf := UploadedFile{Provider: "openai", ID: "file-123", Name: "report.pdf", MimeType: "application/pdf"}
+	part := UploadedFilePart(pdf)
+	assert.Equal(t, ContentTypeFile, part.Type)
+	assert.Equal(t, "file-123", part.File.FileID)
+	assert.Equal(t, "report.pdf", part.File.FileName)
+	assert.Empty(t, part.File.FileData)
+
+	image := UploadedFilePart(UploadedFile{ID: "file_1", MimeType: "image/png"})
+	assert.Equal(t, ContentTypeImage, image.Type)
+	assert.Equal(t, "file_1", image.Image.Source.FileID)
+}
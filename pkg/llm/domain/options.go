@@ -12,6 +12,40 @@ type ProviderOptions struct {
 	FrequencyPenalty float64
 	PresencePenalty  float64
 	Model            string
+	// CandidateCount is the number of alternative responses to generate (0 or 1 for a single response)
+	CandidateCount int
+	// Logprobs requests the log probability of each generated token
+	Logprobs bool
+	// TopLogprobs is the number of most likely alternatives to return for each token position
+	TopLogprobs int
+	// Seed requests best-effort deterministic sampling (nil leaves sampling unseeded)
+	Seed *int64
+	// Citations asks the provider to cite the documents its answer is based on
+	Citations bool
+	// GoogleSearch grounds Gemini responses with Google Search results
+	GoogleSearch bool
+	// PreviousResponseID continues a stateful OpenAI Responses conversation
+	PreviousResponseID string
+	// ReasoningEffort guides how much reasoning models think before answering ("low", "medium", "high")
+	ReasoningEffort string
+	// BuiltinTools are provider-hosted tools, such as OpenAI's web search, in the provider's format
+	BuiltinTools []map[string]interface{}
+
+	// Per-call overrides, merged on top of the provider's construction-time settings
+	// without modifying the provider
+
+	// BaseURL replaces the provider's base URL for this call
+	BaseURL string
+	// Headers are extra HTTP headers sent with this call
+	Headers map[string]string
+	// Organization replaces the OpenAI organization for this call
+	Organization string
+	// User identifies the end user (OpenAI "user", Anthropic metadata "user_id")
+	User string
+	// Metadata is merged into the Anthropic request metadata
+	Metadata map[string]string
+	// SafetySettings replace the Gemini safety settings for the same categories
+	SafetySettings []map[string]interface{}
 }
 
 // DefaultOptions returns the default provider options
@@ -74,3 +108,132 @@ func WithModel(model string) Option {
 		o.Model = model
 	}
 }
+
+// WithCandidateCount sets the number of alternative responses to generate.
+// The alternatives are returned in Response.Choices.
+func WithCandidateCount(count int) Option {
+	return func(o *ProviderOptions) {
+		o.CandidateCount = count
+	}
+}
+
+// WithLogprobs requests log probabilities for the generated tokens.
+// The log probabilities are returned in Response.Choices.
+func WithLogprobs(enabled bool) Option {
+	return func(o *ProviderOptions) {
+		o.Logprobs = enabled
+	}
+}
+
+// WithTopLogprobs requests log probabilities along with the given number of most
+// likely alternatives for each token position
+func WithTopLogprobs(count int) Option {
+	return func(o *ProviderOptions) {
+		o.Logprobs = true
+		o.TopLogprobs = count
+	}
+}
+
+// WithSeed requests deterministic sampling with the given seed, for providers that support it.
+// Determinism is best effort: compare Response.SystemFingerprint to detect backend changes.
+func WithSeed(seed int64) Option {
+	return func(o *ProviderOptions) {
+		o.Seed = &seed
+	}
+}
+
+// WithCitations enables citations for document content, for providers that support them.
+// The cited passages are returned in Response.Citations.
+func WithCitations(enabled bool) Option {
+	return func(o *ProviderOptions) {
+		o.Citations = enabled
+	}
+}
+
+// WithGoogleSearch enables Gemini's built-in Google Search tool, which grounds the response
+// in search results. The search queries and sources are returned in Response.Grounding.
+func WithGoogleSearch(enabled bool) Option {
+	return func(o *ProviderOptions) {
+		o.GoogleSearch = enabled
+	}
+}
+
+// WithPreviousResponseID continues the conversation of an earlier OpenAI Responses API call.
+// The provider keeps the conversation state, so only the new messages need to be sent.
+func WithPreviousResponseID(id string) Option {
+	return func(o *ProviderOptions) {
+		o.PreviousResponseID = id
+	}
+}
+
+// WithReasoningEffort sets how much reasoning models think before answering
+func WithReasoningEffort(effort string) Option {
+	return func(o *ProviderOptions) {
+		o.ReasoningEffort = effort
+	}
+}
+
+// WithBuiltinTools enables provider-hosted tools, such as {"type": "web_search_preview"}
+// for the OpenAI Responses API. Tools are appended when the option is used more than once.
+func WithBuiltinTools(tools ...map[string]interface{}) Option {
+	return func(o *ProviderOptions) {
+		o.BuiltinTools = append(o.BuiltinTools, tools...)
+	}
+}
+
+// WithBaseURL sends this call to a different base URL than the provider's default
+func WithBaseURL(url string) Option {
+	return func(o *ProviderOptions) {
+		o.BaseURL = url
+	}
+}
+
+// WithHeaders adds HTTP headers to this call. Repeated use merges the headers, and
+// headers set here take precedence over the ones the provider sets itself.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *ProviderOptions) {
+		o.Headers = mergeStringMaps(o.Headers, headers)
+	}
+}
+
+// WithOrganization sets the OpenAI organization for this call
+func WithOrganization(organization string) Option {
+	return func(o *ProviderOptions) {
+		o.Organization = organization
+	}
+}
+
+// WithUser identifies the end user making this call, for provider abuse monitoring
+func WithUser(user string) Option {
+	return func(o *ProviderOptions) {
+		o.User = user
+	}
+}
+
+// WithMetadata adds request metadata (Anthropic) for this call. Repeated use merges the metadata.
+func WithMetadata(metadata map[string]string) Option {
+	return func(o *ProviderOptions) {
+		o.Metadata = mergeStringMaps(o.Metadata, metadata)
+	}
+}
+
+// WithSafetySettings sets Gemini safety settings for this call. Each setting replaces
+// the provider's setting for the same category; other categories keep their defaults.
+func WithSafetySettings(settings []map[string]interface{}) Option {
+	return func(o *ProviderOptions) {
+		o.SafetySettings = append(o.SafetySettings, settings...)
+	}
+}
+
+// mergeStringMaps returns a new map with the entries of base overlaid by extra,
+// leaving both inputs untouched
+func mergeStringMaps(base, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
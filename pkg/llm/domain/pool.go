@@ -58,6 +58,12 @@ func (p *ResponsePool) Put(resp *Response) {
 		// For smaller content, simple assignment is faster
 		resp.Content = ""
 	}
+	resp.ID = ""
+	resp.Reasoning = ""
+	resp.Choices = nil
+	resp.SystemFingerprint = ""
+	resp.Citations = nil
+	resp.Grounding = nil
 
 	p.pool.Put(resp)
 }
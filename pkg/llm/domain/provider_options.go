@@ -1,7 +1,11 @@
 package domain
 
 import (
+	"log/slog"
 	"net/http"
+	"regexp"
+
+	"github.com/lexlapax/go-llms/pkg/util/httplog"
 )
 
 // ProviderOption is the base interface for all provider options
@@ -227,6 +231,81 @@ func (o *HeadersOption) ApplyToMock(provider interface{}) {
 	}
 }
 
+// AutoUploadOption uploads inline media larger than ThresholdBytes with the provider's
+// File API and sends a file reference instead of the base64 data
+type AutoUploadOption struct {
+	ThresholdBytes int
+}
+
+// NewAutoUploadOption creates a new AutoUploadOption
+func NewAutoUploadOption(thresholdBytes int) *AutoUploadOption {
+	return &AutoUploadOption{ThresholdBytes: thresholdBytes}
+}
+
+func (o *AutoUploadOption) ProviderType() string { return "all" }
+
+func (o *AutoUploadOption) ApplyToOpenAI(provider interface{}) {
+	if p, ok := provider.(interface{ SetAutoUploadThreshold(bytes int) }); ok {
+		p.SetAutoUploadThreshold(o.ThresholdBytes)
+	}
+}
+
+func (o *AutoUploadOption) ApplyToAnthropic(provider interface{}) {
+	if p, ok := provider.(interface{ SetAutoUploadThreshold(bytes int) }); ok {
+		p.SetAutoUploadThreshold(o.ThresholdBytes)
+	}
+}
+
+func (o *AutoUploadOption) ApplyToGemini(provider interface{}) {
+	if p, ok := provider.(interface{ SetAutoUploadThreshold(bytes int) }); ok {
+		p.SetAutoUploadThreshold(o.ThresholdBytes)
+	}
+}
+
+func (o *AutoUploadOption) ApplyToMock(provider interface{}) {
+	// The mock provider has no File API, so inline media is always sent as is
+}
+
+// RequestLoggingOption logs the provider's HTTP requests and responses with log/slog at
+// debug level. API keys and authentication headers are always redacted; message content
+// is redacted for the patterns of the Redactor.
+type RequestLoggingOption struct {
+	Logger   *slog.Logger
+	Redactor *httplog.Redactor
+}
+
+// NewRequestLoggingOption creates a new RequestLoggingOption, redacting the given content
+// patterns such as httplog.EmailPattern. A nil logger logs to slog.Default().
+func NewRequestLoggingOption(logger *slog.Logger, contentPatterns ...*regexp.Regexp) *RequestLoggingOption {
+	return &RequestLoggingOption{Logger: logger, Redactor: httplog.NewRedactor(contentPatterns...)}
+}
+
+func (o *RequestLoggingOption) ProviderType() string { return "all" }
+
+func (o *RequestLoggingOption) ApplyToOpenAI(provider interface{}) {
+	o.apply(provider)
+}
+
+func (o *RequestLoggingOption) ApplyToAnthropic(provider interface{}) {
+	o.apply(provider)
+}
+
+func (o *RequestLoggingOption) ApplyToGemini(provider interface{}) {
+	o.apply(provider)
+}
+
+func (o *RequestLoggingOption) ApplyToMock(provider interface{}) {
+	// The mock provider makes no HTTP requests
+}
+
+func (o *RequestLoggingOption) apply(provider interface{}) {
+	if p, ok := provider.(interface {
+		SetRequestLogging(logger *slog.Logger, redactor *httplog.Redactor)
+	}); ok {
+		p.SetRequestLogging(o.Logger, o.Redactor)
+	}
+}
+
 // ModelOption sets the model for the provider
 type ModelOption struct {
 	Model string
@@ -283,6 +362,29 @@ func (o *OpenAIOrganizationOption) ApplyToOpenAI(provider interface{}) {
 	}
 }
 
+// OpenAICompatibilityOption selects a named compatibility profile for OpenAI-compatible
+// servers such as OpenRouter, Groq, vLLM or LM Studio
+type OpenAICompatibilityOption struct {
+	Profile string
+}
+
+// NewOpenAICompatibilityOption creates a new OpenAICompatibilityOption
+func NewOpenAICompatibilityOption(profile string) *OpenAICompatibilityOption {
+	return &OpenAICompatibilityOption{Profile: profile}
+}
+
+func (o *OpenAICompatibilityOption) ProviderType() string { return "openai" }
+
+// ApplyToOpenAI selects the profile. Options cannot return errors, so an unknown profile
+// is logged as a warning and the provider keeps its current profile.
+func (o *OpenAICompatibilityOption) ApplyToOpenAI(provider interface{}) {
+	if p, ok := provider.(interface{ SetCompatibilityProfile(name string) error }); ok {
+		if err := p.SetCompatibilityProfile(o.Profile); err != nil {
+			slog.Warn("ignoring OpenAI compatibility option", "error", err)
+		}
+	}
+}
+
 // OpenAILogitBiasOption sets the logit bias for OpenAI API calls
 type OpenAILogitBiasOption struct {
 	LogitBias map[string]float64
@@ -420,6 +522,13 @@ func (o *GeminiGenerationConfigOption) ApplyToGemini(provider interface{}) {
 		}
 	}
 
+	// Set the default candidate count if configured
+	if o.CandidateCount != nil {
+		if p, ok := provider.(interface{ SetCandidateCount(candidateCount int) }); ok {
+			p.SetCandidateCount(*o.CandidateCount)
+		}
+	}
+
 	// Other Gemini-specific generation parameters can be implemented
 	// when the GeminiProvider supports them
 }
@@ -0,0 +1,360 @@
+// Package gateway serves go-llms providers over an OpenAI-compatible HTTP API, so that
+// services in any language can use providers, multi-provider strategies and pools configured in Go.
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// defaultMaxBodyBytes is the default limit on request bodies, including inline images
+const defaultMaxBodyBytes = 20 << 20
+
+// Backend generates responses for a model. domain.Provider, provider.MultiProvider and
+// llmutil.ProviderPool all implement it.
+type Backend interface {
+	GenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error)
+	StreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error)
+}
+
+// Handler is an http.Handler exposing backends through the OpenAI chat completions API:
+//
+//	GET  /v1/models
+//	POST /v1/chat/completions (with "stream": true for server-sent events)
+type Handler struct {
+	mu       sync.RWMutex
+	backends map[string]Backend
+	created  time.Time
+	apiKeys  []string
+	ownedBy  string
+	maxBody  int64
+	fileRefs bool
+}
+
+// NewHandler creates a handler without any models
+func NewHandler() *Handler {
+	return &Handler{
+		backends: make(map[string]Backend),
+		created:  time.Now(),
+		ownedBy:  "go-llms",
+		maxBody:  defaultMaxBodyBytes,
+	}
+}
+
+// Register serves a backend under a model name. Clients select it with the request's "model" field.
+func (h *Handler) Register(model string, backend Backend) *Handler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.backends[model] = backend
+	return h
+}
+
+// WithAPIKeys requires clients to send one of the keys as a bearer token.
+// Without keys, the handler accepts all requests.
+func (h *Handler) WithAPIKeys(keys ...string) *Handler {
+	h.apiKeys = append(h.apiKeys, keys...)
+	return h
+}
+
+// WithMaxBodyBytes limits the size of request bodies, which includes inline images
+// (20 MiB by default). Larger requests are rejected with 413.
+func (h *Handler) WithMaxBodyBytes(maxBytes int64) *Handler {
+	if maxBytes > 0 {
+		h.maxBody = maxBytes
+	}
+	return h
+}
+
+// WithFileReferences allows clients to refer to files by "file_id". The IDs are passed to
+// the backend, which resolves them with its own account, so only enable this when clients
+// may use every file stored there. By default, files must be sent inline as "file_data".
+func (h *Handler) WithFileReferences(enabled bool) *Handler {
+	h.fileRefs = enabled
+	return h
+}
+
+// WithOwner sets the "owned_by" field reported for models
+func (h *Handler) WithOwner(owner string) *Handler {
+	h.ownedBy = owner
+	return h
+}
+
+// Models returns the registered model names in sorted order
+func (h *Handler) Models() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	models := make([]string, 0, len(h.backends))
+	for model := range h.backends {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// backend returns the backend registered for a model
+func (h *Handler) backend(model string) (Backend, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	backend, ok := h.backends[model]
+	return backend, ok
+}
+
+// ServeHTTP routes requests to the API endpoints
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "Invalid API key")
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/v1/models" && r.Method == http.MethodGet:
+		h.serveModels(w)
+	case strings.HasPrefix(path, "/v1/models/") && r.Method == http.MethodGet:
+		h.serveModel(w, strings.TrimPrefix(path, "/v1/models/"))
+	case path == "/v1/chat/completions" && r.Method == http.MethodPost:
+		h.serveChatCompletions(w, r)
+	case path == "/v1/models" || path == "/v1/chat/completions":
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method_not_allowed",
+			fmt.Sprintf("Method %s is not allowed for %s", r.Method, path))
+	default:
+		writeError(w, http.StatusNotFound, "invalid_request_error", "not_found",
+			fmt.Sprintf("Unknown endpoint %s", path))
+	}
+}
+
+// authorized checks the bearer token against the configured API keys
+func (h *Handler) authorized(r *http.Request) bool {
+	if len(h.apiKeys) == 0 {
+		return true
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	for _, key := range h.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// serveModels lists the registered models
+func (h *Handler) serveModels(w http.ResponseWriter) {
+	models := h.Models()
+	list := modelList{Object: "list", Data: make([]model, len(models))}
+	for i, name := range models {
+		list.Data[i] = h.model(name)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// serveModel describes a single registered model
+func (h *Handler) serveModel(w http.ResponseWriter, name string) {
+	if _, ok := h.backend(name); !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("The model '%s' does not exist", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, h.model(name))
+}
+
+// model returns the description of a registered model
+func (h *Handler) model(name string) model {
+	return model{ID: name, Object: "model", Created: h.created.Unix(), OwnedBy: h.ownedBy}
+}
+
+// serveChatCompletions generates a chat completion, streaming it if requested
+func (h *Handler) serveChatCompletions(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "request_too_large",
+				fmt.Sprintf("Request body exceeds the limit of %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid_json",
+			fmt.Sprintf("Failed to read request body: %v", err))
+		return
+	}
+	var request chatRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid_json",
+			fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if param, message := request.unsupportedParameter(); param != "" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "unsupported_parameter",
+			fmt.Sprintf("%s (%s)", message, param))
+		return
+	}
+
+	backend, ok := h.backend(request.Model)
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("The model '%s' does not exist", request.Model))
+		return
+	}
+
+	messages, err := request.toMessages(h.fileRefs)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid_messages", err.Error())
+		return
+	}
+	options := request.toOptions()
+	id := "chatcmpl-" + uuid.NewString()
+
+	if request.Stream {
+		h.streamChatCompletion(w, r, backend, request, id, messages, options)
+		return
+	}
+
+	response, err := backend.GenerateMessage(r.Context(), messages, options...)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newChatResponse(id, request.Model, time.Now(), response))
+}
+
+// streamChatCompletion streams a chat completion as server-sent events
+func (h *Handler) streamChatCompletion(
+	w http.ResponseWriter,
+	r *http.Request,
+	backend Backend,
+	request chatRequest,
+	id string,
+	messages []domain.Message,
+	options []domain.Option,
+) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "server_error", "streaming_unsupported",
+			"Streaming is not supported by the server")
+		return
+	}
+
+	stream, err := backend.StreamMessage(r.Context(), messages, options...)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	created := time.Now().Unix()
+	send := func(chunk chatChunk) bool {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	// The first chunk announces the assistant role, as OpenAI does
+	if !send(newChatChunk(id, request.Model, created, &chatDelta{Role: string(domain.RoleAssistant)}, nil)) {
+		return
+	}
+
+	finishReason := "stop"
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case token, ok := <-stream:
+			if !ok {
+				// The upstream stream closed without finishing, so the response is incomplete.
+				// Clients see an error event and no [DONE].
+				data, _ := json.Marshal(errorResponse{Error: apiError{
+					Message: "The upstream stream ended before the response was complete",
+					Type:    "server_error",
+					Code:    "upstream_error",
+				}})
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+				flusher.Flush()
+				return
+			}
+			if token.Finished {
+				if token.Text != "" && !send(newChatChunk(id, request.Model, created, &chatDelta{Content: token.Text}, nil)) {
+					return
+				}
+				send(newChatChunk(id, request.Model, created, &chatDelta{}, &finishReason))
+				_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+				flusher.Flush()
+				return
+			}
+			if token.Text == "" {
+				continue
+			}
+			if !send(newChatChunk(id, request.Model, created, &chatDelta{Content: token.Text}, nil)) {
+				return
+			}
+		}
+	}
+}
+
+// writeJSON writes a JSON response body
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes an error in the OpenAI error format
+func writeError(w http.ResponseWriter, status int, errorType, code, message string) {
+	writeJSON(w, status, errorResponse{Error: apiError{Message: message, Type: errorType, Code: code}})
+}
+
+// providerErrorStatuses maps provider errors to HTTP statuses and OpenAI error codes
+var providerErrorStatuses = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{domain.ErrAuthenticationFailed, http.StatusBadGateway, "upstream_authentication_failed"},
+	{domain.ErrRateLimitExceeded, http.StatusTooManyRequests, "rate_limit_exceeded"},
+	{domain.ErrTokenQuotaExceeded, http.StatusTooManyRequests, "insufficient_quota"},
+	{domain.ErrContextTooLong, http.StatusBadRequest, "context_length_exceeded"},
+	{domain.ErrContentFiltered, http.StatusBadRequest, "content_filter"},
+	{domain.ErrUnsupportedContentType, http.StatusBadRequest, "unsupported_content_type"},
+	{domain.ErrMediaLimitExceeded, http.StatusBadRequest, "media_limit_exceeded"},
+	{domain.ErrInvalidModelParameters, http.StatusBadRequest, "invalid_parameters"},
+	{domain.ErrModelNotFound, http.StatusNotFound, "model_not_found"},
+	{domain.ErrTimeout, http.StatusGatewayTimeout, "timeout"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"},
+	{domain.ErrProviderUnavailable, http.StatusServiceUnavailable, "provider_unavailable"},
+}
+
+// writeProviderError writes a provider error with the closest matching HTTP status.
+// Upstream authentication failures are reported as 502, since the client's own key was accepted.
+func writeProviderError(w http.ResponseWriter, err error) {
+	for _, mapping := range providerErrorStatuses {
+		if errors.Is(err, mapping.err) {
+			errorType := "invalid_request_error"
+			if mapping.status >= http.StatusInternalServerError {
+				errorType = "server_error"
+			}
+			writeError(w, mapping.status, errorType, mapping.code, err.Error())
+			return
+		}
+	}
+	writeError(w, http.StatusBadGateway, "server_error", "upstream_error", err.Error())
+}
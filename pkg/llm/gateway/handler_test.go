@@ -0,0 +1,325 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/llm/provider"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// newTestServer serves a mock provider as "mock-model"
+func newTestServer(t *testing.T, mock *provider.MockProvider, apiKeys ...string) *httptest.Server {
+	t.Helper()
+	handler := NewHandler().Register("mock-model", mock).WithAPIKeys(apiKeys...)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// post sends a JSON body and decodes the JSON response
+func post(t *testing.T, url, body string, result interface{}) int {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestHandlerModels(t *testing.T) {
+	server := newTestServer(t, provider.NewMockProvider())
+
+	resp, err := http.Get(server.URL + "/v1/models")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var list modelList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode models: %v", err)
+	}
+	if list.Object != "list" || len(list.Data) != 1 || list.Data[0].ID != "mock-model" {
+		t.Errorf("Unexpected models: %+v", list)
+	}
+
+	resp, err = http.Get(server.URL + "/v1/models/unknown")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown model, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandlerChatCompletions(t *testing.T) {
+	var received []domain.Message
+	var options *domain.ProviderOptions
+	mock := provider.NewMockProvider().WithGenerateMessageFunc(
+		func(ctx context.Context, messages []domain.Message, opts ...domain.Option) (domain.Response, error) {
+			received = messages
+			options = domain.DefaultOptions()
+			for _, opt := range opts {
+				opt(options)
+			}
+			return domain.Response{Content: "Hello there"}, nil
+		})
+	server := newTestServer(t, mock)
+
+	var response chatResponse
+	status := post(t, server.URL+"/v1/chat/completions", `{
+		"model": "mock-model",
+		"messages": [
+			{"role": "developer", "content": "Be brief"},
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is this?"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,aGVsbG8="}}
+			]}
+		],
+		"temperature": 0.2,
+		"max_completion_tokens": 50,
+		"stop": "END"
+	}`, &response)
+
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if response.Object != "chat.completion" || !strings.HasPrefix(response.ID, "chatcmpl-") {
+		t.Errorf("Unexpected response envelope: %+v", response)
+	}
+	if len(response.Choices) != 1 || response.Choices[0].Message.Content != "Hello there" || response.Choices[0].FinishReason != "stop" {
+		t.Errorf("Unexpected choices: %+v", response.Choices)
+	}
+	if response.Usage != nil {
+		t.Errorf("Expected usage to be omitted rather than reported as zero, got %+v", response.Usage)
+	}
+
+	if len(received) != 2 || received[0].Role != domain.RoleSystem {
+		t.Fatalf("Unexpected messages: %+v", received)
+	}
+	image := received[1].Content[1].Image
+	if image == nil || image.Source.Type != domain.SourceTypeBase64 || image.Source.MediaType != "image/png" || image.Source.Data != "aGVsbG8=" {
+		t.Errorf("Expected a base64 image, got %+v", received[1].Content[1])
+	}
+	if options.Temperature != 0.2 || options.MaxTokens != 50 || len(options.StopSequences) != 1 || options.StopSequences[0] != "END" {
+		t.Errorf("Unexpected options: %+v", options)
+	}
+}
+
+func TestHandlerStreaming(t *testing.T) {
+	mock := provider.NewMockProvider().WithStreamMessageFunc(
+		func(ctx context.Context, messages []domain.Message, opts ...domain.Option) (domain.ResponseStream, error) {
+			stream := make(chan domain.Token, 3)
+			stream <- domain.Token{Text: "Hel"}
+			stream <- domain.Token{Text: "lo"}
+			stream <- domain.Token{Finished: true}
+			close(stream)
+			return stream, nil
+		})
+	server := newTestServer(t, mock)
+
+	resp, err := http.Post(server.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model": "mock-model", "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	var body strings.Builder
+	buf := make([]byte, 1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		body.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+
+	var text strings.Builder
+	var finishReason string
+	events := strings.Split(strings.TrimSpace(body.String()), "\n\n")
+	for _, event := range events[:len(events)-1] {
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(event, "data: ")), &chunk); err != nil {
+			t.Fatalf("Invalid chunk %q: %v", event, err)
+		}
+		text.WriteString(chunk.Choices[0].Delta.Content)
+		if chunk.Choices[0].FinishReason != nil {
+			finishReason = *chunk.Choices[0].FinishReason
+		}
+	}
+	if text.String() != "Hello" || finishReason != "stop" {
+		t.Errorf("Expected \"Hello\" with finish reason stop, got %q (%q)", text.String(), finishReason)
+	}
+	if events[len(events)-1] != "data: [DONE]" {
+		t.Errorf("Expected the stream to end with [DONE], got %q", events[len(events)-1])
+	}
+}
+
+func TestHandlerStreamBreaksOff(t *testing.T) {
+	mock := provider.NewMockProvider().WithStreamMessageFunc(
+		func(ctx context.Context, messages []domain.Message, opts ...domain.Option) (domain.ResponseStream, error) {
+			// The upstream stream closes without a finished token
+			stream := make(chan domain.Token, 1)
+			stream <- domain.Token{Text: "Hel"}
+			close(stream)
+			return stream, nil
+		})
+	server := newTestServer(t, mock)
+
+	resp, err := http.Post(server.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model": "mock-model", "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+
+	events := strings.Split(strings.TrimSpace(string(data)), "\n\n")
+	if strings.Contains(string(data), "[DONE]") || strings.Contains(string(data), `"finish_reason":"stop"`) {
+		t.Errorf("Expected no [DONE] or stop for an incomplete stream, got %q", data)
+	}
+	var last errorResponse
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(events[len(events)-1], "data: ")), &last); err != nil || last.Error.Code != "upstream_error" {
+		t.Errorf("Expected a final error event, got %q", events[len(events)-1])
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	mock := provider.NewMockProvider().WithGenerateMessageFunc(
+		func(ctx context.Context, messages []domain.Message, opts ...domain.Option) (domain.Response, error) {
+			return domain.Response{}, domain.NewProviderError("mock", "GenerateMessage", 429, "slow down", domain.ErrRateLimitExceeded)
+		})
+	server := newTestServer(t, mock, "secret")
+	chat := `{"model": "mock-model", "messages": [{"role": "user", "content": "Hi"}]}`
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		var response errorResponse
+		if status := post(t, server.URL+"/v1/chat/completions", chat, &response); status != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", status)
+		}
+		if response.Error.Code != "invalid_api_key" {
+			t.Errorf("Unexpected error: %+v", response.Error)
+		}
+	})
+
+	send := func(body string) (int, errorResponse) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var response errorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&response)
+		return resp.StatusCode, response
+	}
+
+	t.Run("ProviderError", func(t *testing.T) {
+		status, response := send(chat)
+		if status != http.StatusTooManyRequests || response.Error.Code != "rate_limit_exceeded" {
+			t.Errorf("Expected a rate limit error, got %d %+v", status, response.Error)
+		}
+	})
+
+	t.Run("UnknownModel", func(t *testing.T) {
+		status, response := send(`{"model": "other", "messages": [{"role": "user", "content": "Hi"}]}`)
+		if status != http.StatusNotFound || response.Error.Code != "model_not_found" {
+			t.Errorf("Expected model_not_found, got %d %+v", status, response.Error)
+		}
+	})
+
+	t.Run("InvalidRole", func(t *testing.T) {
+		status, _ := send(`{"model": "mock-model", "messages": [{"role": "robot", "content": "Hi"}]}`)
+		if status != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", status)
+		}
+	})
+
+	t.Run("RemoteImageURL", func(t *testing.T) {
+		status, response := send(`{"model": "mock-model", "messages": [{"role": "user", "content": [
+			{"type": "image_url", "image_url": {"url": "http://169.254.169.254/latest/meta-data"}}
+		]}]}`)
+		if status != http.StatusBadRequest || !strings.Contains(response.Error.Message, "data URL") {
+			t.Errorf("Expected remote image URLs to be rejected, got %d %+v", status, response.Error)
+		}
+	})
+
+	t.Run("UnsupportedParameters", func(t *testing.T) {
+		for _, param := range []string{
+			`"tools": [{"type": "function", "function": {"name": "lookup"}}]`,
+			`"tool_choice": "required"`,
+			`"stream": true, "stream_options": {"include_usage": true}`,
+			`"stream": true, "n": 2`,
+		} {
+			status, response := send(`{"model": "mock-model", "messages": [{"role": "user", "content": "Hi"}], ` + param + `}`)
+			if status != http.StatusBadRequest || response.Error.Code != "unsupported_parameter" {
+				t.Errorf("Expected %s to be rejected, got %d %+v", param, status, response.Error)
+			}
+		}
+	})
+}
+
+func TestHandlerFileReferences(t *testing.T) {
+	var fileID string
+	mock := provider.NewMockProvider().WithGenerateMessageFunc(
+		func(ctx context.Context, messages []domain.Message, opts ...domain.Option) (domain.Response, error) {
+			for _, part := range messages[0].Content {
+				if part.File != nil {
+					fileID = part.File.FileID
+				}
+			}
+			return domain.Response{Content: "Read it"}, nil
+		})
+	body := `{"model": "mock-model", "messages": [{"role": "user", "content": [
+		{"type": "text", "text": "Summarize"},
+		{"type": "file", "file": {"file_id": "file-123"}}
+	]}]}`
+
+	// File IDs are resolved with the backend's account, so they are rejected by default
+	server := newTestServer(t, mock)
+	var response errorResponse
+	if status := post(t, server.URL+"/v1/chat/completions", body, &response); status != http.StatusBadRequest || !strings.Contains(response.Error.Message, "file_id") {
+		t.Errorf("Expected file_id to be rejected, got %d %+v", status, response.Error)
+	}
+	if fileID != "" {
+		t.Errorf("Expected the backend not to be called, got file ID %q", fileID)
+	}
+
+	allowed := httptest.NewServer(NewHandler().Register("mock-model", mock).WithFileReferences(true))
+	defer allowed.Close()
+	if status := post(t, allowed.URL+"/v1/chat/completions", body, nil); status != http.StatusOK || fileID != "file-123" {
+		t.Errorf("Expected the file ID to be passed on, got %d and %q", status, fileID)
+	}
+}
+
+func TestHandlerBodyLimit(t *testing.T) {
+	handler := NewHandler().Register("mock-model", provider.NewMockProvider()).WithMaxBodyBytes(64)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	var response errorResponse
+	body := `{"model": "mock-model", "messages": [{"role": "user", "content": "` + strings.Repeat("x", 100) + `"}]}`
+	if status := post(t, server.URL+"/v1/chat/completions", body, &response); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", status)
+	}
+	if response.Error.Code != "request_too_large" {
+		t.Errorf("Unexpected error: %+v", response.Error)
+	}
+}
@@ -0,0 +1,392 @@
+package gateway
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// chatRequest is an OpenAI chat completion request
+type chatRequest struct {
+	Model               string            `json:"model"`
+	Messages            []chatMessage     `json:"messages"`
+	Stream              bool              `json:"stream"`
+	Temperature         *float64          `json:"temperature"`
+	TopP                *float64          `json:"top_p"`
+	MaxTokens           *int              `json:"max_tokens"`
+	MaxCompletionTokens *int              `json:"max_completion_tokens"`
+	Stop                json.RawMessage   `json:"stop"`
+	N                   int               `json:"n"`
+	Seed                *int64            `json:"seed"`
+	User                string            `json:"user"`
+	PresencePenalty     *float64          `json:"presence_penalty"`
+	FrequencyPenalty    *float64          `json:"frequency_penalty"`
+	Logprobs            bool              `json:"logprobs"`
+	TopLogprobs         int               `json:"top_logprobs"`
+	Metadata            map[string]string `json:"metadata"`
+
+	// Unsupported parameters, which are rejected rather than silently ignored
+	Tools         json.RawMessage `json:"tools"`
+	ToolChoice    json.RawMessage `json:"tool_choice"`
+	Functions     json.RawMessage `json:"functions"`
+	FunctionCall  json.RawMessage `json:"function_call"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// unsupportedParameter returns the name of a parameter the gateway cannot honour, if any
+func (r *chatRequest) unsupportedParameter() (string, string) {
+	switch {
+	case isSet(r.Tools):
+		return "tools", "Tool calling is not supported by the gateway"
+	case isSet(r.ToolChoice):
+		return "tool_choice", "Tool calling is not supported by the gateway"
+	case isSet(r.Functions):
+		return "functions", "Function calling is not supported by the gateway"
+	case isSet(r.FunctionCall):
+		return "function_call", "Function calling is not supported by the gateway"
+	case r.StreamOptions != nil && r.StreamOptions.IncludeUsage:
+		return "stream_options.include_usage", "Token usage is not reported by the gateway"
+	case r.N > 1 && r.Stream:
+		return "n", "Multiple choices cannot be streamed by the gateway"
+	}
+	return "", ""
+}
+
+// isSet reports whether an optional JSON value was sent
+func isSet(value json.RawMessage) bool {
+	trimmed := strings.TrimSpace(string(value))
+	return trimmed != "" && trimmed != "null" && trimmed != "[]"
+}
+
+// chatMessage is a message of a chat completion request. Content is either a string
+// or an array of content parts.
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// chatContentPart is a content part of a request message
+type chatContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+	File *struct {
+		FileID   string `json:"file_id"`
+		Filename string `json:"filename"`
+		FileData string `json:"file_data"`
+	} `json:"file"`
+}
+
+// toMessages converts the request messages to domain messages. File references are
+// rejected unless fileReferences is set.
+func (r *chatRequest) toMessages(fileReferences bool) ([]domain.Message, error) {
+	if len(r.Messages) == 0 {
+		return nil, fmt.Errorf("messages must not be empty")
+	}
+
+	messages := make([]domain.Message, 0, len(r.Messages))
+	for i, msg := range r.Messages {
+		var role domain.Role
+		switch msg.Role {
+		case "system", "developer":
+			role = domain.RoleSystem
+		case "user":
+			role = domain.RoleUser
+		case "assistant":
+			role = domain.RoleAssistant
+		case "tool":
+			role = domain.RoleTool
+		default:
+			return nil, fmt.Errorf("messages[%d]: unsupported role '%s'", i, msg.Role)
+		}
+
+		parts, err := toContentParts(msg.Content, fileReferences)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		messages = append(messages, domain.Message{Role: role, Content: parts})
+	}
+	return messages, nil
+}
+
+// toContentParts converts string or array message content to domain content parts
+func toContentParts(content json.RawMessage, fileReferences bool) ([]domain.ContentPart, error) {
+	trimmed := strings.TrimSpace(string(content))
+	if trimmed == "" || trimmed == "null" {
+		return []domain.ContentPart{{Type: domain.ContentTypeText, Text: ""}}, nil
+	}
+
+	if strings.HasPrefix(trimmed, `"`) {
+		var text string
+		if err := json.Unmarshal(content, &text); err != nil {
+			return nil, fmt.Errorf("invalid content: %w", err)
+		}
+		return []domain.ContentPart{{Type: domain.ContentTypeText, Text: text}}, nil
+	}
+
+	var rawParts []chatContentPart
+	if err := json.Unmarshal(content, &rawParts); err != nil {
+		return nil, fmt.Errorf("content must be a string or an array of content parts")
+	}
+
+	parts := make([]domain.ContentPart, 0, len(rawParts))
+	for _, part := range rawParts {
+		switch {
+		case part.Type == "text":
+			parts = append(parts, domain.ContentPart{Type: domain.ContentTypeText, Text: part.Text})
+		case part.Type == "image_url" && part.ImageURL != nil:
+			// Remote URLs are rejected so clients cannot make the server or provider
+			// request arbitrary addresses; the body size limit bounds inline images
+			mimeType, data, ok := parseDataURL(part.ImageURL.URL)
+			if !ok {
+				return nil, fmt.Errorf("image_url must be a base64 data URL; remote image URLs are not supported")
+			}
+			source := domain.SourceInfo{Type: domain.SourceTypeBase64, MediaType: mimeType, Data: data}
+			parts = append(parts, domain.ContentPart{Type: domain.ContentTypeImage, Image: &domain.ImageContent{Source: source}})
+		case part.Type == "file" && part.File != nil:
+			// File IDs name files stored with the backend's account, which clients may not own
+			if part.File.FileID != "" && !fileReferences {
+				return nil, fmt.Errorf("file_id references are not supported; send file_data instead")
+			}
+			file := &domain.FileContent{FileName: part.File.Filename, FileID: part.File.FileID}
+			if part.File.FileData != "" {
+				mimeType, data, ok := parseDataURL(part.File.FileData)
+				if !ok {
+					return nil, fmt.Errorf("file_data must be a base64 data URL")
+				}
+				file.MimeType, file.FileData = mimeType, data
+			}
+			parts = append(parts, domain.ContentPart{Type: domain.ContentTypeFile, File: file})
+		default:
+			return nil, fmt.Errorf("unsupported content part type '%s'", part.Type)
+		}
+	}
+	return parts, nil
+}
+
+// parseDataURL splits a base64 data URL ("data:image/png;base64,...") into its MIME type and data
+func parseDataURL(url string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return "", "", false
+	}
+	header, data, ok := strings.Cut(rest, ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(header, ";base64"), data, true
+}
+
+// toOptions converts the request parameters to domain options
+func (r *chatRequest) toOptions() []domain.Option {
+	var options []domain.Option
+	if r.Temperature != nil {
+		options = append(options, domain.WithTemperature(*r.Temperature))
+	}
+	if r.TopP != nil {
+		options = append(options, domain.WithTopP(*r.TopP))
+	}
+	if r.MaxCompletionTokens != nil {
+		options = append(options, domain.WithMaxTokens(*r.MaxCompletionTokens))
+	} else if r.MaxTokens != nil {
+		options = append(options, domain.WithMaxTokens(*r.MaxTokens))
+	}
+	if stop := r.stopSequences(); len(stop) > 0 {
+		options = append(options, domain.WithStopSequences(stop))
+	}
+	if r.N > 1 {
+		options = append(options, domain.WithCandidateCount(r.N))
+	}
+	if r.Seed != nil {
+		options = append(options, domain.WithSeed(*r.Seed))
+	}
+	if r.User != "" {
+		options = append(options, domain.WithUser(r.User))
+	}
+	if r.PresencePenalty != nil {
+		options = append(options, domain.WithPresencePenalty(*r.PresencePenalty))
+	}
+	if r.FrequencyPenalty != nil {
+		options = append(options, domain.WithFrequencyPenalty(*r.FrequencyPenalty))
+	}
+	if r.TopLogprobs > 0 {
+		options = append(options, domain.WithTopLogprobs(r.TopLogprobs))
+	} else if r.Logprobs {
+		options = append(options, domain.WithLogprobs(true))
+	}
+	if len(r.Metadata) > 0 {
+		options = append(options, domain.WithMetadata(r.Metadata))
+	}
+	return options
+}
+
+// stopSequences returns the stop sequences, which may be sent as a string or an array
+func (r *chatRequest) stopSequences() []string {
+	if len(r.Stop) == 0 {
+		return nil
+	}
+	var sequences []string
+	if err := json.Unmarshal(r.Stop, &sequences); err == nil {
+		return sequences
+	}
+	var sequence string
+	if err := json.Unmarshal(r.Stop, &sequence); err == nil && sequence != "" {
+		return []string{sequence}
+	}
+	return nil
+}
+
+// chatResponse is an OpenAI chat completion response
+type chatResponse struct {
+	ID                string       `json:"id"`
+	Object            string       `json:"object"`
+	Created           int64        `json:"created"`
+	Model             string       `json:"model"`
+	SystemFingerprint string       `json:"system_fingerprint,omitempty"`
+	Choices           []chatChoice `json:"choices"`
+	// Usage is omitted: providers don't report token usage through domain.Response
+	Usage *chatUsage `json:"usage,omitempty"`
+}
+
+// chatChoice is a choice of a chat completion response
+type chatChoice struct {
+	Index        int                 `json:"index"`
+	Message      chatResponseMessage `json:"message"`
+	FinishReason string              `json:"finish_reason"`
+	Logprobs     *chatLogprobs       `json:"logprobs"`
+}
+
+// chatResponseMessage is the assistant message of a choice
+type chatResponseMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatLogprobs are the token log probabilities of a choice
+type chatLogprobs struct {
+	Content []chatTokenLogprob `json:"content"`
+}
+
+// chatTokenLogprob is the log probability of a token and its most likely alternatives
+type chatTokenLogprob struct {
+	Token       string             `json:"token"`
+	Logprob     float64            `json:"logprob"`
+	TopLogprobs []chatTokenLogprob `json:"top_logprobs,omitempty"`
+}
+
+// chatUsage reports token usage
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// newChatResponse converts a domain response to a chat completion response
+func newChatResponse(id, model string, created time.Time, response domain.Response) chatResponse {
+	result := chatResponse{
+		ID:                id,
+		Object:            "chat.completion",
+		Created:           created.Unix(),
+		Model:             model,
+		SystemFingerprint: response.SystemFingerprint,
+	}
+
+	if len(response.Choices) == 0 {
+		result.Choices = []chatChoice{{
+			Message:      chatResponseMessage{Role: string(domain.RoleAssistant), Content: response.Content},
+			FinishReason: "stop",
+		}}
+		return result
+	}
+
+	result.Choices = make([]chatChoice, len(response.Choices))
+	for i, choice := range response.Choices {
+		finishReason := choice.FinishReason
+		if finishReason == "" {
+			finishReason = "stop"
+		}
+		result.Choices[i] = chatChoice{
+			Index:        i,
+			Message:      chatResponseMessage{Role: string(domain.RoleAssistant), Content: choice.Content},
+			FinishReason: finishReason,
+		}
+		if len(choice.Logprobs) > 0 {
+			logprobs := &chatLogprobs{Content: make([]chatTokenLogprob, len(choice.Logprobs))}
+			for j, token := range choice.Logprobs {
+				entry := chatTokenLogprob{Token: token.Token, Logprob: token.Logprob}
+				for _, top := range token.TopLogprobs {
+					entry.TopLogprobs = append(entry.TopLogprobs, chatTokenLogprob{Token: top.Token, Logprob: top.Logprob})
+				}
+				logprobs.Content[j] = entry
+			}
+			result.Choices[i].Logprobs = logprobs
+		}
+	}
+	return result
+}
+
+// chatChunk is a chunk of a streamed chat completion
+type chatChunk struct {
+	ID      string            `json:"id"`
+	Object  string            `json:"object"`
+	Created int64             `json:"created"`
+	Model   string            `json:"model"`
+	Choices []chatChunkChoice `json:"choices"`
+}
+
+// chatChunkChoice is the choice of a streamed chunk
+type chatChunkChoice struct {
+	Index        int        `json:"index"`
+	Delta        *chatDelta `json:"delta"`
+	FinishReason *string    `json:"finish_reason"`
+}
+
+// chatDelta is the text added by a streamed chunk
+type chatDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// newChatChunk creates a streamed chunk with a single choice
+func newChatChunk(id, model string, created int64, delta *chatDelta, finishReason *string) chatChunk {
+	return chatChunk{
+		ID:      id,
+		Object:  "chat.completion.chunk",
+		Created: created,
+		Model:   model,
+		Choices: []chatChunkChoice{{Delta: delta, FinishReason: finishReason}},
+	}
+}
+
+// modelList is the response of the models endpoint
+type modelList struct {
+	Object string  `json:"object"`
+	Data   []model `json:"data"`
+}
+
+// model describes a served model
+type model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// errorResponse is an error in the OpenAI format
+type errorResponse struct {
+	Error apiError `json:"error"`
+}
+
+// apiError describes an error
+type apiError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+}
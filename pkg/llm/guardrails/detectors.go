@@ -0,0 +1,353 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+	"github.com/lexlapax/go-llms/pkg/schema/validation"
+	"github.com/lexlapax/go-llms/pkg/structured/processor"
+)
+
+// PIIKind is a kind of personal data detected by PIIGuard
+type PIIKind string
+
+const (
+	PIIEmail      PIIKind = "email"
+	PIIPhone      PIIKind = "phone"
+	PIICardNumber PIIKind = "card_number"
+	PIISSN        PIIKind = "ssn"
+	PIIIPAddress  PIIKind = "ip_address"
+)
+
+// piiKinds are all kinds of personal data, in the order they are checked
+var piiKinds = []PIIKind{PIIEmail, PIICardNumber, PIISSN, PIIPhone, PIIIPAddress}
+
+// piiPatterns match each kind of personal data
+var piiPatterns = map[PIIKind]*regexp.Regexp{
+	PIIEmail:      regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	PIIPhone:      regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?\(?\b\d{3}\)?[ .-]?\d{3}[ .-]?\d{4}\b`),
+	PIICardNumber: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+	PIISSN:        regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	PIIIPAddress:  regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+}
+
+// PIIGuard detects personal data. Rewriting replaces it with placeholders such as [EMAIL].
+// Card numbers are only reported if they pass the Luhn check.
+type PIIGuard struct {
+	kinds []PIIKind
+}
+
+// NewPIIGuard creates a guard for the given kinds of personal data, or all kinds if none are given
+func NewPIIGuard(kinds ...PIIKind) *PIIGuard {
+	if len(kinds) == 0 {
+		kinds = piiKinds
+	}
+	return &PIIGuard{kinds: kinds}
+}
+
+// Name returns "pii"
+func (g *PIIGuard) Name() string {
+	return "pii"
+}
+
+// Check reports the kinds of personal data in the text
+func (g *PIIGuard) Check(ctx context.Context, text string) (string, error) {
+	var found []string
+	for _, kind := range g.kinds {
+		if len(g.matches(kind, text)) > 0 {
+			found = append(found, string(kind))
+		}
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return "contains personal data (" + strings.Join(found, ", ") + ")", nil
+}
+
+// Rewrite replaces personal data with placeholders
+func (g *PIIGuard) Rewrite(ctx context.Context, text string) (string, error) {
+	for _, kind := range g.kinds {
+		placeholder := "[" + strings.ToUpper(string(kind)) + "]"
+		matches := g.matches(kind, text)
+		for i := len(matches) - 1; i >= 0; i-- {
+			text = text[:matches[i][0]] + placeholder + text[matches[i][1]:]
+		}
+	}
+	return text, nil
+}
+
+// matches returns the locations of a kind of personal data
+func (g *PIIGuard) matches(kind PIIKind, text string) [][]int {
+	pattern, ok := piiPatterns[kind]
+	if !ok {
+		return nil
+	}
+	matches := pattern.FindAllStringIndex(text, -1)
+	if kind != PIICardNumber {
+		return matches
+	}
+	valid := matches[:0]
+	for _, match := range matches {
+		if luhnValid(text[match[0]:match[1]]) {
+			valid = append(valid, match)
+		}
+	}
+	return valid
+}
+
+// luhnValid reports whether the digits of a number pass the Luhn checksum
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// PatternGuard rejects text matching any of its patterns. Rewriting replaces the matches.
+type PatternGuard struct {
+	name        string
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+// NewPatternGuard creates a guard rejecting the given patterns
+func NewPatternGuard(name string, patterns ...*regexp.Regexp) *PatternGuard {
+	return &PatternGuard{name: name, patterns: patterns, replacement: "[REDACTED]"}
+}
+
+// WithReplacement sets the text that replaces matches when rewriting
+func (g *PatternGuard) WithReplacement(replacement string) *PatternGuard {
+	g.replacement = replacement
+	return g
+}
+
+// Name returns the guard's name
+func (g *PatternGuard) Name() string {
+	return g.name
+}
+
+// Check reports the patterns that match the text
+func (g *PatternGuard) Check(ctx context.Context, text string) (string, error) {
+	var matched []string
+	for _, pattern := range g.patterns {
+		if pattern.MatchString(text) {
+			matched = append(matched, pattern.String())
+		}
+	}
+	if len(matched) == 0 {
+		return "", nil
+	}
+	return "matches disallowed pattern " + strings.Join(matched, ", "), nil
+}
+
+// Rewrite replaces the matches of all patterns
+func (g *PatternGuard) Rewrite(ctx context.Context, text string) (string, error) {
+	for _, pattern := range g.patterns {
+		text = pattern.ReplaceAllLiteralString(text, g.replacement)
+	}
+	return text, nil
+}
+
+// RequiredPatternGuard rejects text that does not match a pattern, such as a required format
+type RequiredPatternGuard struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// NewRequiredPatternGuard creates a guard requiring a pattern
+func NewRequiredPatternGuard(name string, pattern *regexp.Regexp) *RequiredPatternGuard {
+	return &RequiredPatternGuard{name: name, pattern: pattern}
+}
+
+// Name returns the guard's name
+func (g *RequiredPatternGuard) Name() string {
+	return g.name
+}
+
+// Check reports text that does not match the pattern
+func (g *RequiredPatternGuard) Check(ctx context.Context, text string) (string, error) {
+	if g.pattern.MatchString(text) {
+		return "", nil
+	}
+	return "does not match the required pattern " + g.pattern.String(), nil
+}
+
+// DenylistGuard rejects text containing banned words or phrases, matched case-insensitively
+// as whole words. Rewriting replaces them.
+type DenylistGuard struct {
+	terms       []string
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+// NewDenylistGuard creates a guard for banned terms
+func NewDenylistGuard(terms ...string) *DenylistGuard {
+	g := &DenylistGuard{replacement: "[REDACTED]"}
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		g.terms = append(g.terms, term)
+		g.patterns = append(g.patterns, termPattern(term))
+	}
+	return g
+}
+
+// termPattern matches a term case-insensitively, on word boundaries where the term starts or ends with a word character
+func termPattern(term string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(term)
+	if first, _ := utf8.DecodeRuneInString(term); isWordRune(first) {
+		expr = `\b` + expr
+	}
+	if last, _ := utf8.DecodeLastRuneInString(term); isWordRune(last) {
+		expr += `\b`
+	}
+	return regexp.MustCompile(`(?i)` + expr)
+}
+
+// isWordRune reports whether a rune is a word character for \b
+func isWordRune(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// WithReplacement sets the text that replaces banned terms when rewriting
+func (g *DenylistGuard) WithReplacement(replacement string) *DenylistGuard {
+	g.replacement = replacement
+	return g
+}
+
+// Name returns "denylist"
+func (g *DenylistGuard) Name() string {
+	return "denylist"
+}
+
+// Check reports how many banned terms the text contains, without naming them
+func (g *DenylistGuard) Check(ctx context.Context, text string) (string, error) {
+	found := 0
+	for _, pattern := range g.patterns {
+		if pattern.MatchString(text) {
+			found++
+		}
+	}
+	switch found {
+	case 0:
+		return "", nil
+	case 1:
+		return "contains a banned term", nil
+	default:
+		return fmt.Sprintf("contains %d banned terms", found), nil
+	}
+}
+
+// Rewrite replaces banned terms
+func (g *DenylistGuard) Rewrite(ctx context.Context, text string) (string, error) {
+	for _, pattern := range g.patterns {
+		text = pattern.ReplaceAllLiteralString(text, g.replacement)
+	}
+	return text, nil
+}
+
+// MaxLengthGuard rejects text longer than a number of characters. Rewriting truncates it.
+type MaxLengthGuard struct {
+	maxChars int
+}
+
+// NewMaxLengthGuard creates a guard for a maximum length in characters
+func NewMaxLengthGuard(maxChars int) *MaxLengthGuard {
+	return &MaxLengthGuard{maxChars: maxChars}
+}
+
+// Name returns "max_length"
+func (g *MaxLengthGuard) Name() string {
+	return "max_length"
+}
+
+// Check reports text longer than the maximum
+func (g *MaxLengthGuard) Check(ctx context.Context, text string) (string, error) {
+	if length := utf8.RuneCountInString(text); length > g.maxChars {
+		return fmt.Sprintf("is %d characters long, more than the maximum of %d", length, g.maxChars), nil
+	}
+	return "", nil
+}
+
+// Rewrite truncates the text to the maximum length
+func (g *MaxLengthGuard) Rewrite(ctx context.Context, text string) (string, error) {
+	if utf8.RuneCountInString(text) <= g.maxChars {
+		return text, nil
+	}
+	return string([]rune(text)[:g.maxChars]), nil
+}
+
+// SchemaGuard rejects responses whose JSON does not conform to a schema. The JSON may be
+// wrapped in text or a markdown code block.
+type SchemaGuard struct {
+	schema    *schemaDomain.Schema
+	validator *validation.Validator
+}
+
+// NewSchemaGuard creates a guard validating against a schema
+func NewSchemaGuard(schema *schemaDomain.Schema) *SchemaGuard {
+	return &SchemaGuard{schema: schema, validator: validation.NewValidator()}
+}
+
+// Name returns "json_schema"
+func (g *SchemaGuard) Name() string {
+	return "json_schema"
+}
+
+// Check reports missing JSON and schema validation errors
+func (g *SchemaGuard) Check(ctx context.Context, text string) (string, error) {
+	jsonStr := processor.ExtractJSON(text)
+	if jsonStr == "" {
+		return "contains no JSON", nil
+	}
+	result, err := g.validator.Validate(g.schema, jsonStr)
+	if err != nil {
+		return "contains invalid JSON: " + err.Error(), nil
+	}
+	if !result.Valid {
+		return "does not match the JSON schema: " + strings.Join(result.Errors, "; "), nil
+	}
+	return "", nil
+}
+
+// CheckFunc is a custom check returning a non-empty reason for violating text
+type CheckFunc func(ctx context.Context, text string) (reason string, err error)
+
+// funcGuard is a guard backed by a function
+type funcGuard struct {
+	name  string
+	check CheckFunc
+}
+
+// NewFuncGuard creates a guard from a custom check
+func NewFuncGuard(name string, check CheckFunc) Guard {
+	return &funcGuard{name: name, check: check}
+}
+
+func (g *funcGuard) Name() string {
+	return g.name
+}
+
+func (g *funcGuard) Check(ctx context.Context, text string) (string, error) {
+	return g.check(ctx, text)
+}
@@ -0,0 +1,216 @@
+// Package guardrails enforces policies on the prompts sent to LLMs and the responses they
+// return. A Pipeline runs guards over input and output text and blocks, rewrites or re-asks
+// on a violation. GuardedProvider applies a pipeline to every call of a provider.
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrGuardrailViolation is wrapped by ViolationError
+var ErrGuardrailViolation = errors.New("guardrail violation")
+
+// Stage is the point of a call a guard runs at
+type Stage string
+
+const (
+	// StageInput checks prompts before they are sent
+	StageInput Stage = "input"
+	// StageOutput checks responses before they are returned
+	StageOutput Stage = "output"
+)
+
+// Action is what a pipeline does when a guard reports a violation
+type Action int
+
+const (
+	// ActionBlock fails the call with a ViolationError
+	ActionBlock Action = iota
+	// ActionRewrite replaces the text with the guard's rewrite, such as redacted PII.
+	// Guards that cannot rewrite block instead.
+	ActionRewrite
+	// ActionReask sends the violations back to the model and asks for a new response.
+	// It only applies to output guards; input guards block instead.
+	ActionReask
+)
+
+// Guard checks text against a policy
+type Guard interface {
+	// Name identifies the guard in violations
+	Name() string
+	// Check returns a non-empty reason if the text violates the guard. Reasons should
+	// not repeat the offending text, since they end up in errors and logs.
+	Check(ctx context.Context, text string) (reason string, err error)
+}
+
+// Rewriter is a guard that can fix violating text instead of rejecting it
+type Rewriter interface {
+	Guard
+	// Rewrite returns the text with the violations removed
+	Rewrite(ctx context.Context, text string) (string, error)
+}
+
+// Violation is a guard's finding on a prompt or response
+type Violation struct {
+	Guard  string
+	Stage  Stage
+	Reason string
+	Action Action
+}
+
+// String formats the violation as "guard: reason"
+func (v Violation) String() string {
+	return v.Guard + ": " + v.Reason
+}
+
+// ViolationError is returned when a guard blocks a prompt or a response still violates
+// the guards after the allowed re-asks. It wraps ErrGuardrailViolation.
+type ViolationError struct {
+	// Stage reports whether the prompt or the response was blocked
+	Stage Stage
+	// Violations are the findings that blocked the call
+	Violations []Violation
+	// Attempts is the number of responses checked, including re-asks (0 for prompts)
+	Attempts int
+}
+
+// Error implements the error interface
+func (e *ViolationError) Error() string {
+	findings := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		findings[i] = violation.String()
+	}
+	msg := fmt.Sprintf("guardrails: %s blocked: %s", e.Stage, strings.Join(findings, "; "))
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" (after %d attempts)", e.Attempts)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *ViolationError) Unwrap() error {
+	return ErrGuardrailViolation
+}
+
+// IsViolation checks if the error is a guardrail violation
+func IsViolation(err error) bool {
+	return errors.Is(err, ErrGuardrailViolation)
+}
+
+// rule is a guard with the action taken on its violations
+type rule struct {
+	guard  Guard
+	action Action
+}
+
+// Pipeline runs input guards over prompts and output guards over responses, in the order they were added
+type Pipeline struct {
+	input     []rule
+	output    []rule
+	maxReasks int
+}
+
+// NewPipeline creates an empty pipeline that re-asks once
+func NewPipeline() *Pipeline {
+	return &Pipeline{maxReasks: 1}
+}
+
+// Input adds a guard for prompts
+func (p *Pipeline) Input(guard Guard, action Action) *Pipeline {
+	p.input = append(p.input, rule{guard: guard, action: action})
+	return p
+}
+
+// Output adds a guard for responses
+func (p *Pipeline) Output(guard Guard, action Action) *Pipeline {
+	p.output = append(p.output, rule{guard: guard, action: action})
+	return p
+}
+
+// WithMaxReasks sets how many times a response is re-requested before a ViolationError
+func (p *Pipeline) WithMaxReasks(maxReasks int) *Pipeline {
+	p.maxReasks = maxReasks
+	return p
+}
+
+// CheckInput runs the input guards over a prompt, returning the prompt as rewritten by the guards
+func (p *Pipeline) CheckInput(ctx context.Context, text string) (string, error) {
+	text, violations, err := run(ctx, StageInput, p.input, text)
+	if err != nil {
+		return text, err
+	}
+	if len(violations) > 0 {
+		return text, &ViolationError{Stage: StageInput, Violations: violations}
+	}
+	return text, nil
+}
+
+// CheckOutput runs the output guards over a response, returning the response as rewritten by
+// the guards. Without a model to re-ask, violations of ActionReask guards block.
+func (p *Pipeline) CheckOutput(ctx context.Context, text string) (string, error) {
+	text, violations, err := run(ctx, StageOutput, p.output, text)
+	if err != nil {
+		return text, err
+	}
+	if len(violations) > 0 {
+		return text, &ViolationError{Stage: StageOutput, Violations: violations, Attempts: 1}
+	}
+	return text, nil
+}
+
+// run checks text with each rule, rewriting it where the rule allows, and returns the
+// violations that were not rewritten
+func run(ctx context.Context, stage Stage, rules []rule, text string) (string, []Violation, error) {
+	var violations []Violation
+	for _, r := range rules {
+		reason, err := r.guard.Check(ctx, text)
+		if err != nil {
+			return text, nil, fmt.Errorf("guard %s: %w", r.guard.Name(), err)
+		}
+		if reason == "" {
+			continue
+		}
+
+		action := r.action
+		if action == ActionReask && stage == StageInput {
+			action = ActionBlock
+		}
+		if rewriter, ok := r.guard.(Rewriter); ok && action == ActionRewrite {
+			if text, err = rewriter.Rewrite(ctx, text); err != nil {
+				return text, nil, fmt.Errorf("guard %s: %w", r.guard.Name(), err)
+			}
+			continue
+		}
+		if action == ActionRewrite {
+			action = ActionBlock
+		}
+		violations = append(violations, Violation{Guard: r.guard.Name(), Stage: stage, Reason: reason, Action: action})
+	}
+	return text, violations, nil
+}
+
+// canReask reports whether every violation allows a re-ask
+func canReask(violations []Violation) bool {
+	for _, violation := range violations {
+		if violation.Action != ActionReask {
+			return false
+		}
+	}
+	return len(violations) > 0
+}
+
+// reaskPrompt asks the model to fix the violations of its previous response
+func reaskPrompt(violations []Violation) string {
+	var b strings.Builder
+	b.WriteString("Your previous response did not meet these requirements:\n")
+	for _, violation := range violations {
+		b.WriteString("- ")
+		b.WriteString(violation.Reason)
+		b.WriteString("\n")
+	}
+	b.WriteString("Please respond again, fixing these problems.")
+	return b.String()
+}
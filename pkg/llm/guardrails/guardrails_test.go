@@ -0,0 +1,135 @@
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+)
+
+func TestPIIGuard(t *testing.T) {
+	ctx := context.Background()
+	guard := NewPIIGuard()
+	text := "Mail jane@example.com or call 555-123-4567, card 4111 1111 1111 1111, SSN 123-45-6789, from 10.0.0.1"
+
+	reason, err := guard.Check(ctx, text)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	for _, kind := range []PIIKind{PIIEmail, PIIPhone, PIICardNumber, PIISSN, PIIIPAddress} {
+		if !strings.Contains(reason, string(kind)) {
+			t.Errorf("Expected %s in %q", kind, reason)
+		}
+	}
+	if strings.Contains(reason, "jane@example.com") {
+		t.Errorf("Reasons should not repeat the personal data: %q", reason)
+	}
+
+	rewritten, _ := guard.Rewrite(ctx, text)
+	expected := "Mail [EMAIL] or call [PHONE], card [CARD_NUMBER], SSN [SSN], from [IP_ADDRESS]"
+	if rewritten != expected {
+		t.Errorf("Expected %q, got %q", expected, rewritten)
+	}
+
+	// Numbers failing the Luhn check are not card numbers
+	if reason, _ := NewPIIGuard(PIICardNumber).Check(ctx, "order 1234 5678 9012 3456"); reason != "" {
+		t.Errorf("Expected no card number, got %q", reason)
+	}
+}
+
+func TestDenylistAndPatternGuards(t *testing.T) {
+	ctx := context.Background()
+
+	denylist := NewDenylistGuard("secret project", "C++")
+	if reason, _ := denylist.Check(ctx, "Tell me about the Secret Project in c++"); reason != "contains 2 banned terms" {
+		t.Errorf("Expected both terms counted, got %q", reason)
+	}
+	if reason, _ := denylist.Check(ctx, "the secret project"); strings.Contains(strings.ToLower(reason), "secret project") {
+		t.Errorf("Reasons should not repeat the denylist: %q", reason)
+	}
+	if reason, _ := denylist.Check(ctx, "secret projects are fun"); reason != "" {
+		t.Errorf("Expected whole-word matching, got %q", reason)
+	}
+	if rewritten, _ := denylist.Rewrite(ctx, "the SECRET PROJECT"); rewritten != "the [REDACTED]" {
+		t.Errorf("Unexpected rewrite %q", rewritten)
+	}
+
+	pattern := NewPatternGuard("internal_ids", regexp.MustCompile(`ACCT-\d+`)).WithReplacement("[ACCOUNT]")
+	if rewritten, _ := pattern.Rewrite(ctx, "account ACCT-42"); rewritten != "account [ACCOUNT]" {
+		t.Errorf("Unexpected rewrite %q", rewritten)
+	}
+
+	required := NewRequiredPatternGuard("answer_format", regexp.MustCompile(`^ANSWER: `))
+	if reason, _ := required.Check(ctx, "42"); reason == "" {
+		t.Errorf("Expected a violation without the required format")
+	}
+}
+
+func TestMaxLengthAndSchemaGuards(t *testing.T) {
+	ctx := context.Background()
+
+	maxLength := NewMaxLengthGuard(5)
+	if reason, _ := maxLength.Check(ctx, "héllo"); reason != "" {
+		t.Errorf("Expected 5 characters to pass, got %q", reason)
+	}
+	if truncated, _ := maxLength.Rewrite(ctx, "héllo world"); truncated != "héllo" {
+		t.Errorf("Unexpected truncation %q", truncated)
+	}
+
+	schema := &schemaDomain.Schema{
+		Type:       "object",
+		Properties: map[string]schemaDomain.Property{"name": {Type: "string"}},
+		Required:   []string{"name"},
+	}
+	guard := NewSchemaGuard(schema)
+	if reason, _ := guard.Check(ctx, "Here you go:\n```json\n{\"name\": \"Ada\"}\n```"); reason != "" {
+		t.Errorf("Expected valid JSON to pass, got %q", reason)
+	}
+	if reason, _ := guard.Check(ctx, `{"age": 36}`); !strings.Contains(reason, "schema") {
+		t.Errorf("Expected a schema violation, got %q", reason)
+	}
+	if reason, _ := guard.Check(ctx, "no json here"); reason != "contains no JSON" {
+		t.Errorf("Expected missing JSON, got %q", reason)
+	}
+}
+
+func TestPipelineActions(t *testing.T) {
+	ctx := context.Background()
+	pipeline := NewPipeline().
+		Input(NewPIIGuard(PIIEmail), ActionRewrite).
+		Input(NewDenylistGuard("jailbreak"), ActionBlock).
+		Input(NewFuncGuard("no_shouting", func(ctx context.Context, text string) (string, error) {
+			if text == strings.ToUpper(text) && strings.ToLower(text) != text {
+				return "is written in capitals", nil
+			}
+			return "", nil
+		}), ActionRewrite)
+
+	text, err := pipeline.CheckInput(ctx, "Write to bob@example.com")
+	if err != nil || text != "Write to [EMAIL]" {
+		t.Errorf("Expected a rewrite, got %q (%v)", text, err)
+	}
+
+	_, err = pipeline.CheckInput(ctx, "JAILBREAK NOW")
+	var violation *ViolationError
+	if !errors.As(err, &violation) || !IsViolation(err) {
+		t.Fatalf("Expected a ViolationError, got %v", err)
+	}
+	// Guards that cannot rewrite block
+	if violation.Stage != StageInput || len(violation.Violations) != 2 || violation.Violations[1].Guard != "no_shouting" {
+		t.Errorf("Unexpected violations: %+v", violation.Violations)
+	}
+	if !strings.Contains(err.Error(), "denylist: contains a banned term") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+
+	failing := NewPipeline().Output(NewFuncGuard("broken", func(ctx context.Context, text string) (string, error) {
+		return "", errors.New("checker unavailable")
+	}), ActionBlock)
+	if _, err := failing.CheckOutput(ctx, "text"); err == nil || IsViolation(err) || !strings.Contains(err.Error(), "guard broken") {
+		t.Errorf("Expected the guard's error, got %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// GuardedProvider wraps a provider and runs a pipeline on every call. Input guards check
+// the prompt, or the text of user messages; output guards check the response, re-asking
+// the model when all violations allow it.
+//
+// With output guards, streams are buffered until the response passes the guards, so a
+// violation can be returned as an error instead of after tokens were sent.
+type GuardedProvider struct {
+	provider domain.Provider
+	pipeline *Pipeline
+}
+
+// NewGuardedProvider wraps a provider with a pipeline
+func NewGuardedProvider(provider domain.Provider, pipeline *Pipeline) *GuardedProvider {
+	return &GuardedProvider{provider: provider, pipeline: pipeline}
+}
+
+// Unwrap returns the wrapped provider
+func (p *GuardedProvider) Unwrap() domain.Provider {
+	return p.provider
+}
+
+// Generate produces text from a prompt
+func (p *GuardedProvider) Generate(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+	prompt, err := p.pipeline.CheckInput(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	result, err := p.provider.Generate(ctx, prompt, options...)
+	if err != nil {
+		return "", err
+	}
+
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)}
+	response, err := p.checkResponse(ctx, messages, domain.Response{Content: result}, options)
+	return response.Content, err
+}
+
+// GenerateMessage produces text from a list of messages
+func (p *GuardedProvider) GenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+	messages, err := p.checkMessages(ctx, messages)
+	if err != nil {
+		return domain.Response{}, err
+	}
+	response, err := p.provider.GenerateMessage(ctx, messages, options...)
+	if err != nil {
+		return response, err
+	}
+	return p.checkResponse(ctx, messages, response, options)
+}
+
+// GenerateWithSchema produces structured output conforming to a schema. Output guards check
+// the JSON encoding of the result but rewrite only its string values, so rewrites cannot
+// break the JSON; re-asks repeat the prompt with the violations appended.
+func (p *GuardedProvider) GenerateWithSchema(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+	prompt, err := p.pipeline.CheckInput(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	request := prompt
+	for attempt := 1; ; attempt++ {
+		result, err := p.provider.GenerateWithSchema(ctx, request, schema, options...)
+		if err != nil || len(p.pipeline.output) == 0 {
+			return result, err
+		}
+
+		checked, violations, err := p.checkStructured(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) == 0 {
+			return checked, nil
+		}
+		if !canReask(violations) || attempt > p.pipeline.maxReasks {
+			return nil, &ViolationError{Stage: StageOutput, Violations: violations, Attempts: attempt}
+		}
+		request = prompt + "\n\n" + reaskPrompt(violations)
+	}
+}
+
+// Stream streams responses token by token
+func (p *GuardedProvider) Stream(ctx context.Context, prompt string, options ...domain.Option) (domain.ResponseStream, error) {
+	prompt, err := p.pipeline.CheckInput(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := p.provider.Stream(ctx, prompt, options...)
+	if err != nil {
+		return nil, err
+	}
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)}
+	return p.checkStream(ctx, messages, stream, options)
+}
+
+// StreamMessage streams responses from a list of messages
+func (p *GuardedProvider) StreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
+	messages, err := p.checkMessages(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := p.provider.StreamMessage(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	return p.checkStream(ctx, messages, stream, options)
+}
+
+// checkStructured runs the output guards over the JSON encoding of a structured result.
+// Rewriters are applied to each string value, and a violation that remains afterwards,
+// such as a card number in a number or the length of the document, is re-asked.
+func (p *GuardedProvider) checkStructured(ctx context.Context, result interface{}) (interface{}, []Violation, error) {
+	var violations []Violation
+	for _, r := range p.pipeline.output {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return nil, nil, err
+		}
+		reason, err := r.guard.Check(ctx, string(encoded))
+		if err != nil {
+			return nil, nil, fmt.Errorf("guard %s: %w", r.guard.Name(), err)
+		}
+		if reason == "" {
+			continue
+		}
+
+		action := r.action
+		if rewriter, ok := r.guard.(Rewriter); ok && action == ActionRewrite {
+			var value interface{}
+			if err := json.Unmarshal(encoded, &value); err != nil {
+				return nil, nil, err
+			}
+			rewritten, err := rewriteStrings(ctx, rewriter, value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("guard %s: %w", r.guard.Name(), err)
+			}
+			if encoded, err = json.Marshal(rewritten); err != nil {
+				return nil, nil, err
+			}
+			if reason, err = r.guard.Check(ctx, string(encoded)); err != nil {
+				return nil, nil, fmt.Errorf("guard %s: %w", r.guard.Name(), err)
+			}
+			if reason == "" {
+				result = rewritten
+				continue
+			}
+			action = ActionReask
+		}
+		if action == ActionRewrite {
+			action = ActionBlock
+		}
+		violations = append(violations, Violation{Guard: r.guard.Name(), Stage: StageOutput, Reason: reason, Action: action})
+	}
+	return result, violations, nil
+}
+
+// rewriteStrings rewrites the string values of decoded JSON, leaving keys and other values as they are
+func rewriteStrings(ctx context.Context, rewriter Rewriter, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return rewriter.Rewrite(ctx, v)
+	case map[string]interface{}:
+		rewritten := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			item, err := rewriteStrings(ctx, rewriter, item)
+			if err != nil {
+				return nil, err
+			}
+			rewritten[key] = item
+		}
+		return rewritten, nil
+	case []interface{}:
+		rewritten := make([]interface{}, len(v))
+		for i, item := range v {
+			item, err := rewriteStrings(ctx, rewriter, item)
+			if err != nil {
+				return nil, err
+			}
+			rewritten[i] = item
+		}
+		return rewritten, nil
+	default:
+		return value, nil
+	}
+}
+
+// checkMessages runs the input guards over the text of user messages, copying messages that are rewritten
+func (p *GuardedProvider) checkMessages(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	if len(p.pipeline.input) == 0 {
+		return messages, nil
+	}
+
+	var checked []domain.Message
+	for i, message := range messages {
+		if message.Role != domain.RoleUser {
+			continue
+		}
+
+		var content []domain.ContentPart
+		for j, part := range message.Content {
+			if part.Type != domain.ContentTypeText {
+				continue
+			}
+			text, err := p.pipeline.CheckInput(ctx, part.Text)
+			if err != nil {
+				return nil, err
+			}
+			if text == part.Text {
+				continue
+			}
+			if content == nil {
+				content = append([]domain.ContentPart(nil), message.Content...)
+			}
+			content[j].Text = text
+		}
+
+		if content != nil {
+			if checked == nil {
+				checked = append([]domain.Message(nil), messages...)
+			}
+			checked[i].Content = content
+		}
+	}
+	if checked == nil {
+		return messages, nil
+	}
+	return checked, nil
+}
+
+// checkResponse runs the output guards over a response, re-asking until it passes or the
+// re-asks are used up. Rewritten responses keep only the rewritten content, without choices.
+func (p *GuardedProvider) checkResponse(ctx context.Context, messages []domain.Message, response domain.Response, options []domain.Option) (domain.Response, error) {
+	if len(p.pipeline.output) == 0 {
+		return response, nil
+	}
+
+	conversation := append([]domain.Message(nil), messages...)
+	for attempt := 1; ; attempt++ {
+		content, violations, err := run(ctx, StageOutput, p.pipeline.output, response.Content)
+		if err != nil {
+			return domain.Response{}, err
+		}
+		if len(violations) == 0 {
+			if content != response.Content {
+				response.Content = content
+				response.Choices = nil
+			}
+			return response, nil
+		}
+		if !canReask(violations) || attempt > p.pipeline.maxReasks {
+			return domain.Response{}, &ViolationError{Stage: StageOutput, Violations: violations, Attempts: attempt}
+		}
+
+		conversation = append(conversation,
+			domain.NewTextMessage(domain.RoleAssistant, response.Content),
+			domain.NewTextMessage(domain.RoleUser, reaskPrompt(violations)),
+		)
+		if response, err = p.provider.GenerateMessage(ctx, conversation, options...); err != nil {
+			return domain.Response{}, err
+		}
+	}
+}
+
+// checkStream buffers a stream and checks the full response before replaying it. Responses
+// changed by rewrites or re-asks are sent as a single token.
+func (p *GuardedProvider) checkStream(ctx context.Context, messages []domain.Message, stream domain.ResponseStream, options []domain.Option) (domain.ResponseStream, error) {
+	if len(p.pipeline.output) == 0 {
+		return stream, nil
+	}
+
+	var tokens []domain.Token
+	var b strings.Builder
+	for token := range stream {
+		tokens = append(tokens, token)
+		b.WriteString(token.Text)
+	}
+	content := b.String()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	response, err := p.checkResponse(ctx, messages, domain.Response{Content: content}, options)
+	if err != nil {
+		return nil, err
+	}
+	if response.Content != content {
+		tokens = []domain.Token{{Text: response.Content, Finished: true}}
+	}
+
+	out := make(chan domain.Token, len(tokens))
+	for _, token := range tokens {
+		out <- token
+	}
+	close(out)
+	return out, nil
+}
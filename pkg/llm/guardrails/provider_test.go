@@ -0,0 +1,239 @@
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/llm/provider"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+)
+
+// tokenStream streams each word as a token
+func tokenStream(text string) domain.ResponseStream {
+	words := strings.SplitAfter(text, " ")
+	ch := make(chan domain.Token, len(words))
+	for i, word := range words {
+		ch <- domain.Token{Text: word, Finished: i == len(words)-1}
+	}
+	close(ch)
+	return ch
+}
+
+// collect concatenates the tokens of a stream
+func collect(stream domain.ResponseStream) string {
+	var b strings.Builder
+	for token := range stream {
+		b.WriteString(token.Text)
+	}
+	return b.String()
+}
+
+func TestGuardedProviderReask(t *testing.T) {
+	var calls [][]domain.Message
+	replies := []string{"I think it is 42.", "ANSWER: 42"}
+	mock := provider.NewMockProvider().WithGenerateMessageFunc(func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+		calls = append(calls, messages)
+		return domain.Response{Content: replies[len(calls)-1]}, nil
+	})
+
+	pipeline := NewPipeline().
+		Input(NewPIIGuard(), ActionRewrite).
+		Output(NewFuncGuard("answer_format", func(ctx context.Context, text string) (string, error) {
+			if !strings.HasPrefix(text, "ANSWER: ") {
+				return `must start with "ANSWER: "`, nil
+			}
+			return "", nil
+		}), ActionReask)
+	guarded := NewGuardedProvider(mock, pipeline)
+
+	messages := []domain.Message{
+		domain.NewTextMessage(domain.RoleSystem, "Contact admin@example.com for help"),
+		domain.NewTextMessage(domain.RoleUser, "I am jane@example.com. What is the answer?"),
+	}
+	response, err := guarded.GenerateMessage(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("GenerateMessage failed: %v", err)
+	}
+	if response.Content != "ANSWER: 42" {
+		t.Errorf("Expected the re-asked response, got %q", response.Content)
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 calls, got %d", len(calls))
+	}
+	if got := calls[0][1].Content[0].Text; got != "I am [EMAIL]. What is the answer?" {
+		t.Errorf("Expected the user message to be rewritten, got %q", got)
+	}
+	if got := calls[0][0].Content[0].Text; got != "Contact admin@example.com for help" {
+		t.Errorf("Expected system messages to be left alone, got %q", got)
+	}
+	if messages[1].Content[0].Text != "I am jane@example.com. What is the answer?" {
+		t.Errorf("Expected the caller's messages to be unchanged")
+	}
+
+	reask := calls[1]
+	if len(reask) != 4 || reask[2].Role != domain.RoleAssistant || !strings.Contains(reask[3].Content[0].Text, `must start with "ANSWER: "`) {
+		t.Errorf("Unexpected re-ask conversation: %+v", reask)
+	}
+}
+
+func TestGuardedProviderBlocks(t *testing.T) {
+	calls := 0
+	mock := provider.NewMockProvider().WithGenerateFunc(func(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+		calls++
+		return "This answer is far too long", nil
+	}).WithGenerateMessageFunc(func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+		calls++
+		return domain.Response{Content: "Still far too long"}, nil
+	})
+
+	pipeline := NewPipeline().
+		Input(NewDenylistGuard("forbidden"), ActionBlock).
+		Output(NewMaxLengthGuard(10), ActionReask).
+		WithMaxReasks(2)
+	guarded := NewGuardedProvider(mock, pipeline)
+
+	_, err := guarded.Generate(context.Background(), "Say something")
+	var violation *ViolationError
+	if !errors.As(err, &violation) {
+		t.Fatalf("Expected a ViolationError, got %v", err)
+	}
+	if violation.Stage != StageOutput || violation.Attempts != 3 || calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d attempts and %d calls", violation.Attempts, calls)
+	}
+	if !strings.Contains(err.Error(), "(after 3 attempts)") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+
+	calls = 0
+	if _, err := guarded.Generate(context.Background(), "Something forbidden"); !IsViolation(err) || calls != 0 {
+		t.Errorf("Expected the prompt to be blocked before the call, got %v after %d calls", err, calls)
+	}
+}
+
+func TestGuardedProviderStream(t *testing.T) {
+	mock := provider.NewMockProvider().WithStreamFunc(func(ctx context.Context, prompt string, options ...domain.Option) (domain.ResponseStream, error) {
+		return tokenStream("Write to bob@example.com today"), nil
+	}).WithStreamMessageFunc(func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
+		return tokenStream("The secret password is hunter2"), nil
+	})
+
+	pipeline := NewPipeline().
+		Output(NewPIIGuard(PIIEmail), ActionRewrite).
+		Output(NewDenylistGuard("password"), ActionBlock)
+	guarded := NewGuardedProvider(mock, pipeline)
+
+	stream, err := guarded.Stream(context.Background(), "Who do I write to?")
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if got := collect(stream); got != "Write to [EMAIL] today" {
+		t.Errorf("Expected the rewritten response, got %q", got)
+	}
+
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "What is the password?")}
+	if _, err := guarded.StreamMessage(context.Background(), messages); !IsViolation(err) {
+		t.Errorf("Expected the stream to be blocked, got %v", err)
+	}
+}
+
+func TestGuardedProviderSchema(t *testing.T) {
+	schema := &schemaDomain.Schema{
+		Type: "object",
+		Properties: map[string]schemaDomain.Property{
+			"name":  {Type: "string"},
+			"email": {Type: "string"},
+		},
+		Required: []string{"name"},
+	}
+
+	var prompts []string
+	mock := provider.NewMockProvider().WithGenerateWithSchemaFunc(func(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+		prompts = append(prompts, prompt)
+		if len(prompts) == 1 {
+			return map[string]interface{}{"email": "ada@example.com"}, nil
+		}
+		return map[string]interface{}{"name": "Ada", "email": "ada@example.com"}, nil
+	})
+
+	pipeline := NewPipeline().
+		Output(NewSchemaGuard(schema), ActionReask).
+		Output(NewPIIGuard(PIIEmail), ActionRewrite)
+	guarded := NewGuardedProvider(mock, pipeline)
+
+	result, err := guarded.GenerateWithSchema(context.Background(), "Describe Ada", schema)
+	if err != nil {
+		t.Fatalf("GenerateWithSchema failed: %v", err)
+	}
+	data, ok := result.(map[string]interface{})
+	if !ok || data["name"] != "Ada" || data["email"] != "[EMAIL]" {
+		t.Errorf("Unexpected result: %#v", result)
+	}
+	if len(prompts) != 2 || !strings.HasPrefix(prompts[1], "Describe Ada\n\n") || !strings.Contains(prompts[1], "name") {
+		t.Errorf("Unexpected re-ask prompts: %q", prompts)
+	}
+}
+
+func TestGuardedProviderSchemaRewrites(t *testing.T) {
+	tests := []struct {
+		name   string
+		guard  Guard
+		result map[string]interface{}
+		want   interface{}
+	}{
+		{
+			name:   "replacement with quotes",
+			guard:  NewDenylistGuard("secret").WithReplacement(`"hidden"`),
+			result: map[string]interface{}{"note": "the secret"},
+			want:   `the "hidden"`,
+		},
+		{
+			name:   "truncation",
+			guard:  NewMaxLengthGuard(20),
+			result: map[string]interface{}{"note": strings.Repeat("a", 30)},
+		},
+		{
+			name:   "card number in a number",
+			guard:  NewPIIGuard(PIICardNumber),
+			result: map[string]interface{}{"card": 4111111111111111},
+		},
+		{
+			name:   "match in a key",
+			guard:  NewPatternGuard("internal_ids", regexp.MustCompile(`ACCT-\d+`)),
+			result: map[string]interface{}{"ACCT-42": "open"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			mock := provider.NewMockProvider().WithGenerateWithSchemaFunc(func(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+				calls++
+				return tt.result, nil
+			})
+			guarded := NewGuardedProvider(mock, NewPipeline().Output(tt.guard, ActionRewrite))
+
+			result, err := guarded.GenerateWithSchema(context.Background(), "Describe the account", &schemaDomain.Schema{Type: "object"})
+			if tt.want != nil {
+				data, ok := result.(map[string]interface{})
+				if err != nil || !ok || data["note"] != tt.want {
+					t.Errorf("Expected a rewritten string, got %#v (%v)", result, err)
+				}
+				return
+			}
+
+			// Rewrites that cannot fix the JSON are re-asked instead of breaking it
+			var violation *ViolationError
+			if !errors.As(err, &violation) {
+				t.Fatalf("Expected a ViolationError, got %v", err)
+			}
+			if calls != 2 || violation.Attempts != 2 || violation.Violations[0].Action != ActionReask {
+				t.Errorf("Expected a re-ask, got %d calls and %+v", calls, violation)
+			}
+		})
+	}
+}
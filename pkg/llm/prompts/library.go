@@ -0,0 +1,204 @@
+package prompts
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// Library holds versioned templates and the partials they share. Templates are referenced by
+// name, which resolves to the pinned or latest version, or by "name@version".
+type Library struct {
+	mu        sync.RWMutex
+	templates map[string][]*Template
+	pinned    map[string]string
+	partials  map[string]string
+}
+
+// NewLibrary creates an empty library
+func NewLibrary() *Library {
+	return &Library{
+		templates: make(map[string][]*Template),
+		pinned:    make(map[string]string),
+		partials:  make(map[string]string),
+	}
+}
+
+// Add compiles a template with the library's partials and adds it. Adding a version that
+// already exists is an error.
+func (l *Library) Add(t *Template) error {
+	if t.Name == "" || strings.Contains(t.Name, "@") {
+		return fmt.Errorf("prompts: invalid template name %q", t.Name)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, existing := range l.templates[t.Name] {
+		if existing.Version == t.Version {
+			return fmt.Errorf("prompts: template %s already exists", t.ID())
+		}
+	}
+	if err := t.compile(l.partials); err != nil {
+		return err
+	}
+
+	versions := append(l.templates[t.Name], t)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareVersions(versions[i].Version, versions[j].Version) < 0
+	})
+	l.templates[t.Name] = versions
+	return nil
+}
+
+// AddPartial adds or replaces a partial, which templates include with {{template "name" .}}.
+// Templates already in the library are recompiled.
+func (l *Library) AddPartial(name, content string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous, existed := l.partials[name]
+	l.partials[name] = content
+	for _, versions := range l.templates {
+		for _, t := range versions {
+			if err := t.compile(l.partials); err != nil {
+				if existed {
+					l.partials[name] = previous
+				} else {
+					delete(l.partials, name)
+				}
+				l.recompileLocked()
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recompileLocked compiles all templates after the partials were restored; they compiled
+// with these partials before, so errors cannot occur
+func (l *Library) recompileLocked() {
+	for _, versions := range l.templates {
+		for _, t := range versions {
+			_ = t.compile(l.partials)
+		}
+	}
+}
+
+// Get returns a template by reference: "name" for the pinned or latest version, or
+// "name@version" for a specific version
+func (l *Library) Get(ref string) (*Template, error) {
+	name, version, versioned := strings.Cut(ref, "@")
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	versions := l.templates[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("prompts: %s: %w", name, ErrNotFound)
+	}
+	if !versioned {
+		pinned, ok := l.pinned[name]
+		if !ok {
+			return versions[len(versions)-1], nil
+		}
+		version = pinned
+	}
+	for _, t := range versions {
+		if t.Version == version {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("prompts: %s@%s: %w", name, version, ErrNotFound)
+}
+
+// Versions returns the versions of a template, oldest first
+func (l *Library) Versions(name string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	versions := make([]string, len(l.templates[name]))
+	for i, t := range l.templates[name] {
+		versions[i] = t.Version
+	}
+	return versions
+}
+
+// Names returns the names of all templates, sorted
+func (l *Library) Names() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	names := make([]string, 0, len(l.templates))
+	for name := range l.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Pin makes Get return a specific version of a template instead of the latest, for example
+// to roll back a prompt
+func (l *Library) Pin(name, version string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, t := range l.templates[name] {
+		if t.Version == version {
+			l.pinned[name] = version
+			return nil
+		}
+	}
+	return fmt.Errorf("prompts: %s@%s: %w", name, version, ErrNotFound)
+}
+
+// Unpin makes Get return the latest version of a template again
+func (l *Library) Unpin(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.pinned, name)
+}
+
+// Render renders a single-message template by reference
+func (l *Library) Render(ref string, vars Vars) (string, error) {
+	t, err := l.Get(ref)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(vars)
+}
+
+// RenderMessages renders a template by reference to the messages of a conversation
+func (l *Library) RenderMessages(ref string, vars Vars) ([]domain.Message, error) {
+	t, err := l.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+	return t.RenderMessages(vars)
+}
+
+// compareVersions orders versions by their dot-separated parts, numerically where both parts
+// are numbers. A leading "v" is ignored, so "v1.10" comes after "1.9".
+func compareVersions(a, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		if i >= len(aParts) {
+			return -1
+		}
+		if i >= len(bParts) {
+			return 1
+		}
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && aParts[i] != bParts[i]:
+			return strings.Compare(aParts[i], bParts[i])
+		}
+	}
+	return 0
+}
@@ -0,0 +1,195 @@
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// templateFile is a YAML template file, or the front matter of a Markdown template file
+type templateFile struct {
+	Name        string            `yaml:"name"`
+	Version     string            `yaml:"version"`
+	Description string            `yaml:"description"`
+	Role        domain.Role       `yaml:"role"`
+	Variables   []Variable        `yaml:"variables"`
+	Messages    []MessageTemplate `yaml:"messages"`
+}
+
+// template converts the file to a template
+func (f *templateFile) template() *Template {
+	return &Template{
+		Name:        f.Name,
+		Version:     f.Version,
+		Description: f.Description,
+		Variables:   f.Variables,
+		Messages:    f.Messages,
+	}
+}
+
+// ParseYAML parses a YAML template with a list of messages:
+//
+//	name: support
+//	version: 1.2.0
+//	variables:
+//	  - name: question
+//	    type: string
+//	    required: true
+//	messages:
+//	  - role: system
+//	    content: You are a support agent.
+//	  - role: user
+//	    content: "{{.question}}"
+func ParseYAML(data []byte) (*Template, error) {
+	var file templateFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("prompts: parsing YAML template: %w", err)
+	}
+	return file.template(), nil
+}
+
+// ParseMarkdown parses a Markdown template: optional YAML front matter between "---" lines,
+// with the same fields as a YAML template, followed by the content of a single message. The
+// message is a system message unless the front matter sets a role.
+func ParseMarkdown(data []byte) (*Template, error) {
+	frontMatter, body, err := splitFrontMatter(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var file templateFile
+	if err := yaml.Unmarshal(frontMatter, &file); err != nil {
+		return nil, fmt.Errorf("prompts: parsing front matter: %w", err)
+	}
+	if len(file.Messages) > 0 {
+		return nil, fmt.Errorf("prompts: Markdown templates take their message from the body, not the front matter")
+	}
+	role := file.Role
+	if role == "" {
+		role = domain.RoleSystem
+	}
+	file.Messages = []MessageTemplate{{Role: role, Content: strings.TrimSpace(string(body))}}
+	return file.template(), nil
+}
+
+// splitFrontMatter separates YAML front matter from the rest of a Markdown file
+func splitFrontMatter(data []byte) ([]byte, []byte, error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(data, []byte("---\n")) {
+		return nil, data, nil
+	}
+	rest := data[len("---\n"):]
+	if bytes.HasPrefix(rest, []byte("---\n")) {
+		return nil, rest[len("---\n"):], nil
+	}
+	end := bytes.Index(rest, []byte("\n---\n"))
+	if end < 0 {
+		if !bytes.HasSuffix(rest, []byte("\n---")) {
+			return nil, nil, fmt.Errorf("prompts: unterminated front matter")
+		}
+		return rest[:len(rest)-len("\n---")], nil, nil
+	}
+	return rest[:end], rest[end+len("\n---\n"):], nil
+}
+
+// LoadDir loads a library from the template files in a directory and its subdirectories
+func LoadDir(dir string) (*Library, error) {
+	return LoadFS(os.DirFS(dir))
+}
+
+// LoadFS loads a library from the template files in a file system, such as an embed.FS
+func LoadFS(fsys fs.FS) (*Library, error) {
+	l := NewLibrary()
+	if err := l.LoadFS(fsys); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// LoadFS adds the template files in a file system to the library:
+//
+//   - .md and .markdown files are Markdown templates, .yaml and .yml files YAML templates
+//   - files whose name starts with "_" are partials, named after the file without the "_"
+//     and extension; their front matter is optional and may set the name
+//   - templates are named after the file unless they set a name, and must set a version,
+//     so several files can hold versions of the same template
+func (l *Library) LoadFS(fsys fs.FS) error {
+	var partials, templates []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch path.Ext(p) {
+		case ".md", ".markdown", ".yaml", ".yml":
+		default:
+			return nil
+		}
+		if strings.HasPrefix(path.Base(p), "_") {
+			partials = append(partials, p)
+		} else {
+			templates = append(templates, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("prompts: loading templates: %w", err)
+	}
+	sort.Strings(partials)
+	sort.Strings(templates)
+
+	for _, p := range partials {
+		t, err := parseFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		if len(t.Messages) != 1 {
+			return fmt.Errorf("%s: prompts: partials must have exactly one message", p)
+		}
+		if err := l.AddPartial(t.Name, t.Messages[0].Content); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	for _, p := range templates {
+		t, err := parseFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		if t.Version == "" {
+			return fmt.Errorf("%s: prompts: template %s has no version", p, t.Name)
+		}
+		if err := l.Add(t); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// parseFile parses a template file, naming the template after the file if it has no name
+func parseFile(fsys fs.FS, p string) (*Template, error) {
+	data, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return nil, fmt.Errorf("prompts: %w", err)
+	}
+
+	var t *Template
+	if ext := path.Ext(p); ext == ".yaml" || ext == ".yml" {
+		t, err = ParseYAML(data)
+	} else {
+		t, err = ParseMarkdown(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimPrefix(strings.TrimSuffix(path.Base(p), path.Ext(p)), "_")
+	}
+	return t, nil
+}
@@ -0,0 +1,235 @@
+package prompts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+func TestTemplateVariables(t *testing.T) {
+	tmpl := NewTemplate("support",
+		System("You support {{.product}} customers.{{if .tone}} Be {{.tone}}.{{end}} Topics: {{join \", \" .topics}}."),
+		User("{{.question}}"),
+	).WithVersion("1.0.0").
+		WithVariable(Variable{Name: "product", Type: VarString, Required: true}).
+		WithVariable(Variable{Name: "tone", Type: VarString}).
+		WithVariable(Variable{Name: "topics", Type: VarList, Default: []interface{}{"billing"}}).
+		WithVariable(Variable{Name: "question", Type: VarString, Required: true})
+
+	messages, err := tmpl.RenderMessages(Vars{"product": "Acme", "question": "Where is my invoice?"})
+	if err != nil {
+		t.Fatalf("RenderMessages failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Role != domain.RoleSystem || messages[1].Role != domain.RoleUser {
+		t.Fatalf("Unexpected messages: %+v", messages)
+	}
+	if got := messages[0].Content[0].Text; got != "You support Acme customers. Topics: billing." {
+		t.Errorf("Unexpected system message %q", got)
+	}
+	if got := messages[1].Content[0].Text; got != "Where is my invoice?" {
+		t.Errorf("Unexpected user message %q", got)
+	}
+
+	for name, vars := range map[string]Vars{
+		"missing":    {"question": "?"},
+		"mistyped":   {"product": 42, "question": "?"},
+		"undeclared": {"product": "Acme", "question": "?", "prodcut": "typo"},
+	} {
+		if _, err := tmpl.RenderMessages(vars); !errors.Is(err, ErrInvalidVariable) {
+			t.Errorf("%s: expected ErrInvalidVariable, got %v", name, err)
+		}
+	}
+
+	if _, err := tmpl.Render(Vars{"product": "Acme", "question": "?"}); err == nil || !strings.Contains(err.Error(), "use RenderMessages") {
+		t.Errorf("Expected Render to reject multiple messages, got %v", err)
+	}
+}
+
+func TestVariableTypes(t *testing.T) {
+	valid := map[VarType]interface{}{
+		VarString: "text",
+		VarInt:    3.0, // numbers decoded from JSON
+		VarNumber: 2.5,
+		VarBool:   true,
+		VarList:   []string{"a"},
+		VarMap:    struct{ Name string }{"x"},
+		VarAny:    nil,
+	}
+	for varType, value := range valid {
+		if err := (Variable{Name: "v", Type: varType}).check(value); err != nil {
+			t.Errorf("Expected %v to be a valid %s: %v", value, varType, err)
+		}
+	}
+	if err := (Variable{Name: "v", Type: VarInt}).check(2.5); err == nil {
+		t.Errorf("Expected 2.5 to be rejected as an int")
+	}
+
+	// Templates without declarations accept any variables, but not missing ones
+	tmpl := NewTemplate("free", System("Hello {{.name}}"))
+	if got, err := tmpl.Render(Vars{"name": "Ada", "extra": 1}); err != nil || got != "Hello Ada" {
+		t.Errorf("Unexpected render %q (%v)", got, err)
+	}
+	if _, err := tmpl.Render(nil); err == nil {
+		t.Errorf("Expected an error for a missing variable")
+	}
+}
+
+func TestLibraryVersions(t *testing.T) {
+	lib := NewLibrary()
+	for _, version := range []string{"1.10.0", "1.2.0", "1.9.1"} {
+		if err := lib.Add(NewTemplate("greet", System("v"+version+" {{.name}}")).WithVersion(version)); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := lib.Add(NewTemplate("greet", System("dup")).WithVersion("1.2.0")); err == nil {
+		t.Errorf("Expected duplicate versions to be rejected")
+	}
+
+	if versions := strings.Join(lib.Versions("greet"), ","); versions != "1.2.0,1.9.1,1.10.0" {
+		t.Errorf("Unexpected version order %s", versions)
+	}
+	if got, _ := lib.Render("greet", Vars{"name": "Ada"}); got != "v1.10.0 Ada" {
+		t.Errorf("Expected the latest version, got %q", got)
+	}
+	if got, _ := lib.Render("greet@1.2.0", Vars{"name": "Ada"}); got != "v1.2.0 Ada" {
+		t.Errorf("Expected the requested version, got %q", got)
+	}
+
+	if err := lib.Pin("greet", "1.9.1"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if tmpl, _ := lib.Get("greet"); tmpl.ID() != "greet@1.9.1" {
+		t.Errorf("Expected the pinned version, got %s", tmpl.ID())
+	}
+	lib.Unpin("greet")
+	if tmpl, _ := lib.Get("greet"); tmpl.Version != "1.10.0" {
+		t.Errorf("Expected the latest version after unpinning, got %s", tmpl.Version)
+	}
+
+	for _, ref := range []string{"missing", "greet@2.0.0"} {
+		if _, err := lib.Get(ref); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for %s, got %v", ref, err)
+		}
+	}
+	if err := lib.Pin("greet", "2.0.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when pinning a missing version, got %v", err)
+	}
+}
+
+func TestLibraryPartials(t *testing.T) {
+	lib := NewLibrary()
+	if err := lib.AddPartial("rules", "Never share {{.secret_kind}}."); err != nil {
+		t.Fatalf("AddPartial failed: %v", err)
+	}
+	if err := lib.Add(NewTemplate("agent", System(`You are helpful. {{template "rules" .}}`)).WithVersion("1")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if got, _ := lib.Render("agent", Vars{"secret_kind": "passwords"}); got != "You are helpful. Never share passwords." {
+		t.Errorf("Unexpected render %q", got)
+	}
+
+	// Replacing a partial recompiles the templates using it
+	if err := lib.AddPartial("rules", "Be kind."); err != nil {
+		t.Fatalf("AddPartial failed: %v", err)
+	}
+	if got, _ := lib.Render("agent", nil); got != "You are helpful. Be kind." {
+		t.Errorf("Unexpected render %q", got)
+	}
+
+	// An invalid partial is rejected and the previous one kept
+	if err := lib.AddPartial("rules", "{{.broken"); err == nil {
+		t.Errorf("Expected an invalid partial to be rejected")
+	}
+	if got, _ := lib.Render("agent", nil); got != "You are helpful. Be kind." {
+		t.Errorf("Expected the previous partial, got %q", got)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_tone.md": {Data: []byte("Answer in a {{.tone}} tone.")},
+		"support/v1.md": {Data: []byte(`---
+name: support
+version: 1.0.0
+description: First support prompt
+variables:
+  - name: tone
+    type: string
+    default: friendly
+---
+You answer support questions. {{template "tone" .}}
+`)},
+		"support/v2.md": {Data: []byte("---\nname: support\nversion: 2.0.0\n---\nYou answer support questions briefly.\n")},
+		"classify.yaml": {Data: []byte(`version: "3"
+variables:
+  - name: text
+    type: string
+    required: true
+  - name: labels
+    type: list
+    required: true
+messages:
+  - role: system
+    content: "Classify the text as one of: {{join \", \" .labels}}."
+  - role: user
+    content: "{{.text}}"
+`)},
+		"README.txt": {Data: []byte("ignored")},
+	}
+
+	lib, err := LoadFS(fsys)
+	if err != nil {
+		t.Fatalf("LoadFS failed: %v", err)
+	}
+	if names := strings.Join(lib.Names(), ","); names != "classify,support" {
+		t.Errorf("Unexpected templates %s", names)
+	}
+
+	if got, _ := lib.Render("support@1.0.0", nil); got != "You answer support questions. Answer in a friendly tone." {
+		t.Errorf("Unexpected render %q", got)
+	}
+	if tmpl, _ := lib.Get("support@1.0.0"); tmpl.Description != "First support prompt" {
+		t.Errorf("Expected the description from the front matter, got %q", tmpl.Description)
+	}
+	if got, _ := lib.Render("support", nil); got != "You answer support questions briefly." {
+		t.Errorf("Expected the latest version, got %q", got)
+	}
+
+	messages, err := lib.RenderMessages("classify", Vars{"text": "I love it", "labels": []string{"positive", "negative"}})
+	if err != nil {
+		t.Fatalf("RenderMessages failed: %v", err)
+	}
+	if messages[0].Content[0].Text != "Classify the text as one of: positive, negative." || messages[1].Role != domain.RoleUser {
+		t.Errorf("Unexpected messages %+v", messages)
+	}
+
+	if _, err := LoadFS(fstest.MapFS{"unversioned.md": {Data: []byte("Hello")}}); err == nil || !strings.Contains(err.Error(), "has no version") {
+		t.Errorf("Expected templates without versions to be rejected, got %v", err)
+	}
+	if _, err := LoadFS(fstest.MapFS{"broken.md": {Data: []byte("---\nversion: 1\n---\n{{.x")}}); err == nil || !strings.HasPrefix(err.Error(), "broken.md: ") {
+		t.Errorf("Expected the file in the error, got %v", err)
+	}
+}
+
+func TestParseMarkdownRole(t *testing.T) {
+	tmpl, err := ParseMarkdown([]byte("---\r\nrole: user\r\n---\r\n\r\nSummarise {{.doc}}\r\n"))
+	if err != nil {
+		t.Fatalf("ParseMarkdown failed: %v", err)
+	}
+	if tmpl.Messages[0].Role != domain.RoleUser || tmpl.Messages[0].Content != "Summarise {{.doc}}" {
+		t.Errorf("Unexpected message %+v", tmpl.Messages[0])
+	}
+
+	if tmpl, err := ParseMarkdown([]byte("No front matter")); err != nil || tmpl.Messages[0].Role != domain.RoleSystem {
+		t.Errorf("Expected a system message, got %+v (%v)", tmpl, err)
+	}
+	if _, err := ParseMarkdown([]byte("---\nname: x\nNo end")); err == nil {
+		t.Errorf("Expected unterminated front matter to be rejected")
+	}
+	if _, err := NewTemplate("bad", MessageTemplate{Role: "narrator", Content: "x"}).Render(nil); err == nil {
+		t.Errorf("Expected an invalid role to be rejected")
+	}
+}
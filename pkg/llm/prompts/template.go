@@ -0,0 +1,337 @@
+// Package prompts manages prompt templates. Templates are built on text/template, declare
+// typed variables, can include partials and carry explicit versions, so prompts can be kept
+// in files, reviewed and rolled back independently of code. Rendering produces a string or
+// the []domain.Message of a conversation.
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// Common errors
+var (
+	// ErrNotFound is returned for unknown templates and versions
+	ErrNotFound = errors.New("prompt template not found")
+	// ErrInvalidVariable is returned for missing, unknown or mistyped variables
+	ErrInvalidVariable = errors.New("invalid template variable")
+)
+
+// VarType is the type of a template variable
+type VarType string
+
+const (
+	VarString VarType = "string"
+	VarInt    VarType = "int"
+	VarNumber VarType = "number"
+	VarBool   VarType = "bool"
+	VarList   VarType = "list"
+	VarMap    VarType = "map"
+	VarAny    VarType = "any"
+)
+
+// Variable declares a value a template expects
+type Variable struct {
+	Name        string      `yaml:"name"`
+	Type        VarType     `yaml:"type"`
+	Description string      `yaml:"description"`
+	Required    bool        `yaml:"required"`
+	Default     interface{} `yaml:"default"`
+}
+
+// MessageTemplate is the template of one message of a conversation
+type MessageTemplate struct {
+	Role    domain.Role `yaml:"role"`
+	Content string      `yaml:"content"`
+}
+
+// System creates a system message template
+func System(content string) MessageTemplate {
+	return MessageTemplate{Role: domain.RoleSystem, Content: content}
+}
+
+// User creates a user message template
+func User(content string) MessageTemplate {
+	return MessageTemplate{Role: domain.RoleUser, Content: content}
+}
+
+// Assistant creates an assistant message template, for example for few-shot answers
+func Assistant(content string) MessageTemplate {
+	return MessageTemplate{Role: domain.RoleAssistant, Content: content}
+}
+
+// Vars are the values a template is rendered with
+type Vars map[string]interface{}
+
+// Template is a versioned prompt of one or more messages. Message contents use text/template
+// syntax, with variables accessed as {{.name}} and partials included with {{template "name" .}}.
+type Template struct {
+	Name        string
+	Version     string
+	Description string
+	Variables   []Variable
+	Messages    []MessageTemplate
+
+	mu       sync.Mutex
+	compiled []*template.Template
+}
+
+// NewTemplate creates a template from message templates
+func NewTemplate(name string, messages ...MessageTemplate) *Template {
+	return &Template{Name: name, Messages: messages}
+}
+
+// WithVersion sets the template's version
+func (t *Template) WithVersion(version string) *Template {
+	t.Version = version
+	return t
+}
+
+// WithDescription sets the template's description
+func (t *Template) WithDescription(description string) *Template {
+	t.Description = description
+	return t
+}
+
+// WithVariable declares a variable
+func (t *Template) WithVariable(variable Variable) *Template {
+	t.Variables = append(t.Variables, variable)
+	return t
+}
+
+// ID returns the template's reference, "name@version", or the name for unversioned templates
+func (t *Template) ID() string {
+	if t.Version == "" {
+		return t.Name
+	}
+	return t.Name + "@" + t.Version
+}
+
+// Render renders a template of a single message to a string, such as a system prompt
+func (t *Template) Render(vars Vars) (string, error) {
+	if len(t.Messages) != 1 {
+		return "", fmt.Errorf("prompts: template %s has %d messages, use RenderMessages", t.ID(), len(t.Messages))
+	}
+	messages, err := t.RenderMessages(vars)
+	if err != nil {
+		return "", err
+	}
+	return messages[0].Content[0].Text, nil
+}
+
+// RenderMessages renders the template to the messages of a conversation
+func (t *Template) RenderMessages(vars Vars) ([]domain.Message, error) {
+	compiled, err := t.templates()
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.resolve(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]domain.Message, len(t.Messages))
+	for i, message := range t.Messages {
+		var b strings.Builder
+		if err := compiled[i].Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("prompts: rendering template %s: %w", t.ID(), err)
+		}
+		messages[i] = domain.NewTextMessage(message.Role, b.String())
+	}
+	return messages, nil
+}
+
+// templates returns the compiled message templates, compiling them without partials if the
+// template was not added to a library
+func (t *Template) templates() ([]*template.Template, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.compiled == nil {
+		if err := t.compileLocked(nil); err != nil {
+			return nil, err
+		}
+	}
+	return t.compiled, nil
+}
+
+// compile parses the message templates with the given partials
+func (t *Template) compile(partials map[string]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.compileLocked(partials)
+}
+
+func (t *Template) compileLocked(partials map[string]string) error {
+	if len(t.Messages) == 0 {
+		return fmt.Errorf("prompts: template %s has no messages", t.ID())
+	}
+	for _, variable := range t.Variables {
+		if variable.Default == nil {
+			continue
+		}
+		if err := variable.check(variable.Default); err != nil {
+			return fmt.Errorf("prompts: template %s: default of %w", t.ID(), err)
+		}
+	}
+
+	compiled := make([]*template.Template, len(t.Messages))
+	for i, message := range t.Messages {
+		switch message.Role {
+		case domain.RoleSystem, domain.RoleUser, domain.RoleAssistant, domain.RoleTool:
+		default:
+			return fmt.Errorf("prompts: template %s: message %d has invalid role %q", t.ID(), i+1, message.Role)
+		}
+
+		tmpl := template.New(fmt.Sprintf("%s#%d", t.ID(), i+1)).Funcs(funcs).Option("missingkey=error")
+		for name, content := range partials {
+			if _, err := tmpl.New(name).Parse(content); err != nil {
+				return fmt.Errorf("prompts: parsing partial %s: %w", name, err)
+			}
+		}
+		if _, err := tmpl.Parse(message.Content); err != nil {
+			return fmt.Errorf("prompts: parsing template %s: %w", t.ID(), err)
+		}
+		compiled[i] = tmpl
+	}
+	t.compiled = compiled
+	return nil
+}
+
+// resolve checks the variables against the declarations and fills in defaults. Templates
+// without declared variables accept any variables.
+func (t *Template) resolve(vars Vars) (map[string]interface{}, error) {
+	data := make(map[string]interface{}, len(vars)+len(t.Variables))
+	for name, value := range vars {
+		data[name] = value
+	}
+	if len(t.Variables) == 0 {
+		return data, nil
+	}
+
+	declared := make(map[string]bool, len(t.Variables))
+	for _, variable := range t.Variables {
+		declared[variable.Name] = true
+		value, ok := data[variable.Name]
+		if !ok || value == nil {
+			switch {
+			case variable.Default != nil:
+				data[variable.Name] = variable.Default
+			case variable.Required:
+				return nil, fmt.Errorf("prompts: template %s: variable %q is required: %w", t.ID(), variable.Name, ErrInvalidVariable)
+			default:
+				data[variable.Name] = zeroValue(variable.Type)
+			}
+			continue
+		}
+		if err := variable.check(value); err != nil {
+			return nil, fmt.Errorf("prompts: template %s: %w", t.ID(), err)
+		}
+	}
+	for name := range vars {
+		if !declared[name] {
+			return nil, fmt.Errorf("prompts: template %s: variable %q is not declared: %w", t.ID(), name, ErrInvalidVariable)
+		}
+	}
+	return data, nil
+}
+
+// check reports a value that does not match the variable's type
+func (v Variable) check(value interface{}) error {
+	if v.Type == "" || v.Type == VarAny {
+		return nil
+	}
+
+	val := reflect.ValueOf(value)
+	kind := val.Kind()
+	ok := false
+	switch v.Type {
+	case VarString:
+		ok = kind == reflect.String
+	case VarInt:
+		ok = isInt(kind) || isFloat(kind) && val.Float() == float64(int64(val.Float()))
+	case VarNumber:
+		ok = isInt(kind) || isFloat(kind)
+	case VarBool:
+		ok = kind == reflect.Bool
+	case VarList:
+		ok = kind == reflect.Slice || kind == reflect.Array
+	case VarMap:
+		ok = kind == reflect.Map || kind == reflect.Struct || kind == reflect.Ptr && val.Elem().Kind() == reflect.Struct
+	default:
+		return fmt.Errorf("variable %q has unknown type %q: %w", v.Name, v.Type, ErrInvalidVariable)
+	}
+	if !ok {
+		return fmt.Errorf("variable %q must be %s, got %T: %w", v.Name, v.Type, value, ErrInvalidVariable)
+	}
+	return nil
+}
+
+func isInt(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Uint64
+}
+
+func isFloat(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// zeroValue is the value of an optional variable that was not given, so templates can test it with if
+func zeroValue(varType VarType) interface{} {
+	switch varType {
+	case VarString:
+		return ""
+	case VarInt:
+		return 0
+	case VarNumber:
+		return 0.0
+	case VarBool:
+		return false
+	case VarList:
+		return []interface{}{}
+	case VarMap:
+		return map[string]interface{}{}
+	default:
+		return nil
+	}
+}
+
+// funcs are the helper functions available in templates
+var funcs = template.FuncMap{
+	"join":  join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"indent": func(spaces int, text string) string {
+		pad := strings.Repeat(" ", spaces)
+		return pad + strings.ReplaceAll(text, "\n", "\n"+pad)
+	},
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || reflect.ValueOf(value).IsZero() {
+			return fallback
+		}
+		return value
+	},
+}
+
+// join joins the items of a list, which may hold any values, with a separator
+func join(sep string, items interface{}) (string, error) {
+	val := reflect.ValueOf(items)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected a list, got %T", items)
+	}
+	parts := make([]string, val.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(val.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
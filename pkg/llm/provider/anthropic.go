@@ -6,12 +6,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/lexlapax/go-llms/pkg/llm/domain"
 	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
 	"github.com/lexlapax/go-llms/pkg/structured/processor"
+	"github.com/lexlapax/go-llms/pkg/util/httplog"
 	"github.com/lexlapax/go-llms/pkg/util/json"
 )
 
@@ -29,6 +31,13 @@ type AnthropicProvider struct {
 	metadata     map[string]string
 	// Optimization: cache for converted messages
 	messageCache *MessageCache
+	// Inline media above this size is uploaded with the Files API (0 disables)
+	autoUploadThreshold int
+	uploadCache         *fileUploadCache
+	// mediaNormalizer prepares media for the provider's limits (nil disables)
+	mediaNormalizer *MediaNormalizer
+	// requestLogging logs HTTP requests and responses (nil disables)
+	requestLogging *requestLogging
 }
 
 // NewAnthropicProvider creates a new Anthropic provider
@@ -40,6 +49,9 @@ func NewAnthropicProvider(apiKey, model string, options ...domain.ProviderOption
 		httpClient:   http.DefaultClient,
 		metadata:     make(map[string]string),
 		messageCache: NewMessageCache(),
+		uploadCache:  newFileUploadCache(),
+
+		mediaNormalizer: NewMediaNormalizer("anthropic", AnthropicMediaLimits()),
 	}
 
 	for _, option := range options {
@@ -60,7 +72,13 @@ func (p *AnthropicProvider) SetBaseURL(url string) {
 
 // SetHTTPClient sets the HTTP client
 func (p *AnthropicProvider) SetHTTPClient(client *http.Client) {
-	p.httpClient = client
+	p.httpClient = p.requestLogging.wrap(client)
+}
+
+// SetRequestLogging logs HTTP requests and responses, redacting the API key and the redactor's patterns
+func (p *AnthropicProvider) SetRequestLogging(logger *slog.Logger, redactor *httplog.Redactor) {
+	p.requestLogging = newRequestLogging(logger, redactor, p.apiKey)
+	p.httpClient = p.requestLogging.wrap(p.httpClient)
 }
 
 // SetSystemPrompt sets the system prompt for Anthropic API calls
@@ -73,6 +91,33 @@ func (p *AnthropicProvider) SetMetadata(metadata map[string]string) {
 	p.metadata = metadata
 }
 
+// SetAutoUploadThreshold uploads inline images and files larger than bytes with the Files API
+func (p *AnthropicProvider) SetAutoUploadThreshold(bytes int) {
+	p.autoUploadThreshold = bytes
+}
+
+// SetMediaNormalizer sets the media normalization stage run before each request; nil disables it
+func (p *AnthropicProvider) SetMediaNormalizer(normalizer *MediaNormalizer) {
+	p.mediaNormalizer = normalizer
+}
+
+// prepareMessages normalizes media, uploads large media and validates the messages
+func (p *AnthropicProvider) prepareMessages(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	messages, err := prepareMedia(ctx, p.mediaNormalizer, p.uploadLargeMedia, messages)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.validateContentTypesForAnthropic(messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// uploadLargeMedia replaces inline media above the auto-upload threshold with uploaded file references
+func (p *AnthropicProvider) uploadLargeMedia(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	return uploadLargeMedia(ctx, p, p.uploadCache, p.autoUploadThreshold, anthropicUploadableTypes, messages)
+}
+
 // Generate produces text from a prompt
 func (p *AnthropicProvider) Generate(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
 	// Create a simple text message using the new structure
@@ -91,7 +136,14 @@ func (p *AnthropicProvider) validateContentTypesForAnthropic(messages []domain.M
 	for _, msg := range messages {
 		if msg.Content != nil {
 			for _, part := range msg.Content {
-				// Anthropic currently supports text and image content types
+				// Anthropic currently supports text and image content types, PDF and text
+				// documents, and files uploaded with the Files API
+				if part.Type == domain.ContentTypeFile && part.File != nil {
+					if part.File.FileID == "" && !isAnthropicDocumentType(part.File.MimeType) {
+						return domain.NewUnsupportedContentTypeError("Anthropic", part.Type)
+					}
+					continue
+				}
 				if part.Type != domain.ContentTypeText && part.Type != domain.ContentTypeImage {
 					return domain.NewUnsupportedContentTypeError("Anthropic", part.Type)
 				}
@@ -157,6 +209,10 @@ func (p *AnthropicProvider) ConvertMessagesToAnthropicFormat(messages []domain.M
 							// URL-based image
 							sourcePart["type"] = "url"
 							sourcePart["url"] = part.Image.Source.URL
+						} else if part.Image.Source.Type == domain.SourceTypeFile {
+							// Image uploaded with the Files API
+							sourcePart["type"] = "file"
+							sourcePart["file_id"] = part.Image.Source.FileID
 						} else {
 							// Base64-encoded image
 							sourcePart["type"] = "base64"
@@ -166,6 +222,9 @@ func (p *AnthropicProvider) ConvertMessagesToAnthropicFormat(messages []domain.M
 
 						imagePart["source"] = sourcePart
 						contentParts = append(contentParts, imagePart)
+					case domain.ContentTypeFile:
+						// PDF or text document, inline or uploaded with the Files API
+						contentParts = append(contentParts, anthropicDocumentBlock(part.File))
 					}
 				}
 
@@ -199,7 +258,11 @@ func (p *AnthropicProvider) buildAnthropicRequestBody(
 
 	// Add required fields
 	requestBody["model"] = p.model
-	requestBody["messages"] = messages
+	if options.Citations {
+		requestBody["messages"] = withAnthropicCitations(messages)
+	} else {
+		requestBody["messages"] = messages
+	}
 
 	// Add system message if present from messages or from provider configuration
 	if systemMessage != "" {
@@ -208,9 +271,9 @@ func (p *AnthropicProvider) buildAnthropicRequestBody(
 		requestBody["system"] = p.systemPrompt
 	}
 
-	// Add metadata if present
-	if len(p.metadata) > 0 {
-		requestBody["metadata"] = p.metadata
+	// Add metadata if present, merging per-call metadata over the provider's
+	if metadata := mergeAnthropicMetadata(p.metadata, options); len(metadata) > 0 {
+		requestBody["metadata"] = metadata
 	}
 
 	// Add temperature if it differs from default
@@ -236,9 +299,10 @@ func (p *AnthropicProvider) buildAnthropicRequestBody(
 
 // GenerateMessage produces text from a list of messages - optimized version
 func (p *AnthropicProvider) GenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
-	// Validate content types
-	if err := p.validateContentTypesForAnthropic(messages); err != nil {
-		return domain.Response{}, err
+	// Normalize media, upload large media and validate content types
+	messages, prepareErr := p.prepareMessages(ctx, messages)
+	if prepareErr != nil {
+		return domain.Response{}, prepareErr
 	}
 
 	// Apply options
@@ -261,7 +325,7 @@ func (p *AnthropicProvider) GenerateMessage(ctx context.Context, messages []doma
 	}
 
 	// Create HTTP request - reuse the buffer directly
-	url := fmt.Sprintf("%s/v1/messages", p.baseURL)
+	url := fmt.Sprintf("%s/v1/messages", requestBaseURL(p.baseURL, providerOptions))
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, requestBuffer)
 	if err != nil {
 		return domain.Response{}, fmt.Errorf("failed to create request: %w", err)
@@ -271,6 +335,10 @@ func (p *AnthropicProvider) GenerateMessage(ctx context.Context, messages []doma
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", p.apiKey)
 	req.Header.Set("anthropic-version", "2023-06-01") // Use appropriate API version
+	if anthropicUsesFiles(messages) {
+		req.Header.Set("anthropic-beta", anthropicFilesBeta)
+	}
+	setOverrideHeaders(req, providerOptions)
 
 	// Make the request
 	resp, err := p.httpClient.Do(req)
@@ -292,27 +360,33 @@ func (p *AnthropicProvider) GenerateMessage(ctx context.Context, messages []doma
 
 	// Parse response
 	var anthropicResp struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
+		Content    []anthropicContentBlock `json:"content"`
+		StopReason string                  `json:"stop_reason"`
 	}
 	// Use optimized unmarshaling which is ~2x faster than standard library
 	if err := json.Unmarshal(body, &anthropicResp); err != nil {
 		return domain.Response{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	// Extract text from response
-	var responseContent string
-	for _, content := range anthropicResp.Content {
-		if content.Type == "text" {
-			responseContent = content.Text
-			break
-		}
+	// Extract text and citations from response
+	responseContent, citations := anthropicResponseText(anthropicResp.Content)
+
+	// The model declined to answer for safety reasons
+	if anthropicResp.StopReason == "refusal" {
+		return domain.Response{}, domain.NewContentFilteredError(
+			"anthropic",
+			"GenerateMessage",
+			domain.ContentFilterResponse,
+			anthropicResp.StopReason,
+			responseContent,
+			nil,
+		)
 	}
 
 	// Use the response pool to reduce allocations
-	return domain.GetResponsePool().NewResponse(responseContent), nil
+	response := domain.GetResponsePool().NewResponse(responseContent)
+	response.Citations = citations
+	return response, nil
 }
 
 // GenerateWithSchema produces structured output conforming to a schema
@@ -352,9 +426,10 @@ func (p *AnthropicProvider) Stream(ctx context.Context, prompt string, options .
 
 // StreamMessage streams responses from a list of messages
 func (p *AnthropicProvider) StreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
-	// Validate content types
-	if err := p.validateContentTypesForAnthropic(messages); err != nil {
-		return nil, err
+	// Normalize media, upload large media and validate content types
+	messages, prepareErr := p.prepareMessages(ctx, messages)
+	if prepareErr != nil {
+		return nil, prepareErr
 	}
 
 	// Apply options
@@ -380,7 +455,7 @@ func (p *AnthropicProvider) StreamMessage(ctx context.Context, messages []domain
 	}
 
 	// Create HTTP request - reuse the buffer directly
-	url := fmt.Sprintf("%s/v1/messages", p.baseURL)
+	url := fmt.Sprintf("%s/v1/messages", requestBaseURL(p.baseURL, providerOptions))
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, requestBuffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -391,6 +466,10 @@ func (p *AnthropicProvider) StreamMessage(ctx context.Context, messages []domain
 	req.Header.Set("x-api-key", p.apiKey)
 	req.Header.Set("anthropic-version", "2023-06-01") // Use appropriate API version
 	req.Header.Set("Accept", "text/event-stream")
+	if anthropicUsesFiles(messages) {
+		req.Header.Set("anthropic-beta", anthropicFilesBeta)
+	}
+	setOverrideHeaders(req, providerOptions)
 
 	// Make the request
 	resp, err := p.httpClient.Do(req)
@@ -481,6 +560,10 @@ func (p *AnthropicProvider) StreamMessage(ctx context.Context, messages []domain
 					continue
 				}
 
+				// Like a failed stream, a refusal closes without a finished token
+				if stopEvent.Delta.StopReason == "refusal" {
+					return
+				}
 				if stopEvent.Delta.StopReason != "" {
 					// Send final token - use token pool to reduce allocations
 					select {
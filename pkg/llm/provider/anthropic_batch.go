@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// anthropicBatch is the message batch object returned by the Message Batches API
+type anthropicBatch struct {
+	ID               string `json:"id"`
+	ProcessingStatus string `json:"processing_status"`
+	ResultsURL       string `json:"results_url"`
+	RequestCounts    struct {
+		Processing int `json:"processing"`
+		Succeeded  int `json:"succeeded"`
+		Errored    int `json:"errored"`
+		Canceled   int `json:"canceled"`
+		Expired    int `json:"expired"`
+	} `json:"request_counts"`
+}
+
+// SubmitBatch creates a batch with Anthropic's Message Batches API
+func (p *AnthropicProvider) SubmitBatch(ctx context.Context, requests []BatchRequest) (*BatchState, error) {
+	batchRequests := make([]map[string]interface{}, 0, len(requests))
+	beta := ""
+	for _, req := range requests {
+		if anthropicUsesFiles(req.Messages) {
+			beta = anthropicFilesBeta
+		}
+		if err := p.validateContentTypesForAnthropic(req.Messages); err != nil {
+			return nil, err
+		}
+		anthMessages, systemMessage := p.ConvertMessagesToAnthropicFormat(req.Messages)
+		batchRequests = append(batchRequests, map[string]interface{}{
+			"custom_id": req.CustomID,
+			"params":    p.buildAnthropicRequestBody(anthMessages, systemMessage, batchOptions(req)),
+		})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"requests": batchRequests})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	var batch anthropicBatch
+	if err := p.apiRequest(ctx, http.MethodPost, p.baseURL+"/v1/messages/batches", "application/json", beta, bytes.NewReader(body), "SubmitBatch", &batch); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state := &BatchState{
+		Provider:    "anthropic",
+		ID:          batch.ID,
+		CustomIDs:   batchCustomIDs(requests),
+		SubmittedAt: now,
+		UpdatedAt:   now,
+	}
+	applyAnthropicBatch(state, &batch)
+	return state, nil
+}
+
+// RefreshBatch updates the state with the batch's current status
+func (p *AnthropicProvider) RefreshBatch(ctx context.Context, state *BatchState) error {
+	var batch anthropicBatch
+	if err := p.apiRequest(ctx, http.MethodGet, p.baseURL+"/v1/messages/batches/"+state.ID, "", "", nil, "RefreshBatch", &batch); err != nil {
+		return err
+	}
+	applyAnthropicBatch(state, &batch)
+	state.UpdatedAt = time.Now()
+	return nil
+}
+
+// CancelBatch asks Anthropic to stop processing the batch
+func (p *AnthropicProvider) CancelBatch(ctx context.Context, state *BatchState) error {
+	var batch anthropicBatch
+	if err := p.apiRequest(ctx, http.MethodPost, p.baseURL+"/v1/messages/batches/"+state.ID+"/cancel", "", "", nil, "CancelBatch", &batch); err != nil {
+		return err
+	}
+	applyAnthropicBatch(state, &batch)
+	state.UpdatedAt = time.Now()
+	return nil
+}
+
+// BatchResults downloads the results of an ended batch
+func (p *AnthropicProvider) BatchResults(ctx context.Context, state *BatchState) (map[string]BatchResult, error) {
+	url := state.ResultsURL
+	if url == "" {
+		url = p.baseURL + "/v1/messages/batches/" + state.ID + "/results"
+	}
+
+	var content bytes.Buffer
+	if err := p.apiRequest(ctx, http.MethodGet, url, "", "", nil, "BatchResults", &content); err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]BatchResult, len(state.CustomIDs))
+	scanner := bufio.NewScanner(&content)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		result, err := parseAnthropicBatchLine(line)
+		if err != nil {
+			return nil, err
+		}
+		results[result.CustomID] = result
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch results: %w", err)
+	}
+
+	return results, nil
+}
+
+// parseAnthropicBatchLine converts one line of the results file into a result
+func parseAnthropicBatchLine(line []byte) (BatchResult, error) {
+	var entry struct {
+		CustomID string `json:"custom_id"`
+		Result   struct {
+			Type    string `json:"type"`
+			Message struct {
+				Content []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"content"`
+				StopReason string `json:"stop_reason"`
+			} `json:"message"`
+			Error struct {
+				Error struct {
+					Type    string `json:"type"`
+					Message string `json:"message"`
+				} `json:"error"`
+			} `json:"error"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return BatchResult{}, fmt.Errorf("failed to parse batch result: %w", err)
+	}
+
+	result := BatchResult{CustomID: entry.CustomID}
+	switch entry.Result.Type {
+	case "succeeded":
+		var text strings.Builder
+		for _, content := range entry.Result.Message.Content {
+			if content.Type == "text" {
+				text.WriteString(content.Text)
+			}
+		}
+		if entry.Result.Message.StopReason == "refusal" {
+			result.Err = domain.NewContentFilteredError("anthropic", "Batch", domain.ContentFilterResponse,
+				"refusal", text.String(), nil)
+		} else {
+			result.Response = domain.Response{Content: text.String()}
+		}
+	case "errored":
+		apiErr := entry.Result.Error.Error
+		result.Err = mapAnthropicErrorToStandard(0, apiErr.Type, apiErr.Message, "Batch")
+	default: // canceled, expired
+		result.Err = domain.NewProviderError("anthropic", "Batch", 0,
+			fmt.Sprintf("batch request %s", entry.Result.Type), domain.ErrRequestFailed)
+	}
+
+	return result, nil
+}
+
+// applyAnthropicBatch copies a message batch object onto the state
+func applyAnthropicBatch(state *BatchState, batch *anthropicBatch) {
+	state.ProviderStatus = batch.ProcessingStatus
+	state.ResultsURL = batch.ResultsURL
+
+	if batch.ProcessingStatus != "ended" {
+		state.Status = BatchStatusPending
+		return
+	}
+
+	// An ended batch is reported as canceled or expired when no request succeeded for that reason
+	counts := batch.RequestCounts
+	switch {
+	case counts.Succeeded == 0 && counts.Errored == 0 && counts.Canceled > 0:
+		state.Status = BatchStatusCanceled
+	case counts.Succeeded == 0 && counts.Errored == 0 && counts.Expired > 0:
+		state.Status = BatchStatusExpired
+	default:
+		state.Status = BatchStatusCompleted
+	}
+}
+
+// apiRequest sends a request to the Anthropic API and decodes the response into out.
+// If out is a *bytes.Buffer the raw body is copied into it instead. A non-empty beta
+// enables a beta API with the anthropic-beta header.
+func (p *AnthropicProvider) apiRequest(ctx context.Context, method, url, contentType, beta string, body io.Reader, operation string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	if beta != "" {
+		req.Header.Set("anthropic-beta", beta)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ParseJSONError(respBody, resp.StatusCode, "anthropic", operation)
+	}
+
+	if buf, ok := out.(*bytes.Buffer); ok {
+		buf.Write(respBody)
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
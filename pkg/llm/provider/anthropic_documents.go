@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// isAnthropicDocumentType reports whether inline files of the MIME type can be sent as
+// Anthropic document blocks. PDFs are sent as base64 data and text files as plain text.
+func isAnthropicDocumentType(mimeType string) bool {
+	return mimeType == "application/pdf" || strings.HasPrefix(mimeType, "text/")
+}
+
+// anthropicDocumentBlock converts a file part to an Anthropic document block
+func anthropicDocumentBlock(file *domain.FileContent) map[string]interface{} {
+	var source map[string]interface{}
+	switch {
+	case file.FileID != "":
+		// Document uploaded with the Files API
+		source = map[string]interface{}{
+			"type":    "file",
+			"file_id": file.FileID,
+		}
+	case file.MimeType == "application/pdf":
+		source = map[string]interface{}{
+			"type":       "base64",
+			"media_type": file.MimeType,
+			"data":       file.FileData,
+		}
+	default:
+		// Text documents are sent as plain text, which enables character-level citations
+		text, err := base64.StdEncoding.DecodeString(file.FileData)
+		if err != nil {
+			text = []byte(file.FileData)
+		}
+		source = map[string]interface{}{
+			"type":       "text",
+			"media_type": "text/plain",
+			"data":       string(text),
+		}
+	}
+
+	block := map[string]interface{}{
+		"type":   "document",
+		"source": source,
+	}
+	if file.FileName != "" {
+		block["title"] = file.FileName
+	}
+	return block
+}
+
+// withAnthropicCitations returns the messages with citations enabled on every document
+// block. The converted messages are cached, so blocks are copied rather than modified.
+func withAnthropicCitations(messages []map[string]interface{}) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(messages))
+	for i, message := range messages {
+		result[i] = message
+
+		parts, ok := message["content"].([]map[string]interface{})
+		if !ok {
+			continue
+		}
+		var copied []map[string]interface{}
+		for j, part := range parts {
+			if part["type"] != "document" {
+				continue
+			}
+			if copied == nil {
+				copied = append([]map[string]interface{}(nil), parts...)
+			}
+			block := make(map[string]interface{}, len(part)+1)
+			for key, value := range part {
+				block[key] = value
+			}
+			block["citations"] = map[string]interface{}{"enabled": true}
+			copied[j] = block
+		}
+
+		if copied != nil {
+			msg := make(map[string]interface{}, len(message))
+			for key, value := range message {
+				msg[key] = value
+			}
+			msg["content"] = copied
+			result[i] = msg
+		}
+	}
+	return result
+}
+
+// anthropicContentBlock is a content block of an Anthropic response
+type anthropicContentBlock struct {
+	Type      string              `json:"type"`
+	Text      string              `json:"text"`
+	Citations []anthropicCitation `json:"citations"`
+}
+
+// anthropicCitation is a citation attached to an Anthropic text block
+type anthropicCitation struct {
+	Type            string `json:"type"`
+	CitedText       string `json:"cited_text"`
+	DocumentIndex   int    `json:"document_index"`
+	DocumentTitle   string `json:"document_title"`
+	StartCharIndex  int    `json:"start_char_index"`
+	EndCharIndex    int    `json:"end_char_index"`
+	StartPageNumber int    `json:"start_page_number"`
+	EndPageNumber   int    `json:"end_page_number"`
+	StartBlockIndex int    `json:"start_block_index"`
+	EndBlockIndex   int    `json:"end_block_index"`
+}
+
+// anthropicResponseText joins the text blocks of a response and collects their citations.
+// With citations enabled, Anthropic splits the answer into one text block per cited claim.
+func anthropicResponseText(blocks []anthropicContentBlock) (string, []domain.Citation) {
+	var text strings.Builder
+	var citations []domain.Citation
+	for _, block := range blocks {
+		if block.Type != "text" {
+			continue
+		}
+
+		start := text.Len()
+		text.WriteString(block.Text)
+		for _, c := range block.Citations {
+			citation := domain.Citation{
+				Type:          c.Type,
+				CitedText:     c.CitedText,
+				DocumentIndex: c.DocumentIndex,
+				DocumentTitle: c.DocumentTitle,
+				ResponseStart: start,
+				ResponseEnd:   text.Len(),
+			}
+			switch c.Type {
+			case domain.CitationCharLocation:
+				citation.Start, citation.End = c.StartCharIndex, c.EndCharIndex
+			case domain.CitationPageLocation:
+				citation.Start, citation.End = c.StartPageNumber, c.EndPageNumber
+			case domain.CitationContentBlockLocation:
+				citation.Start, citation.End = c.StartBlockIndex, c.EndBlockIndex
+			}
+			citations = append(citations, citation)
+		}
+	}
+	return text.String(), citations
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+func TestAnthropicDocuments(t *testing.T) {
+	reply := `{
+		"content": [
+			{"type": "text", "text": "The contract "},
+			{"type": "text", "text": "renews every year", "citations": [
+				{"type": "char_location", "cited_text": "renews annually", "document_index": 0,
+				 "document_title": "terms.txt", "start_char_index": 12, "end_char_index": 27}
+			]},
+			{"type": "text", "text": " and ends with notice", "citations": [
+				{"type": "page_location", "cited_text": "90 days notice", "document_index": 1,
+				 "document_title": "contract.pdf", "start_page_number": 3, "end_page_number": 4}
+			]}
+		],
+		"stop_reason": "end_turn"
+	}`
+	server, requests := newCaptureServer(t, reply)
+
+	provider := NewAnthropicProvider("test-key", "claude-3-5-sonnet-latest", domain.NewBaseURLOption(server.URL))
+	messages := []domain.Message{{
+		Role: domain.RoleUser,
+		Content: []domain.ContentPart{
+			domain.NewFileMessage(domain.RoleUser, "terms.txt", []byte("The lease renews annually."), "text/plain", "").Content[0],
+			domain.NewFileMessage(domain.RoleUser, "contract.pdf", []byte("%PDF-1.4 contract"), "application/pdf", "").Content[0],
+			{Type: domain.ContentTypeText, Text: "When does the contract renew?"},
+		},
+	}}
+
+	response, err := provider.GenerateMessage(context.Background(), messages, domain.WithCitations(true))
+	if err != nil {
+		t.Fatalf("GenerateMessage failed: %v", err)
+	}
+
+	t.Run("DocumentBlocks", func(t *testing.T) {
+		content := requests()[0].body["messages"].([]interface{})[0].(map[string]interface{})["content"].([]interface{})
+
+		text := content[0].(map[string]interface{})
+		source := text["source"].(map[string]interface{})
+		if text["type"] != "document" || text["title"] != "terms.txt" || source["type"] != "text" || source["data"] != "The lease renews annually." {
+			t.Errorf("Unexpected text document block: %v", text)
+		}
+		if citations, _ := text["citations"].(map[string]interface{}); citations["enabled"] != true {
+			t.Errorf("Expected citations to be enabled, got %v", text["citations"])
+		}
+
+		pdf := content[1].(map[string]interface{})
+		source = pdf["source"].(map[string]interface{})
+		if pdf["type"] != "document" || source["type"] != "base64" || source["media_type"] != "application/pdf" {
+			t.Errorf("Unexpected PDF document block: %v", pdf)
+		}
+	})
+
+	t.Run("Citations", func(t *testing.T) {
+		if response.Content != "The contract renews every year and ends with notice" {
+			t.Errorf("Expected all text blocks to be joined, got %q", response.Content)
+		}
+		if len(response.Citations) != 2 {
+			t.Fatalf("Expected 2 citations, got %d", len(response.Citations))
+		}
+
+		first := response.Citations[0]
+		if first.Type != domain.CitationCharLocation || first.CitedText != "renews annually" || first.Start != 12 || first.End != 27 {
+			t.Errorf("Unexpected citation: %+v", first)
+		}
+		if response.Content[first.ResponseStart:first.ResponseEnd] != "renews every year" {
+			t.Errorf("Unexpected supported text %q", response.Content[first.ResponseStart:first.ResponseEnd])
+		}
+
+		second := response.Citations[1]
+		if second.Type != domain.CitationPageLocation || second.DocumentIndex != 1 || second.DocumentTitle != "contract.pdf" || second.Start != 3 || second.End != 4 {
+			t.Errorf("Unexpected citation: %+v", second)
+		}
+	})
+
+	t.Run("CitationsNotCached", func(t *testing.T) {
+		if _, err := provider.GenerateMessage(context.Background(), messages); err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+		content := requests()[1].body["messages"].([]interface{})[0].(map[string]interface{})["content"].([]interface{})
+		if _, ok := content[0].(map[string]interface{})["citations"]; ok {
+			t.Errorf("Citations should only be enabled when requested")
+		}
+	})
+
+	t.Run("UnsupportedFileType", func(t *testing.T) {
+		msg := domain.NewFileMessage(domain.RoleUser, "sheet.xlsx", []byte("PK"), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "")
+		_, err := provider.GenerateMessage(context.Background(), []domain.Message{msg})
+		if !domain.IsUnsupportedContentTypeError(err) {
+			t.Errorf("Expected an unsupported content type error, got %v", err)
+		}
+	})
+}
+
+func TestAnthropicDocumentsSharingPrefix(t *testing.T) {
+	// Two contracts from one template only differ after their long shared opening
+	template := "%PDF-1.4 " + strings.Repeat("Master services agreement between the parties. ", 10)
+	first := domain.NewFileMessage(domain.RoleUser, "contract.pdf", []byte(template+"Term: 12 months"), "application/pdf", "")
+	second := domain.NewFileMessage(domain.RoleUser, "contract.pdf", []byte(template+"Term: 36 months"), "application/pdf", "")
+
+	if GenerateMessagesKey([]domain.Message{first}) == GenerateMessagesKey([]domain.Message{second}) {
+		t.Fatal("Expected documents with a shared prefix to have different cache keys")
+	}
+
+	provider := NewAnthropicProvider("test-key", "claude-3-5-sonnet-latest")
+	for _, message := range []domain.Message{first, second} {
+		converted, _ := provider.ConvertMessagesToAnthropicFormat([]domain.Message{message})
+		block := converted[0]["content"].([]map[string]interface{})[0]
+		data := block["source"].(map[string]interface{})["data"]
+		if data != message.Content[0].File.FileData {
+			t.Errorf("Expected the document's own data to be sent, got a different document")
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// anthropicFilesBeta enables the Files API and file references in messages
+const anthropicFilesBeta = "files-api-2025-04-14"
+
+// anthropicUploadableTypes are the content types Anthropic accepts as file references
+var anthropicUploadableTypes = map[domain.ContentType]bool{
+	domain.ContentTypeImage: true,
+	domain.ContentTypeFile:  true,
+}
+
+// anthropicFile is the file metadata returned by the Anthropic Files API
+type anthropicFile struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	MimeType  string    `json:"mime_type"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// toUploadedFile converts the file metadata to a domain file
+func (f anthropicFile) toUploadedFile() domain.UploadedFile {
+	return domain.UploadedFile{
+		Provider:  "anthropic",
+		ID:        f.ID,
+		Name:      f.Filename,
+		MimeType:  f.MimeType,
+		SizeBytes: f.SizeBytes,
+		CreatedAt: f.CreatedAt,
+	}
+}
+
+// UploadFile uploads a file with the Anthropic Files API
+func (p *AnthropicProvider) UploadFile(ctx context.Context, name string, data []byte, mimeType string) (domain.UploadedFile, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	header.Set("Content-Type", mimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return domain.UploadedFile{}, fmt.Errorf("failed to build upload request: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return domain.UploadedFile{}, fmt.Errorf("failed to build upload request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return domain.UploadedFile{}, fmt.Errorf("failed to build upload request: %w", err)
+	}
+
+	var file anthropicFile
+	if err := p.apiRequest(ctx, http.MethodPost, p.baseURL+"/v1/files", writer.FormDataContentType(),
+		anthropicFilesBeta, &body, "UploadFile", &file); err != nil {
+		return domain.UploadedFile{}, err
+	}
+	return file.toUploadedFile(), nil
+}
+
+// ListFiles returns all files stored with the Anthropic Files API
+func (p *AnthropicProvider) ListFiles(ctx context.Context) ([]domain.UploadedFile, error) {
+	var files []domain.UploadedFile
+	afterID := ""
+	for {
+		query := url.Values{"limit": {"1000"}}
+		if afterID != "" {
+			query.Set("after_id", afterID)
+		}
+
+		var page struct {
+			Data    []anthropicFile `json:"data"`
+			HasMore bool            `json:"has_more"`
+			LastID  string          `json:"last_id"`
+		}
+		if err := p.apiRequest(ctx, http.MethodGet, p.baseURL+"/v1/files?"+query.Encode(), "",
+			anthropicFilesBeta, nil, "ListFiles", &page); err != nil {
+			return nil, err
+		}
+
+		for _, file := range page.Data {
+			files = append(files, file.toUploadedFile())
+		}
+		if !page.HasMore || page.LastID == "" {
+			return files, nil
+		}
+		afterID = page.LastID
+	}
+}
+
+// DeleteFile deletes a file from the Anthropic Files API
+func (p *AnthropicProvider) DeleteFile(ctx context.Context, id string) error {
+	var deleted struct {
+		ID string `json:"id"`
+	}
+	if err := p.apiRequest(ctx, http.MethodDelete, p.baseURL+"/v1/files/"+url.PathEscape(id), "",
+		anthropicFilesBeta, nil, "DeleteFile", &deleted); err != nil {
+		return err
+	}
+	p.uploadCache.forget(id)
+	return nil
+}
+
+// anthropicUsesFiles reports whether the messages reference uploaded files, which
+// requires the Files API beta header
+func anthropicUsesFiles(messages []domain.Message) bool {
+	for _, msg := range messages {
+		for _, part := range msg.Content {
+			switch {
+			case part.Type == domain.ContentTypeImage && part.Image != nil && part.Image.Source.Type == domain.SourceTypeFile:
+				return true
+			case part.Type == domain.ContentTypeFile && part.File != nil && part.File.FileID != "":
+				return true
+			}
+		}
+	}
+	return false
+}
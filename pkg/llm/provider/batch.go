@@ -0,0 +1,345 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// Default settings for batch processing
+const (
+	defaultBatchPollInterval = 30 * time.Second
+)
+
+// BatchStatus is the provider-independent status of a batch
+type BatchStatus string
+
+const (
+	// BatchStatusPending means the batch is being validated or processed
+	BatchStatusPending BatchStatus = "pending"
+	// BatchStatusCompleted means processing finished and results are available
+	BatchStatusCompleted BatchStatus = "completed"
+	// BatchStatusFailed means the batch as a whole was rejected
+	BatchStatusFailed BatchStatus = "failed"
+	// BatchStatusExpired means the batch did not finish in time; partial results may be available
+	BatchStatusExpired BatchStatus = "expired"
+	// BatchStatusCanceled means the batch was canceled; partial results may be available
+	BatchStatusCanceled BatchStatus = "canceled"
+)
+
+// Done reports whether the status is terminal
+func (s BatchStatus) Done() bool {
+	return s != BatchStatusPending && s != ""
+}
+
+// BatchRequest is a single request in a batch
+type BatchRequest struct {
+	// CustomID identifies the request and its result; it must be unique within the batch
+	CustomID string
+	// Messages is the conversation to send
+	Messages []domain.Message
+	// Options configure the request (temperature, max tokens, ...)
+	Options []domain.Option
+}
+
+// BatchResult is the outcome of a single request in a batch
+type BatchResult struct {
+	CustomID string
+	Response domain.Response
+	Err      error
+}
+
+// BatchState describes a submitted batch. It is JSON-serializable so a batch can be
+// resumed by a later process.
+type BatchState struct {
+	// Provider is the name of the provider that owns the batch
+	Provider string `json:"provider"`
+	// ID is the provider's batch ID
+	ID string `json:"id"`
+	// Status is the provider-independent status
+	Status BatchStatus `json:"status"`
+	// ProviderStatus is the raw status reported by the provider
+	ProviderStatus string `json:"provider_status,omitempty"`
+	// CustomIDs lists the submitted request IDs in order
+	CustomIDs []string `json:"custom_ids"`
+	// InputFileID is the uploaded input file (OpenAI)
+	InputFileID string `json:"input_file_id,omitempty"`
+	// OutputFileID is the file holding successful results (OpenAI)
+	OutputFileID string `json:"output_file_id,omitempty"`
+	// ErrorFileID is the file holding failed results (OpenAI)
+	ErrorFileID string `json:"error_file_id,omitempty"`
+	// ResultsURL is where results can be downloaded (Anthropic)
+	ResultsURL string `json:"results_url,omitempty"`
+	// SubmittedAt is when the batch was created
+	SubmittedAt time.Time `json:"submitted_at"`
+	// UpdatedAt is when the status was last refreshed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BatchProvider is implemented by providers that support asynchronous batch processing
+type BatchProvider interface {
+	// SubmitBatch uploads the requests and creates a batch
+	SubmitBatch(ctx context.Context, requests []BatchRequest) (*BatchState, error)
+
+	// RefreshBatch updates the state with the batch's current status
+	RefreshBatch(ctx context.Context, state *BatchState) error
+
+	// BatchResults downloads the results of a finished batch, keyed by custom ID
+	BatchResults(ctx context.Context, state *BatchState) (map[string]BatchResult, error)
+
+	// CancelBatch asks the provider to stop processing the batch
+	CancelBatch(ctx context.Context, state *BatchState) error
+}
+
+// BatchClient submits requests through a provider's batch API, polls until the batch
+// finishes and maps the results back to their requests. When a state file is
+// configured the batch survives restarts: a later Run with the same requests resumes
+// polling the existing batch instead of submitting a new one.
+type BatchClient struct {
+	provider     BatchProvider
+	stateFile    string
+	pollInterval time.Duration
+	onPoll       func(*BatchState)
+}
+
+// NewBatchClient creates a batch client for the given provider
+func NewBatchClient(provider BatchProvider) *BatchClient {
+	return &BatchClient{
+		provider:     provider,
+		pollInterval: defaultBatchPollInterval,
+	}
+}
+
+// WithStateFile persists the batch state to path so an interrupted run can resume
+func (c *BatchClient) WithStateFile(path string) *BatchClient {
+	c.stateFile = path
+	return c
+}
+
+// WithPollInterval sets how often the batch status is checked
+func (c *BatchClient) WithPollInterval(interval time.Duration) *BatchClient {
+	if interval > 0 {
+		c.pollInterval = interval
+	}
+	return c
+}
+
+// WithPollCallback registers a function called after every status refresh
+func (c *BatchClient) WithPollCallback(fn func(*BatchState)) *BatchClient {
+	c.onPoll = fn
+	return c
+}
+
+// Submit creates a new batch, or returns the saved batch if the state file holds one
+// for the same requests
+func (c *BatchClient) Submit(ctx context.Context, requests []BatchRequest) (*BatchState, error) {
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("%w: batch has no requests", domain.ErrInvalidConfiguration)
+	}
+
+	seen := make(map[string]bool, len(requests))
+	for _, req := range requests {
+		if req.CustomID == "" {
+			return nil, fmt.Errorf("%w: batch request is missing a custom ID", domain.ErrInvalidConfiguration)
+		}
+		if seen[req.CustomID] {
+			return nil, fmt.Errorf("%w: duplicate batch custom ID %q", domain.ErrInvalidConfiguration, req.CustomID)
+		}
+		seen[req.CustomID] = true
+	}
+
+	state, err := c.LoadState()
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		if !sameCustomIDs(state.CustomIDs, requests) {
+			return nil, fmt.Errorf("%w: state file %s belongs to a different batch", domain.ErrInvalidConfiguration, c.stateFile)
+		}
+		return state, nil
+	}
+
+	state, err = c.provider.SubmitBatch(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.saveState(state); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+// Wait polls the batch until it reaches a terminal status
+func (c *BatchClient) Wait(ctx context.Context, state *BatchState) error {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for !state.Status.Done() {
+		if err := c.provider.RefreshBatch(ctx, state); err != nil {
+			return err
+		}
+		if err := c.saveState(state); err != nil {
+			return err
+		}
+		if c.onPoll != nil {
+			c.onPoll(state)
+		}
+		if state.Status.Done() {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrProviderTimeout
+			}
+			return ErrContextCanceled
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// Run submits (or resumes) the batch, waits for it to finish and returns one result per
+// request, keyed by custom ID. Requests without a result (for example because the batch
+// expired) get an error result.
+func (c *BatchClient) Run(ctx context.Context, requests []BatchRequest) (map[string]BatchResult, error) {
+	state, err := c.Submit(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Wait(ctx, state); err != nil {
+		return nil, err
+	}
+
+	if state.Status == BatchStatusFailed {
+		return nil, domain.NewProviderError(state.Provider, "Batch", 0,
+			fmt.Sprintf("batch %s failed (%s)", state.ID, state.ProviderStatus), domain.ErrRequestFailed)
+	}
+
+	results, err := c.provider.BatchResults(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range state.CustomIDs {
+		if _, ok := results[id]; !ok {
+			results[id] = BatchResult{
+				CustomID: id,
+				Err: domain.NewProviderError(state.Provider, "Batch", 0,
+					fmt.Sprintf("no result for request (batch %s)", state.Status), domain.ErrRequestFailed),
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Cancel asks the provider to stop processing the batch and saves the updated state
+func (c *BatchClient) Cancel(ctx context.Context, state *BatchState) error {
+	if err := c.provider.CancelBatch(ctx, state); err != nil {
+		return err
+	}
+	return c.saveState(state)
+}
+
+// LoadState reads the saved batch state, returning nil if there is none
+func (c *BatchClient) LoadState() (*BatchState, error) {
+	if c.stateFile == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(c.stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch state: %w", err)
+	}
+
+	var state BatchState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse batch state: %w", err)
+	}
+	return &state, nil
+}
+
+// ClearState removes the saved batch state so the next Run submits a new batch
+func (c *BatchClient) ClearState() error {
+	if c.stateFile == "" {
+		return nil
+	}
+	if err := os.Remove(c.stateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove batch state: %w", err)
+	}
+	return nil
+}
+
+// saveState atomically writes the batch state to the state file
+func (c *BatchClient) saveState(state *BatchState) error {
+	if c.stateFile == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode batch state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.stateFile), filepath.Base(c.stateFile)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write batch state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write batch state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write batch state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.stateFile); err != nil {
+		return fmt.Errorf("failed to write batch state: %w", err)
+	}
+	return nil
+}
+
+// sameCustomIDs reports whether a saved batch was created for the given requests
+func sameCustomIDs(ids []string, requests []BatchRequest) bool {
+	if len(ids) != len(requests) {
+		return false
+	}
+	for i, req := range requests {
+		if ids[i] != req.CustomID {
+			return false
+		}
+	}
+	return true
+}
+
+// batchCustomIDs returns the custom IDs of the requests in order
+func batchCustomIDs(requests []BatchRequest) []string {
+	ids := make([]string, len(requests))
+	for i, req := range requests {
+		ids[i] = req.CustomID
+	}
+	return ids
+}
+
+// batchOptions applies the request's options to the defaults
+func batchOptions(req BatchRequest) *domain.ProviderOptions {
+	options := domain.DefaultOptions()
+	for _, option := range req.Options {
+		option(options)
+	}
+	return options
+}
@@ -0,0 +1,312 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+func batchTestRequests() []BatchRequest {
+	return []BatchRequest{
+		{CustomID: "req-1", Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "one")}},
+		{CustomID: "req-2", Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "two")}},
+		{CustomID: "req-3", Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "three")}, Options: []domain.Option{domain.WithMaxTokens(50)}},
+	}
+}
+
+// fakeOpenAIBatchServer emulates the OpenAI Files and Batch APIs. The batch completes
+// after the given number of status checks; req-3 ends up in the error file.
+type fakeOpenAIBatchServer struct {
+	mu           sync.Mutex
+	pollsToEnd   int
+	polls        int
+	batches      int
+	uploadedFile string
+}
+
+func (s *fakeOpenAIBatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer test-api-key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, `{"error":{"message":"Invalid API key"}}`)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+		if r.FormValue("purpose") != "batch" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"error":{"message":"invalid purpose"}}`)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"error":{"message":"missing file"}}`)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		s.uploadedFile = string(data)
+		fmt.Fprintln(w, `{"id":"file-input","purpose":"batch"}`)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/batches":
+		s.batches++
+		fmt.Fprintln(w, `{"id":"batch_1","status":"validating","input_file_id":"file-input"}`)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/batches/batch_1":
+		s.polls++
+		if s.polls < s.pollsToEnd {
+			fmt.Fprintln(w, `{"id":"batch_1","status":"in_progress"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"id":"batch_1","status":"completed","output_file_id":"file-output","error_file_id":"file-errors"}`)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file-output/content":
+		fmt.Fprintln(w, `{"id":"r1","custom_id":"req-2","response":{"status_code":200,"body":{"choices":[{"message":{"content":"second"}}]}},"error":null}`)
+		fmt.Fprintln(w, `{"id":"r2","custom_id":"req-1","response":{"status_code":200,"body":{"choices":[{"message":{"content":"first"}}]}},"error":null}`)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file-errors/content":
+		fmt.Fprintln(w, `{"id":"r3","custom_id":"req-3","response":{"status_code":429,"body":{"error":{"message":"Rate limit reached"}}},"error":null}`)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/batches/batch_1/cancel":
+		fmt.Fprintln(w, `{"id":"batch_1","status":"cancelling"}`)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error":{"message":"unexpected request %s %s"}}`, r.Method, r.URL.Path)
+	}
+}
+
+func TestOpenAIBatch(t *testing.T) {
+	fake := &fakeOpenAIBatchServer{pollsToEnd: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	openai := NewOpenAIProvider("test-api-key", "gpt-4o-mini")
+	openai.SetBaseURL(server.URL)
+
+	var polls []BatchStatus
+	client := NewBatchClient(openai).
+		WithPollInterval(time.Millisecond).
+		WithPollCallback(func(state *BatchState) { polls = append(polls, state.Status) })
+
+	results, err := client.Run(context.Background(), batchTestRequests())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(polls) != 2 || polls[0] != BatchStatusPending || polls[1] != BatchStatusCompleted {
+		t.Errorf("Unexpected poll sequence: %v", polls)
+	}
+
+	if results["req-1"].Response.Content != "first" || results["req-2"].Response.Content != "second" {
+		t.Errorf("Results not mapped by custom ID: %+v", results)
+	}
+	if !errors.Is(results["req-3"].Err, domain.ErrRateLimitExceeded) {
+		t.Errorf("Expected rate limit error for req-3, got %v", results["req-3"].Err)
+	}
+
+	// The input file holds one chat completion request per line
+	scanner := bufio.NewScanner(strings.NewReader(fake.uploadedFile))
+	var lines []map[string]interface{}
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Invalid JSONL line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines in the input file, got %d", len(lines))
+	}
+	if lines[2]["custom_id"] != "req-3" || lines[2]["url"] != "/v1/chat/completions" {
+		t.Errorf("Unexpected input line: %v", lines[2])
+	}
+	if body := lines[2]["body"].(map[string]interface{}); body["model"] != "gpt-4o-mini" || body["max_tokens"] != float64(50) {
+		t.Errorf("Expected request options in body, got %v", body)
+	}
+}
+
+func TestBatchClientResume(t *testing.T) {
+	fake := &fakeOpenAIBatchServer{pollsToEnd: 3}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	openai := NewOpenAIProvider("test-api-key", "gpt-4o-mini")
+	openai.SetBaseURL(server.URL)
+	stateFile := filepath.Join(t.TempDir(), "batch.json")
+
+	// The first run is interrupted after one status check
+	ctx, cancel := context.WithCancel(context.Background())
+	first := NewBatchClient(openai).
+		WithStateFile(stateFile).
+		WithPollInterval(time.Hour).
+		WithPollCallback(func(*BatchState) { cancel() })
+	if _, err := first.Run(ctx, batchTestRequests()); !errors.Is(err, ErrContextCanceled) {
+		t.Fatalf("Expected canceled run, got %v", err)
+	}
+
+	saved, err := first.LoadState()
+	if err != nil || saved == nil || saved.ID != "batch_1" || saved.Status != BatchStatusPending {
+		t.Fatalf("Expected pending state to be saved, got %+v (%v)", saved, err)
+	}
+
+	// A new client resumes the saved batch instead of submitting another one
+	second := NewBatchClient(openai).WithStateFile(stateFile).WithPollInterval(time.Millisecond)
+	results, err := second.Run(context.Background(), batchTestRequests())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fake.batches != 1 {
+		t.Errorf("Expected a single batch to be created, got %d", fake.batches)
+	}
+	if results["req-1"].Response.Content != "first" {
+		t.Errorf("Unexpected resumed results: %+v", results)
+	}
+
+	// A different set of requests does not reuse the saved batch
+	other := []BatchRequest{{CustomID: "other", Messages: batchTestRequests()[0].Messages}}
+	if _, err := second.Run(context.Background(), other); !errors.Is(err, domain.ErrInvalidConfiguration) {
+		t.Errorf("Expected mismatched state error, got %v", err)
+	}
+
+	if err := second.ClearState(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state, _ := second.LoadState(); state != nil {
+		t.Errorf("Expected state to be cleared, got %+v", state)
+	}
+}
+
+func TestBatchClientValidation(t *testing.T) {
+	client := NewBatchClient(NewOpenAIProvider("test-api-key", "gpt-4o-mini"))
+
+	tests := map[string][]BatchRequest{
+		"Empty":     nil,
+		"MissingID": {{Messages: batchTestRequests()[0].Messages}},
+		"Duplicate": {{CustomID: "a"}, {CustomID: "a"}},
+	}
+	for name, requests := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := client.Submit(context.Background(), requests); !errors.Is(err, domain.ErrInvalidConfiguration) {
+				t.Errorf("Expected invalid configuration error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAnthropicBatch(t *testing.T) {
+	var mu sync.Mutex
+	var submitted map[string]interface{}
+	polls := 0
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Header.Get("x-api-key") != "test-api-key" || r.Header.Get("anthropic-version") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/batches":
+			data, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(data, &submitted)
+			fmt.Fprintln(w, `{"id":"msgbatch_1","type":"message_batch","processing_status":"in_progress","results_url":null}`)
+
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/messages/batches/msgbatch_1":
+			polls++
+			if polls < 2 {
+				fmt.Fprintln(w, `{"id":"msgbatch_1","processing_status":"in_progress","request_counts":{"processing":3}}`)
+				return
+			}
+			fmt.Fprintf(w, `{"id":"msgbatch_1","processing_status":"ended","results_url":"%s/v1/messages/batches/msgbatch_1/results","request_counts":{"succeeded":1,"errored":1,"expired":1}}`+"\n", server.URL)
+
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/messages/batches/msgbatch_1/results":
+			fmt.Fprintln(w, `{"custom_id":"req-1","result":{"type":"succeeded","message":{"content":[{"type":"text","text":"first"}]}}}`)
+			fmt.Fprintln(w, `{"custom_id":"req-2","result":{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"Invalid request"}}}}`)
+			fmt.Fprintln(w, `{"custom_id":"req-3","result":{"type":"expired"}}`)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"type":"error","error":{"type":"not_found_error","message":"unexpected request %s %s"}}`, r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	anthropic := NewAnthropicProvider("test-api-key", "claude-3-5-haiku-latest")
+	anthropic.SetBaseURL(server.URL)
+
+	requests := batchTestRequests()
+	requests[0].Messages = append([]domain.Message{domain.NewTextMessage(domain.RoleSystem, "Be brief.")}, requests[0].Messages...)
+
+	results, err := NewBatchClient(anthropic).WithPollInterval(time.Millisecond).Run(context.Background(), requests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if results["req-1"].Err != nil || results["req-1"].Response.Content != "first" {
+		t.Errorf("Unexpected result for req-1: %+v", results["req-1"])
+	}
+	if results["req-2"].Err == nil || results["req-3"].Err == nil {
+		t.Errorf("Expected errors for req-2 and req-3, got %+v", results)
+	}
+
+	batchRequests := submitted["requests"].([]interface{})
+	if len(batchRequests) != 3 {
+		t.Fatalf("Expected 3 submitted requests, got %d", len(batchRequests))
+	}
+	first := batchRequests[0].(map[string]interface{})
+	params := first["params"].(map[string]interface{})
+	if first["custom_id"] != "req-1" || params["system"] != "Be brief." || params["model"] != "claude-3-5-haiku-latest" {
+		t.Errorf("Unexpected submitted request: %v", first)
+	}
+}
+
+func TestBatchStatusMapping(t *testing.T) {
+	tests := []struct {
+		status string
+		want   BatchStatus
+	}{
+		{"validating", BatchStatusPending},
+		{"finalizing", BatchStatusPending},
+		{"completed", BatchStatusCompleted},
+		{"failed", BatchStatusFailed},
+		{"expired", BatchStatusExpired},
+		{"cancelled", BatchStatusCanceled},
+	}
+	for _, tt := range tests {
+		state := &BatchState{}
+		applyOpenAIBatch(state, &openAIBatch{Status: tt.status})
+		if state.Status != tt.want {
+			t.Errorf("OpenAI status %q: expected %s, got %s", tt.status, tt.want, state.Status)
+		}
+	}
+
+	canceled := &anthropicBatch{ProcessingStatus: "ended"}
+	canceled.RequestCounts.Canceled = 2
+	state := &BatchState{}
+	applyAnthropicBatch(state, canceled)
+	if state.Status != BatchStatusCanceled {
+		t.Errorf("Expected canceled status, got %s", state.Status)
+	}
+}
@@ -17,6 +17,8 @@ const (
 	ConsensusSimilarity
 	// ConsensusWeighted considers provider weights
 	ConsensusWeighted
+	// ConsensusJudge asks a judge provider to pick or synthesize the best response (see WithJudge)
+	ConsensusJudge
 )
 
 // consensusConfig contains the configuration for consensus algorithms
@@ -26,13 +28,22 @@ type consensusConfig struct {
 	// SimilarityThreshold is the minimum similarity score (0.0-1.0) for responses to be considered similar
 	// Used only for ConsensusSimilarity strategy
 	SimilarityThreshold float64
+	// FieldLevel enables per-field voting for structured results
+	FieldLevel bool
+	// FieldAgreementThreshold is the minimum agreement (0.0-1.0) for a field to count as consensus
+	// Used only when FieldLevel is enabled
+	FieldAgreementThreshold float64
+	// Judge configures the judge provider
+	// Used only for ConsensusJudge strategy
+	Judge *JudgeConfig
 }
 
 // defaultConsensusConfig returns the default consensus configuration
 func defaultConsensusConfig() consensusConfig {
 	return consensusConfig{
-		Strategy:            ConsensusMajority,
-		SimilarityThreshold: 0.7, // Default 70% similarity threshold
+		Strategy:                ConsensusMajority,
+		SimilarityThreshold:     0.7, // Default 70% similarity threshold
+		FieldAgreementThreshold: 0.5, // Default simple majority per field
 	}
 }
 
@@ -74,6 +85,10 @@ func selectConsensusTextResult(results []fallbackResult) (string, error) {
 		return selectSimilarityConsensus(successfulResults, config.SimilarityThreshold)
 	case ConsensusWeighted:
 		return selectWeightedConsensus(successfulResults)
+	case ConsensusJudge:
+		// The judge needs a context and the original request, which are not available here.
+		// Callers that can reach the judge use selectJudgeResult instead.
+		return selectSimilarityConsensus(successfulResults, config.SimilarityThreshold)
 	default:
 		// Default to majority if unknown strategy
 		return selectMajorityConsensus(successfulResults)
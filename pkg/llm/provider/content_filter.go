@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"sort"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// geminiBlockedFinishReasons are the candidate finish reasons that mean the response was blocked
+var geminiBlockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
+}
+
+// geminiSafetyRating is a Gemini safety rating for one harm category
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Severity    string `json:"severity"`
+	Blocked     bool   `json:"blocked"`
+}
+
+// toSafetyRatings converts Gemini safety ratings to domain ratings
+func toSafetyRatings(ratings []geminiSafetyRating) []domain.SafetyRating {
+	if len(ratings) == 0 {
+		return nil
+	}
+	result := make([]domain.SafetyRating, len(ratings))
+	for i, rating := range ratings {
+		result[i] = domain.SafetyRating{
+			Category:    rating.Category,
+			Probability: rating.Probability,
+			Severity:    rating.Severity,
+			Blocked:     rating.Blocked,
+		}
+	}
+	return result
+}
+
+// openAIContentFilterResult is an Azure OpenAI content filter result for one category
+type openAIContentFilterResult struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity"`
+	Detected bool   `json:"detected"`
+}
+
+// openAIContentFilterRatings converts Azure OpenAI content filter results to domain ratings,
+// sorted by category so errors are stable
+func openAIContentFilterRatings(results map[string]openAIContentFilterResult) []domain.SafetyRating {
+	if len(results) == 0 {
+		return nil
+	}
+
+	categories := make([]string, 0, len(results))
+	for category := range results {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	ratings := make([]domain.SafetyRating, len(categories))
+	for i, category := range categories {
+		result := results[category]
+		ratings[i] = domain.SafetyRating{
+			Category: category,
+			Severity: result.Severity,
+			Blocked:  result.Filtered,
+		}
+	}
+	return ratings
+}
+
+// parseOpenAIContentFilterError returns a ContentFilteredError if the error body reports
+// a content filter block (the "content_filter" code used by Azure OpenAI), or nil otherwise
+func parseOpenAIContentFilterError(body []byte, statusCode int, operation string) error {
+	var errorResponse struct {
+		Error struct {
+			Message    string `json:"message"`
+			Code       string `json:"code"`
+			InnerError struct {
+				Code                string                               `json:"code"`
+				ContentFilterResult map[string]openAIContentFilterResult `json:"content_filter_result"`
+			} `json:"innererror"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorResponse); err != nil || errorResponse.Error.Code != "content_filter" {
+		return nil
+	}
+
+	filtered := domain.NewContentFilteredError("openai", operation, domain.ContentFilterPrompt,
+		"content_filter", errorResponse.Error.Message,
+		openAIContentFilterRatings(errorResponse.Error.InnerError.ContentFilterResult))
+	filtered.StatusCode = statusCode
+	return filtered
+}
+
+// newContentFilteredHTTPError creates a ContentFilteredError for a block reported as an HTTP error
+func newContentFilteredHTTPError(provider, operation string, statusCode int, reason, message string) error {
+	filtered := domain.NewContentFilteredError(provider, operation, domain.ContentFilterPrompt, reason, message, nil)
+	filtered.StatusCode = statusCode
+	return filtered
+}
@@ -0,0 +1,257 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// contentFilterServer returns a test server that always replies with the given status and body
+func contentFilterServer(status int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintln(w, body)
+	}))
+}
+
+// contentFilterProvider creates a provider of the given name sending requests to a test server
+func contentFilterProvider(name, url string) domain.Provider {
+	switch name {
+	case "openai":
+		openai := NewOpenAIProvider("test-api-key", "gpt-4o")
+		openai.SetBaseURL(url)
+		return openai
+	case "anthropic":
+		anthropic := NewAnthropicProvider("test-api-key", "claude-3-5-sonnet-latest")
+		anthropic.SetBaseURL(url)
+		return anthropic
+	default:
+		gemini := NewGeminiProvider("test-api-key", "")
+		gemini.SetBaseURL(url)
+		return gemini
+	}
+}
+
+func TestContentFilteredErrors(t *testing.T) {
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "something unsafe")}
+
+	tests := []struct {
+		name       string
+		provider   string
+		status     int
+		body       string
+		source     domain.ContentFilterSource
+		reason     string
+		message    string
+		categories []string
+	}{
+		{
+			name:     "GeminiPromptBlocked",
+			provider: "gemini",
+			status:   http.StatusOK,
+			body: `{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[
+				{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true},
+				{"category":"HARM_CATEGORY_HATE_SPEECH","probability":"NEGLIGIBLE"}]}}`,
+			source:     domain.ContentFilterPrompt,
+			reason:     "SAFETY",
+			categories: []string{"HARM_CATEGORY_HARASSMENT"},
+		},
+		{
+			name:     "GeminiCandidateBlocked",
+			provider: "gemini",
+			status:   http.StatusOK,
+			body: `{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY","safetyRatings":[
+				{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"MEDIUM","severity":"HARM_SEVERITY_HIGH","blocked":true}]}]}`,
+			source:     domain.ContentFilterResponse,
+			reason:     "SAFETY",
+			categories: []string{"HARM_CATEGORY_DANGEROUS_CONTENT"},
+		},
+		{
+			name:     "OpenAIRefusal",
+			provider: "openai",
+			status:   http.StatusOK,
+			body:     `{"choices":[{"index":0,"message":{"content":null,"refusal":"I can't help with that."},"finish_reason":"stop"}]}`,
+			source:   domain.ContentFilterResponse,
+			reason:   "refusal",
+			message:  "I can't help with that.",
+		},
+		{
+			name:     "OpenAIFinishReasonContentFilter",
+			provider: "openai",
+			status:   http.StatusOK,
+			body: `{"choices":[{"index":0,"message":{"content":""},"finish_reason":"content_filter",
+				"content_filter_results":{"violence":{"filtered":true,"severity":"high"},"hate":{"filtered":false,"severity":"safe"}}}]}`,
+			source:     domain.ContentFilterResponse,
+			reason:     "content_filter",
+			categories: []string{"violence"},
+		},
+		{
+			name:     "AzurePromptFiltered",
+			provider: "openai",
+			status:   http.StatusBadRequest,
+			body: `{"error":{"message":"The response was filtered","code":"content_filter","status":400,
+				"innererror":{"code":"ResponsibleAIPolicyViolation","content_filter_result":{
+					"self_harm":{"filtered":true,"severity":"medium"},"sexual":{"filtered":false,"severity":"safe"}}}}}`,
+			source:     domain.ContentFilterPrompt,
+			reason:     "content_filter",
+			message:    "The response was filtered",
+			categories: []string{"self_harm"},
+		},
+		{
+			name:     "AnthropicRefusal",
+			provider: "anthropic",
+			status:   http.StatusOK,
+			body:     `{"content":[{"type":"text","text":"I won't help with that."}],"stop_reason":"refusal"}`,
+			source:   domain.ContentFilterResponse,
+			reason:   "refusal",
+			message:  "I won't help with that.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := contentFilterServer(tt.status, tt.body)
+			defer server.Close()
+
+			_, err := contentFilterProvider(tt.provider, server.URL).GenerateMessage(context.Background(), messages)
+			if !domain.IsContentFilteredError(err) {
+				t.Fatalf("Expected content filtered error, got %v", err)
+			}
+
+			filtered, ok := domain.AsContentFilteredError(err)
+			if !ok {
+				t.Fatalf("Expected a ContentFilteredError, got %T", err)
+			}
+			if filtered.Provider != tt.provider || filtered.Source != tt.source || filtered.Reason != tt.reason {
+				t.Errorf("Unexpected error details: %+v", filtered)
+			}
+			if filtered.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, filtered.Message)
+			}
+			if got := filtered.BlockedCategories(); fmt.Sprint(got) != fmt.Sprint(tt.categories) {
+				t.Errorf("Expected blocked categories %v, got %v", tt.categories, got)
+			}
+		})
+	}
+}
+
+func TestContentFilteredStreams(t *testing.T) {
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "something unsafe")}
+
+	tests := []struct {
+		name     string
+		provider string
+		events   []string
+		finished bool
+	}{
+		{
+			name:     "GeminiCandidateBlocked",
+			provider: "gemini",
+			events: []string{
+				`{"candidates":[{"content":{"parts":[{"text":"Here is"}]}}]}`,
+				`{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY"}]}`,
+			},
+		},
+		{
+			name:     "GeminiCompleted",
+			provider: "gemini",
+			events: []string{
+				`{"candidates":[{"content":{"parts":[{"text":"Here is"}]}}]}`,
+				`{"candidates":[{"content":{"parts":[]},"finishReason":"STOP"}]}`,
+			},
+			finished: true,
+		},
+		{
+			name:     "AnthropicRefusal",
+			provider: "anthropic",
+			events: []string{
+				`{"type":"content_block_delta","delta":{"type":"text_delta","text":"I won't"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"refusal"}}`,
+				`{"type":"message_stop"}`,
+			},
+		},
+		{
+			name:     "AnthropicCompleted",
+			provider: "anthropic",
+			events: []string{
+				`{"type":"content_block_delta","delta":{"type":"text_delta","text":"Sure"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`,
+			},
+			finished: true,
+		},
+		{
+			name:     "OpenAIContentFilter",
+			provider: "openai",
+			events: []string{
+				`{"choices":[{"delta":{"content":"Here is"}}]}`,
+				`{"choices":[{"delta":{},"finish_reason":"content_filter"}]}`,
+			},
+		},
+		{
+			name:     "OpenAIRefusal",
+			provider: "openai",
+			events: []string{
+				`{"choices":[{"delta":{"refusal":"I can't help with that."}}]}`,
+				`{"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+			},
+		},
+		{
+			name:     "OpenAICompleted",
+			provider: "openai",
+			events: []string{
+				`{"choices":[{"delta":{"content":"Sure"}}]}`,
+				`{"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+			},
+			finished: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, event := range tt.events {
+					fmt.Fprintf(w, "data: %s\n\n", event)
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			stream, err := contentFilterProvider(tt.provider, server.URL).StreamMessage(context.Background(), messages)
+			if err != nil {
+				t.Fatalf("StreamMessage failed: %v", err)
+			}
+			finished := false
+			for token := range stream {
+				finished = finished || token.Finished
+			}
+			if finished != tt.finished {
+				t.Errorf("Expected finished %v, got %v", tt.finished, finished)
+			}
+		})
+	}
+
+	t.Run("GeminiPromptBlocked", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `data: {"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[`+
+				`{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}}`+"\n\n")
+		}))
+		defer server.Close()
+
+		_, err := contentFilterProvider("gemini", server.URL).StreamMessage(context.Background(), messages)
+		filtered, ok := domain.AsContentFilteredError(err)
+		if !ok {
+			t.Fatalf("Expected a ContentFilteredError, got %v", err)
+		}
+		if filtered.Source != domain.ContentFilterPrompt || filtered.Reason != "SAFETY" ||
+			fmt.Sprint(filtered.BlockedCategories()) != "[HARM_CATEGORY_HARASSMENT]" {
+			t.Errorf("Unexpected error details: %+v", filtered)
+		}
+	})
+}
@@ -27,7 +27,7 @@ func mapOpenAIErrorToStandard(statusCode int, errorMsg string, operation string)
 		return domain.NewProviderError("openai", operation, statusCode, errorMsg, domain.ErrContextTooLong)
 
 	case strings.Contains(lowerErrorMsg, "content filter"):
-		return domain.NewProviderError("openai", operation, statusCode, errorMsg, domain.ErrContentFiltered)
+		return newContentFilteredHTTPError("openai", operation, statusCode, "content_filter", errorMsg)
 
 	case strings.Contains(lowerErrorMsg, "model not found"):
 		return domain.NewProviderError("openai", operation, statusCode, errorMsg, domain.ErrModelNotFound)
@@ -77,7 +77,7 @@ func mapAnthropicErrorToStandard(statusCode int, errorType, errorMsg string, ope
 	case strings.Contains(lowerErrorType, "content_filter") ||
 		strings.Contains(lowerErrorMsg, "content filtered") ||
 		strings.Contains(lowerErrorMsg, "content policy"):
-		return domain.NewProviderError("anthropic", operation, statusCode, errorMsg, domain.ErrContentFiltered)
+		return newContentFilteredHTTPError("anthropic", operation, statusCode, errorType, errorMsg)
 
 	case strings.Contains(lowerErrorType, "model_not_found") ||
 		strings.Contains(lowerErrorMsg, "model not found"):
@@ -124,6 +124,13 @@ func ParseJSONError(body []byte, statusCode int, provider, operation string) err
 		)
 	}
 
+	// Azure OpenAI reports content filter blocks with per-category results
+	if provider == "openai" {
+		if filtered := parseOpenAIContentFilterError(body, statusCode, operation); filtered != nil {
+			return filtered
+		}
+	}
+
 	// Look for common error patterns in JSON
 	errorRegex := regexp.MustCompile(`"error":\s*\{\s*"message":\s*"([^"]+)"`)
 	matches := errorRegex.FindSubmatch(body)
@@ -0,0 +1,472 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+)
+
+// FieldConsensus records the outcome of the vote for a single field of a structured result
+type FieldConsensus struct {
+	// Path identifies the field, e.g. "name", "address.city" or "tags[1]"
+	Path string
+	// Value is the value selected for the field (nil when the majority omitted it)
+	Value interface{}
+	// Agreement is the share of vote weight behind Value, between 0.0 and 1.0
+	Agreement float64
+	// Consensus reports whether Agreement reached the configured threshold without a tie
+	Consensus bool
+	// Supporters lists the providers whose value matched Value
+	Supporters []string
+	// Dissenters lists the providers that returned a different value or omitted the field
+	Dissenters []string
+}
+
+// StructuredConsensusResult is the merged result of field-level consensus
+type StructuredConsensusResult struct {
+	// Value is the merged structured result
+	Value interface{}
+	// Fields holds the vote outcome for every field, keyed by path
+	Fields map[string]*FieldConsensus
+	// Agreement is the mean agreement across all fields
+	Agreement float64
+	// Providers lists the providers whose results took part in the vote
+	Providers []string
+}
+
+// NoConsensusFields returns the sorted paths of fields that did not reach consensus
+func (r *StructuredConsensusResult) NoConsensusFields() []string {
+	var paths []string
+	for path, field := range r.Fields {
+		if !field.Consensus {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// WithFieldConsensus enables per-field voting for GenerateWithSchema under StrategyConsensus.
+// Each property and array element is decided separately, so the merged result can combine
+// values from different providers.
+func (mp *MultiProvider) WithFieldConsensus(enabled bool) *MultiProvider {
+	mp.consensusConfig.FieldLevel = enabled
+	return mp
+}
+
+// WithFieldAgreementThreshold sets the minimum agreement (0.0-1.0) a field needs to count as consensus
+func (mp *MultiProvider) WithFieldAgreementThreshold(threshold float64) *MultiProvider {
+	// Ensure threshold is within valid range
+	if threshold < 0.0 {
+		threshold = 0.0
+	}
+	if threshold > 1.0 {
+		threshold = 1.0
+	}
+	mp.consensusConfig.FieldAgreementThreshold = threshold
+	return mp
+}
+
+// GenerateWithSchemaConsensus runs GenerateWithSchema on all providers concurrently and merges
+// the results field by field, reporting the agreement reached for each field.
+// It ignores the selection strategy; provider weights are used when the consensus strategy is
+// ConsensusWeighted, otherwise every provider has one vote.
+func (mp *MultiProvider) GenerateWithSchemaConsensus(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (*StructuredConsensusResult, error) {
+	if len(mp.providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	// Apply the configured timeout if not overridden in the context
+	ctx, cancel := applyTimeoutFromContext(ctx, mp.defaultTimeout)
+	defer cancel()
+
+	results := mp.concurrentGenerateWithSchema(ctx, prompt, schema, options)
+	return mp.selectFieldConsensusResult(results, schema)
+}
+
+// selectFieldConsensusResult merges successful structured results using per-field voting
+func (mp *MultiProvider) selectFieldConsensusResult(results []fallbackResult, schema *schemaDomain.Schema) (*StructuredConsensusResult, error) {
+	providerErrors := make(map[string]error)
+	for _, result := range results {
+		if result.err != nil {
+			// Context-related errors take precedence over other errors
+			if errors.Is(result.err, context.DeadlineExceeded) {
+				return nil, ErrProviderTimeout
+			}
+			if errors.Is(result.err, context.Canceled) {
+				return nil, ErrContextCanceled
+			}
+			providerErrors[result.provider] = result.err
+		}
+	}
+
+	candidates := make([]fieldCandidate, 0, len(results))
+	for _, result := range results {
+		if result.err != nil || result.structured == nil {
+			continue
+		}
+
+		normalized, err := normalizeStructured(result.structured)
+		if err != nil {
+			providerErrors[result.provider] = err
+			continue
+		}
+
+		weight := 1.0
+		if mp.consensusConfig.Strategy == ConsensusWeighted && result.weight > 0 {
+			weight = result.weight
+		}
+
+		candidates = append(candidates, fieldCandidate{
+			provider: result.provider,
+			value:    normalized,
+			present:  true,
+			weight:   weight,
+		})
+	}
+
+	if len(candidates) == 0 {
+		if len(providerErrors) == 0 {
+			return nil, ErrNoSuccessfulCalls
+		}
+		return nil, NewMultiProviderError(providerErrors, ErrNoSuccessfulCalls.Error())
+	}
+
+	// Goroutines finish in arbitrary order, so sort to keep tie-breaking deterministic
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].provider < candidates[j].provider
+	})
+
+	merger := &fieldMerger{
+		threshold: mp.consensusConfig.FieldAgreementThreshold,
+		fields:    make(map[string]*FieldConsensus),
+	}
+
+	var root *schemaDomain.Property
+	if schema != nil {
+		root = &schemaDomain.Property{
+			Type:       schema.Type,
+			Properties: schema.Properties,
+			Required:   schema.Required,
+		}
+	}
+
+	value, _ := merger.merge("", root, candidates)
+
+	providers := make([]string, len(candidates))
+	for i, c := range candidates {
+		providers[i] = c.provider
+	}
+
+	consensus := &StructuredConsensusResult{
+		Value:     value,
+		Fields:    merger.fields,
+		Agreement: 1.0,
+		Providers: providers,
+	}
+
+	if len(merger.fields) > 0 {
+		total := 0.0
+		for _, field := range merger.fields {
+			total += field.Agreement
+		}
+		consensus.Agreement = total / float64(len(merger.fields))
+	}
+
+	return consensus, nil
+}
+
+// fieldCandidate is one provider's value for a field
+type fieldCandidate struct {
+	provider string
+	value    interface{}
+	present  bool
+	weight   float64
+}
+
+// fieldMerger walks candidate values and records the vote for each field
+type fieldMerger struct {
+	threshold float64
+	fields    map[string]*FieldConsensus
+}
+
+// merge decides the value at path, recursing into objects and arrays.
+// It returns the merged value and whether the field should be present in the output.
+func (m *fieldMerger) merge(path string, prop *schemaDomain.Property, candidates []fieldCandidate) (interface{}, bool) {
+	switch fieldKind(prop, candidates) {
+	case "object":
+		return m.mergeObject(path, prop, candidates)
+	case "array":
+		return m.mergeArray(path, prop, candidates)
+	default:
+		return m.mergeScalar(path, candidates)
+	}
+}
+
+// mergeObject merges each property of an object separately
+func (m *fieldMerger) mergeObject(path string, prop *schemaDomain.Property, candidates []fieldCandidate) (interface{}, bool) {
+	// Decide whether the object itself is present
+	present := m.vote(path, candidates, func(c fieldCandidate) string {
+		if _, ok := c.value.(map[string]interface{}); ok && c.present {
+			return "object"
+		}
+		return ""
+	}, nil)
+	if present.key == "" && path != "" {
+		return nil, false
+	}
+
+	// Collect the keys from the schema and from every candidate
+	keySet := make(map[string]bool)
+	if prop != nil {
+		for key := range prop.Properties {
+			keySet[key] = true
+		}
+	}
+	for _, c := range candidates {
+		if obj, ok := c.value.(map[string]interface{}); ok && c.present {
+			for key := range obj {
+				keySet[key] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	merged := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		children := make([]fieldCandidate, len(candidates))
+		for i, c := range candidates {
+			children[i] = fieldCandidate{provider: c.provider, weight: c.weight}
+			if obj, ok := c.value.(map[string]interface{}); ok && c.present {
+				children[i].value, children[i].present = obj[key]
+			}
+		}
+
+		var childProp *schemaDomain.Property
+		if prop != nil {
+			if p, ok := prop.Properties[key]; ok {
+				childProp = &p
+			}
+		}
+
+		if value, ok := m.merge(joinFieldPath(path, key), childProp, children); ok {
+			merged[key] = value
+		}
+	}
+
+	if field, ok := m.fields[path]; ok {
+		field.Value = merged
+	}
+	return merged, true
+}
+
+// mergeArray votes on the array length and then merges each element position
+func (m *fieldMerger) mergeArray(path string, prop *schemaDomain.Property, candidates []fieldCandidate) (interface{}, bool) {
+	outcome := m.vote(path, candidates, func(c fieldCandidate) string {
+		if arr, ok := c.value.([]interface{}); ok && c.present {
+			return fmt.Sprintf("%d", len(arr))
+		}
+		return ""
+	}, nil)
+	if outcome.key == "" {
+		return nil, false
+	}
+
+	var length int
+	fmt.Sscanf(outcome.key, "%d", &length)
+
+	var itemProp *schemaDomain.Property
+	if prop != nil {
+		itemProp = prop.Items
+	}
+
+	merged := make([]interface{}, 0, length)
+	for i := 0; i < length; i++ {
+		elements := make([]fieldCandidate, len(candidates))
+		for j, c := range candidates {
+			elements[j] = fieldCandidate{provider: c.provider, weight: c.weight}
+			if arr, ok := c.value.([]interface{}); ok && c.present && i < len(arr) {
+				elements[j].value = arr[i]
+				elements[j].present = true
+			}
+		}
+
+		value, _ := m.merge(fmt.Sprintf("%s[%d]", path, i), itemProp, elements)
+		merged = append(merged, value)
+	}
+
+	if field, ok := m.fields[path]; ok {
+		field.Value = merged
+	}
+	return merged, true
+}
+
+// mergeScalar votes on a leaf value
+func (m *fieldMerger) mergeScalar(path string, candidates []fieldCandidate) (interface{}, bool) {
+	outcome := m.vote(path, candidates, func(c fieldCandidate) string {
+		if !c.present {
+			return ""
+		}
+		return scalarVoteKey(c.value)
+	}, func(c fieldCandidate) interface{} {
+		return c.value
+	})
+	return outcome.value, outcome.key != ""
+}
+
+// voteOutcome is the winning group of a field vote
+type voteOutcome struct {
+	key   string
+	value interface{}
+}
+
+// vote groups candidates by key, records the result for path and returns the winning group.
+// An empty key stands for an omitted value.
+func (m *fieldMerger) vote(path string, candidates []fieldCandidate, keyFn func(fieldCandidate) string, valueFn func(fieldCandidate) interface{}) voteOutcome {
+	type voteGroup struct {
+		key     string
+		weight  float64
+		members []fieldCandidate
+	}
+
+	groups := make(map[string]*voteGroup)
+	order := make([]string, 0, len(candidates))
+	totalWeight := 0.0
+
+	for _, c := range candidates {
+		key := keyFn(c)
+		group, exists := groups[key]
+		if !exists {
+			group = &voteGroup{key: key}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.weight += c.weight
+		group.members = append(group.members, c)
+		totalWeight += c.weight
+	}
+
+	// Pick the heaviest group; first appearance wins ties
+	var winner *voteGroup
+	tied := false
+	for _, key := range order {
+		group := groups[key]
+		switch {
+		case winner == nil || group.weight > winner.weight:
+			winner = group
+			tied = false
+		case group.weight == winner.weight:
+			tied = true
+		}
+	}
+
+	agreement := 0.0
+	if totalWeight > 0 {
+		agreement = winner.weight / totalWeight
+	}
+
+	outcome := voteOutcome{key: winner.key}
+	if valueFn != nil && winner.key != "" {
+		// Use the value from the heaviest supporter as the representative
+		best := winner.members[0]
+		for _, c := range winner.members[1:] {
+			if c.weight > best.weight {
+				best = c
+			}
+		}
+		outcome.value = valueFn(best)
+	}
+
+	if path != "" {
+		field := &FieldConsensus{
+			Path:      path,
+			Value:     outcome.value,
+			Agreement: agreement,
+			Consensus: !tied && agreement >= m.threshold,
+		}
+		for _, c := range candidates {
+			if keyFn(c) == winner.key {
+				field.Supporters = append(field.Supporters, c.provider)
+			} else {
+				field.Dissenters = append(field.Dissenters, c.provider)
+			}
+		}
+		m.fields[path] = field
+	}
+
+	return outcome
+}
+
+// fieldKind determines how a field should be merged, preferring the schema type
+func fieldKind(prop *schemaDomain.Property, candidates []fieldCandidate) string {
+	if prop != nil && (prop.Type == "object" || prop.Type == "array") {
+		return prop.Type
+	}
+
+	kind := ""
+	for _, c := range candidates {
+		if !c.present {
+			continue
+		}
+		var current string
+		switch c.value.(type) {
+		case map[string]interface{}:
+			current = "object"
+		case []interface{}:
+			current = "array"
+		default:
+			current = "scalar"
+		}
+		if kind != "" && kind != current {
+			// Mixed types are compared as whole values
+			return "scalar"
+		}
+		kind = current
+	}
+	return kind
+}
+
+// scalarVoteKey builds the grouping key for a value.
+// Strings are compared case-insensitively and without surrounding whitespace.
+func scalarVoteKey(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return "s:" + strings.ToLower(strings.TrimSpace(s))
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("v:%v", value)
+	}
+	return "v:" + string(data)
+}
+
+// normalizeStructured converts a structured result into generic JSON values
+// so results from different providers can be compared field by field
+func normalizeStructured(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize structured result: %w", err)
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to normalize structured result: %w", err)
+	}
+	return normalized, nil
+}
+
+// joinFieldPath appends a property name to a field path
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
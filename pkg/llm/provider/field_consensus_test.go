@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+)
+
+// structuredMock returns a provider whose GenerateWithSchema returns the given value
+func structuredMock(value interface{}, err error) *MockProvider {
+	return NewMockProvider().WithGenerateWithSchemaFunc(
+		func(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+			return value, err
+		})
+}
+
+func extractionSchema() *schemaDomain.Schema {
+	return &schemaDomain.Schema{
+		Type: "object",
+		Properties: map[string]schemaDomain.Property{
+			"name": {Type: "string"},
+			"age":  {Type: "integer"},
+			"address": {
+				Type: "object",
+				Properties: map[string]schemaDomain.Property{
+					"city":    {Type: "string"},
+					"country": {Type: "string"},
+				},
+			},
+			"tags": {Type: "array", Items: &schemaDomain.Property{Type: "string"}},
+		},
+	}
+}
+
+func TestFieldConsensus(t *testing.T) {
+	t.Run("MergesFieldsFromDifferentProviders", func(t *testing.T) {
+		providers := []ProviderWeight{
+			{Name: "a", Weight: 1.0, Provider: structuredMock(map[string]interface{}{
+				"name":    "Ada Lovelace",
+				"age":     36,
+				"address": map[string]interface{}{"city": "London", "country": "UK"},
+				"tags":    []string{"math", "computing"},
+			}, nil)},
+			{Name: "b", Weight: 1.0, Provider: structuredMock(map[string]interface{}{
+				"name":    "ada lovelace ",
+				"age":     37,
+				"address": map[string]interface{}{"city": "London", "country": "England"},
+				"tags":    []string{"math", "poetry"},
+			}, nil)},
+			{Name: "c", Weight: 1.0, Provider: structuredMock(map[string]interface{}{
+				"name":    "Ada Lovelace",
+				"age":     36,
+				"address": map[string]interface{}{"city": "Paris", "country": "UK"},
+				"tags":    []string{"math", "computing", "poetry"},
+			}, nil)},
+		}
+
+		mp := NewMultiProvider(providers, StrategyConsensus).WithFieldConsensus(true)
+		result, err := mp.GenerateWithSchemaConsensus(context.Background(), "extract", extractionSchema())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := map[string]interface{}{
+			"name":    "Ada Lovelace",
+			"age":     float64(36),
+			"address": map[string]interface{}{"city": "London", "country": "UK"},
+			"tags":    []interface{}{"math", "computing"},
+		}
+		if !reflect.DeepEqual(result.Value, expected) {
+			t.Errorf("Expected merged value %v, got %v", expected, result.Value)
+		}
+
+		// Names differ only in case and whitespace, so all three providers agree
+		if got := result.Fields["name"].Agreement; got != 1.0 {
+			t.Errorf("Expected full agreement on name, got %f", got)
+		}
+
+		age := result.Fields["age"]
+		if !age.Consensus || len(age.Supporters) != 2 || age.Dissenters[0] != "b" {
+			t.Errorf("Unexpected vote for age: %+v", age)
+		}
+
+		if _, ok := result.Fields["tags[1]"]; !ok {
+			t.Errorf("Expected per-element field for tags[1]")
+		}
+
+		if len(result.NoConsensusFields()) != 0 {
+			t.Errorf("Expected every field to reach consensus, got %v", result.NoConsensusFields())
+		}
+
+		// GenerateWithSchema returns the same merged value
+		value, err := mp.GenerateWithSchema(context.Background(), "extract", extractionSchema())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(value, expected) {
+			t.Errorf("Expected GenerateWithSchema to return %v, got %v", expected, value)
+		}
+	})
+
+	t.Run("FlagsFieldsWithoutConsensus", func(t *testing.T) {
+		providers := []ProviderWeight{
+			{Name: "a", Provider: structuredMock(map[string]interface{}{"name": "Alice", "age": 30}, nil)},
+			{Name: "b", Provider: structuredMock(map[string]interface{}{"name": "Bob", "age": 30}, nil)},
+		}
+
+		mp := NewMultiProvider(providers, StrategyConsensus).WithFieldConsensus(true)
+		result, err := mp.GenerateWithSchemaConsensus(context.Background(), "extract", extractionSchema())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		disputed := result.NoConsensusFields()
+		if !reflect.DeepEqual(disputed, []string{"name"}) {
+			t.Errorf("Expected only name to lack consensus, got %v", disputed)
+		}
+		if result.Fields["name"].Agreement != 0.5 {
+			t.Errorf("Expected 0.5 agreement on name, got %f", result.Fields["name"].Agreement)
+		}
+	})
+
+	t.Run("UsesProviderWeights", func(t *testing.T) {
+		providers := []ProviderWeight{
+			{Name: "cheap1", Weight: 0.2, Provider: structuredMock(map[string]interface{}{"name": "Alice"}, nil)},
+			{Name: "cheap2", Weight: 0.2, Provider: structuredMock(map[string]interface{}{"name": "Alice"}, nil)},
+			{Name: "strong", Weight: 1.0, Provider: structuredMock(map[string]interface{}{"name": "Alicia"}, nil)},
+		}
+
+		mp := NewMultiProvider(providers, StrategyConsensus).
+			WithConsensusStrategy(ConsensusWeighted).
+			WithFieldConsensus(true)
+		result, err := mp.GenerateWithSchemaConsensus(context.Background(), "extract", extractionSchema())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if got := result.Fields["name"].Value; got != "Alicia" {
+			t.Errorf("Expected weighted vote to select Alicia, got %v", got)
+		}
+	})
+
+	t.Run("OmitsFieldsMissingFromMajority", func(t *testing.T) {
+		providers := []ProviderWeight{
+			{Name: "a", Provider: structuredMock(map[string]interface{}{"name": "Alice", "nickname": "Al"}, nil)},
+			{Name: "b", Provider: structuredMock(map[string]interface{}{"name": "Alice"}, nil)},
+			{Name: "c", Provider: structuredMock(map[string]interface{}{"name": "Alice"}, nil)},
+		}
+
+		mp := NewMultiProvider(providers, StrategyConsensus).WithFieldConsensus(true)
+		result, err := mp.GenerateWithSchemaConsensus(context.Background(), "extract", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		merged := result.Value.(map[string]interface{})
+		if _, ok := merged["nickname"]; ok {
+			t.Errorf("Expected nickname to be omitted, got %v", merged)
+		}
+	})
+
+	t.Run("IgnoresFailedProviders", func(t *testing.T) {
+		providers := []ProviderWeight{
+			{Name: "ok", Provider: structuredMock(map[string]interface{}{"name": "Alice"}, nil)},
+			{Name: "failed", Provider: structuredMock(nil, errors.New("boom"))},
+		}
+
+		mp := NewMultiProvider(providers, StrategyConsensus).WithTimeout(time.Second)
+		result, err := mp.GenerateWithSchemaConsensus(context.Background(), "extract", extractionSchema())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(result.Providers, []string{"ok"}) {
+			t.Errorf("Expected only the successful provider to vote, got %v", result.Providers)
+		}
+	})
+
+	t.Run("AllProvidersFail", func(t *testing.T) {
+		providers := []ProviderWeight{
+			{Name: "failed", Provider: structuredMock(nil, errors.New("boom"))},
+		}
+
+		mp := NewMultiProvider(providers, StrategyConsensus)
+		_, err := mp.GenerateWithSchemaConsensus(context.Background(), "extract", extractionSchema())
+		if err == nil {
+			t.Fatal("Expected an error when all providers fail")
+		}
+	})
+}
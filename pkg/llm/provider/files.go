@@ -0,0 +1,191 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// uploadExpiryMargin is how long before expiry a cached upload is considered stale
+const uploadExpiryMargin = time.Hour
+
+// FileUploader is implemented by providers with a File API. Uploaded files can be
+// referenced in messages with domain.NewUploadedFileMessage instead of inlining the bytes.
+type FileUploader interface {
+	// UploadFile uploads data and returns the stored file
+	UploadFile(ctx context.Context, name string, data []byte, mimeType string) (domain.UploadedFile, error)
+
+	// ListFiles returns the files stored with the provider
+	ListFiles(ctx context.Context) ([]domain.UploadedFile, error)
+
+	// DeleteFile deletes a stored file by ID
+	DeleteFile(ctx context.Context, id string) error
+}
+
+// fileUploadCache remembers automatic uploads by content hash so the same media is
+// uploaded once even when it is sent again with every turn of a conversation
+type fileUploadCache struct {
+	mu    sync.Mutex
+	files map[string]domain.UploadedFile
+}
+
+// newFileUploadCache creates an empty upload cache
+func newFileUploadCache() *fileUploadCache {
+	return &fileUploadCache{files: make(map[string]domain.UploadedFile)}
+}
+
+// get returns the cached upload for key if it has not expired
+func (c *fileUploadCache) get(key string) (domain.UploadedFile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file, ok := c.files[key]
+	if ok && !file.ExpiresAt.IsZero() && time.Until(file.ExpiresAt) < uploadExpiryMargin {
+		delete(c.files, key)
+		return domain.UploadedFile{}, false
+	}
+	return file, ok
+}
+
+// put caches an upload under key
+func (c *fileUploadCache) put(key string, file domain.UploadedFile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[key] = file
+}
+
+// forget removes a deleted file from the cache
+func (c *fileUploadCache) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, file := range c.files {
+		if file.ID == id {
+			delete(c.files, key)
+		}
+	}
+}
+
+// uploadLargeMedia returns messages with inline media larger than threshold bytes replaced
+// by references to uploaded files. Only content types in uploadable are uploaded. The input
+// messages are never modified; if nothing is uploaded they are returned as is.
+func uploadLargeMedia(
+	ctx context.Context,
+	uploader FileUploader,
+	cache *fileUploadCache,
+	threshold int,
+	uploadable map[domain.ContentType]bool,
+	messages []domain.Message,
+) ([]domain.Message, error) {
+	if threshold <= 0 {
+		return messages, nil
+	}
+
+	var result []domain.Message
+	copied := make(map[int]bool)
+	for i, msg := range messages {
+		for j, part := range msg.Content {
+			if !uploadable[part.Type] {
+				continue
+			}
+			data, mimeType, name, ok := inlineMedia(part)
+			if !ok || base64.StdEncoding.DecodedLen(len(data)) <= threshold {
+				continue
+			}
+
+			sum := sha256.Sum256([]byte(data))
+			key := hex.EncodeToString(sum[:])
+			file, found := cache.get(key)
+			if !found {
+				raw, err := base64.StdEncoding.DecodeString(data)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decode %s content for upload: %w", part.Type, err)
+				}
+				if name == "" {
+					name = fmt.Sprintf("%s-%s", part.Type, key[:12])
+				}
+				file, err = uploader.UploadFile(ctx, name, raw, mimeType)
+				if err != nil {
+					return nil, err
+				}
+				cache.put(key, file)
+			}
+
+			// Copy on first change so the caller's messages stay untouched
+			if result == nil {
+				result = append([]domain.Message(nil), messages...)
+			}
+			if !copied[i] {
+				result[i].Content = append([]domain.ContentPart(nil), msg.Content...)
+				copied[i] = true
+			}
+			result[i].Content[j] = fileReferencePart(part, file)
+		}
+	}
+
+	if result == nil {
+		return messages, nil
+	}
+	return result, nil
+}
+
+// inlineMedia returns the base64 data, MIME type and file name of an inline media part
+func inlineMedia(part domain.ContentPart) (data, mimeType, name string, ok bool) {
+	var source *domain.SourceInfo
+	switch part.Type {
+	case domain.ContentTypeImage:
+		if part.Image != nil {
+			source = &part.Image.Source
+		}
+	case domain.ContentTypeVideo:
+		if part.Video != nil {
+			source = &part.Video.Source
+		}
+	case domain.ContentTypeAudio:
+		if part.Audio != nil {
+			source = &part.Audio.Source
+		}
+	case domain.ContentTypeFile:
+		if part.File != nil && part.File.FileID == "" && part.File.FileData != "" {
+			return part.File.FileData, part.File.MimeType, part.File.FileName, true
+		}
+		return "", "", "", false
+	}
+
+	if source == nil || source.Type != domain.SourceTypeBase64 || source.Data == "" {
+		return "", "", "", false
+	}
+	return source.Data, source.MediaType, "", true
+}
+
+// fileReferencePart returns a part of the same content type that references file
+// instead of inlining its data
+func fileReferencePart(part domain.ContentPart, file domain.UploadedFile) domain.ContentPart {
+	source := domain.SourceInfo{Type: domain.SourceTypeFile, FileID: file.Reference()}
+
+	switch part.Type {
+	case domain.ContentTypeImage:
+		source.MediaType = part.Image.Source.MediaType
+		return domain.ContentPart{Type: part.Type, Image: &domain.ImageContent{Source: source}}
+	case domain.ContentTypeVideo:
+		source.MediaType = part.Video.Source.MediaType
+		return domain.ContentPart{Type: part.Type, Video: &domain.VideoContent{Source: source}}
+	case domain.ContentTypeAudio:
+		source.MediaType = part.Audio.Source.MediaType
+		return domain.ContentPart{Type: part.Type, Audio: &domain.AudioContent{Source: source}}
+	default:
+		return domain.ContentPart{
+			Type: domain.ContentTypeFile,
+			File: &domain.FileContent{
+				FileName: part.File.FileName,
+				MimeType: part.File.MimeType,
+				FileID:   file.Reference(),
+			},
+		}
+	}
+}
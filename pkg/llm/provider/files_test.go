@@ -0,0 +1,331 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// fakeFileUploader records uploads for auto-upload tests
+type fakeFileUploader struct {
+	mu      sync.Mutex
+	uploads []string
+}
+
+func (f *fakeFileUploader) UploadFile(ctx context.Context, name string, data []byte, mimeType string) (domain.UploadedFile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.uploads = append(f.uploads, name)
+	return domain.UploadedFile{ID: fmt.Sprintf("file-%d", len(f.uploads)), Name: name, MimeType: mimeType}, nil
+}
+
+func (f *fakeFileUploader) ListFiles(ctx context.Context) ([]domain.UploadedFile, error) {
+	return nil, nil
+}
+
+func (f *fakeFileUploader) DeleteFile(ctx context.Context, id string) error {
+	return nil
+}
+
+func TestUploadLargeMedia(t *testing.T) {
+	large := make([]byte, 2048)
+	messages := []domain.Message{
+		domain.NewTextMessage(domain.RoleSystem, "Be brief"),
+		domain.NewFileMessage(domain.RoleUser, "report.pdf", large, "application/pdf", "Summarize"),
+		domain.NewImageMessage(domain.RoleUser, []byte("small"), "image/png", "Describe"),
+	}
+	uploadable := map[domain.ContentType]bool{domain.ContentTypeFile: true, domain.ContentTypeImage: true}
+
+	t.Run("ReplacesLargeMediaOnly", func(t *testing.T) {
+		uploader := &fakeFileUploader{}
+		result, err := uploadLargeMedia(context.Background(), uploader, newFileUploadCache(), 1024, uploadable, messages)
+		if err != nil {
+			t.Fatalf("uploadLargeMedia failed: %v", err)
+		}
+
+		if len(uploader.uploads) != 1 || uploader.uploads[0] != "report.pdf" {
+			t.Fatalf("Expected report.pdf to be uploaded once, got %v", uploader.uploads)
+		}
+		file := result[1].Content[0].File
+		if file.FileID != "file-1" || file.FileData != "" || file.FileName != "report.pdf" {
+			t.Errorf("Expected a file reference, got %+v", file)
+		}
+		if result[2].Content[0].Image.Source.Type != domain.SourceTypeBase64 {
+			t.Errorf("Small image should stay inline")
+		}
+		if messages[1].Content[0].File.FileID != "" || messages[1].Content[0].File.FileData == "" {
+			t.Errorf("Input messages were modified")
+		}
+	})
+
+	t.Run("CachesUploads", func(t *testing.T) {
+		uploader := &fakeFileUploader{}
+		cache := newFileUploadCache()
+		for i := 0; i < 3; i++ {
+			if _, err := uploadLargeMedia(context.Background(), uploader, cache, 1024, uploadable, messages); err != nil {
+				t.Fatalf("uploadLargeMedia failed: %v", err)
+			}
+		}
+		if len(uploader.uploads) != 1 {
+			t.Errorf("Expected 1 upload for repeated media, got %d", len(uploader.uploads))
+		}
+
+		cache.forget("file-1")
+		if _, err := uploadLargeMedia(context.Background(), uploader, cache, 1024, uploadable, messages); err != nil {
+			t.Fatalf("uploadLargeMedia failed: %v", err)
+		}
+		if len(uploader.uploads) != 2 {
+			t.Errorf("Expected a new upload after the file was deleted, got %d uploads", len(uploader.uploads))
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		uploader := &fakeFileUploader{}
+		result, err := uploadLargeMedia(context.Background(), uploader, newFileUploadCache(), 0, uploadable, messages)
+		if err != nil {
+			t.Fatalf("uploadLargeMedia failed: %v", err)
+		}
+		if len(uploader.uploads) != 0 || &result[0] != &messages[0] {
+			t.Errorf("Expected messages to pass through unchanged")
+		}
+	})
+}
+
+func TestOpenAIFiles(t *testing.T) {
+	var mu sync.Mutex
+	var chatBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+			file, header, err := r.FormFile("file")
+			if err != nil || r.FormValue("purpose") != openAIFilePurpose {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, `{"error":{"message":"bad upload"}}`)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			fmt.Fprintf(w, `{"id":"file-abc","filename":%q,"bytes":%d,"created_at":1700000000}`, header.Filename, len(data))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files":
+			if r.URL.Query().Get("after") == "" {
+				fmt.Fprintln(w, `{"data":[{"id":"file-abc","filename":"a.pdf"}],"has_more":true}`)
+			} else {
+				fmt.Fprintln(w, `{"data":[{"id":"file-def","filename":"b.pdf"}],"has_more":false}`)
+			}
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/files/file-abc":
+			fmt.Fprintln(w, `{"id":"file-abc","deleted":true}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/chat/completions":
+			_ = json.NewDecoder(r.Body).Decode(&chatBody)
+			fmt.Fprintln(w, `{"choices":[{"message":{"content":"summary"}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"error":{"message":"not found"}}`)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("test-key", "gpt-4o",
+		domain.NewBaseURLOption(server.URL), domain.NewAutoUploadOption(1024))
+	ctx := context.Background()
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		file, err := provider.UploadFile(ctx, "report.pdf", []byte("%PDF-1.4"), "application/pdf")
+		if err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+		if file.ID != "file-abc" || file.Provider != "openai" || file.SizeBytes != 8 || file.CreatedAt.IsZero() {
+			t.Errorf("Unexpected uploaded file: %+v", file)
+		}
+
+		files, err := provider.ListFiles(ctx)
+		if err != nil {
+			t.Fatalf("ListFiles failed: %v", err)
+		}
+		if len(files) != 2 || files[1].ID != "file-def" {
+			t.Errorf("Expected 2 files across pages, got %+v", files)
+		}
+
+		if err := provider.DeleteFile(ctx, "file-abc"); err != nil {
+			t.Fatalf("DeleteFile failed: %v", err)
+		}
+	})
+
+	t.Run("AutoUpload", func(t *testing.T) {
+		msg := domain.NewFileMessage(domain.RoleUser, "large.pdf", make([]byte, 4096), "application/pdf", "Summarize")
+		if _, err := provider.GenerateMessage(ctx, []domain.Message{msg}); err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		encoded, _ := json.Marshal(chatBody)
+		if !strings.Contains(string(encoded), `"file_id":"file-abc"`) || strings.Contains(string(encoded), "file_data") {
+			t.Errorf("Expected a file reference in the request, got %s", encoded)
+		}
+	})
+}
+
+func TestAnthropicFiles(t *testing.T) {
+	var mu sync.Mutex
+	var messagesBeta string
+	var messagesBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/v1/files") && r.Header.Get("anthropic-beta") != anthropicFilesBeta {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"type":"error","error":{"type":"invalid_request_error","message":"missing beta"}}`)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+			_, header, err := r.FormFile("file")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"id":"file_011","filename":%q,"mime_type":%q,"size_bytes":8,"created_at":"2025-01-01T00:00:00Z"}`,
+				header.Filename, header.Header.Get("Content-Type"))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files":
+			fmt.Fprintln(w, `{"data":[{"id":"file_011","filename":"a.pdf"}],"has_more":false,"last_id":"file_011"}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/files/file_011":
+			fmt.Fprintln(w, `{"id":"file_011","type":"file_deleted"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/messages":
+			messagesBeta = r.Header.Get("anthropic-beta")
+			_ = json.NewDecoder(r.Body).Decode(&messagesBody)
+			fmt.Fprintln(w, `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider("test-key", "claude-3-5-sonnet-latest", domain.NewBaseURLOption(server.URL))
+	ctx := context.Background()
+
+	file, err := provider.UploadFile(ctx, "report.pdf", []byte("%PDF-1.4"), "application/pdf")
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if file.ID != "file_011" || file.MimeType != "application/pdf" || file.CreatedAt.IsZero() {
+		t.Errorf("Unexpected uploaded file: %+v", file)
+	}
+
+	files, err := provider.ListFiles(ctx)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("ListFiles returned %v, %v", files, err)
+	}
+
+	msg := domain.NewUploadedFileMessage(domain.RoleUser, file, "Summarize")
+	if _, err := provider.GenerateMessage(ctx, []domain.Message{msg}); err != nil {
+		t.Fatalf("GenerateMessage failed: %v", err)
+	}
+
+	mu.Lock()
+	if messagesBeta != anthropicFilesBeta {
+		t.Errorf("Expected files beta header on messages request, got %q", messagesBeta)
+	}
+	encoded, _ := json.Marshal(messagesBody)
+	if !strings.Contains(string(encoded), `"type":"document"`) || !strings.Contains(string(encoded), `"file_id":"file_011"`) {
+		t.Errorf("Expected a document file reference, got %s", encoded)
+	}
+	mu.Unlock()
+
+	if err := provider.DeleteFile(ctx, "file_011"); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+}
+
+func TestGeminiFiles(t *testing.T) {
+	var mu sync.Mutex
+	var polls int
+	var uploaded []byte
+	var generateBody map[string]interface{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/v1beta/files":
+			if r.Header.Get("X-Goog-Upload-Command") != "start" || r.URL.Query().Get("key") != "test-key" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("X-Goog-Upload-URL", server.URL+"/upload/session/1")
+			fmt.Fprintln(w, `{}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/session/1":
+			if r.Header.Get("X-Goog-Upload-Command") != "upload, finalize" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			uploaded, _ = io.ReadAll(r.Body)
+			fmt.Fprintf(w, `{"file":{"name":"files/vid1","displayName":"clip","mimeType":"video/mp4","sizeBytes":"%d","uri":"https://example.test/v1beta/files/vid1","state":"PROCESSING","createTime":"2025-01-01T00:00:00Z","expirationTime":"2099-01-03T00:00:00Z"}}`, len(uploaded))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1beta/files/vid1":
+			polls++
+			state := "PROCESSING"
+			if polls >= 2 {
+				state = "ACTIVE"
+			}
+			fmt.Fprintf(w, `{"name":"files/vid1","mimeType":"video/mp4","uri":"https://example.test/v1beta/files/vid1","state":%q}`, state)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1beta/files":
+			if r.URL.Query().Get("pageToken") == "" {
+				fmt.Fprintln(w, `{"files":[{"name":"files/vid1"}],"nextPageToken":"next"}`)
+			} else {
+				fmt.Fprintln(w, `{"files":[{"name":"files/vid2"}]}`)
+			}
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1beta/files/vid1":
+			fmt.Fprintln(w, `{}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, ":generateContent"):
+			_ = json.NewDecoder(r.Body).Decode(&generateBody)
+			fmt.Fprintln(w, `{"candidates":[{"content":{"parts":[{"text":"a cat video"}]}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewGeminiProvider("test-key", "gemini-2.0-flash",
+		domain.NewBaseURLOption(server.URL+"/v1beta"), domain.NewAutoUploadOption(1024))
+	provider.filePollInterval = time.Millisecond
+	ctx := context.Background()
+
+	msg := domain.NewVideoMessage(domain.RoleUser, make([]byte, 4096), "video/mp4", "What happens?")
+	if _, err := provider.GenerateMessage(ctx, []domain.Message{msg}); err != nil {
+		t.Fatalf("GenerateMessage failed: %v", err)
+	}
+
+	mu.Lock()
+	if len(uploaded) != 4096 || polls != 2 {
+		t.Errorf("Expected a 4096 byte upload polled until active, got %d bytes and %d polls", len(uploaded), polls)
+	}
+	encoded, _ := json.Marshal(generateBody)
+	if !strings.Contains(string(encoded), `"file_uri":"https://example.test/v1beta/files/vid1"`) || strings.Contains(string(encoded), "inline_data") {
+		t.Errorf("Expected a file_data reference, got %s", encoded)
+	}
+	mu.Unlock()
+
+	files, err := provider.ListFiles(ctx)
+	if err != nil || len(files) != 2 {
+		t.Fatalf("ListFiles returned %v, %v", files, err)
+	}
+
+	if err := provider.DeleteFile(ctx, "vid1"); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+	if len(provider.uploadCache.files) != 0 {
+		t.Errorf("Expected the deleted file to be removed from the upload cache")
+	}
+}
@@ -6,12 +6,15 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/lexlapax/go-llms/pkg/llm/domain"
 	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
 	"github.com/lexlapax/go-llms/pkg/structured/processor"
+	"github.com/lexlapax/go-llms/pkg/util/httplog"
 	"github.com/lexlapax/go-llms/pkg/util/json"
 )
 
@@ -27,7 +30,17 @@ type GeminiProvider struct {
 	httpClient     *http.Client
 	messageCache   *MessageCache
 	topK           int
+	candidateCount int
 	safetySettings []map[string]interface{}
+	// Inline media above this size is uploaded with the File API (0 disables)
+	autoUploadThreshold int
+	uploadCache         *fileUploadCache
+	// mediaNormalizer prepares media for the provider's limits (nil disables)
+	mediaNormalizer *MediaNormalizer
+	// filePollInterval is how often an uploaded file is checked while it is processed
+	filePollInterval time.Duration
+	// requestLogging logs HTTP requests and responses (nil disables)
+	requestLogging *requestLogging
 }
 
 // NewGeminiProvider creates a new Google Gemini provider
@@ -46,6 +59,10 @@ func NewGeminiProvider(apiKey, model string, options ...domain.ProviderOption) *
 		messageCache:   NewMessageCache(),
 		topK:           40, // Default topK value
 		safetySettings: nil,
+		uploadCache:    newFileUploadCache(),
+
+		filePollInterval: defaultGeminiFilePollInterval,
+		mediaNormalizer:  NewMediaNormalizer("gemini", GeminiMediaLimits()),
 	}
 
 	for _, option := range options {
@@ -66,7 +83,13 @@ func (p *GeminiProvider) SetBaseURL(url string) {
 
 // SetHTTPClient sets the HTTP client
 func (p *GeminiProvider) SetHTTPClient(client *http.Client) {
-	p.httpClient = client
+	p.httpClient = p.requestLogging.wrap(client)
+}
+
+// SetRequestLogging logs HTTP requests and responses, redacting the API key and the redactor's patterns
+func (p *GeminiProvider) SetRequestLogging(logger *slog.Logger, redactor *httplog.Redactor) {
+	p.requestLogging = newRequestLogging(logger, redactor, p.apiKey)
+	p.httpClient = p.requestLogging.wrap(p.httpClient)
 }
 
 // SetTopK sets the topK parameter for Gemini API calls
@@ -74,11 +97,43 @@ func (p *GeminiProvider) SetTopK(topK int) {
 	p.topK = topK
 }
 
+// SetCandidateCount sets the default number of candidates for Gemini API calls
+func (p *GeminiProvider) SetCandidateCount(candidateCount int) {
+	p.candidateCount = candidateCount
+}
+
 // SetSafetySettings sets the safety settings for Gemini API calls
 func (p *GeminiProvider) SetSafetySettings(settings []map[string]interface{}) {
 	p.safetySettings = settings
 }
 
+// SetAutoUploadThreshold uploads inline media larger than bytes with the File API
+func (p *GeminiProvider) SetAutoUploadThreshold(bytes int) {
+	p.autoUploadThreshold = bytes
+}
+
+// SetMediaNormalizer sets the media normalization stage run before each request; nil disables it
+func (p *GeminiProvider) SetMediaNormalizer(normalizer *MediaNormalizer) {
+	p.mediaNormalizer = normalizer
+}
+
+// prepareMessages normalizes media, uploads large media and validates the messages
+func (p *GeminiProvider) prepareMessages(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	messages, err := prepareMedia(ctx, p.mediaNormalizer, p.uploadLargeMedia, messages)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.validateContentTypesForGemini(messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// uploadLargeMedia replaces inline media above the auto-upload threshold with uploaded file references
+func (p *GeminiProvider) uploadLargeMedia(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	return uploadLargeMedia(ctx, p, p.uploadCache, p.autoUploadThreshold, geminiUploadableTypes, messages)
+}
+
 // ConvertMessagesToGeminiFormat converts domain messages to Gemini API format
 func (p *GeminiProvider) ConvertMessagesToGeminiFormat(messages []domain.Message) []map[string]interface{} {
 	// Check cache first
@@ -127,42 +182,28 @@ func (p *GeminiProvider) ConvertMessagesToGeminiFormat(messages []domain.Message
 					})
 				case domain.ContentTypeImage:
 					// Image part
-					if part.Image.Source.Type == domain.SourceTypeURL {
-						// URL-based image
-						parts = append(parts, map[string]interface{}{
-							"inline_data": map[string]interface{}{
-								"mime_type": part.Image.Source.MediaType,
-								"url":       part.Image.Source.URL,
-							},
-						})
-					} else {
-						// Base64-encoded image
-						parts = append(parts, map[string]interface{}{
-							"inline_data": map[string]interface{}{
-								"mime_type": part.Image.Source.MediaType,
-								"data":      part.Image.Source.Data,
-							},
-						})
-					}
+					parts = append(parts, geminiMediaPart(part.Image.Source))
 				case domain.ContentTypeVideo:
 					// Video part
-					if part.Video.Source.Type == domain.SourceTypeURL {
-						// URL-based video
-						parts = append(parts, map[string]interface{}{
-							"inline_data": map[string]interface{}{
-								"mime_type": part.Video.Source.MediaType,
-								"url":       part.Video.Source.URL,
-							},
-						})
-					} else {
-						// Base64-encoded video
-						parts = append(parts, map[string]interface{}{
-							"inline_data": map[string]interface{}{
-								"mime_type": part.Video.Source.MediaType,
-								"data":      part.Video.Source.Data,
-							},
-						})
+					parts = append(parts, geminiMediaPart(part.Video.Source))
+				case domain.ContentTypeAudio:
+					// Audio part
+					parts = append(parts, geminiMediaPart(part.Audio.Source))
+				case domain.ContentTypeFile:
+					// File part (for example a PDF), inline or uploaded with the File API
+					source := domain.SourceInfo{
+						Type:      domain.SourceTypeBase64,
+						MediaType: part.File.MimeType,
+						Data:      part.File.FileData,
+					}
+					if part.File.FileID != "" {
+						source = domain.SourceInfo{
+							Type:      domain.SourceTypeFile,
+							MediaType: part.File.MimeType,
+							FileID:    part.File.FileID,
+						}
 					}
+					parts = append(parts, geminiMediaPart(source))
 				}
 			}
 
@@ -185,6 +226,36 @@ func (p *GeminiProvider) ConvertMessagesToGeminiFormat(messages []domain.Message
 	return contents
 }
 
+// geminiMediaPart converts a media source to a Gemini part
+func geminiMediaPart(source domain.SourceInfo) map[string]interface{} {
+	switch source.Type {
+	case domain.SourceTypeURL:
+		// URL-based media
+		return map[string]interface{}{
+			"inline_data": map[string]interface{}{
+				"mime_type": source.MediaType,
+				"url":       source.URL,
+			},
+		}
+	case domain.SourceTypeFile:
+		// Media uploaded with the File API
+		return map[string]interface{}{
+			"file_data": map[string]interface{}{
+				"mime_type": source.MediaType,
+				"file_uri":  source.FileID,
+			},
+		}
+	default:
+		// Base64-encoded media
+		return map[string]interface{}{
+			"inline_data": map[string]interface{}{
+				"mime_type": source.MediaType,
+				"data":      source.Data,
+			},
+		}
+	}
+}
+
 // buildGeminiRequestBody creates a request body for the Gemini API
 func (p *GeminiProvider) buildGeminiRequestBody(
 	contents []map[string]interface{},
@@ -219,14 +290,38 @@ func (p *GeminiProvider) buildGeminiRequestBody(
 		generationConfig["stopSequences"] = options.StopSequences
 	}
 
+	// Add seed for reproducible sampling
+	if options.Seed != nil {
+		generationConfig["seed"] = *options.Seed
+	}
+
+	// Request multiple candidates and token log probabilities if asked for
+	if candidateCount := p.effectiveCandidateCount(options); candidateCount > 1 {
+		generationConfig["candidateCount"] = candidateCount
+	}
+
+	if options.Logprobs {
+		generationConfig["responseLogprobs"] = true
+		if options.TopLogprobs > 0 {
+			generationConfig["logprobs"] = options.TopLogprobs
+		}
+	}
+
 	// Only add the generationConfig if it has entries
 	if len(generationConfig) > 0 {
 		requestBody["generationConfig"] = generationConfig
 	}
 
-	// Add safety settings if configured
-	if len(p.safetySettings) > 0 {
-		requestBody["safetySettings"] = p.safetySettings
+	// Add safety settings if configured, merging per-call settings over the provider's
+	if safetySettings := mergeGeminiSafetySettings(p.safetySettings, options); len(safetySettings) > 0 {
+		requestBody["safetySettings"] = safetySettings
+	}
+
+	// Ground the response with Google Search
+	if options.GoogleSearch {
+		requestBody["tools"] = []map[string]interface{}{
+			{"google_search": map[string]interface{}{}},
+		}
 	}
 
 	return requestBody
@@ -237,10 +332,12 @@ func (p *GeminiProvider) validateContentTypesForGemini(messages []domain.Message
 	for _, msg := range messages {
 		if msg.Content != nil {
 			for _, part := range msg.Content {
-				// Gemini currently supports text, image, and video content types
+				// Gemini currently supports text, image, video, audio and file content types
 				if part.Type != domain.ContentTypeText &&
 					part.Type != domain.ContentTypeImage &&
-					part.Type != domain.ContentTypeVideo {
+					part.Type != domain.ContentTypeVideo &&
+					part.Type != domain.ContentTypeAudio &&
+					part.Type != domain.ContentTypeFile {
 					return domain.NewUnsupportedContentTypeError("Gemini", part.Type)
 				}
 			}
@@ -264,9 +361,10 @@ func (p *GeminiProvider) Generate(ctx context.Context, prompt string, options ..
 
 // GenerateMessage produces text from a list of messages
 func (p *GeminiProvider) GenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
-	// Validate content types
-	if err := p.validateContentTypesForGemini(messages); err != nil {
-		return domain.Response{}, err
+	// Normalize media, upload large media and validate content types
+	messages, prepareErr := p.prepareMessages(ctx, messages)
+	if prepareErr != nil {
+		return domain.Response{}, prepareErr
 	}
 
 	// Apply options
@@ -289,7 +387,7 @@ func (p *GeminiProvider) GenerateMessage(ctx context.Context, messages []domain.
 	}
 
 	// Create HTTP request with API key in URL
-	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.baseURL, p.model, p.apiKey)
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", requestBaseURL(p.baseURL, providerOptions), p.model, p.apiKey)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, requestBuffer)
 	if err != nil {
 		return domain.Response{}, fmt.Errorf("failed to create request: %w", err)
@@ -297,6 +395,7 @@ func (p *GeminiProvider) GenerateMessage(ctx context.Context, messages []domain.
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
+	setOverrideHeaders(req, providerOptions)
 
 	// Make the request
 	resp, err := p.httpClient.Do(req)
@@ -334,20 +433,11 @@ func (p *GeminiProvider) GenerateMessage(ctx context.Context, messages []domain.
 
 	// Parse response
 	var geminiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-			FinishReason string `json:"finishReason"`
-		} `json:"candidates"`
+		Candidates     []geminiCandidate `json:"candidates"`
+		ModelVersion   string            `json:"modelVersion"`
 		PromptFeedback struct {
-			BlockReason   string `json:"blockReason"`
-			SafetyRatings []struct {
-				Category    string `json:"category"`
-				Probability string `json:"probability"`
-			} `json:"safetyRatings"`
+			BlockReason   string               `json:"blockReason"`
+			SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
 		} `json:"promptFeedback"`
 	}
 
@@ -358,12 +448,13 @@ func (p *GeminiProvider) GenerateMessage(ctx context.Context, messages []domain.
 
 	// Check for blocked content
 	if geminiResp.PromptFeedback.BlockReason != "" {
-		return domain.Response{}, domain.NewProviderError(
+		return domain.Response{}, domain.NewContentFilteredError(
 			"gemini",
 			"GenerateMessage",
-			resp.StatusCode,
-			fmt.Sprintf("content blocked: %s", geminiResp.PromptFeedback.BlockReason),
-			domain.ErrContentFiltered,
+			domain.ContentFilterPrompt,
+			geminiResp.PromptFeedback.BlockReason,
+			"",
+			toSafetyRatings(geminiResp.PromptFeedback.SafetyRatings),
 		)
 	}
 
@@ -372,15 +463,111 @@ func (p *GeminiProvider) GenerateMessage(ctx context.Context, messages []domain.
 		return domain.Response{}, fmt.Errorf("no candidates in response")
 	}
 
-	// Extract text from response - combine all parts
-	var responseBuilder strings.Builder
-	for _, part := range geminiResp.Candidates[0].Content.Parts {
-		responseBuilder.WriteString(part.Text)
+	// Check if the response itself was blocked
+	if first := &geminiResp.Candidates[0]; geminiBlockedFinishReasons[first.FinishReason] && first.text() == "" {
+		return domain.Response{}, domain.NewContentFilteredError(
+			"gemini",
+			"GenerateMessage",
+			domain.ContentFilterResponse,
+			first.FinishReason,
+			"",
+			toSafetyRatings(first.SafetyRatings),
+		)
 	}
-	responseContent := responseBuilder.String()
 
 	// Use the response pool to reduce allocations
-	return domain.GetResponsePool().NewResponse(responseContent), nil
+	response := domain.GetResponsePool().NewResponse(geminiResp.Candidates[0].text())
+
+	// Gemini has no system fingerprint; the model version is the closest equivalent
+	response.SystemFingerprint = geminiResp.ModelVersion
+
+	// Search grounding and recitation sources of the first candidate. Citations are only
+	// returned when asked for, with WithCitations or by grounding with Google Search.
+	grounding, citations := geminiResp.Candidates[0].grounding()
+	response.Grounding = grounding
+	if providerOptions.Citations || providerOptions.GoogleSearch {
+		response.Citations = citations
+	}
+
+	// Only expose choices when they carry extra information, to keep the common path cheap
+	if p.effectiveCandidateCount(providerOptions) > 1 || providerOptions.Logprobs {
+		response.Choices = make([]domain.Choice, len(geminiResp.Candidates))
+		for i, candidate := range geminiResp.Candidates {
+			response.Choices[i] = candidate.toChoice(i)
+		}
+	}
+
+	return response, nil
+}
+
+// geminiCandidate is one candidate in a generateContent response
+type geminiCandidate struct {
+	Index   *int `json:"index"`
+	Content struct {
+		Parts []struct {
+			Text string `json:"text"`
+		} `json:"parts"`
+	} `json:"content"`
+	FinishReason      string                   `json:"finishReason"`
+	SafetyRatings     []geminiSafetyRating     `json:"safetyRatings"`
+	GroundingMetadata *geminiGroundingMetadata `json:"groundingMetadata"`
+	CitationMetadata  *geminiCitationMetadata  `json:"citationMetadata"`
+	LogprobsResult    *struct {
+		TopCandidates []struct {
+			Candidates []geminiLogprob `json:"candidates"`
+		} `json:"topCandidates"`
+		ChosenCandidates []geminiLogprob `json:"chosenCandidates"`
+	} `json:"logprobsResult"`
+}
+
+// geminiLogprob is a token and its log probability
+type geminiLogprob struct {
+	Token          string  `json:"token"`
+	LogProbability float64 `json:"logProbability"`
+}
+
+// text combines the text of all parts of the candidate
+func (c *geminiCandidate) text() string {
+	var builder strings.Builder
+	for _, part := range c.Content.Parts {
+		builder.WriteString(part.Text)
+	}
+	return builder.String()
+}
+
+// toChoice converts the candidate, including log probabilities, to a domain choice
+func (c *geminiCandidate) toChoice(position int) domain.Choice {
+	choice := domain.Choice{
+		Index:        position,
+		Content:      c.text(),
+		FinishReason: c.FinishReason,
+	}
+	if c.Index != nil {
+		choice.Index = *c.Index
+	}
+
+	if c.LogprobsResult != nil {
+		choice.Logprobs = make([]domain.TokenLogprob, len(c.LogprobsResult.ChosenCandidates))
+		for i, chosen := range c.LogprobsResult.ChosenCandidates {
+			tokenLogprob := domain.TokenLogprob{Token: chosen.Token, Logprob: chosen.LogProbability}
+			if i < len(c.LogprobsResult.TopCandidates) {
+				for _, top := range c.LogprobsResult.TopCandidates[i].Candidates {
+					tokenLogprob.TopLogprobs = append(tokenLogprob.TopLogprobs, domain.TopLogprob{Token: top.Token, Logprob: top.LogProbability})
+				}
+			}
+			choice.Logprobs[i] = tokenLogprob
+		}
+	}
+
+	return choice
+}
+
+// effectiveCandidateCount returns the requested candidate count, falling back to the provider default
+func (p *GeminiProvider) effectiveCandidateCount(options *domain.ProviderOptions) int {
+	if options.CandidateCount > 0 {
+		return options.CandidateCount
+	}
+	return p.candidateCount
 }
 
 // GenerateWithSchema produces structured output conforming to a schema
@@ -420,9 +607,10 @@ func (p *GeminiProvider) Stream(ctx context.Context, prompt string, options ...d
 
 // StreamMessage streams responses from a list of messages
 func (p *GeminiProvider) StreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
-	// Validate content types
-	if err := p.validateContentTypesForGemini(messages); err != nil {
-		return nil, err
+	// Normalize media, upload large media and validate content types
+	messages, prepareErr := p.prepareMessages(ctx, messages)
+	if prepareErr != nil {
+		return nil, prepareErr
 	}
 
 	// Apply options
@@ -434,8 +622,11 @@ func (p *GeminiProvider) StreamMessage(ctx context.Context, messages []domain.Me
 	// Convert messages to Gemini format - with caching
 	geminiContents := p.ConvertMessagesToGeminiFormat(messages)
 
-	// Build request body
+	// Build request body; streams carry a single candidate
 	requestBody := p.buildGeminiRequestBody(geminiContents, providerOptions)
+	if generationConfig, ok := requestBody["generationConfig"].(map[string]interface{}); ok {
+		delete(generationConfig, "candidateCount")
+	}
 
 	// Use optimized JSON marshaling with buffer reuse for request body
 	requestBuffer := &bytes.Buffer{}
@@ -448,7 +639,7 @@ func (p *GeminiProvider) StreamMessage(ctx context.Context, messages []domain.Me
 	// IMPORTANT: The alt=sse parameter is REQUIRED for the Gemini API to return responses in Server-Sent Events format.
 	// Without this parameter, the API returns standard JSON responses that don't conform to SSE protocol,
 	// causing the streaming implementation to fail. This requirement is verified in TestGeminiAltSSEParameter.
-	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s", p.baseURL, p.model, p.apiKey)
+	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s", requestBaseURL(p.baseURL, providerOptions), p.model, p.apiKey)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, requestBuffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -458,6 +649,7 @@ func (p *GeminiProvider) StreamMessage(ctx context.Context, messages []domain.Me
 	req.Header.Set("Content-Type", "application/json")
 	// Add Accept header for SSE (Server-Sent Events)
 	req.Header.Set("Accept", "text/event-stream")
+	setOverrideHeaders(req, providerOptions)
 
 	// Make the request
 	resp, err := p.httpClient.Do(req)
@@ -489,6 +681,21 @@ func (p *GeminiProvider) StreamMessage(ctx context.Context, messages []domain.Me
 		return nil, ParseJSONError(body, resp.StatusCode, "gemini", "StreamMessage")
 	}
 
+	// A blocked prompt is reported by the first chunk, so it is returned as an error
+	scanner := bufio.NewScanner(resp.Body)
+	first, done := readGeminiStreamChunk(scanner)
+	if !done && first.PromptFeedback.BlockReason != "" {
+		resp.Body.Close()
+		return nil, domain.NewContentFilteredError(
+			"gemini",
+			"StreamMessage",
+			domain.ContentFilterPrompt,
+			first.PromptFeedback.BlockReason,
+			"",
+			toSafetyRatings(first.PromptFeedback.SafetyRatings),
+		)
+	}
+
 	// Get a channel from the pool
 	responseStream, tokenCh := domain.GetChannelPool().GetResponseStream()
 
@@ -497,76 +704,39 @@ func (p *GeminiProvider) StreamMessage(ctx context.Context, messages []domain.Me
 		defer resp.Body.Close()
 		defer close(tokenCh)
 
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			// Check if context is canceled
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				// Continue
-			}
-
-			line := scanner.Text()
-			if !strings.HasPrefix(line, "data: ") {
-				continue
-			}
-
-			// Extract the data part
-			data := strings.TrimPrefix(line, "data: ")
-
-			// Skip empty data lines
-			if data == "" {
-				continue
-			}
-
-			// Handle special case for end of stream marker if present
-			if data == "[DONE]" {
+		for chunk := first; ; chunk, done = readGeminiStreamChunk(scanner) {
+			// The stream ends with [DONE] or without a finish reason, so send a final token
+			if done {
 				select {
 				case <-ctx.Done():
-					return
 				case tokenCh <- domain.GetTokenPool().NewToken("", true):
-					// Sent finish token
 				}
 				return
 			}
 
-			// Parse the data as JSON
-			var streamResponse struct {
-				Candidates []struct {
-					Content struct {
-						Parts []struct {
-							Text string `json:"text"`
-						} `json:"parts"`
-					} `json:"content"`
-					FinishReason string `json:"finishReason"`
-				} `json:"candidates"`
-			}
-
-			// Use optimized JSON unmarshaling
-			if err := json.UnmarshalFromString(data, &streamResponse); err != nil {
-				continue
+			// Check if context is canceled
+			if ctx.Err() != nil {
+				return
 			}
 
 			// Check if we have candidates
-			if len(streamResponse.Candidates) == 0 {
+			if len(chunk.Candidates) == 0 {
 				continue
 			}
+			candidate := &chunk.Candidates[0]
 
-			// Extract text from response - combine all parts
-			var text string
-			for _, part := range streamResponse.Candidates[0].Content.Parts {
-				text += part.Text
+			// Like a failed stream, a blocked response closes without a finished token
+			if geminiBlockedFinishReasons[candidate.FinishReason] {
+				return
 			}
 
-			// Skip empty text
-			if text == "" {
+			// Check if this is the final message with a finish reason
+			text := candidate.text()
+			isFinished := candidate.FinishReason != ""
+			if text == "" && !isFinished {
 				continue
 			}
 
-			// Check if this is the final message with a finish reason
-			isFinished := streamResponse.Candidates[0].FinishReason != ""
-
 			// Send the token - use token pool to reduce allocations
 			select {
 			case <-ctx.Done():
@@ -580,19 +750,40 @@ func (p *GeminiProvider) StreamMessage(ctx context.Context, messages []domain.Me
 				return
 			}
 		}
-
-		// If the scanner ends without a finish reason, send a final token
-		select {
-		case <-ctx.Done():
-			return
-		case tokenCh <- domain.GetTokenPool().NewToken("", true):
-			// Sent successfully
-		}
 	}()
 
 	return responseStream, nil
 }
 
+// geminiStreamChunk is an event of a Gemini stream
+type geminiStreamChunk struct {
+	Candidates     []geminiCandidate `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason   string               `json:"blockReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"promptFeedback"`
+}
+
+// readGeminiStreamChunk reads the next chunk of a stream, skipping invalid ones. done is
+// true at the [DONE] marker or the end of the stream.
+func readGeminiStreamChunk(scanner *bufio.Scanner) (chunk geminiStreamChunk, done bool) {
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			return chunk, true
+		}
+		if err := json.UnmarshalFromString(data, &chunk); err != nil {
+			chunk = geminiStreamChunk{}
+			continue
+		}
+		return chunk, false
+	}
+	return chunk, true
+}
+
 // mapGeminiErrorToStandard maps Gemini API error messages to standard error types
 func mapGeminiErrorToStandard(statusCode int, errorType, errorMsg string, operation string) error {
 	// Convert error message and type to lowercase for case-insensitive matching
@@ -622,7 +813,7 @@ func mapGeminiErrorToStandard(statusCode int, errorType, errorMsg string, operat
 		strings.Contains(lowerErrorMsg, "content filtered") ||
 		strings.Contains(lowerErrorMsg, "content policy") ||
 		strings.Contains(lowerErrorMsg, "safety"):
-		return domain.NewProviderError("gemini", operation, statusCode, errorMsg, domain.ErrContentFiltered)
+		return newContentFilteredHTTPError("gemini", operation, statusCode, errorType, errorMsg)
 
 	case strings.Contains(lowerErrorType, "not_found") ||
 		strings.Contains(lowerErrorMsg, "model not found"):
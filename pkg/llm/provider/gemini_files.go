@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// defaultGeminiFilePollInterval is how often an uploaded file is checked while it is processed
+const defaultGeminiFilePollInterval = 2 * time.Second
+
+// geminiUploadableTypes are the content types Gemini accepts as file references
+var geminiUploadableTypes = map[domain.ContentType]bool{
+	domain.ContentTypeImage: true,
+	domain.ContentTypeVideo: true,
+	domain.ContentTypeAudio: true,
+	domain.ContentTypeFile:  true,
+}
+
+// geminiFile is the file resource returned by the Gemini File API
+type geminiFile struct {
+	Name           string `json:"name"`
+	DisplayName    string `json:"displayName"`
+	MimeType       string `json:"mimeType"`
+	SizeBytes      string `json:"sizeBytes"`
+	CreateTime     string `json:"createTime"`
+	ExpirationTime string `json:"expirationTime"`
+	URI            string `json:"uri"`
+	State          string `json:"state"`
+	Error          *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// toUploadedFile converts the file resource to a domain file
+func (f geminiFile) toUploadedFile() domain.UploadedFile {
+	file := domain.UploadedFile{
+		Provider: "gemini",
+		ID:       f.Name,
+		URI:      f.URI,
+		Name:     f.DisplayName,
+		MimeType: f.MimeType,
+	}
+	file.SizeBytes, _ = strconv.ParseInt(f.SizeBytes, 10, 64)
+	file.CreatedAt, _ = time.Parse(time.RFC3339, f.CreateTime)
+	file.ExpiresAt, _ = time.Parse(time.RFC3339, f.ExpirationTime)
+	return file
+}
+
+// UploadFile uploads a file with the Gemini File API using the resumable upload protocol.
+// Video and other media that need processing are polled until they are ready to use.
+func (p *GeminiProvider) UploadFile(ctx context.Context, name string, data []byte, mimeType string) (domain.UploadedFile, error) {
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	// Start a resumable upload session
+	metadata, err := json.Marshal(map[string]interface{}{
+		"file": map[string]interface{}{"display_name": name},
+	})
+	if err != nil {
+		return domain.UploadedFile{}, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		p.uploadURL()+"?key="+url.QueryEscape(p.apiKey), bytes.NewReader(metadata))
+	if err != nil {
+		return domain.UploadedFile{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Upload-Protocol", "resumable")
+	req.Header.Set("X-Goog-Upload-Command", "start")
+	req.Header.Set("X-Goog-Upload-Header-Content-Length", strconv.Itoa(len(data)))
+	req.Header.Set("X-Goog-Upload-Header-Content-Type", mimeType)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return domain.UploadedFile{}, fmt.Errorf("failed to make request: %w", err)
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return domain.UploadedFile{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return domain.UploadedFile{}, ParseJSONError(respBody, resp.StatusCode, "gemini", "UploadFile")
+	}
+	sessionURL := resp.Header.Get("X-Goog-Upload-URL")
+	if sessionURL == "" {
+		return domain.UploadedFile{}, domain.NewProviderError("gemini", "UploadFile", resp.StatusCode,
+			"upload session URL missing from response", domain.ErrResponseParsing)
+	}
+
+	// Send the bytes and finalize the upload in one request
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, sessionURL, bytes.NewReader(data))
+	if err != nil {
+		return domain.UploadedFile{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Goog-Upload-Offset", "0")
+	req.Header.Set("X-Goog-Upload-Command", "upload, finalize")
+
+	var uploaded struct {
+		File geminiFile `json:"file"`
+	}
+	if err := p.doFileRequest(req, "UploadFile", &uploaded); err != nil {
+		return domain.UploadedFile{}, err
+	}
+
+	file, err := p.waitForFile(ctx, uploaded.File)
+	if err != nil {
+		return domain.UploadedFile{}, err
+	}
+	return file.toUploadedFile(), nil
+}
+
+// ListFiles returns all files stored with the Gemini File API
+func (p *GeminiProvider) ListFiles(ctx context.Context) ([]domain.UploadedFile, error) {
+	var files []domain.UploadedFile
+	pageToken := ""
+	for {
+		query := url.Values{"pageSize": {"100"}, "key": {p.apiKey}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		var page struct {
+			Files         []geminiFile `json:"files"`
+			NextPageToken string       `json:"nextPageToken"`
+		}
+		if err := p.fileRequest(ctx, http.MethodGet, p.baseURL+"/files?"+query.Encode(), "ListFiles", &page); err != nil {
+			return nil, err
+		}
+
+		for _, file := range page.Files {
+			files = append(files, file.toUploadedFile())
+		}
+		if page.NextPageToken == "" {
+			return files, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// DeleteFile deletes a file from the Gemini File API. The ID is the file's
+// resource name ("files/abc123"); the "files/" prefix may be omitted.
+func (p *GeminiProvider) DeleteFile(ctx context.Context, id string) error {
+	name := geminiFileName(id)
+	if err := p.fileRequest(ctx, http.MethodDelete, p.fileURL(name), "DeleteFile", nil); err != nil {
+		return err
+	}
+	p.uploadCache.forget(name)
+	return nil
+}
+
+// waitForFile polls a file until it leaves the PROCESSING state
+func (p *GeminiProvider) waitForFile(ctx context.Context, file geminiFile) (geminiFile, error) {
+	for file.State == "PROCESSING" {
+		select {
+		case <-ctx.Done():
+			return geminiFile{}, ctx.Err()
+		case <-time.After(p.filePollInterval):
+		}
+
+		if err := p.fileRequest(ctx, http.MethodGet, p.fileURL(file.Name), "UploadFile", &file); err != nil {
+			return geminiFile{}, err
+		}
+	}
+
+	if file.State == "FAILED" {
+		message := "file processing failed"
+		if file.Error != nil && file.Error.Message != "" {
+			message = file.Error.Message
+		}
+		return geminiFile{}, domain.NewProviderError("gemini", "UploadFile", 0, message, domain.ErrRequestFailed)
+	}
+	return file, nil
+}
+
+// fileRequest sends a File API request and decodes the response into out (if not nil)
+func (p *GeminiProvider) fileRequest(ctx context.Context, method, endpoint, operation string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return p.doFileRequest(req, operation, out)
+}
+
+// doFileRequest sends a prepared File API request and decodes the response into out (if not nil)
+func (p *GeminiProvider) doFileRequest(req *http.Request, operation string, out interface{}) error {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ParseJSONError(respBody, resp.StatusCode, "gemini", operation)
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// uploadURL returns the File API upload endpoint, which lives under /upload on the API host
+// (https://generativelanguage.googleapis.com/upload/v1beta/files for the default base URL)
+func (p *GeminiProvider) uploadURL() string {
+	base := p.baseURL
+	hostEnd := len(base)
+	if scheme := strings.Index(base, "://"); scheme >= 0 {
+		if slash := strings.Index(base[scheme+3:], "/"); slash >= 0 {
+			hostEnd = scheme + 3 + slash
+		}
+	}
+	return base[:hostEnd] + "/upload" + base[hostEnd:] + "/files"
+}
+
+// fileURL returns the URL of a file resource, including the API key
+func (p *GeminiProvider) fileURL(name string) string {
+	return fmt.Sprintf("%s/%s?key=%s", p.baseURL, name, url.QueryEscape(p.apiKey))
+}
+
+// geminiFileName returns the resource name of a file given its ID, name or URI
+func geminiFileName(id string) string {
+	if i := strings.Index(id, "files/"); i >= 0 {
+		return id[i:]
+	}
+	return "files/" + id
+}
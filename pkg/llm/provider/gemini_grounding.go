@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// geminiGroundingMetadata describes the Google Search results a candidate is grounded in
+type geminiGroundingMetadata struct {
+	WebSearchQueries []string `json:"webSearchQueries"`
+	SearchEntryPoint *struct {
+		RenderedContent string `json:"renderedContent"`
+	} `json:"searchEntryPoint"`
+	GroundingChunks []struct {
+		Web *struct {
+			URI   string `json:"uri"`
+			Title string `json:"title"`
+		} `json:"web"`
+	} `json:"groundingChunks"`
+	GroundingSupports []struct {
+		Segment struct {
+			StartIndex int `json:"startIndex"`
+			EndIndex   int `json:"endIndex"`
+		} `json:"segment"`
+		GroundingChunkIndices []int `json:"groundingChunkIndices"`
+	} `json:"groundingSupports"`
+}
+
+// geminiCitationMetadata lists the sources a candidate recites
+type geminiCitationMetadata struct {
+	CitationSources []struct {
+		StartIndex int    `json:"startIndex"`
+		EndIndex   int    `json:"endIndex"`
+		URI        string `json:"uri"`
+	} `json:"citationSources"`
+}
+
+// grounding converts the candidate's grounding and citation metadata to domain types.
+// Segment offsets are clamped to the candidate text, as Gemini reports them in bytes. The
+// cited text of a web citation is the part of the response it supports.
+func (c *geminiCandidate) grounding() (*domain.Grounding, []domain.Citation) {
+	text := c.text()
+	clamp := func(index int) int {
+		if index < 0 {
+			return 0
+		}
+		if index > len(text) {
+			return len(text)
+		}
+		return index
+	}
+	citedText := func(start, end int) string {
+		if start >= end {
+			return ""
+		}
+		return text[start:end]
+	}
+
+	var grounding *domain.Grounding
+	var citations []domain.Citation
+
+	if metadata := c.GroundingMetadata; metadata != nil {
+		grounding = &domain.Grounding{Queries: metadata.WebSearchQueries}
+		if metadata.SearchEntryPoint != nil {
+			grounding.SearchEntryPoint = metadata.SearchEntryPoint.RenderedContent
+		}
+
+		// Chunks are indexed by position, so sources without a web page are kept empty
+		grounding.Sources = make([]domain.GroundingSource, len(metadata.GroundingChunks))
+		for i, chunk := range metadata.GroundingChunks {
+			if chunk.Web != nil {
+				grounding.Sources[i] = domain.GroundingSource{URL: chunk.Web.URI, Title: chunk.Web.Title}
+			}
+		}
+
+		for _, support := range metadata.GroundingSupports {
+			for _, index := range support.GroundingChunkIndices {
+				if index < 0 || index >= len(grounding.Sources) {
+					continue
+				}
+				start, end := clamp(support.Segment.StartIndex), clamp(support.Segment.EndIndex)
+				citations = append(citations, domain.Citation{
+					Type:          domain.CitationWebLocation,
+					CitedText:     citedText(start, end),
+					DocumentIndex: index,
+					DocumentTitle: grounding.Sources[index].Title,
+					URL:           grounding.Sources[index].URL,
+					ResponseStart: start,
+					ResponseEnd:   end,
+				})
+			}
+		}
+	}
+
+	if c.CitationMetadata != nil {
+		for _, source := range c.CitationMetadata.CitationSources {
+			start, end := clamp(source.StartIndex), clamp(source.EndIndex)
+			citations = append(citations, domain.Citation{
+				Type:          domain.CitationWebLocation,
+				CitedText:     citedText(start, end),
+				DocumentIndex: -1,
+				URL:           source.URI,
+				ResponseStart: start,
+				ResponseEnd:   end,
+			})
+		}
+	}
+
+	return grounding, citations
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+func TestGeminiGrounding(t *testing.T) {
+	reply := `{
+		"candidates": [{
+			"content": {"parts": [{"text": "Spain won Euro 2024. "}, {"text": "The final was in Berlin."}]},
+			"finishReason": "STOP",
+			"groundingMetadata": {
+				"webSearchQueries": ["euro 2024 winner"],
+				"searchEntryPoint": {"renderedContent": "<div>chips</div>"},
+				"groundingChunks": [
+					{"web": {"uri": "https://example.com/uefa", "title": "uefa.com"}},
+					{"web": {"uri": "https://example.com/news", "title": "news.com"}}
+				],
+				"groundingSupports": [
+					{"segment": {"startIndex": 0, "endIndex": 20, "text": "Spain won Euro 2024."}, "groundingChunkIndices": [0, 1]},
+					{"segment": {"startIndex": 21, "endIndex": 500}, "groundingChunkIndices": [1, 7]}
+				]
+			},
+			"citationMetadata": {
+				"citationSources": [{"startIndex": 21, "endIndex": 46, "uri": "https://example.com/recited"}]
+			}
+		}]
+	}`
+	server, requests := newCaptureServer(t, reply)
+
+	provider := NewGeminiProvider("test-key", "gemini-2.0-flash", domain.NewBaseURLOption(server.URL))
+	response, err := provider.GenerateMessage(context.Background(),
+		[]domain.Message{domain.NewTextMessage(domain.RoleUser, "Who won Euro 2024?")},
+		domain.WithGoogleSearch(true))
+	if err != nil {
+		t.Fatalf("GenerateMessage failed: %v", err)
+	}
+
+	t.Run("SearchTool", func(t *testing.T) {
+		tools, ok := requests()[0].body["tools"].([]interface{})
+		if !ok || len(tools) != 1 {
+			t.Fatalf("Expected one tool, got %v", requests()[0].body["tools"])
+		}
+		if _, ok := tools[0].(map[string]interface{})["google_search"]; !ok {
+			t.Errorf("Expected the google_search tool, got %v", tools[0])
+		}
+	})
+
+	t.Run("Grounding", func(t *testing.T) {
+		grounding := response.Grounding
+		if grounding == nil {
+			t.Fatal("Expected grounding metadata")
+		}
+		if len(grounding.Queries) != 1 || grounding.Queries[0] != "euro 2024 winner" {
+			t.Errorf("Unexpected queries: %v", grounding.Queries)
+		}
+		if grounding.SearchEntryPoint != "<div>chips</div>" {
+			t.Errorf("Unexpected search entry point: %q", grounding.SearchEntryPoint)
+		}
+		if len(grounding.Sources) != 2 || grounding.Sources[1].URL != "https://example.com/news" || grounding.Sources[1].Title != "news.com" {
+			t.Errorf("Unexpected sources: %+v", grounding.Sources)
+		}
+	})
+
+	t.Run("Citations", func(t *testing.T) {
+		// Two sources for the first segment, one valid source for the second and one recitation
+		if len(response.Citations) != 4 {
+			t.Fatalf("Expected 4 citations, got %+v", response.Citations)
+		}
+
+		first := response.Citations[0]
+		if first.Type != domain.CitationWebLocation || first.URL != "https://example.com/uefa" || first.DocumentIndex != 0 {
+			t.Errorf("Unexpected citation: %+v", first)
+		}
+		if response.Content[first.ResponseStart:first.ResponseEnd] != "Spain won Euro 2024." {
+			t.Errorf("Unexpected supported text %q", response.Content[first.ResponseStart:first.ResponseEnd])
+		}
+		if first.CitedText != "Spain won Euro 2024." {
+			t.Errorf("Expected the supported text as cited text, got %q", first.CitedText)
+		}
+
+		if clamped := response.Citations[2]; clamped.ResponseEnd != len(response.Content) {
+			t.Errorf("Expected the segment end to be clamped, got %d", clamped.ResponseEnd)
+		}
+
+		recited := response.Citations[3]
+		if recited.DocumentIndex != -1 || recited.URL != "https://example.com/recited" || recited.ResponseStart != 21 {
+			t.Errorf("Unexpected recitation citation: %+v", recited)
+		}
+	})
+
+	t.Run("NoGrounding", func(t *testing.T) {
+		if _, err := provider.GenerateMessage(context.Background(),
+			[]domain.Message{domain.NewTextMessage(domain.RoleUser, "Hi")}); err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+		if _, ok := requests()[1].body["tools"]; ok {
+			t.Errorf("Search should only be enabled when requested")
+		}
+	})
+
+	t.Run("CitationsOnlyWhenRequested", func(t *testing.T) {
+		messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "Who won Euro 2024?")}
+		plain, err := provider.GenerateMessage(context.Background(), messages)
+		if err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+		if plain.Citations != nil {
+			t.Errorf("Expected no citations without WithCitations or WithGoogleSearch, got %+v", plain.Citations)
+		}
+
+		cited, err := provider.GenerateMessage(context.Background(), messages, domain.WithCitations(true))
+		if err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+		if len(cited.Citations) != 4 {
+			t.Errorf("Expected 4 citations with WithCitations, got %+v", cited.Citations)
+		}
+	})
+}
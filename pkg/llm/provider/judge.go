@@ -0,0 +1,288 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+)
+
+// DefaultJudgeRubric is the rubric used when no rubric is configured for the judge
+const DefaultJudgeRubric = "Score each response from 0 to 10 for correctness, completeness and clarity in answering the request."
+
+// JudgeConfig configures the ConsensusJudge strategy
+type JudgeConfig struct {
+	// Judge is the provider that compares the candidate responses
+	Judge domain.Provider
+	// Rubric describes how candidates should be scored (DefaultJudgeRubric if empty)
+	Rubric string
+	// Synthesize asks the judge to write a combined answer instead of picking a candidate
+	Synthesize bool
+	// Options are passed to the judge provider on every call
+	Options []domain.Option
+	// Timeout bounds the judge call, which starts once the providers have answered
+	// (the MultiProvider's timeout if zero)
+	Timeout time.Duration
+}
+
+// JudgeVerdict is the outcome of a judge-based consensus
+type JudgeVerdict struct {
+	// Content is the selected or synthesized response
+	Content string
+	// SelectedProvider is the provider whose response was picked (empty when synthesized)
+	SelectedProvider string
+	// Synthesized reports whether Content was written by the judge
+	Synthesized bool
+	// Rationale is the judge's explanation of its decision
+	Rationale string
+	// Scores holds the judge's score for each candidate, keyed by provider name
+	Scores map[string]float64
+	// JudgeError is set when the judge failed and a lexical consensus was used instead
+	JudgeError error
+}
+
+// WithJudge configures the judge used by the ConsensusJudge strategy
+func (mp *MultiProvider) WithJudge(config JudgeConfig) *MultiProvider {
+	if config.Rubric == "" {
+		config.Rubric = DefaultJudgeRubric
+	}
+	mp.consensusConfig.Judge = &config
+	return mp
+}
+
+// GenerateWithVerdict runs the prompt on all providers and lets the configured judge pick
+// or synthesize the best response, returning the judge's rationale and scores
+func (mp *MultiProvider) GenerateWithVerdict(ctx context.Context, prompt string, options ...domain.Option) (*JudgeVerdict, error) {
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)}
+	return mp.GenerateMessageWithVerdict(ctx, messages, options...)
+}
+
+// GenerateMessageWithVerdict runs the messages on all providers and lets the configured judge
+// pick or synthesize the best response, returning the judge's rationale and scores
+func (mp *MultiProvider) GenerateMessageWithVerdict(ctx context.Context, messages []domain.Message, options ...domain.Option) (*JudgeVerdict, error) {
+	if len(mp.providers) == 0 {
+		return nil, ErrNoProviders
+	}
+	if mp.consensusConfig.Judge == nil || mp.consensusConfig.Judge.Judge == nil {
+		return nil, domain.NewProviderError("multi", "GenerateMessageWithVerdict", 0, "no judge configured", domain.ErrInvalidConfiguration)
+	}
+
+	// Apply the configured timeout if not overridden in the context. The judge runs
+	// afterwards on the caller's context, with a timeout of its own.
+	providerCtx, cancel := applyTimeoutFromContext(ctx, mp.defaultTimeout)
+	defer cancel()
+
+	results := mp.concurrentGenerateMessage(providerCtx, messages, options)
+	for i := range results {
+		results[i].content = results[i].response.Content
+	}
+
+	return mp.selectJudgeResult(ctx, messages, results)
+}
+
+// usesJudge reports whether results should be selected by the judge
+func (mp *MultiProvider) usesJudge() bool {
+	return mp.selectionStrat == StrategyConsensus &&
+		mp.consensusConfig.Strategy == ConsensusJudge &&
+		mp.consensusConfig.Judge != nil &&
+		mp.consensusConfig.Judge.Judge != nil
+}
+
+// selectJudgeResult asks the judge to pick the best of the successful results, on the
+// caller's context rather than the one the providers used up. If the judge fails,
+// similarity consensus is used and the failure is recorded on the verdict.
+func (mp *MultiProvider) selectJudgeResult(ctx context.Context, messages []domain.Message, results []fallbackResult) (*JudgeVerdict, error) {
+	providerErrors := make(map[string]error)
+	candidates := make([]fallbackResult, 0, len(results))
+	for _, result := range results {
+		if result.err != nil {
+			providerErrors[result.provider] = result.err
+			continue
+		}
+		if result.content != "" {
+			candidates = append(candidates, result)
+		}
+	}
+
+	if len(candidates) == 0 {
+		// Reuse the standard selection to report context and provider errors consistently
+		_, err := mp.selectTextResult(results)
+		if err == nil {
+			err = NewMultiProviderError(providerErrors, ErrNoSuccessfulCalls.Error())
+		}
+		return nil, err
+	}
+
+	// Keep candidate numbering stable regardless of which provider finished first
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].provider < candidates[j].provider
+	})
+
+	// Fast path: nothing to compare
+	if len(candidates) == 1 {
+		return &JudgeVerdict{
+			Content:          candidates[0].content,
+			SelectedProvider: candidates[0].provider,
+			Rationale:        "only one provider returned a response",
+			Scores:           map[string]float64{},
+		}, nil
+	}
+
+	verdict, err := mp.judgeCandidates(ctx, messages, candidates)
+	if err != nil {
+		content, consensusErr := selectSimilarityConsensus(candidates, mp.consensusConfig.SimilarityThreshold)
+		if consensusErr != nil {
+			return nil, consensusErr
+		}
+		verdict = &JudgeVerdict{
+			Content:    content,
+			Rationale:  "judge unavailable, selected by similarity consensus",
+			Scores:     map[string]float64{},
+			JudgeError: err,
+		}
+		for _, c := range candidates {
+			if c.content == content {
+				verdict.SelectedProvider = c.provider
+				break
+			}
+		}
+	}
+
+	return verdict, nil
+}
+
+// judgeResponse is the structured output requested from the judge
+type judgeResponse struct {
+	Scores      []judgeScore `json:"scores"`
+	Best        int          `json:"best"`
+	Rationale   string       `json:"rationale"`
+	Synthesized string       `json:"synthesized"`
+}
+
+// judgeScore is the judge's score for one numbered candidate
+type judgeScore struct {
+	Candidate int     `json:"candidate"`
+	Score     float64 `json:"score"`
+}
+
+// judgeSchema returns the schema the judge must answer with
+func judgeSchema(synthesize bool) *schemaDomain.Schema {
+	schema := &schemaDomain.Schema{
+		Type: "object",
+		Properties: map[string]schemaDomain.Property{
+			"scores": {
+				Type:        "array",
+				Description: "One score per candidate response",
+				Items: &schemaDomain.Property{
+					Type: "object",
+					Properties: map[string]schemaDomain.Property{
+						"candidate": {Type: "integer", Description: "Candidate number"},
+						"score":     {Type: "number", Description: "Score according to the rubric"},
+					},
+					Required: []string{"candidate", "score"},
+				},
+			},
+			"best":      {Type: "integer", Description: "Number of the best candidate"},
+			"rationale": {Type: "string", Description: "Brief explanation of the decision"},
+		},
+		Required: []string{"scores", "best", "rationale"},
+	}
+	if synthesize {
+		schema.Properties["synthesized"] = schemaDomain.Property{
+			Type:        "string",
+			Description: "A single best answer combining the strengths of the candidates",
+		}
+		schema.Required = append(schema.Required, "synthesized")
+	}
+	return schema
+}
+
+// judgeCandidates asks the judge provider to score the candidates
+func (mp *MultiProvider) judgeCandidates(ctx context.Context, messages []domain.Message, candidates []fallbackResult) (*JudgeVerdict, error) {
+	config := mp.consensusConfig.Judge
+
+	var cancel context.CancelFunc
+	if config.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+	} else {
+		ctx, cancel = applyTimeoutFromContext(ctx, mp.defaultTimeout)
+	}
+	defer cancel()
+
+	raw, err := config.Judge.GenerateWithSchema(ctx, buildJudgePrompt(config, messages, candidates), judgeSchema(config.Synthesize), config.Options...)
+	if err != nil {
+		return nil, fmt.Errorf("judge request failed: %w", err)
+	}
+
+	// Round-trip through JSON to decode whatever generic structure the provider returned
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode judge response: %w", err)
+	}
+	var response judgeResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse judge response: %w", err)
+	}
+
+	if response.Best < 1 || response.Best > len(candidates) {
+		return nil, fmt.Errorf("judge selected unknown candidate %d", response.Best)
+	}
+
+	verdict := &JudgeVerdict{
+		Rationale: response.Rationale,
+		Scores:    make(map[string]float64, len(candidates)),
+	}
+	for _, score := range response.Scores {
+		if score.Candidate >= 1 && score.Candidate <= len(candidates) {
+			verdict.Scores[candidates[score.Candidate-1].provider] = score.Score
+		}
+	}
+
+	if config.Synthesize && strings.TrimSpace(response.Synthesized) != "" {
+		verdict.Content = response.Synthesized
+		verdict.Synthesized = true
+		return verdict, nil
+	}
+
+	best := candidates[response.Best-1]
+	verdict.Content = best.content
+	verdict.SelectedProvider = best.provider
+	return verdict, nil
+}
+
+// buildJudgePrompt renders the request, rubric and anonymized candidates for the judge
+func buildJudgePrompt(config *JudgeConfig, messages []domain.Message, candidates []fallbackResult) string {
+	var sb strings.Builder
+
+	sb.WriteString("You are an impartial judge comparing candidate responses to the same request.\n\n")
+	sb.WriteString("## Request\n\n")
+	for _, msg := range messages {
+		for _, part := range msg.Content {
+			if part.Type == domain.ContentTypeText && part.Text != "" {
+				sb.WriteString(fmt.Sprintf("[%s] %s\n", msg.Role, part.Text))
+			}
+		}
+	}
+
+	sb.WriteString("\n## Rubric\n\n")
+	sb.WriteString(config.Rubric)
+	sb.WriteString("\n\n## Candidates\n\n")
+	for i, candidate := range candidates {
+		sb.WriteString(fmt.Sprintf("### Candidate %d\n\n%s\n\n", i+1, candidate.content))
+	}
+
+	sb.WriteString("## Instructions\n\n")
+	sb.WriteString("Score every candidate according to the rubric, choose the best candidate by number, ")
+	sb.WriteString("and explain your decision briefly.")
+	if config.Synthesize {
+		sb.WriteString(" Then write a single answer that combines the strengths of the candidates and corrects their mistakes.")
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
@@ -0,0 +1,216 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+)
+
+// textMock returns a provider whose Generate and GenerateMessage return the given text
+func textMock(text string) *MockProvider {
+	return NewMockProvider().
+		WithGenerateFunc(func(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+			return text, nil
+		}).
+		WithGenerateMessageFunc(func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+			return domain.Response{Content: text}, nil
+		})
+}
+
+func TestJudgeConsensus(t *testing.T) {
+	providers := []ProviderWeight{
+		{Name: "a", Provider: textMock("Paris is the capital of France.")},
+		{Name: "b", Provider: textMock("The capital of France is Paris, on the Seine.")},
+		{Name: "c", Provider: textMock("Lyon.")},
+	}
+
+	t.Run("PicksJudgedCandidate", func(t *testing.T) {
+		var judgePrompt string
+		judge := NewMockProvider().WithGenerateWithSchemaFunc(
+			func(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+				judgePrompt = prompt
+				return map[string]interface{}{
+					"scores": []interface{}{
+						map[string]interface{}{"candidate": 1, "score": 7},
+						map[string]interface{}{"candidate": 2, "score": 9},
+						map[string]interface{}{"candidate": 3, "score": 1},
+					},
+					"best":      2,
+					"rationale": "Candidate 2 is correct and adds context.",
+				}, nil
+			})
+
+		mp := NewMultiProvider(providers, StrategyConsensus).
+			WithConsensusStrategy(ConsensusJudge).
+			WithJudge(JudgeConfig{Judge: judge, Rubric: "Prefer factual accuracy."})
+
+		verdict, err := mp.GenerateWithVerdict(context.Background(), "What is the capital of France?")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if verdict.SelectedProvider != "b" || verdict.Synthesized {
+			t.Errorf("Expected provider b to be selected, got %+v", verdict)
+		}
+		if verdict.Scores["c"] != 1 || verdict.Scores["b"] != 9 {
+			t.Errorf("Unexpected scores: %v", verdict.Scores)
+		}
+		if verdict.Rationale == "" {
+			t.Errorf("Expected a rationale")
+		}
+
+		for _, want := range []string{"Prefer factual accuracy.", "What is the capital of France?", "Candidate 3", "Lyon."} {
+			if !strings.Contains(judgePrompt, want) {
+				t.Errorf("Expected judge prompt to contain %q", want)
+			}
+		}
+
+		// Generate and GenerateMessage use the judge as well
+		content, err := mp.Generate(context.Background(), "What is the capital of France?")
+		if err != nil || content != "The capital of France is Paris, on the Seine." {
+			t.Errorf("Expected Generate to return the judged response, got %q (%v)", content, err)
+		}
+
+		response, err := mp.GenerateMessage(context.Background(), []domain.Message{
+			domain.NewTextMessage(domain.RoleUser, "What is the capital of France?"),
+		})
+		if err != nil || response.Content != "The capital of France is Paris, on the Seine." {
+			t.Errorf("Expected GenerateMessage to return the judged response, got %q (%v)", response.Content, err)
+		}
+	})
+
+	t.Run("SynthesizesAnswer", func(t *testing.T) {
+		judge := NewMockProvider().WithGenerateWithSchemaFunc(
+			func(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+				if _, ok := schema.Properties["synthesized"]; !ok {
+					t.Errorf("Expected synthesized field in judge schema")
+				}
+				return map[string]interface{}{
+					"scores":      []interface{}{},
+					"best":        1,
+					"rationale":   "Combined the correct answers.",
+					"synthesized": "Paris, on the Seine, is the capital of France.",
+				}, nil
+			})
+
+		mp := NewMultiProvider(providers, StrategyConsensus).
+			WithConsensusStrategy(ConsensusJudge).
+			WithJudge(JudgeConfig{Judge: judge, Synthesize: true})
+
+		verdict, err := mp.GenerateWithVerdict(context.Background(), "What is the capital of France?")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !verdict.Synthesized || verdict.SelectedProvider != "" {
+			t.Errorf("Expected a synthesized verdict, got %+v", verdict)
+		}
+		if verdict.Content != "Paris, on the Seine, is the capital of France." {
+			t.Errorf("Unexpected synthesized content: %q", verdict.Content)
+		}
+	})
+
+	t.Run("FallsBackWhenJudgeFails", func(t *testing.T) {
+		judge := NewMockProvider().WithGenerateWithSchemaFunc(
+			func(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+				return nil, errors.New("judge unavailable")
+			})
+
+		mp := NewMultiProvider(providers, StrategyConsensus).
+			WithConsensusStrategy(ConsensusJudge).
+			WithJudge(JudgeConfig{Judge: judge})
+
+		verdict, err := mp.GenerateWithVerdict(context.Background(), "What is the capital of France?")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if verdict.JudgeError == nil || verdict.Content == "" {
+			t.Errorf("Expected a fallback verdict with the judge error, got %+v", verdict)
+		}
+	})
+
+	t.Run("KeepsSelectedResponse", func(t *testing.T) {
+		withID := func(id, text string) *MockProvider {
+			return NewMockProvider().WithGenerateMessageFunc(func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+				return domain.Response{Content: text, ID: id}, nil
+			})
+		}
+		judge := NewMockProvider().WithGenerateWithSchemaFunc(
+			func(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+				return map[string]interface{}{"scores": []interface{}{}, "best": 2, "rationale": "More detail."}, nil
+			})
+
+		mp := NewMultiProvider([]ProviderWeight{
+			{Name: "a", Provider: withID("resp-a", "Paris.")},
+			{Name: "b", Provider: withID("resp-b", "Paris, on the Seine.")},
+		}, StrategyConsensus).
+			WithConsensusStrategy(ConsensusJudge).
+			WithJudge(JudgeConfig{Judge: judge})
+
+		response, err := mp.GenerateMessage(context.Background(), []domain.Message{
+			domain.NewTextMessage(domain.RoleUser, "What is the capital of France?"),
+		})
+		if err != nil || response.ID != "resp-b" || response.Content != "Paris, on the Seine." {
+			t.Errorf("Expected the selected provider's response, got %+v (%v)", response, err)
+		}
+	})
+
+	t.Run("JudgeHasOwnTimeout", func(t *testing.T) {
+		slow := func(text string) *MockProvider {
+			return NewMockProvider().WithGenerateMessageFunc(func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+				select {
+				case <-time.After(40 * time.Millisecond):
+					return domain.Response{Content: text}, nil
+				case <-ctx.Done():
+					return domain.Response{}, ctx.Err()
+				}
+			})
+		}
+		slowProviders := []ProviderWeight{
+			{Name: "a", Provider: slow("Paris.")},
+			{Name: "b", Provider: slow("Paris, on the Seine.")},
+		}
+		judge := NewMockProvider().WithGenerateWithSchemaFunc(
+			func(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+				select {
+				case <-time.After(40 * time.Millisecond):
+					return map[string]interface{}{"scores": []interface{}{}, "best": 1, "rationale": "Shorter."}, nil
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			})
+
+		// The providers and the judge together take longer than the providers' timeout
+		mp := NewMultiProvider(slowProviders, StrategyConsensus).
+			WithTimeout(70 * time.Millisecond).
+			WithConsensusStrategy(ConsensusJudge).
+			WithJudge(JudgeConfig{Judge: judge})
+		verdict, err := mp.GenerateWithVerdict(context.Background(), "What is the capital of France?")
+		if err != nil || verdict.JudgeError != nil || verdict.SelectedProvider != "a" {
+			t.Errorf("Expected the judge to run after the providers, got %+v (%v)", verdict, err)
+		}
+
+		mp.WithJudge(JudgeConfig{Judge: judge, Timeout: 5 * time.Millisecond})
+		verdict, err = mp.GenerateWithVerdict(context.Background(), "What is the capital of France?")
+		if err != nil || !errors.Is(verdict.JudgeError, context.DeadlineExceeded) {
+			t.Errorf("Expected the judge timeout to apply, got %+v (%v)", verdict, err)
+		}
+	})
+
+	t.Run("RequiresJudge", func(t *testing.T) {
+		mp := NewMultiProvider(providers, StrategyConsensus).WithConsensusStrategy(ConsensusJudge)
+
+		if _, err := mp.GenerateWithVerdict(context.Background(), "prompt"); !errors.Is(err, domain.ErrInvalidConfiguration) {
+			t.Errorf("Expected invalid configuration error, got %v", err)
+		}
+
+		// Without a judge, Generate falls back to lexical consensus
+		if _, err := mp.Generate(context.Background(), "prompt"); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
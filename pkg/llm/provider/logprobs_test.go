@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+func TestOpenAICandidatesAndLogprobs(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requestBody = nil
+		_ = json.Unmarshal(data, &requestBody)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{
+			"choices": [
+				{
+					"index": 0,
+					"message": {"role": "assistant", "content": "positive"},
+					"finish_reason": "stop",
+					"logprobs": {"content": [
+						{"token": "positive", "logprob": -0.7, "top_logprobs": [
+							{"token": "positive", "logprob": -0.7},
+							{"token": "negative", "logprob": -0.9}
+						]}
+					]}
+				},
+				{
+					"index": 1,
+					"message": {"role": "assistant", "content": "negative"},
+					"finish_reason": "stop",
+					"logprobs": {"content": [{"token": "negative", "logprob": -0.1, "top_logprobs": []}]}
+				}
+			]
+		}`)
+	}))
+	defer server.Close()
+
+	openai := NewOpenAIProvider("test-api-key", "gpt-4o-mini")
+	openai.SetBaseURL(server.URL)
+
+	t.Run("RequestsCandidatesAndLogprobs", func(t *testing.T) {
+		response, err := openai.Generate(context.Background(), "Classify: great product", domain.WithCandidateCount(2), domain.WithTopLogprobs(2))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response != "positive" {
+			t.Errorf("Expected first choice as content, got %q", response)
+		}
+		if requestBody["n"] != float64(2) || requestBody["logprobs"] != true || requestBody["top_logprobs"] != float64(2) {
+			t.Errorf("Expected n, logprobs and top_logprobs in request, got %v", requestBody)
+		}
+	})
+
+	t.Run("ReturnsChoices", func(t *testing.T) {
+		response, err := openai.GenerateMessage(context.Background(),
+			[]domain.Message{domain.NewTextMessage(domain.RoleUser, "Classify: great product")},
+			domain.WithCandidateCount(2), domain.WithTopLogprobs(2))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(response.Choices) != 2 {
+			t.Fatalf("Expected 2 choices, got %d", len(response.Choices))
+		}
+		first := response.Choices[0]
+		if first.Content != "positive" || first.FinishReason != "stop" || len(first.Logprobs) != 1 {
+			t.Errorf("Unexpected first choice: %+v", first)
+		}
+		if len(first.Logprobs[0].TopLogprobs) != 2 || first.Logprobs[0].TopLogprobs[1].Token != "negative" {
+			t.Errorf("Unexpected top logprobs: %+v", first.Logprobs[0].TopLogprobs)
+		}
+
+		// The second choice has the higher average log probability
+		if best := response.BestChoice(); best.Index != 1 {
+			t.Errorf("Expected choice 1 to be best, got %+v", best)
+		}
+	})
+
+	t.Run("OmitsChoicesByDefault", func(t *testing.T) {
+		response, err := openai.GenerateMessage(context.Background(),
+			[]domain.Message{domain.NewTextMessage(domain.RoleUser, "Classify: great product")})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Choices != nil {
+			t.Errorf("Expected no choices without candidate or logprob options, got %+v", response.Choices)
+		}
+		for _, key := range []string{"n", "logprobs", "top_logprobs"} {
+			if _, ok := requestBody[key]; ok {
+				t.Errorf("Expected %s to be omitted from request", key)
+			}
+		}
+	})
+}
+
+func TestGeminiCandidatesAndLogprobs(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requestBody = nil
+		_ = json.Unmarshal(data, &requestBody)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{
+			"candidates": [
+				{
+					"index": 0,
+					"content": {"parts": [{"text": "yes"}]},
+					"finishReason": "STOP",
+					"logprobsResult": {
+						"topCandidates": [{"candidates": [{"token": "yes", "logProbability": -0.2}, {"token": "no", "logProbability": -1.7}]}],
+						"chosenCandidates": [{"token": "yes", "logProbability": -0.2}]
+					}
+				},
+				{
+					"index": 1,
+					"content": {"parts": [{"text": "no"}]},
+					"finishReason": "STOP",
+					"logprobsResult": {
+						"topCandidates": [],
+						"chosenCandidates": [{"token": "no", "logProbability": -1.7}]
+					}
+				}
+			]
+		}`)
+	}))
+	defer server.Close()
+
+	// The candidate count configured through the Gemini option is used by default
+	gemini := NewGeminiProvider("test-api-key", "", domain.NewGeminiGenerationConfigOption().WithCandidateCount(2))
+	gemini.SetBaseURL(server.URL)
+
+	response, err := gemini.GenerateMessage(context.Background(),
+		[]domain.Message{domain.NewTextMessage(domain.RoleUser, "Is the sky blue?")},
+		domain.WithTopLogprobs(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config := requestBody["generationConfig"].(map[string]interface{})
+	if config["candidateCount"] != float64(2) || config["responseLogprobs"] != true || config["logprobs"] != float64(2) {
+		t.Errorf("Expected candidate and logprob settings in generationConfig, got %v", config)
+	}
+
+	if response.Content != "yes" || len(response.Choices) != 2 {
+		t.Fatalf("Expected 2 choices with the first as content, got %+v", response)
+	}
+	first := response.Choices[0]
+	if len(first.Logprobs) != 1 || first.Logprobs[0].Logprob != -0.2 || len(first.Logprobs[0].TopLogprobs) != 2 {
+		t.Errorf("Unexpected logprobs: %+v", first.Logprobs)
+	}
+	if best := response.BestChoice(); best.Content != "yes" {
+		t.Errorf("Expected yes to be the best choice, got %+v", best)
+	}
+}
@@ -0,0 +1,613 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register the GIF decoder for image.Decode
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	modelDomain "github.com/lexlapax/go-llms/pkg/util/llmutil/modelinfo/domain"
+)
+
+// Default media normalization settings
+const (
+	// defaultMaxFetchBytes is the largest media file downloaded from a URL
+	defaultMaxFetchBytes = 50 << 20
+	// sniffLength is the number of bytes used to detect a MIME type
+	sniffLength = 512
+	// downsizeJPEGQuality is the JPEG quality used when re-encoding downsized images
+	downsizeJPEGQuality = 85
+	// maxDownsizeAttempts bounds how often an image is shrunk to fit the byte limit
+	maxDownsizeAttempts = 6
+	// maxDecodePixels is the largest image decoded for downsizing, so a small compressed
+	// file cannot expand to gigabytes of memory
+	maxDecodePixels = 50_000_000
+	// maxFetchRedirects is the number of redirects followed when downloading media
+	maxFetchRedirects = 5
+)
+
+// MediaLimits describes the media a provider accepts. Zero values mean no limit.
+type MediaLimits struct {
+	// FetchURLs lists the content types the provider cannot fetch by URL itself.
+	// Media of these types given as a URL is downloaded and inlined.
+	FetchURLs map[domain.ContentType]bool
+	// MaxImageBytes is the largest image accepted; larger images are downsized
+	MaxImageBytes int
+	// MaxImageDimension is the longest image side accepted in pixels; larger images are downsized
+	MaxImageDimension int
+	// MaxImages is the largest number of images accepted in one request
+	MaxImages int
+	// MaxInlineBytes is the largest total size of inline media accepted in one request
+	MaxInlineBytes int
+}
+
+// OpenAIMediaLimits returns the media limits of the OpenAI chat completions API
+func OpenAIMediaLimits() MediaLimits {
+	return MediaLimits{
+		FetchURLs: map[domain.ContentType]bool{
+			domain.ContentTypeVideo: true,
+			domain.ContentTypeAudio: true,
+		},
+		MaxImageBytes: 20 << 20,
+		MaxImages:     500,
+	}
+}
+
+// AnthropicMediaLimits returns the media limits of the Anthropic Messages API
+func AnthropicMediaLimits() MediaLimits {
+	return MediaLimits{
+		MaxImageBytes:     5 << 20,
+		MaxImageDimension: 8000,
+		MaxImages:         100,
+		MaxInlineBytes:    32 << 20,
+	}
+}
+
+// GeminiMediaLimits returns the media limits of the Gemini API for inline data
+func GeminiMediaLimits() MediaLimits {
+	return MediaLimits{
+		FetchURLs: map[domain.ContentType]bool{
+			domain.ContentTypeImage: true,
+			domain.ContentTypeVideo: true,
+			domain.ContentTypeAudio: true,
+			domain.ContentTypeFile:  true,
+		},
+		MaxImages:      3000,
+		MaxInlineBytes: 20 << 20,
+	}
+}
+
+// MediaNormalizer prepares multimodal messages for a provider before they are sent:
+// it detects missing MIME types, downsizes images above the provider's limits and
+// validates content types, counts and sizes, so problems are reported before the request
+// instead of by the API. With URL fetching enabled, it also downloads URL media the
+// provider cannot fetch itself.
+type MediaNormalizer struct {
+	provider      string
+	limits        MediaLimits
+	capabilities  *modelDomain.Capabilities
+	httpClient    *http.Client
+	maxFetchBytes int64
+	fetchURLs     bool
+}
+
+// NewMediaNormalizer creates a normalizer for the named provider with the given limits.
+// URL fetching is disabled.
+func NewMediaNormalizer(provider string, limits MediaLimits) *MediaNormalizer {
+	return &MediaNormalizer{
+		provider:      provider,
+		limits:        limits,
+		httpClient:    publicMediaClient,
+		maxFetchBytes: defaultMaxFetchBytes,
+	}
+}
+
+// WithURLFetching enables downloading URL media the provider cannot fetch itself. Only
+// enable it for trusted input: by default downloads are restricted to http(s) URLs of
+// public hosts, but the URLs are still requested from the machine running the normalizer.
+func (n *MediaNormalizer) WithURLFetching(enabled bool) *MediaNormalizer {
+	n.fetchURLs = enabled
+	return n
+}
+
+// WithHTTPClient sets the client used to download URL media. The client is used as is,
+// without the restriction to public hosts.
+func (n *MediaNormalizer) WithHTTPClient(client *http.Client) *MediaNormalizer {
+	n.httpClient = client
+	return n
+}
+
+// WithMaxFetchBytes sets the largest media file downloaded from a URL
+func (n *MediaNormalizer) WithMaxFetchBytes(maxBytes int64) *MediaNormalizer {
+	if maxBytes > 0 {
+		n.maxFetchBytes = maxBytes
+	}
+	return n
+}
+
+// WithModel validates content types against the model's capabilities. A model without
+// information (empty Name) is assumed to accept every content type.
+func (n *MediaNormalizer) WithModel(model modelDomain.Model) *MediaNormalizer {
+	if model.Name == "" {
+		n.capabilities = nil
+		return n
+	}
+	capabilities := model.Capabilities
+	n.capabilities = &capabilities
+	return n
+}
+
+// Normalize returns messages with URL media downloaded where needed and enabled, MIME types filled
+// in and oversized images downsized. The input messages are never modified; if nothing
+// changes they are returned as is.
+func (n *MediaNormalizer) Normalize(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	if err := n.checkCapabilities(messages); err != nil {
+		return nil, err
+	}
+
+	var result []domain.Message
+	copied := make(map[int]bool)
+	for i, msg := range messages {
+		for j, part := range msg.Content {
+			normalized, changed, err := n.normalizePart(ctx, part)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+
+			// Copy on first change so the caller's messages stay untouched
+			if result == nil {
+				result = append([]domain.Message(nil), messages...)
+			}
+			if !copied[i] {
+				result[i].Content = append([]domain.ContentPart(nil), msg.Content...)
+				copied[i] = true
+			}
+			result[i].Content[j] = normalized
+		}
+	}
+
+	if result == nil {
+		return messages, nil
+	}
+	return result, nil
+}
+
+// Validate checks the messages against the model's capabilities and the provider's
+// count and size limits
+func (n *MediaNormalizer) Validate(messages []domain.Message) error {
+	if err := n.checkCapabilities(messages); err != nil {
+		return err
+	}
+
+	images, inlineBytes := 0, 0
+	for _, msg := range messages {
+		for _, part := range msg.Content {
+			if part.Type == domain.ContentTypeImage {
+				images++
+			}
+
+			data := inlineData(part)
+			if data == "" {
+				continue
+			}
+			size := base64.StdEncoding.DecodedLen(len(data))
+			inlineBytes += size
+			if part.Type == domain.ContentTypeImage && n.limits.MaxImageBytes > 0 && size > n.limits.MaxImageBytes {
+				return n.limitError(fmt.Sprintf("image of %d bytes exceeds the %d byte limit", size, n.limits.MaxImageBytes))
+			}
+		}
+	}
+
+	if n.limits.MaxImages > 0 && images > n.limits.MaxImages {
+		return n.limitError(fmt.Sprintf("%d images exceed the limit of %d per request", images, n.limits.MaxImages))
+	}
+	if n.limits.MaxInlineBytes > 0 && inlineBytes > n.limits.MaxInlineBytes {
+		return n.limitError(fmt.Sprintf("%d bytes of inline media exceed the %d byte request limit; upload large media with the File API instead",
+			inlineBytes, n.limits.MaxInlineBytes))
+	}
+	return nil
+}
+
+// checkCapabilities rejects content types the model cannot read
+func (n *MediaNormalizer) checkCapabilities(messages []domain.Message) error {
+	if n.capabilities == nil {
+		return nil
+	}
+
+	for _, msg := range messages {
+		for _, part := range msg.Content {
+			readable := true
+			switch part.Type {
+			case domain.ContentTypeImage:
+				readable = n.capabilities.Image.Read
+			case domain.ContentTypeVideo:
+				readable = n.capabilities.Video.Read
+			case domain.ContentTypeAudio:
+				readable = n.capabilities.Audio.Read
+			case domain.ContentTypeFile:
+				readable = n.capabilities.File.Read
+			}
+			if !readable {
+				return domain.NewUnsupportedContentTypeError(n.provider, part.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizePart normalizes a single content part, reporting whether it changed
+func (n *MediaNormalizer) normalizePart(ctx context.Context, part domain.ContentPart) (domain.ContentPart, bool, error) {
+	switch part.Type {
+	case domain.ContentTypeImage:
+		if part.Image == nil {
+			return part, false, nil
+		}
+		source, changed, err := n.normalizeSource(ctx, part.Type, part.Image.Source)
+		if err != nil {
+			return part, false, err
+		}
+		fitted, resized, err := n.fitImage(source)
+		if err != nil || !(changed || resized) {
+			return part, false, err
+		}
+		return domain.ContentPart{Type: part.Type, Image: &domain.ImageContent{Source: fitted}}, true, nil
+	case domain.ContentTypeVideo:
+		if part.Video == nil {
+			return part, false, nil
+		}
+		source, changed, err := n.normalizeSource(ctx, part.Type, part.Video.Source)
+		if err != nil || !changed {
+			return part, false, err
+		}
+		return domain.ContentPart{Type: part.Type, Video: &domain.VideoContent{Source: source}}, true, nil
+	case domain.ContentTypeAudio:
+		if part.Audio == nil {
+			return part, false, nil
+		}
+		source, changed, err := n.normalizeSource(ctx, part.Type, part.Audio.Source)
+		if err != nil || !changed {
+			return part, false, err
+		}
+		return domain.ContentPart{Type: part.Type, Audio: &domain.AudioContent{Source: source}}, true, nil
+	case domain.ContentTypeFile:
+		if part.File == nil || part.File.FileData == "" || !needsSniffing(part.File.MimeType) {
+			return part, false, nil
+		}
+		file := *part.File
+		file.MimeType = sniffBase64(file.FileData)
+		return domain.ContentPart{Type: part.Type, File: &file}, true, nil
+	}
+	return part, false, nil
+}
+
+// normalizeSource downloads URL media the provider cannot fetch and fills in missing MIME types
+func (n *MediaNormalizer) normalizeSource(ctx context.Context, contentType domain.ContentType, source domain.SourceInfo) (domain.SourceInfo, bool, error) {
+	switch {
+	case source.Type == domain.SourceTypeURL && n.fetchURLs && n.limits.FetchURLs[contentType]:
+		data, mimeType, err := n.fetch(ctx, source.URL)
+		if err != nil {
+			return source, false, err
+		}
+		if !needsSniffing(source.MediaType) {
+			mimeType = source.MediaType
+		}
+		if needsSniffing(mimeType) {
+			mimeType = sniffBytes(data)
+		}
+		return domain.SourceInfo{
+			Type:      domain.SourceTypeBase64,
+			MediaType: mimeType,
+			Data:      base64.StdEncoding.EncodeToString(data),
+		}, true, nil
+	case source.Type == domain.SourceTypeBase64 && source.Data != "" && needsSniffing(source.MediaType):
+		source.MediaType = sniffBase64(source.Data)
+		return source, true, nil
+	}
+	return source, false, nil
+}
+
+// fetch downloads media from a URL
+func (n *MediaNormalizer) fetch(ctx context.Context, url string) ([]byte, string, error) {
+	if err := checkFetchURL(url); err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return nil, "", domain.NewProviderError(n.provider, "FetchMedia", 0,
+			fmt.Sprintf("failed to fetch %s: %v", url, err), domain.ErrNetworkConnectivity)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", domain.NewProviderError(n.provider, "FetchMedia", resp.StatusCode,
+			fmt.Sprintf("failed to fetch %s: %s", url, resp.Status), domain.ErrRequestFailed)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, n.maxFetchBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if int64(len(data)) > n.maxFetchBytes {
+		return nil, "", n.limitError(fmt.Sprintf("%s is larger than the %d byte download limit", url, n.maxFetchBytes))
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return data, mimeType, nil
+}
+
+// fitImage downsizes a base64 image that exceeds the byte or dimension limits, reporting
+// whether it changed. Images in formats the standard library cannot decode are left as is
+// unless they exceed the byte limit.
+func (n *MediaNormalizer) fitImage(source domain.SourceInfo) (domain.SourceInfo, bool, error) {
+	if source.Type != domain.SourceTypeBase64 || source.Data == "" ||
+		(n.limits.MaxImageBytes <= 0 && n.limits.MaxImageDimension <= 0) {
+		return source, false, nil
+	}
+
+	tooLarge := n.limits.MaxImageBytes > 0 && base64.StdEncoding.DecodedLen(len(source.Data)) > n.limits.MaxImageBytes
+	if !tooLarge && n.limits.MaxImageDimension <= 0 {
+		return source, false, nil
+	}
+
+	// Only the image header is decoded to read the dimensions
+	config, _, err := image.DecodeConfig(base64.NewDecoder(base64.StdEncoding, strings.NewReader(source.Data)))
+	if err != nil {
+		if tooLarge {
+			return source, false, n.limitError(fmt.Sprintf("image of %d bytes exceeds the %d byte limit and its format cannot be downsized",
+				base64.StdEncoding.DecodedLen(len(source.Data)), n.limits.MaxImageBytes))
+		}
+		return source, false, nil
+	}
+	if !tooLarge && max(config.Width, config.Height) <= n.limits.MaxImageDimension {
+		return source, false, nil
+	}
+	if int64(config.Width)*int64(config.Height) > maxDecodePixels {
+		return source, false, n.limitError(fmt.Sprintf("image of %dx%d pixels is too large to downsize", config.Width, config.Height))
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(source.Data)
+	if err != nil {
+		return source, false, fmt.Errorf("failed to decode image: %w", err)
+	}
+	img, format, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		if tooLarge {
+			return source, false, n.limitError(fmt.Sprintf("image of %d bytes exceeds the %d byte limit and its format cannot be downsized",
+				len(raw), n.limits.MaxImageBytes))
+		}
+		return source, false, nil
+	}
+
+	data, mimeType, err := n.downsizeImage(img, format)
+	if err != nil {
+		return source, false, err
+	}
+	return domain.SourceInfo{
+		Type:      domain.SourceTypeBase64,
+		MediaType: mimeType,
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}, true, nil
+}
+
+// downsizeImage scales an image to fit the dimension limit, then keeps shrinking it until
+// the encoded image fits the byte limit
+func (n *MediaNormalizer) downsizeImage(img image.Image, format string) ([]byte, string, error) {
+	bounds := img.Bounds()
+	longest := max(bounds.Dx(), bounds.Dy())
+
+	scale := 1.0
+	if n.limits.MaxImageDimension > 0 && longest > n.limits.MaxImageDimension {
+		scale = float64(n.limits.MaxImageDimension) / float64(longest)
+	}
+
+	for attempt := 0; attempt < maxDownsizeAttempts; attempt++ {
+		resized := img
+		if scale < 1 {
+			resized = resizeImage(img, max(1, int(float64(bounds.Dx())*scale)), max(1, int(float64(bounds.Dy())*scale)))
+		}
+
+		data, mimeType, err := encodeImage(resized, format)
+		if err != nil {
+			return nil, "", err
+		}
+		if n.limits.MaxImageBytes <= 0 || len(data) <= n.limits.MaxImageBytes {
+			return data, mimeType, nil
+		}
+		scale *= 0.7
+	}
+
+	return nil, "", n.limitError(fmt.Sprintf("image could not be downsized below the %d byte limit", n.limits.MaxImageBytes))
+}
+
+// limitError creates an error for media exceeding the provider's limits
+func (n *MediaNormalizer) limitError(message string) error {
+	return domain.NewProviderError(n.provider, "NormalizeMedia", 0, message, domain.ErrMediaLimitExceeded)
+}
+
+// resizeImage scales an image to the given size by averaging the source pixels that
+// fall into each destination pixel. Source rows are converted to RGBA one strip at a
+// time, using the fast paths of image/draw rather than reading every pixel with At.
+func resizeImage(src image.Image, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	var strip *image.RGBA
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcHeight/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcHeight/height)
+		rows := y1 - y0
+		if strip == nil || strip.Rect.Dy() < rows {
+			strip = image.NewRGBA(image.Rect(0, 0, srcWidth, rows))
+		}
+		draw.Draw(strip, image.Rect(0, 0, srcWidth, rows), src, image.Pt(bounds.Min.X, y0), draw.Src)
+
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var r, g, b, a uint64
+			for sy := 0; sy < rows; sy++ {
+				row := strip.Pix[sy*strip.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4 : sx*4+4]
+					r, g, b, a = r+uint64(p[0]), g+uint64(p[1]), b+uint64(p[2]), a+uint64(p[3])
+				}
+			}
+			count := uint64(rows * (x1 - x0))
+			i := dst.PixOffset(x, y)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/count), uint8(g/count), uint8(b/count), uint8(a/count)
+		}
+	}
+	return dst
+}
+
+// encodeImage encodes an image as JPEG if it came from a JPEG or has no transparency,
+// and as PNG otherwise
+func encodeImage(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if format == "jpeg" || isOpaque(img) {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: downsizeJPEGQuality}); err != nil {
+			return nil, "", fmt.Errorf("failed to encode image: %w", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// isOpaque reports whether an image has no transparent pixels
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+	return false
+}
+
+// publicMediaClient downloads media only from public hosts, so URLs in messages cannot
+// reach loopback, private or link-local addresses such as cloud metadata endpoints. The
+// check runs on the resolved address of every connection, including redirects.
+var publicMediaClient = &http.Client{
+	Timeout: 60 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return fmt.Errorf("media downloads from non-public address %s are not allowed", host)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxFetchRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxFetchRedirects)
+		}
+		return checkFetchURL(req.URL.String())
+	},
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which some clouds use for metadata services
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether an address is routable on the public internet
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip))
+}
+
+// checkFetchURL rejects URLs that are not http(s)
+func checkFetchURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("media URL %q is not an http(s) URL", rawURL)
+	}
+	return nil
+}
+
+// needsSniffing reports whether a MIME type is missing or too generic to be useful
+func needsSniffing(mimeType string) bool {
+	return mimeType == "" || mimeType == "application/octet-stream"
+}
+
+// sniffBase64 detects the MIME type of base64 data from its first bytes
+func sniffBase64(data string) string {
+	head := make([]byte, sniffLength)
+	n, _ := io.ReadFull(base64.NewDecoder(base64.StdEncoding, strings.NewReader(data)), head)
+	return sniffBytes(head[:n])
+}
+
+// sniffBytes detects the MIME type of data, without parameters such as the charset
+func sniffBytes(data []byte) string {
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	return mimeType
+}
+
+// inlineData returns the base64 data of an inline media part, or "" if it has none
+func inlineData(part domain.ContentPart) string {
+	data, _, _, ok := inlineMedia(part)
+	if !ok {
+		return ""
+	}
+	return data
+}
+
+// prepareMedia runs the media pipeline shared by the providers: normalization, automatic
+// upload of large media and validation of the result
+func prepareMedia(
+	ctx context.Context,
+	normalizer *MediaNormalizer,
+	upload func(context.Context, []domain.Message) ([]domain.Message, error),
+	messages []domain.Message,
+) ([]domain.Message, error) {
+	var err error
+	if normalizer != nil {
+		if messages, err = normalizer.Normalize(ctx, messages); err != nil {
+			return nil, err
+		}
+	}
+
+	if messages, err = upload(ctx, messages); err != nil {
+		return nil, err
+	}
+
+	if normalizer != nil {
+		if err := normalizer.Validate(messages); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
@@ -0,0 +1,299 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+	modelDomain "github.com/lexlapax/go-llms/pkg/util/llmutil/modelinfo/domain"
+)
+
+// testPNG encodes an opaque or transparent test image of the given size
+func testPNG(t *testing.T, width, height int, opaque bool) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			alpha := uint8(255)
+			if !opaque && x < width/2 {
+				alpha = 0
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: alpha})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// noisyPNG encodes an opaque image of pseudo-random pixels, which compresses poorly
+func noisyPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = uint8(seed >> 24)
+		if i%4 == 3 {
+			img.Pix[i] = 255
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// decodedImageSize returns the dimensions of a base64 image part
+func decodedImageSize(t *testing.T, part domain.ContentPart) (int, int) {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(part.Image.Source.Data)
+	if err != nil {
+		t.Fatalf("Invalid base64 image: %v", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Invalid image: %v", err)
+	}
+	return config.Width, config.Height
+}
+
+func TestMediaNormalizer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("DownsizesLargeDimensions", func(t *testing.T) {
+		normalizer := NewMediaNormalizer("anthropic", MediaLimits{MaxImageDimension: 100})
+		messages := []domain.Message{domain.NewImageMessage(domain.RoleUser, testPNG(t, 400, 200, false), "image/png", "Describe")}
+
+		result, err := normalizer.Normalize(ctx, messages)
+		if err != nil {
+			t.Fatalf("Normalize failed: %v", err)
+		}
+
+		width, height := decodedImageSize(t, result[0].Content[0])
+		if width != 100 || height != 50 {
+			t.Errorf("Expected 100x50, got %dx%d", width, height)
+		}
+		if result[0].Content[0].Image.Source.MediaType != "image/png" {
+			t.Errorf("Transparent images should stay PNG, got %s", result[0].Content[0].Image.Source.MediaType)
+		}
+		if width, _ := decodedImageSize(t, messages[0].Content[0]); width != 400 {
+			t.Errorf("Input message was modified")
+		}
+		if result[0].Content[1].Text != "Describe" {
+			t.Errorf("Text part was lost")
+		}
+	})
+
+	t.Run("DownsizesLargeFiles", func(t *testing.T) {
+		original := noisyPNG(t, 300, 300)
+		normalizer := NewMediaNormalizer("anthropic", MediaLimits{MaxImageBytes: len(original) / 4})
+		messages := []domain.Message{domain.NewImageMessage(domain.RoleUser, original, "image/png", "")}
+
+		result, err := normalizer.Normalize(ctx, messages)
+		if err != nil {
+			t.Fatalf("Normalize failed: %v", err)
+		}
+		source := result[0].Content[0].Image.Source
+		if base64.StdEncoding.DecodedLen(len(source.Data)) > len(original)/4 {
+			t.Errorf("Image was not downsized below the limit")
+		}
+		if source.MediaType != "image/jpeg" {
+			t.Errorf("Opaque images should be re-encoded as JPEG, got %s", source.MediaType)
+		}
+	})
+
+	t.Run("LeavesSmallImages", func(t *testing.T) {
+		normalizer := NewMediaNormalizer("anthropic", AnthropicMediaLimits())
+		messages := []domain.Message{domain.NewImageMessage(domain.RoleUser, testPNG(t, 10, 10, true), "image/png", "")}
+
+		result, err := normalizer.Normalize(ctx, messages)
+		if err != nil {
+			t.Fatalf("Normalize failed: %v", err)
+		}
+		if &result[0] != &messages[0] {
+			t.Errorf("Expected unchanged messages to be returned as is")
+		}
+	})
+
+	t.Run("FetchesURLsAndSniffsTypes", func(t *testing.T) {
+		imageData := testPNG(t, 8, 8, true)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/cat" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write(imageData)
+		}))
+		defer server.Close()
+
+		normalizer := NewMediaNormalizer("gemini", GeminiMediaLimits()).WithURLFetching(true).WithHTTPClient(server.Client())
+		result, err := normalizer.Normalize(ctx, []domain.Message{domain.NewImageURLMessage(domain.RoleUser, server.URL+"/cat", "")})
+		if err != nil {
+			t.Fatalf("Normalize failed: %v", err)
+		}
+		source := result[0].Content[0].Image.Source
+		if source.Type != domain.SourceTypeBase64 || source.MediaType != "image/png" || source.Data != base64.StdEncoding.EncodeToString(imageData) {
+			t.Errorf("Expected inlined PNG, got %s %s", source.Type, source.MediaType)
+		}
+
+		// Providers that fetch URLs themselves keep the URL
+		kept, err := NewMediaNormalizer("anthropic", AnthropicMediaLimits()).Normalize(ctx,
+			[]domain.Message{domain.NewImageURLMessage(domain.RoleUser, server.URL+"/cat", "")})
+		if err != nil || kept[0].Content[0].Image.Source.Type != domain.SourceTypeURL {
+			t.Errorf("Expected the URL to be kept, got %v", err)
+		}
+
+		_, err = normalizer.Normalize(ctx, []domain.Message{domain.NewImageURLMessage(domain.RoleUser, server.URL+"/missing", "")})
+		var providerErr *domain.ProviderError
+		if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected a fetch error with status 404, got %v", err)
+		}
+
+		_, err = NewMediaNormalizer("gemini", GeminiMediaLimits()).WithURLFetching(true).WithHTTPClient(server.Client()).WithMaxFetchBytes(16).Normalize(ctx,
+			[]domain.Message{domain.NewImageURLMessage(domain.RoleUser, server.URL+"/cat", "")})
+		if !errors.Is(err, domain.ErrMediaLimitExceeded) {
+			t.Errorf("Expected ErrMediaLimitExceeded for an oversized download, got %v", err)
+		}
+
+		file := domain.NewFileMessage(domain.RoleUser, "doc", []byte("%PDF-1.4 test"), "", "")
+		result, err = normalizer.Normalize(ctx, []domain.Message{file})
+		if err != nil || result[0].Content[0].File.MimeType != "application/pdf" {
+			t.Errorf("Expected sniffed application/pdf, got %q (%v)", result[0].Content[0].File.MimeType, err)
+		}
+	})
+
+	t.Run("URLFetchingIsRestricted", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("Unexpected media download of %s", r.URL)
+		}))
+		defer server.Close()
+		messages := []domain.Message{domain.NewImageURLMessage(domain.RoleUser, server.URL+"/cat", "")}
+
+		// Fetching is opt-in
+		kept, err := NewMediaNormalizer("gemini", GeminiMediaLimits()).Normalize(ctx, messages)
+		if err != nil || kept[0].Content[0].Image.Source.Type != domain.SourceTypeURL {
+			t.Errorf("Expected the URL to be kept without URL fetching, got %v", err)
+		}
+
+		// The default client only reaches public hosts
+		normalizer := NewMediaNormalizer("gemini", GeminiMediaLimits()).WithURLFetching(true)
+		if _, err := normalizer.Normalize(ctx, messages); err == nil || !strings.Contains(err.Error(), "non-public address") {
+			t.Errorf("Expected a loopback download to be refused, got %v", err)
+		}
+		_, err = normalizer.Normalize(ctx, []domain.Message{domain.NewImageURLMessage(domain.RoleUser, "file:///etc/passwd", "")})
+		if err == nil || !strings.Contains(err.Error(), "not an http(s) URL") {
+			t.Errorf("Expected a file URL to be refused, got %v", err)
+		}
+
+		for _, ip := range []string{"127.0.0.1", "10.1.2.3", "169.254.169.254", "100.100.100.200", "::1", "fd00::1"} {
+			if isPublicIP(net.ParseIP(ip)) {
+				t.Errorf("Expected %s to be non-public", ip)
+			}
+		}
+		if !isPublicIP(net.ParseIP("93.184.216.34")) {
+			t.Error("Expected 93.184.216.34 to be public")
+		}
+	})
+
+	t.Run("RefusesDecompressionBombs", func(t *testing.T) {
+		// A PNG header claiming 100000x100000 pixels, which would take 40GB to decode
+		header := make([]byte, 13)
+		binary.BigEndian.PutUint32(header[0:], 100000)
+		binary.BigEndian.PutUint32(header[4:], 100000)
+		header[8], header[9] = 8, 6
+		chunk := append([]byte("IHDR"), header...)
+		var bomb bytes.Buffer
+		bomb.WriteString("\x89PNG\r\n\x1a\n")
+		_ = binary.Write(&bomb, binary.BigEndian, uint32(len(header)))
+		bomb.Write(chunk)
+		_ = binary.Write(&bomb, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+
+		normalizer := NewMediaNormalizer("anthropic", AnthropicMediaLimits())
+		_, err := normalizer.Normalize(ctx, []domain.Message{domain.NewImageMessage(domain.RoleUser, bomb.Bytes(), "image/png", "")})
+		if !errors.Is(err, domain.ErrMediaLimitExceeded) {
+			t.Errorf("Expected ErrMediaLimitExceeded for a decompression bomb, got %v", err)
+		}
+	})
+
+	t.Run("ValidatesCapabilities", func(t *testing.T) {
+		model := modelDomain.Model{Name: "text-only", Capabilities: modelDomain.Capabilities{Text: modelDomain.MediaTypeCapability{Read: true}}}
+		normalizer := NewMediaNormalizer("openai", OpenAIMediaLimits()).WithModel(model)
+
+		_, err := normalizer.Normalize(ctx, []domain.Message{domain.NewAudioMessage(domain.RoleUser, []byte("audio"), "audio/wav", "")})
+		if !domain.IsUnsupportedContentTypeError(err) {
+			t.Errorf("Expected an unsupported content type error, got %v", err)
+		}
+		if err := normalizer.Validate([]domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}); err != nil {
+			t.Errorf("Text should be accepted: %v", err)
+		}
+	})
+
+	t.Run("ValidatesLimits", func(t *testing.T) {
+		normalizer := NewMediaNormalizer("anthropic", MediaLimits{MaxImages: 1, MaxInlineBytes: 100})
+
+		twoImages := []domain.Message{
+			domain.NewImageMessage(domain.RoleUser, []byte("a"), "image/png", ""),
+			domain.NewImageMessage(domain.RoleUser, []byte("b"), "image/png", ""),
+		}
+		if err := normalizer.Validate(twoImages); !errors.Is(err, domain.ErrMediaLimitExceeded) {
+			t.Errorf("Expected image count error, got %v", err)
+		}
+
+		large := []domain.Message{domain.NewAudioMessage(domain.RoleUser, make([]byte, 200), "audio/wav", "")}
+		if err := normalizer.Validate(large); !errors.Is(err, domain.ErrMediaLimitExceeded) || !strings.Contains(err.Error(), "File API") {
+			t.Errorf("Expected inline size error, got %v", err)
+		}
+	})
+}
+
+func TestGeminiFetchesURLMedia(t *testing.T) {
+	imageData := testPNG(t, 4, 4, true)
+	var mu sync.Mutex
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/image" {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(imageData)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"a gradient"}]}}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewGeminiProvider("test-key", "gemini-2.0-flash", domain.NewBaseURLOption(server.URL))
+	provider.SetMediaNormalizer(NewMediaNormalizer("gemini", GeminiMediaLimits()).WithURLFetching(true).WithHTTPClient(server.Client()))
+	msg := domain.NewImageURLMessage(domain.RoleUser, server.URL+"/image", "What is this?")
+	if _, err := provider.GenerateMessage(context.Background(), []domain.Message{msg}); err != nil {
+		t.Fatalf("GenerateMessage failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	encoded, _ := json.Marshal(requestBody)
+	if !strings.Contains(string(encoded), base64.StdEncoding.EncodeToString(imageData)) || !strings.Contains(string(encoded), `"mime_type":"image/png"`) {
+		t.Errorf("Expected the image to be fetched and inlined, got %s", encoded)
+	}
+}
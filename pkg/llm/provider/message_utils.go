@@ -78,40 +78,31 @@ func GenerateMessagesKey(messages []domain.Message) uint64 {
 					hasher.Write([]byte(part.Image.Source.Type))
 					if part.Image.Source.Type == domain.SourceTypeBase64 {
 						hasher.Write([]byte(part.Image.Source.MediaType))
-						// Only hash a portion of the data to avoid excessive memory usage
-						if len(part.Image.Source.Data) > 100 {
-							hasher.Write([]byte(part.Image.Source.Data[:100]))
-						} else {
-							hasher.Write([]byte(part.Image.Source.Data))
-						}
+						// Hash all of the data: documents from one template share long prefixes
+						hasher.Write([]byte(part.Image.Source.Data))
 					} else {
 						hasher.Write([]byte(part.Image.Source.URL))
+						hasher.Write([]byte(part.Image.Source.FileID))
 					}
 				}
 			case domain.ContentTypeFile:
 				if part.File != nil {
 					hasher.Write([]byte(part.File.FileName))
 					hasher.Write([]byte(part.File.MimeType))
-					// Only hash a portion of the data to avoid excessive memory usage
-					if len(part.File.FileData) > 100 {
-						hasher.Write([]byte(part.File.FileData[:100]))
-					} else {
-						hasher.Write([]byte(part.File.FileData))
-					}
+					hasher.Write([]byte(part.File.FileID))
+					// Hash all of the data: documents from one template share long prefixes
+					hasher.Write([]byte(part.File.FileData))
 				}
 			case domain.ContentTypeVideo:
 				if part.Video != nil {
 					hasher.Write([]byte(part.Video.Source.Type))
 					if part.Video.Source.Type == domain.SourceTypeBase64 {
 						hasher.Write([]byte(part.Video.Source.MediaType))
-						// Only hash a portion of the data to avoid excessive memory usage
-						if len(part.Video.Source.Data) > 100 {
-							hasher.Write([]byte(part.Video.Source.Data[:100]))
-						} else {
-							hasher.Write([]byte(part.Video.Source.Data))
-						}
+						// Hash all of the data: documents from one template share long prefixes
+						hasher.Write([]byte(part.Video.Source.Data))
 					} else {
 						hasher.Write([]byte(part.Video.Source.URL))
+						hasher.Write([]byte(part.Video.Source.FileID))
 					}
 				}
 			case domain.ContentTypeAudio:
@@ -119,14 +110,11 @@ func GenerateMessagesKey(messages []domain.Message) uint64 {
 					hasher.Write([]byte(part.Audio.Source.Type))
 					if part.Audio.Source.Type == domain.SourceTypeBase64 {
 						hasher.Write([]byte(part.Audio.Source.MediaType))
-						// Only hash a portion of the data to avoid excessive memory usage
-						if len(part.Audio.Source.Data) > 100 {
-							hasher.Write([]byte(part.Audio.Source.Data[:100]))
-						} else {
-							hasher.Write([]byte(part.Audio.Source.Data))
-						}
+						// Hash all of the data: documents from one template share long prefixes
+						hasher.Write([]byte(part.Audio.Source.Data))
 					} else {
 						hasher.Write([]byte(part.Audio.Source.URL))
+						hasher.Write([]byte(part.Audio.Source.FileID))
 					}
 				}
 			}
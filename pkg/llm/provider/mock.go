@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"math/rand"
 	"net/http"
 	"strings"
 	"time"
@@ -27,55 +29,13 @@ type MockProvider struct {
 	headers        map[string]string
 	safetySettings []map[string]interface{}
 	customSettings map[string]interface{}
+	// Default seed for deterministic responses (nil means unseeded)
+	seed *int64
 }
 
 // NewMockProvider creates a new mock provider with default implementations and options
 func NewMockProvider(options ...domain.ProviderOption) *MockProvider {
 	provider := &MockProvider{
-		generateFunc: func(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
-			return `{"result": "This is a mock response"}`, nil
-		},
-		generateMessageFunc: func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
-			return domain.Response{Content: "This is a mock message response"}, nil
-		},
-		streamFunc: func(ctx context.Context, prompt string, options ...domain.Option) (domain.ResponseStream, error) {
-			ch := make(chan domain.Token)
-			go func() {
-				defer close(ch)
-				words := strings.Split("This is a mock streamed response", " ")
-				for i, word := range words {
-					select {
-					case <-ctx.Done():
-						return
-					case ch <- domain.Token{
-						Text:     word,
-						Finished: i == len(words)-1,
-					}:
-						time.Sleep(50 * time.Millisecond) // Simulate delay
-					}
-				}
-			}()
-			return ch, nil
-		},
-		streamMessageFunc: func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
-			ch := make(chan domain.Token)
-			go func() {
-				defer close(ch)
-				words := strings.Split("This is a mock streamed message response", " ")
-				for i, word := range words {
-					select {
-					case <-ctx.Done():
-						return
-					case ch <- domain.Token{
-						Text:     word,
-						Finished: i == len(words)-1,
-					}:
-						time.Sleep(50 * time.Millisecond) // Simulate delay
-					}
-				}
-			}()
-			return ch, nil
-		},
 		generateWithSchemaFunc: func(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
 			// Return a mock response based on schema
 			if schema.Type == "object" {
@@ -143,6 +103,12 @@ func NewMockProvider(options ...domain.ProviderOption) *MockProvider {
 		customSettings: make(map[string]interface{}),
 	}
 
+	// Default text generation honors seeds, so it needs the provider
+	provider.generateFunc = provider.defaultGenerate
+	provider.generateMessageFunc = provider.defaultGenerateMessage
+	provider.streamFunc = provider.defaultStream
+	provider.streamMessageFunc = provider.defaultStreamMessage
+
 	// Apply provider options
 	for _, option := range options {
 		if mockOption, ok := option.(domain.MockOption); ok {
@@ -208,6 +174,125 @@ func (p *MockProvider) WithStreamMessageFunc(f func(ctx context.Context, message
 	return p
 }
 
+// WithSeed makes the default responses deterministic: the same seed and input always
+// produce the same response, and different seeds produce different responses.
+// A seed passed per call with domain.WithSeed takes precedence.
+func (p *MockProvider) WithSeed(seed int64) *MockProvider {
+	p.seed = &seed
+	return p
+}
+
+// mockSeedVocabulary is the word list used for seeded responses
+var mockSeedVocabulary = []string{
+	"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel",
+	"india", "juliett", "kilo", "lima", "mike", "november", "oscar", "papa",
+}
+
+// mockSeedWords is the number of words in a seeded response
+const mockSeedWords = 8
+
+// effectiveSeed returns the per-call seed, falling back to the provider seed
+func (p *MockProvider) effectiveSeed(options []domain.Option) *int64 {
+	providerOptions := domain.DefaultOptions()
+	for _, option := range options {
+		option(providerOptions)
+	}
+	if providerOptions.Seed != nil {
+		return providerOptions.Seed
+	}
+	return p.seed
+}
+
+// seededResponse deterministically derives a response from the seed and input
+func seededResponse(seed int64, input string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(input))
+	rng := rand.New(rand.NewSource(seed ^ int64(hash.Sum64())))
+
+	words := make([]string, mockSeedWords)
+	for i := range words {
+		words[i] = mockSeedVocabulary[rng.Intn(len(mockSeedVocabulary))]
+	}
+	return "This is a mock response: " + strings.Join(words, " ")
+}
+
+// seededFingerprint returns the system fingerprint reported for seeded responses
+func seededFingerprint(seed int64) string {
+	return fmt.Sprintf("fp_mock_%d", seed)
+}
+
+// mockMessagesInput flattens the text of the messages into a single input for seeding
+func mockMessagesInput(messages []domain.Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		sb.WriteString(string(msg.Role))
+		sb.WriteString(":")
+		for _, part := range msg.Content {
+			sb.WriteString(part.Text)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// defaultGenerate is the default Generate implementation
+func (p *MockProvider) defaultGenerate(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+	if seed := p.effectiveSeed(options); seed != nil {
+		return seededResponse(*seed, prompt), nil
+	}
+	return `{"result": "This is a mock response"}`, nil
+}
+
+// defaultGenerateMessage is the default GenerateMessage implementation
+func (p *MockProvider) defaultGenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+	if seed := p.effectiveSeed(options); seed != nil {
+		return domain.Response{
+			Content:           seededResponse(*seed, mockMessagesInput(messages)),
+			SystemFingerprint: seededFingerprint(*seed),
+		}, nil
+	}
+	return domain.Response{Content: "This is a mock message response"}, nil
+}
+
+// defaultStream is the default Stream implementation
+func (p *MockProvider) defaultStream(ctx context.Context, prompt string, options ...domain.Option) (domain.ResponseStream, error) {
+	text := "This is a mock streamed response"
+	if seed := p.effectiveSeed(options); seed != nil {
+		text = seededResponse(*seed, prompt)
+	}
+	return mockWordStream(ctx, text), nil
+}
+
+// defaultStreamMessage is the default StreamMessage implementation
+func (p *MockProvider) defaultStreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
+	text := "This is a mock streamed message response"
+	if seed := p.effectiveSeed(options); seed != nil {
+		text = seededResponse(*seed, mockMessagesInput(messages))
+	}
+	return mockWordStream(ctx, text), nil
+}
+
+// mockWordStream streams the words of the text with a simulated delay
+func mockWordStream(ctx context.Context, text string) domain.ResponseStream {
+	ch := make(chan domain.Token)
+	go func() {
+		defer close(ch)
+		words := strings.Split(text, " ")
+		for i, word := range words {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- domain.Token{
+				Text:     word,
+				Finished: i == len(words)-1,
+			}:
+				time.Sleep(50 * time.Millisecond) // Simulate delay
+			}
+		}
+	}()
+	return ch
+}
+
 // WithPredefinedResponses sets predefined responses for specific prompts
 func (p *MockProvider) WithPredefinedResponses(responses map[string]string) *MockProvider {
 	// Initialize the map if it's nil
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -193,3 +194,74 @@ func TestMockProvider(t *testing.T) {
 		}
 	})
 }
+
+func TestMockProviderSeed(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("same seed and prompt give the same response", func(t *testing.T) {
+		first, _ := NewMockProvider().WithSeed(42).Generate(ctx, "Tell me about Go")
+		second, _ := NewMockProvider().WithSeed(42).Generate(ctx, "Tell me about Go")
+		if first != second {
+			t.Errorf("Expected identical seeded responses, got %q and %q", first, second)
+		}
+
+		other, _ := NewMockProvider().WithSeed(7).Generate(ctx, "Tell me about Go")
+		if other == first {
+			t.Errorf("Expected a different response for a different seed")
+		}
+
+		otherPrompt, _ := NewMockProvider().WithSeed(42).Generate(ctx, "Tell me about Rust")
+		if otherPrompt == first {
+			t.Errorf("Expected a different response for a different prompt")
+		}
+	})
+
+	t.Run("per-call seed takes precedence", func(t *testing.T) {
+		mock := NewMockProvider().WithSeed(1)
+		perCall, _ := mock.Generate(ctx, "prompt", domain.WithSeed(2))
+		expected, _ := NewMockProvider().WithSeed(2).Generate(ctx, "prompt")
+		if perCall != expected {
+			t.Errorf("Expected per-call seed to be used, got %q want %q", perCall, expected)
+		}
+	})
+
+	t.Run("seeded messages report a fingerprint", func(t *testing.T) {
+		messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "Hello")}
+		response, err := NewMockProvider().GenerateMessage(ctx, messages, domain.WithSeed(42))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.SystemFingerprint != "fp_mock_42" {
+			t.Errorf("Expected fingerprint fp_mock_42, got %q", response.SystemFingerprint)
+		}
+
+		again, _ := NewMockProvider().GenerateMessage(ctx, messages, domain.WithSeed(42))
+		if again.Content != response.Content {
+			t.Errorf("Expected identical seeded message responses")
+		}
+	})
+
+	t.Run("seeded stream matches seeded generate", func(t *testing.T) {
+		mock := NewMockProvider().WithSeed(42)
+		expected, _ := mock.Generate(ctx, "prompt")
+
+		stream, err := mock.Stream(ctx, "prompt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var words []string
+		for token := range stream {
+			words = append(words, token.Text)
+		}
+		if got := strings.Join(words, " "); got != expected {
+			t.Errorf("Expected stream %q, got %q", expected, got)
+		}
+	})
+
+	t.Run("unseeded responses are unchanged", func(t *testing.T) {
+		response, _ := NewMockProvider().Generate(ctx, "prompt")
+		if response != `{"result": "This is a mock response"}` {
+			t.Errorf("Unexpected unseeded response %q", response)
+		}
+	})
+}
@@ -60,6 +60,8 @@ type fallbackResult struct {
 
 // MultiProvider implements domain.Provider interface and distributes operations
 // across multiple underlying providers with fallback and selection strategies
+// Its streams are not measured; wrap it, or its providers, with NewStreamMetricsProvider to
+// record stream metrics.
 type MultiProvider struct {
 	providers       []ProviderWeight
 	selectionStrat  SelectionStrategy
@@ -119,7 +121,9 @@ func (mp *MultiProvider) Generate(ctx context.Context, prompt string, options ..
 		return "", ErrNoProviders
 	}
 
-	// Apply the configured timeout if not overridden in the context
+	// Apply the configured timeout if not overridden in the context. The judge runs
+	// afterwards on the caller's context, with a timeout of its own.
+	callerCtx := ctx
 	ctx, cancel := applyTimeoutFromContext(ctx, mp.defaultTimeout)
 	defer cancel()
 
@@ -131,6 +135,16 @@ func (mp *MultiProvider) Generate(ctx context.Context, prompt string, options ..
 	// Use concurrent execution for other strategies
 	results := mp.concurrentGenerate(ctx, prompt, options)
 
+	// Let the judge pick the best response when configured
+	if mp.usesJudge() {
+		messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)}
+		verdict, err := mp.selectJudgeResult(callerCtx, messages, results)
+		if err != nil {
+			return "", err
+		}
+		return verdict.Content, nil
+	}
+
 	// Apply selection strategy
 	return mp.selectTextResult(results)
 }
@@ -141,7 +155,9 @@ func (mp *MultiProvider) GenerateMessage(ctx context.Context, messages []domain.
 		return domain.Response{}, ErrNoProviders
 	}
 
-	// Apply the configured timeout if not overridden in the context
+	// Apply the configured timeout if not overridden in the context. The judge runs
+	// afterwards on the caller's context, with a timeout of its own.
+	callerCtx := ctx
 	ctx, cancel := applyTimeoutFromContext(ctx, mp.defaultTimeout)
 	defer cancel()
 
@@ -153,6 +169,26 @@ func (mp *MultiProvider) GenerateMessage(ctx context.Context, messages []domain.
 	// Use concurrent execution for other strategies
 	results := mp.concurrentGenerateMessage(ctx, messages, options)
 
+	// Let the judge pick the best response when configured
+	if mp.usesJudge() {
+		for i := range results {
+			results[i].content = results[i].response.Content
+		}
+		verdict, err := mp.selectJudgeResult(callerCtx, messages, results)
+		if err != nil {
+			return domain.Response{}, err
+		}
+		// Keep the selected provider's metadata; only synthesized answers are new responses
+		if !verdict.Synthesized {
+			for _, result := range results {
+				if result.err == nil && result.provider == verdict.SelectedProvider {
+					return result.response, nil
+				}
+			}
+		}
+		return domain.GetResponsePool().NewResponse(verdict.Content), nil
+	}
+
 	// Apply selection strategy
 	return mp.selectMessageResult(results)
 }
@@ -176,7 +212,7 @@ func (mp *MultiProvider) GenerateWithSchema(ctx context.Context, prompt string,
 	results := mp.concurrentGenerateWithSchema(ctx, prompt, schema, options)
 
 	// Apply selection strategy
-	return mp.selectStructuredResult(results)
+	return mp.selectStructuredResult(results, schema)
 }
 
 // Stream streams responses token by token from the fastest or primary provider
@@ -669,7 +705,7 @@ func (mp *MultiProvider) selectMessageResult(results []fallbackResult) (domain.R
 }
 
 // selectStructuredResult selects a structured result based on the configured strategy
-func (mp *MultiProvider) selectStructuredResult(results []fallbackResult) (interface{}, error) {
+func (mp *MultiProvider) selectStructuredResult(results []fallbackResult, schema *schemaDomain.Schema) (interface{}, error) {
 	if len(results) == 0 {
 		return nil, ErrNoSuccessfulCalls
 	}
@@ -716,6 +752,15 @@ func (mp *MultiProvider) selectStructuredResult(results []fallbackResult) (inter
 		// This allows the selectConsensusTextResult to use our configuration
 		globalConsensusConfig = &mp.consensusConfig
 
+		// Field-level consensus votes on each property and array element separately
+		if mp.consensusConfig.FieldLevel {
+			consensus, err := mp.selectFieldConsensusResult(results, schema)
+			if err != nil {
+				return nil, err
+			}
+			return consensus.Value, nil
+		}
+
 		// For structured results, we need specialized handling based on schema types
 		// Optimized implementation: use similarity-based grouping on JSON representations
 		// and return the most common structure
@@ -106,7 +106,7 @@ func (mp *MultiProvider) sequentialGenerateWithSchemaForPrimary(ctx context.Cont
 	// If we're not using StrategyPrimary, fall back to concurrent implementation
 	if mp.selectionStrat != StrategyPrimary {
 		results := mp.concurrentGenerateWithSchema(ctx, prompt, schema, options)
-		return mp.selectStructuredResult(results)
+		return mp.selectStructuredResult(results, schema)
 	}
 
 	// Get the primary provider index
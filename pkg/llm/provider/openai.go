@@ -6,12 +6,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/lexlapax/go-llms/pkg/llm/domain"
 	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
 	"github.com/lexlapax/go-llms/pkg/structured/processor"
+	"github.com/lexlapax/go-llms/pkg/util/httplog"
 	"github.com/lexlapax/go-llms/pkg/util/json"
 )
 
@@ -27,8 +29,17 @@ type OpenAIProvider struct {
 	httpClient   *http.Client
 	organization string
 	logitBias    map[string]float64
+	// compatibility adjusts requests and responses for OpenAI-compatible servers
+	compatibility OpenAICompatibilityProfile
 	// Optimization: cache for converted messages
 	messageCache *MessageCache
+	// Inline media above this size is uploaded with the Files API (0 disables)
+	autoUploadThreshold int
+	uploadCache         *fileUploadCache
+	// mediaNormalizer prepares media for the provider's limits (nil disables)
+	mediaNormalizer *MediaNormalizer
+	// requestLogging logs HTTP requests and responses (nil disables)
+	requestLogging *requestLogging
 }
 
 // NewOpenAIProvider creates a new OpenAI provider
@@ -39,6 +50,9 @@ func NewOpenAIProvider(apiKey, model string, options ...domain.ProviderOption) *
 		baseURL:      defaultBaseURL,
 		httpClient:   http.DefaultClient,
 		messageCache: NewMessageCache(),
+		uploadCache:  newFileUploadCache(),
+
+		mediaNormalizer: NewMediaNormalizer("openai", OpenAIMediaLimits()),
 	}
 
 	for _, option := range options {
@@ -59,7 +73,13 @@ func (p *OpenAIProvider) SetBaseURL(url string) {
 
 // SetHTTPClient sets the HTTP client
 func (p *OpenAIProvider) SetHTTPClient(client *http.Client) {
-	p.httpClient = client
+	p.httpClient = p.requestLogging.wrap(client)
+}
+
+// SetRequestLogging logs HTTP requests and responses, redacting the API key and the redactor's patterns
+func (p *OpenAIProvider) SetRequestLogging(logger *slog.Logger, redactor *httplog.Redactor) {
+	p.requestLogging = newRequestLogging(logger, redactor, p.apiKey)
+	p.httpClient = p.requestLogging.wrap(p.httpClient)
 }
 
 // SetOrganization sets the organization ID
@@ -72,6 +92,33 @@ func (p *OpenAIProvider) SetLogitBias(logitBias map[string]float64) {
 	p.logitBias = logitBias
 }
 
+// SetAutoUploadThreshold uploads inline files larger than bytes with the Files API
+func (p *OpenAIProvider) SetAutoUploadThreshold(bytes int) {
+	p.autoUploadThreshold = bytes
+}
+
+// SetMediaNormalizer sets the media normalization stage run before each request; nil disables it
+func (p *OpenAIProvider) SetMediaNormalizer(normalizer *MediaNormalizer) {
+	p.mediaNormalizer = normalizer
+}
+
+// prepareMessages normalizes media, uploads large media and validates the messages
+func (p *OpenAIProvider) prepareMessages(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	messages, err := prepareMedia(ctx, p.mediaNormalizer, p.uploadLargeMedia, messages)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.validateContentTypesForOpenAI(messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// uploadLargeMedia replaces inline files above the auto-upload threshold with uploaded file references
+func (p *OpenAIProvider) uploadLargeMedia(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	return uploadLargeMedia(ctx, p, p.uploadCache, p.autoUploadThreshold, openAIUploadableTypes, messages)
+}
+
 // Generate produces text from a prompt
 func (p *OpenAIProvider) Generate(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
 	// Create a simple text message using the new structure
@@ -140,13 +187,17 @@ func (p *OpenAIProvider) ConvertMessagesToOpenAIFormat(messages []domain.Message
 					imagePart["image_url"] = imageURL
 					contentParts = append(contentParts, imagePart)
 				case domain.ContentTypeFile:
-					// File part
+					// File part, either inline or a reference to an uploaded file
+					file := map[string]interface{}{
+						"file_name": part.File.FileName,
+						"file_data": part.File.FileData,
+					}
+					if part.File.FileID != "" {
+						file = map[string]interface{}{"file_id": part.File.FileID}
+					}
 					contentParts = append(contentParts, map[string]interface{}{
 						"type": "file",
-						"file": map[string]interface{}{
-							"file_name": part.File.FileName,
-							"file_data": part.File.FileData,
-						},
+						"file": file,
 					})
 				case domain.ContentTypeVideo:
 					// Video part
@@ -251,6 +302,7 @@ func (p *OpenAIProvider) ConvertMessagesToOpenAIFormat(messages []domain.Message
 			message["content"] = textContent
 		}
 
+		p.compatibility.adjustMessage(message)
 		oaiMessages = append(oaiMessages, message)
 	}
 
@@ -261,8 +313,17 @@ func (p *OpenAIProvider) ConvertMessagesToOpenAIFormat(messages []domain.Message
 
 // validateContentTypesForOpenAI checks if the content types in the messages are supported by OpenAI
 func (p *OpenAIProvider) validateContentTypesForOpenAI(messages []domain.Message) error {
-	// OpenAI supports all content types in our implementation as of now
-	// If there are limitations in the future, we can add checks here
+	// OpenAI supports all content types in our implementation; some compatible servers don't
+	if !p.compatibility.RejectsImages {
+		return nil
+	}
+	for _, msg := range messages {
+		for _, part := range msg.Content {
+			if part.Type == domain.ContentTypeImage {
+				return domain.NewUnsupportedContentTypeError("OpenAI ("+p.compatibility.Name+")", part.Type)
+			}
+		}
+	}
 	return nil
 }
 
@@ -309,14 +370,40 @@ func (p *OpenAIProvider) buildOpenAIRequestBody(
 		requestBody["logit_bias"] = p.logitBias
 	}
 
+	// Identify the end user for abuse monitoring
+	if options.User != "" {
+		requestBody["user"] = options.User
+	}
+
+	// Add seed for reproducible sampling
+	if options.Seed != nil {
+		requestBody["seed"] = *options.Seed
+	}
+
+	// Request multiple candidates and token log probabilities if asked for
+	if options.CandidateCount > 1 {
+		requestBody["n"] = options.CandidateCount
+	}
+
+	if options.Logprobs {
+		requestBody["logprobs"] = true
+		if options.TopLogprobs > 0 {
+			requestBody["top_logprobs"] = options.TopLogprobs
+		}
+	}
+
+	// Rename and drop fields the server doesn't accept
+	p.compatibility.adjustRequestBody(requestBody)
+
 	return requestBody
 }
 
 // GenerateMessage produces text from a list of messages - optimized version
 func (p *OpenAIProvider) GenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
-	// Validate content types
-	if err := p.validateContentTypesForOpenAI(messages); err != nil {
-		return domain.Response{}, err
+	// Normalize media, upload large media and validate content types
+	messages, prepareErr := p.prepareMessages(ctx, messages)
+	if prepareErr != nil {
+		return domain.Response{}, prepareErr
 	}
 
 	// Apply options - reuse the same options object for all requests
@@ -339,7 +426,7 @@ func (p *OpenAIProvider) GenerateMessage(ctx context.Context, messages []domain.
 	}
 
 	// Create HTTP request - reuse the buffer directly
-	url := fmt.Sprintf("%s/v1/chat/completions", p.baseURL)
+	url := fmt.Sprintf("%s/v1/chat/completions", requestBaseURL(p.baseURL, providerOptions))
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, requestBuffer)
 	if err != nil {
 		return domain.Response{}, fmt.Errorf("failed to create request: %w", err)
@@ -349,10 +436,15 @@ func (p *OpenAIProvider) GenerateMessage(ctx context.Context, messages []domain.
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
 
-	// Set organization header if provided
-	if p.organization != "" {
-		req.Header.Set("OpenAI-Organization", p.organization)
+	// Set organization header if provided, preferring the per-call organization
+	organization := p.organization
+	if providerOptions.Organization != "" {
+		organization = providerOptions.Organization
+	}
+	if organization != "" {
+		req.Header.Set("OpenAI-Organization", organization)
 	}
+	setOverrideHeaders(req, providerOptions)
 
 	// Make the request
 	resp, err := p.httpClient.Do(req)
@@ -373,14 +465,7 @@ func (p *OpenAIProvider) GenerateMessage(ctx context.Context, messages []domain.
 	}
 
 	// Parse response - use optimized JSON unmarshaling
-	var openAIResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-			FinishReason string `json:"finish_reason"`
-		} `json:"choices"`
-	}
+	var openAIResp openAIChatResponse
 	// Use optimized unmarshaling which is ~2x faster than standard library
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
 		return domain.Response{}, fmt.Errorf("failed to parse response: %w", err)
@@ -391,8 +476,91 @@ func (p *OpenAIProvider) GenerateMessage(ctx context.Context, messages []domain.
 		return domain.Response{}, fmt.Errorf("API returned no choices")
 	}
 
+	// Surface refusals and content filter blocks as structured errors
+	if err := openAIResp.Choices[0].contentFilterError("GenerateMessage"); err != nil {
+		return domain.Response{}, err
+	}
+
 	// Use the response pool to reduce allocations
-	return domain.GetResponsePool().NewResponse(openAIResp.Choices[0].Message.Content), nil
+	response := domain.GetResponsePool().NewResponse(openAIResp.Choices[0].Message.Content)
+	response.SystemFingerprint = openAIResp.SystemFingerprint
+	response.Reasoning = p.compatibility.reasoning(&openAIResp.Choices[0])
+
+	// Only expose choices when they carry extra information, to keep the common path cheap
+	if providerOptions.CandidateCount > 1 || providerOptions.Logprobs {
+		response.Choices = openAIResp.toChoices()
+	}
+
+	return response, nil
+}
+
+// openAIChatResponse is the chat completion response body
+type openAIChatResponse struct {
+	SystemFingerprint string             `json:"system_fingerprint"`
+	Choices           []openAIChatChoice `json:"choices"`
+}
+
+// openAIChatChoice is one choice in a chat completion response
+type openAIChatChoice struct {
+	Index   int `json:"index"`
+	Message struct {
+		Content string `json:"content"`
+		Refusal string `json:"refusal"`
+		// Reasoning fields of OpenAI-compatible servers, see OpenAICompatibilityProfile
+		ReasoningContent string `json:"reasoning_content"`
+		Reasoning        string `json:"reasoning"`
+	} `json:"message"`
+	FinishReason string `json:"finish_reason"`
+	// ContentFilterResults is reported by Azure OpenAI
+	ContentFilterResults map[string]openAIContentFilterResult `json:"content_filter_results"`
+	Logprobs             *struct {
+		Content []struct {
+			Token       string  `json:"token"`
+			Logprob     float64 `json:"logprob"`
+			TopLogprobs []struct {
+				Token   string  `json:"token"`
+				Logprob float64 `json:"logprob"`
+			} `json:"top_logprobs"`
+		} `json:"content"`
+	} `json:"logprobs"`
+}
+
+// contentFilterError returns a ContentFilteredError if the model refused or the choice was filtered
+func (c *openAIChatChoice) contentFilterError(operation string) error {
+	switch {
+	case c.Message.Refusal != "":
+		return domain.NewContentFilteredError("openai", operation, domain.ContentFilterResponse,
+			"refusal", c.Message.Refusal, openAIContentFilterRatings(c.ContentFilterResults))
+	case c.FinishReason == "content_filter":
+		return domain.NewContentFilteredError("openai", operation, domain.ContentFilterResponse,
+			"content_filter", "", openAIContentFilterRatings(c.ContentFilterResults))
+	default:
+		return nil
+	}
+}
+
+// toChoices converts the response choices, including log probabilities, to domain choices
+func (r *openAIChatResponse) toChoices() []domain.Choice {
+	choices := make([]domain.Choice, len(r.Choices))
+	for i, c := range r.Choices {
+		choice := domain.Choice{
+			Index:        c.Index,
+			Content:      c.Message.Content,
+			FinishReason: c.FinishReason,
+		}
+		if c.Logprobs != nil {
+			choice.Logprobs = make([]domain.TokenLogprob, len(c.Logprobs.Content))
+			for j, lp := range c.Logprobs.Content {
+				tokenLogprob := domain.TokenLogprob{Token: lp.Token, Logprob: lp.Logprob}
+				for _, top := range lp.TopLogprobs {
+					tokenLogprob.TopLogprobs = append(tokenLogprob.TopLogprobs, domain.TopLogprob{Token: top.Token, Logprob: top.Logprob})
+				}
+				choice.Logprobs[j] = tokenLogprob
+			}
+		}
+		choices[i] = choice
+	}
+	return choices
 }
 
 // GenerateWithSchema produces structured output conforming to a schema
@@ -432,9 +600,10 @@ func (p *OpenAIProvider) Stream(ctx context.Context, prompt string, options ...d
 
 // StreamMessage streams responses from a list of messages
 func (p *OpenAIProvider) StreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
-	// Validate content types
-	if err := p.validateContentTypesForOpenAI(messages); err != nil {
-		return nil, err
+	// Normalize media, upload large media and validate content types
+	messages, prepareErr := p.prepareMessages(ctx, messages)
+	if prepareErr != nil {
+		return nil, prepareErr
 	}
 
 	// Apply options
@@ -449,8 +618,9 @@ func (p *OpenAIProvider) StreamMessage(ctx context.Context, messages []domain.Me
 	// Build request body - optimized with pre-allocation
 	requestBody := p.buildOpenAIRequestBody(oaiMessages, providerOptions)
 
-	// Add streaming flag
+	// Add streaming flag; streams carry a single candidate
 	requestBody["stream"] = true
+	delete(requestBody, "n")
 
 	// Use optimized JSON marshaling with buffer reuse for request body
 	requestBuffer := &bytes.Buffer{}
@@ -460,7 +630,7 @@ func (p *OpenAIProvider) StreamMessage(ctx context.Context, messages []domain.Me
 	}
 
 	// Create HTTP request - reuse the buffer directly
-	url := fmt.Sprintf("%s/v1/chat/completions", p.baseURL)
+	url := fmt.Sprintf("%s/v1/chat/completions", requestBaseURL(p.baseURL, providerOptions))
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, requestBuffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -471,10 +641,15 @@ func (p *OpenAIProvider) StreamMessage(ctx context.Context, messages []domain.Me
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
 	req.Header.Set("Accept", "text/event-stream")
 
-	// Set organization header if provided
-	if p.organization != "" {
-		req.Header.Set("OpenAI-Organization", p.organization)
+	// Set organization header if provided, preferring the per-call organization
+	organization := p.organization
+	if providerOptions.Organization != "" {
+		organization = providerOptions.Organization
+	}
+	if organization != "" {
+		req.Header.Set("OpenAI-Organization", organization)
 	}
+	setOverrideHeaders(req, providerOptions)
 
 	// Make the request
 	resp, err := p.httpClient.Do(req)
@@ -535,6 +710,7 @@ func (p *OpenAIProvider) StreamMessage(ctx context.Context, messages []domain.Me
 				Choices []struct {
 					Delta struct {
 						Content string `json:"content"`
+						Refusal string `json:"refusal"`
 					} `json:"delta"`
 					FinishReason *string `json:"finish_reason"`
 				} `json:"choices"`
@@ -554,6 +730,11 @@ func (p *OpenAIProvider) StreamMessage(ctx context.Context, messages []domain.Me
 			choice := streamResp.Choices[0]
 			content := choice.Delta.Content
 
+			// Like a failed stream, a refusal or filtered response closes without a finished token
+			if choice.Delta.Refusal != "" || (choice.FinishReason != nil && *choice.FinishReason == "content_filter") {
+				return
+			}
+
 			// If content is empty and finish_reason is set, it means we're done
 			if content == "" && choice.FinishReason != nil {
 				// Send final token if needed
@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+const (
+	openAIBatchEndpoint         = "/v1/chat/completions"
+	openAIBatchCompletionWindow = "24h"
+)
+
+// openAIBatch is the batch object returned by the OpenAI Batch API
+type openAIBatch struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	InputFileID  string `json:"input_file_id"`
+	OutputFileID string `json:"output_file_id"`
+	ErrorFileID  string `json:"error_file_id"`
+}
+
+// SubmitBatch uploads the requests as a JSONL file and creates a batch with OpenAI's Batch API
+func (p *OpenAIProvider) SubmitBatch(ctx context.Context, requests []BatchRequest) (*BatchState, error) {
+	// Build the JSONL input file, one chat completion request per line
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	for _, req := range requests {
+		line := map[string]interface{}{
+			"custom_id": req.CustomID,
+			"method":    http.MethodPost,
+			"url":       openAIBatchEndpoint,
+			"body":      p.buildOpenAIRequestBody(p.ConvertMessagesToOpenAIFormat(req.Messages), batchOptions(req)),
+		}
+		if err := encoder.Encode(line); err != nil {
+			return nil, fmt.Errorf("failed to encode batch request %s: %w", req.CustomID, err)
+		}
+	}
+
+	file, err := p.uploadFile(ctx, "batch", "batch.jsonl", "application/jsonl", input.Bytes(), "UploadBatchFile")
+	if err != nil {
+		return nil, err
+	}
+	fileID := file.ID
+
+	requestBody := map[string]interface{}{
+		"input_file_id":     fileID,
+		"endpoint":          openAIBatchEndpoint,
+		"completion_window": openAIBatchCompletionWindow,
+	}
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	var batch openAIBatch
+	if err := p.apiRequest(ctx, http.MethodPost, "/v1/batches", "application/json", bytes.NewReader(body), "SubmitBatch", &batch); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state := &BatchState{
+		Provider:    "openai",
+		ID:          batch.ID,
+		CustomIDs:   batchCustomIDs(requests),
+		InputFileID: fileID,
+		SubmittedAt: now,
+		UpdatedAt:   now,
+	}
+	applyOpenAIBatch(state, &batch)
+	return state, nil
+}
+
+// RefreshBatch updates the state with the batch's current status
+func (p *OpenAIProvider) RefreshBatch(ctx context.Context, state *BatchState) error {
+	var batch openAIBatch
+	if err := p.apiRequest(ctx, http.MethodGet, "/v1/batches/"+state.ID, "", nil, "RefreshBatch", &batch); err != nil {
+		return err
+	}
+	applyOpenAIBatch(state, &batch)
+	state.UpdatedAt = time.Now()
+	return nil
+}
+
+// CancelBatch asks OpenAI to stop processing the batch
+func (p *OpenAIProvider) CancelBatch(ctx context.Context, state *BatchState) error {
+	var batch openAIBatch
+	if err := p.apiRequest(ctx, http.MethodPost, "/v1/batches/"+state.ID+"/cancel", "", nil, "CancelBatch", &batch); err != nil {
+		return err
+	}
+	applyOpenAIBatch(state, &batch)
+	state.UpdatedAt = time.Now()
+	return nil
+}
+
+// BatchResults downloads the output and error files of a finished batch
+func (p *OpenAIProvider) BatchResults(ctx context.Context, state *BatchState) (map[string]BatchResult, error) {
+	results := make(map[string]BatchResult, len(state.CustomIDs))
+
+	for _, fileID := range []string{state.OutputFileID, state.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+
+		var content bytes.Buffer
+		if err := p.apiRequest(ctx, http.MethodGet, "/v1/files/"+fileID+"/content", "", nil, "BatchResults", &content); err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(&content)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			result, err := parseOpenAIBatchLine(line)
+			if err != nil {
+				return nil, err
+			}
+			results[result.CustomID] = result
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read batch results: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+// parseOpenAIBatchLine converts one line of an output or error file into a result
+func parseOpenAIBatchLine(line []byte) (BatchResult, error) {
+	var entry struct {
+		CustomID string `json:"custom_id"`
+		Response *struct {
+			StatusCode int             `json:"status_code"`
+			Body       json.RawMessage `json:"body"`
+		} `json:"response"`
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return BatchResult{}, fmt.Errorf("failed to parse batch result: %w", err)
+	}
+
+	result := BatchResult{CustomID: entry.CustomID}
+	switch {
+	case entry.Error != nil:
+		result.Err = mapOpenAIErrorToStandard(0, entry.Error.Message, "Batch")
+	case entry.Response == nil:
+		result.Err = domain.NewProviderError("openai", "Batch", 0, "batch result has no response", domain.ErrRequestFailed)
+	case entry.Response.StatusCode != http.StatusOK:
+		result.Err = ParseJSONError(entry.Response.Body, entry.Response.StatusCode, "openai", "Batch")
+	default:
+		var completion openAIChatResponse
+		if err := json.Unmarshal(entry.Response.Body, &completion); err != nil {
+			result.Err = fmt.Errorf("failed to parse response: %w", err)
+		} else if len(completion.Choices) == 0 {
+			result.Err = fmt.Errorf("API returned no choices")
+		} else if err := completion.Choices[0].contentFilterError("Batch"); err != nil {
+			result.Err = err
+		} else {
+			result.Response = domain.Response{
+				Content:           completion.Choices[0].Message.Content,
+				SystemFingerprint: completion.SystemFingerprint,
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// applyOpenAIBatch copies an OpenAI batch object onto the state
+func applyOpenAIBatch(state *BatchState, batch *openAIBatch) {
+	state.ProviderStatus = batch.Status
+	state.OutputFileID = batch.OutputFileID
+	state.ErrorFileID = batch.ErrorFileID
+
+	switch batch.Status {
+	case "completed":
+		state.Status = BatchStatusCompleted
+	case "failed":
+		state.Status = BatchStatusFailed
+	case "expired":
+		state.Status = BatchStatusExpired
+	case "cancelled":
+		state.Status = BatchStatusCanceled
+	default: // validating, in_progress, finalizing, cancelling
+		state.Status = BatchStatusPending
+	}
+}
+
+// apiRequest sends a request to the OpenAI API and decodes the response into out.
+// If out is a *bytes.Buffer the raw body is copied into it instead.
+func (p *OpenAIProvider) apiRequest(ctx context.Context, method, path, contentType string, body io.Reader, operation string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+	if p.organization != "" {
+		req.Header.Set("OpenAI-Organization", p.organization)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ParseJSONError(respBody, resp.StatusCode, "openai", operation)
+	}
+
+	if buf, ok := out.(*bytes.Buffer); ok {
+		buf.Write(respBody)
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
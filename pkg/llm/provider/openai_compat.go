@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// OpenAICompatibilityProfile describes the quirks of an OpenAI-compatible server.
+// The zero value describes the OpenAI API itself.
+type OpenAICompatibilityProfile struct {
+	// Name identifies the profile
+	Name string
+	// SystemRole is the role system messages are sent with, for servers that reject the
+	// "system" role ("developer" or "user"). Empty keeps "system".
+	SystemRole string
+	// MaxTokensField is the request field for the token limit. Empty uses "max_tokens".
+	MaxTokensField string
+	// UnsupportedParams are request fields the server rejects; they are never sent
+	UnsupportedParams []string
+	// RejectsImages reports that the server does not accept image content parts
+	RejectsImages bool
+	// FlattenTextContent sends text-only messages as a plain string instead of content parts
+	FlattenTextContent bool
+	// ReasoningField is the response message field that carries the model's reasoning,
+	// such as "reasoning_content" (DeepSeek, vLLM) or "reasoning" (OpenRouter)
+	ReasoningField string
+}
+
+// openAICompatibilityProfiles are the built-in profiles by name
+var openAICompatibilityProfiles = map[string]OpenAICompatibilityProfile{
+	"openai": {Name: "openai"},
+	"openai-reasoning": {
+		Name:           "openai-reasoning",
+		SystemRole:     "developer",
+		MaxTokensField: "max_completion_tokens",
+		UnsupportedParams: []string{
+			"temperature", "top_p", "presence_penalty", "frequency_penalty",
+			"logit_bias", "logprobs", "top_logprobs",
+		},
+	},
+	"openrouter": {
+		Name:           "openrouter",
+		ReasoningField: "reasoning",
+	},
+	"groq": {
+		Name:              "groq",
+		MaxTokensField:    "max_completion_tokens",
+		UnsupportedParams: []string{"logit_bias", "logprobs", "top_logprobs", "n"},
+	},
+	"vllm": {
+		Name:           "vllm",
+		ReasoningField: "reasoning_content",
+	},
+	"lmstudio": {
+		Name:               "lmstudio",
+		UnsupportedParams:  []string{"logit_bias", "logprobs", "top_logprobs", "n"},
+		FlattenTextContent: true,
+	},
+	"ollama": {
+		Name:              "ollama",
+		UnsupportedParams: []string{"logit_bias", "logprobs", "top_logprobs", "n"},
+	},
+	"deepseek": {
+		Name:              "deepseek",
+		UnsupportedParams: []string{"logit_bias", "n"},
+		RejectsImages:     true,
+		ReasoningField:    "reasoning_content",
+	},
+}
+
+// LookupOpenAICompatibilityProfile returns the built-in profile with the given name
+func LookupOpenAICompatibilityProfile(name string) (OpenAICompatibilityProfile, bool) {
+	profile, ok := openAICompatibilityProfiles[name]
+	return profile, ok
+}
+
+// OpenAICompatibilityProfileNames returns the names of the built-in profiles in sorted order
+func OpenAICompatibilityProfileNames() []string {
+	names := make([]string, 0, len(openAICompatibilityProfiles))
+	for name := range openAICompatibilityProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// adjustMessage applies the profile's message quirks to a converted message
+func (c *OpenAICompatibilityProfile) adjustMessage(message map[string]interface{}) {
+	if c.SystemRole != "" && message["role"] == string(domain.RoleSystem) {
+		message["role"] = c.SystemRole
+	}
+
+	if !c.FlattenTextContent {
+		return
+	}
+	parts, ok := message["content"].([]map[string]interface{})
+	if !ok {
+		return
+	}
+	text := ""
+	for _, part := range parts {
+		if part["type"] != "text" {
+			return
+		}
+		partText, _ := part["text"].(string)
+		text += partText
+	}
+	message["content"] = text
+}
+
+// adjustRequestBody applies the profile's request quirks to a request body
+func (c *OpenAICompatibilityProfile) adjustRequestBody(requestBody map[string]interface{}) {
+	if c.MaxTokensField != "" && c.MaxTokensField != "max_tokens" {
+		if maxTokens, ok := requestBody["max_tokens"]; ok {
+			delete(requestBody, "max_tokens")
+			requestBody[c.MaxTokensField] = maxTokens
+		}
+	}
+	for _, param := range c.UnsupportedParams {
+		delete(requestBody, param)
+	}
+}
+
+// reasoning returns the reasoning of a response message according to the profile
+func (c *OpenAICompatibilityProfile) reasoning(choice *openAIChatChoice) string {
+	switch c.ReasoningField {
+	case "reasoning_content":
+		return choice.Message.ReasoningContent
+	case "reasoning":
+		return choice.Message.Reasoning
+	default:
+		return ""
+	}
+}
+
+// SetCompatibilityProfile selects a built-in compatibility profile by name. Unknown names
+// return an error and leave the current profile in place.
+func (p *OpenAIProvider) SetCompatibilityProfile(name string) error {
+	profile, ok := LookupOpenAICompatibilityProfile(name)
+	if !ok {
+		return fmt.Errorf("unknown OpenAI compatibility profile: %s (available: %s)",
+			name, strings.Join(OpenAICompatibilityProfileNames(), ", "))
+	}
+	p.SetCompatibility(profile)
+	return nil
+}
+
+// SetCompatibility sets the compatibility profile, which may be a custom profile
+func (p *OpenAIProvider) SetCompatibility(profile OpenAICompatibilityProfile) {
+	p.compatibility = profile
+	// Converted messages depend on the profile
+	p.messageCache.Clear()
+}
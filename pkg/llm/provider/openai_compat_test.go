@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+func TestOpenAICompatibilityProfiles(t *testing.T) {
+	reply := `{"choices":[{"index":0,"message":{"content":"4","reasoning_content":"2+2 is 4"},"finish_reason":"stop"}]}`
+	messages := []domain.Message{
+		domain.NewTextMessage(domain.RoleSystem, "Be brief"),
+		domain.NewTextMessage(domain.RoleUser, "What is 2+2?"),
+	}
+	options := []domain.Option{
+		domain.WithMaxTokens(100),
+		domain.WithLogprobs(true),
+		domain.WithCandidateCount(2),
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		server, requests := newCaptureServer(t, reply)
+		provider := NewOpenAIProvider("test-key", "gpt-4o", domain.NewBaseURLOption(server.URL))
+		response, err := provider.GenerateMessage(context.Background(), messages, options...)
+		if err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+
+		body := requests()[0].body
+		if body["max_tokens"] != float64(100) || body["logprobs"] != true || body["n"] != float64(2) {
+			t.Errorf("Expected OpenAI request fields, got %v", body)
+		}
+		if response.Reasoning != "" {
+			t.Errorf("Reasoning should only be read with a profile, got %q", response.Reasoning)
+		}
+	})
+
+	t.Run("Groq", func(t *testing.T) {
+		server, requests := newCaptureServer(t, reply)
+		provider := NewOpenAIProvider("test-key", "llama-3.3-70b-versatile",
+			domain.NewBaseURLOption(server.URL), domain.NewOpenAICompatibilityOption("groq"))
+		if _, err := provider.GenerateMessage(context.Background(), messages, options...); err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+
+		body := requests()[0].body
+		if _, ok := body["max_tokens"]; ok || body["max_completion_tokens"] != float64(100) {
+			t.Errorf("Expected max_completion_tokens, got %v", body)
+		}
+		for _, param := range []string{"logprobs", "n"} {
+			if _, ok := body[param]; ok {
+				t.Errorf("Expected %s to be dropped", param)
+			}
+		}
+	})
+
+	t.Run("DeepSeek", func(t *testing.T) {
+		server, _ := newCaptureServer(t, reply)
+		provider := NewOpenAIProvider("test-key", "deepseek-reasoner",
+			domain.NewBaseURLOption(server.URL), domain.NewOpenAICompatibilityOption("deepseek"))
+		response, err := provider.GenerateMessage(context.Background(), messages)
+		if err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+		if response.Reasoning != "2+2 is 4" {
+			t.Errorf("Expected reasoning_content, got %q", response.Reasoning)
+		}
+
+		image := domain.NewImageURLMessage(domain.RoleUser, "https://example.com/a.png", "What is this?")
+		if _, err := provider.GenerateMessage(context.Background(), []domain.Message{image}); !domain.IsUnsupportedContentTypeError(err) {
+			t.Errorf("Expected an unsupported content type error, got %v", err)
+		}
+	})
+
+	t.Run("CustomProfile", func(t *testing.T) {
+		server, requests := newCaptureServer(t, reply)
+		provider := NewOpenAIProvider("test-key", "local-model", domain.NewBaseURLOption(server.URL))
+
+		// Convert once with the default profile to make sure the cache is not reused
+		if _, err := provider.GenerateMessage(context.Background(), messages); err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+		provider.SetCompatibility(OpenAICompatibilityProfile{
+			Name:               "local",
+			SystemRole:         "user",
+			FlattenTextContent: true,
+		})
+		if _, err := provider.GenerateMessage(context.Background(), messages); err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+
+		sent := requests()[1].body["messages"].([]interface{})
+		system := sent[0].(map[string]interface{})
+		if system["role"] != "user" || system["content"] != "Be brief" {
+			t.Errorf("Expected a flattened user message, got %v", system)
+		}
+	})
+
+	t.Run("UnknownProfile", func(t *testing.T) {
+		provider := NewOpenAIProvider("test-key", "gpt-4o", domain.NewOpenAICompatibilityOption("acme"))
+		if provider.compatibility.Name != "" {
+			t.Errorf("Unknown profiles should be ignored, got %q", provider.compatibility.Name)
+		}
+		if err := provider.SetCompatibilityProfile("acme"); err == nil || !strings.Contains(err.Error(), "groq") {
+			t.Errorf("Expected an error listing the available profiles, got %v", err)
+		}
+		if err := provider.SetCompatibilityProfile("groq"); err != nil || provider.compatibility.Name != "groq" {
+			t.Errorf("Expected the groq profile, got %q (%v)", provider.compatibility.Name, err)
+		}
+		if _, ok := LookupOpenAICompatibilityProfile("acme"); ok {
+			t.Errorf("Expected lookup of an unknown profile to fail")
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// openAIFilePurpose is the purpose of files uploaded for use in chat messages
+const openAIFilePurpose = "user_data"
+
+// openAIUploadableTypes are the content types OpenAI accepts as file references in chat messages
+var openAIUploadableTypes = map[domain.ContentType]bool{
+	domain.ContentTypeFile: true,
+}
+
+// openAIFile is the file object returned by the OpenAI Files API
+type openAIFile struct {
+	ID        string `json:"id"`
+	Filename  string `json:"filename"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// toUploadedFile converts the file object to a domain file
+func (f openAIFile) toUploadedFile(mimeType string) domain.UploadedFile {
+	file := domain.UploadedFile{
+		Provider:  "openai",
+		ID:        f.ID,
+		Name:      f.Filename,
+		MimeType:  mimeType,
+		SizeBytes: f.Bytes,
+	}
+	if f.CreatedAt > 0 {
+		file.CreatedAt = time.Unix(f.CreatedAt, 0)
+	}
+	if f.ExpiresAt > 0 {
+		file.ExpiresAt = time.Unix(f.ExpiresAt, 0)
+	}
+	return file
+}
+
+// UploadFile uploads a file with the OpenAI Files API for use in chat messages
+func (p *OpenAIProvider) UploadFile(ctx context.Context, name string, data []byte, mimeType string) (domain.UploadedFile, error) {
+	file, err := p.uploadFile(ctx, openAIFilePurpose, name, mimeType, data, "UploadFile")
+	if err != nil {
+		return domain.UploadedFile{}, err
+	}
+	return file.toUploadedFile(mimeType), nil
+}
+
+// ListFiles returns all files stored with the OpenAI Files API
+func (p *OpenAIProvider) ListFiles(ctx context.Context) ([]domain.UploadedFile, error) {
+	var files []domain.UploadedFile
+	after := ""
+	for {
+		query := url.Values{"limit": {"10000"}}
+		if after != "" {
+			query.Set("after", after)
+		}
+
+		var page struct {
+			Data    []openAIFile `json:"data"`
+			HasMore bool         `json:"has_more"`
+		}
+		if err := p.apiRequest(ctx, http.MethodGet, "/v1/files?"+query.Encode(), "", nil, "ListFiles", &page); err != nil {
+			return nil, err
+		}
+
+		for _, file := range page.Data {
+			files = append(files, file.toUploadedFile(""))
+		}
+		if !page.HasMore || len(page.Data) == 0 {
+			return files, nil
+		}
+		after = page.Data[len(page.Data)-1].ID
+	}
+}
+
+// DeleteFile deletes a file from the OpenAI Files API
+func (p *OpenAIProvider) DeleteFile(ctx context.Context, id string) error {
+	var deleted struct {
+		Deleted bool `json:"deleted"`
+	}
+	if err := p.apiRequest(ctx, http.MethodDelete, "/v1/files/"+url.PathEscape(id), "", nil, "DeleteFile", &deleted); err != nil {
+		return err
+	}
+	p.uploadCache.forget(id)
+	return nil
+}
+
+// uploadFile uploads data to the Files API with the given purpose
+func (p *OpenAIProvider) uploadFile(ctx context.Context, purpose, name, mimeType string, data []byte, operation string) (openAIFile, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("purpose", purpose); err != nil {
+		return openAIFile{}, fmt.Errorf("failed to build upload request: %w", err)
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	header.Set("Content-Type", mimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return openAIFile{}, fmt.Errorf("failed to build upload request: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return openAIFile{}, fmt.Errorf("failed to build upload request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return openAIFile{}, fmt.Errorf("failed to build upload request: %w", err)
+	}
+
+	var file openAIFile
+	if err := p.apiRequest(ctx, http.MethodPost, "/v1/files", writer.FormDataContentType(), &body, operation, &file); err != nil {
+		return openAIFile{}, err
+	}
+	return file, nil
+}
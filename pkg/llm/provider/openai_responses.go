@@ -0,0 +1,611 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+	"github.com/lexlapax/go-llms/pkg/structured/processor"
+	"github.com/lexlapax/go-llms/pkg/util/httplog"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// OpenAIResponsesProvider implements the Provider interface with the OpenAI Responses API
+// (/v1/responses). Unlike OpenAIProvider, which uses chat completions, it supports stateful
+// conversations with WithPreviousResponseID, built-in tools and reasoning summaries.
+type OpenAIResponsesProvider struct {
+	apiKey       string
+	model        string
+	baseURL      string
+	httpClient   *http.Client
+	organization string
+	// mediaNormalizer prepares media for the provider's limits (nil disables)
+	mediaNormalizer *MediaNormalizer
+	// requestLogging logs HTTP requests and responses (nil disables)
+	requestLogging *requestLogging
+}
+
+// NewOpenAIResponsesProvider creates a new OpenAI Responses API provider.
+// It accepts the same options as NewOpenAIProvider; options it has no use for are ignored.
+func NewOpenAIResponsesProvider(apiKey, model string, options ...domain.ProviderOption) *OpenAIResponsesProvider {
+	provider := &OpenAIResponsesProvider{
+		apiKey:     apiKey,
+		model:      model,
+		baseURL:    defaultBaseURL,
+		httpClient: http.DefaultClient,
+
+		mediaNormalizer: NewMediaNormalizer("openai", OpenAIMediaLimits()),
+	}
+
+	for _, option := range options {
+		// Check if the option is compatible with OpenAI
+		if openAIOption, ok := option.(domain.OpenAIOption); ok {
+			openAIOption.ApplyToOpenAI(provider)
+		}
+	}
+
+	return provider
+}
+
+// SetBaseURL sets the base URL for the OpenAI API
+func (p *OpenAIResponsesProvider) SetBaseURL(url string) {
+	p.baseURL = url
+}
+
+// SetHTTPClient sets the HTTP client
+func (p *OpenAIResponsesProvider) SetHTTPClient(client *http.Client) {
+	p.httpClient = p.requestLogging.wrap(client)
+}
+
+// SetRequestLogging logs HTTP requests and responses, redacting the API key and the redactor's patterns
+func (p *OpenAIResponsesProvider) SetRequestLogging(logger *slog.Logger, redactor *httplog.Redactor) {
+	p.requestLogging = newRequestLogging(logger, redactor, p.apiKey)
+	p.httpClient = p.requestLogging.wrap(p.httpClient)
+}
+
+// SetOrganization sets the organization ID
+func (p *OpenAIResponsesProvider) SetOrganization(org string) {
+	p.organization = org
+}
+
+// SetMediaNormalizer sets the media normalization stage run before each request; nil disables it
+func (p *OpenAIResponsesProvider) SetMediaNormalizer(normalizer *MediaNormalizer) {
+	p.mediaNormalizer = normalizer
+}
+
+// prepareMessages normalizes media and validates the messages
+func (p *OpenAIResponsesProvider) prepareMessages(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	noUpload := func(_ context.Context, messages []domain.Message) ([]domain.Message, error) {
+		return messages, nil
+	}
+	messages, err := prepareMedia(ctx, p.mediaNormalizer, noUpload, messages)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateContentTypesForOpenAIResponses(messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// validateContentTypesForOpenAIResponses checks if the content types in the messages are
+// supported by the Responses API, which accepts text, images and files
+func validateContentTypesForOpenAIResponses(messages []domain.Message) error {
+	for _, msg := range messages {
+		for _, part := range msg.Content {
+			switch part.Type {
+			case domain.ContentTypeText, domain.ContentTypeImage, domain.ContentTypeFile:
+			default:
+				return domain.NewUnsupportedContentTypeError("OpenAI Responses", part.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// ConvertMessagesToResponsesInput converts domain messages to Responses API input items
+func (p *OpenAIResponsesProvider) ConvertMessagesToResponsesInput(messages []domain.Message) []map[string]interface{} {
+	input := make([]map[string]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		role := string(msg.Role)
+		textType := "input_text"
+		switch msg.Role {
+		case domain.RoleAssistant:
+			// Earlier answers are sent back as output text
+			textType = "output_text"
+		case domain.RoleTool:
+			// Tool results without a matching function call are passed on as user text
+			role = string(domain.RoleUser)
+		}
+
+		content := make([]map[string]interface{}, 0, len(msg.Content))
+		for _, part := range msg.Content {
+			switch part.Type {
+			case domain.ContentTypeText:
+				text := part.Text
+				if msg.Role == domain.RoleTool {
+					text = "Tool result: " + text
+				}
+				content = append(content, map[string]interface{}{
+					"type": textType,
+					"text": text,
+				})
+			case domain.ContentTypeImage:
+				image := map[string]interface{}{"type": "input_image"}
+				switch part.Image.Source.Type {
+				case domain.SourceTypeURL:
+					image["image_url"] = part.Image.Source.URL
+				case domain.SourceTypeFile:
+					image["file_id"] = part.Image.Source.FileID
+				default:
+					image["image_url"] = fmt.Sprintf("data:%s;base64,%s",
+						part.Image.Source.MediaType, part.Image.Source.Data)
+				}
+				content = append(content, image)
+			case domain.ContentTypeFile:
+				file := map[string]interface{}{"type": "input_file"}
+				if part.File.FileID != "" {
+					file["file_id"] = part.File.FileID
+				} else {
+					file["filename"] = part.File.FileName
+					file["file_data"] = fmt.Sprintf("data:%s;base64,%s", part.File.MimeType, part.File.FileData)
+				}
+				content = append(content, file)
+			}
+		}
+
+		input = append(input, map[string]interface{}{
+			"role":    role,
+			"content": content,
+		})
+	}
+	return input
+}
+
+// buildResponsesRequestBody creates a request body for the Responses API
+func (p *OpenAIResponsesProvider) buildResponsesRequestBody(
+	input []map[string]interface{},
+	options *domain.ProviderOptions,
+) map[string]interface{} {
+	requestBody := make(map[string]interface{}, 8)
+
+	// Add required fields
+	requestBody["model"] = p.model
+	requestBody["input"] = input
+
+	// Add common options if they're not default values
+	if options.Temperature != 0.7 {
+		requestBody["temperature"] = options.Temperature
+	}
+
+	if options.MaxTokens != 1024 {
+		requestBody["max_output_tokens"] = options.MaxTokens
+	}
+
+	if options.TopP != 1.0 {
+		requestBody["top_p"] = options.TopP
+	}
+
+	// Identify the end user for abuse monitoring
+	if options.User != "" {
+		requestBody["user"] = options.User
+	}
+
+	if len(options.Metadata) > 0 {
+		requestBody["metadata"] = options.Metadata
+	}
+
+	// Continue a stored conversation
+	if options.PreviousResponseID != "" {
+		requestBody["previous_response_id"] = options.PreviousResponseID
+	}
+
+	// Ask reasoning models for a summary of their reasoning
+	if options.ReasoningEffort != "" {
+		requestBody["reasoning"] = map[string]interface{}{
+			"effort":  options.ReasoningEffort,
+			"summary": "auto",
+		}
+	}
+
+	if len(options.BuiltinTools) > 0 {
+		requestBody["tools"] = options.BuiltinTools
+	}
+
+	return requestBody
+}
+
+// newRequest creates a Responses API request for the messages
+func (p *OpenAIResponsesProvider) newRequest(
+	ctx context.Context,
+	messages []domain.Message,
+	options []domain.Option,
+	stream bool,
+) (*http.Request, error) {
+	// Apply options
+	providerOptions := domain.DefaultOptions()
+	for _, option := range options {
+		option(providerOptions)
+	}
+
+	requestBody := p.buildResponsesRequestBody(p.ConvertMessagesToResponsesInput(messages), providerOptions)
+	if stream {
+		requestBody["stream"] = true
+	}
+
+	requestBuffer := &bytes.Buffer{}
+	if err := json.MarshalWithBuffer(requestBody, requestBuffer); err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/responses", requestBaseURL(p.baseURL, providerOptions))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, requestBuffer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	// Set organization header if provided, preferring the per-call organization
+	organization := p.organization
+	if providerOptions.Organization != "" {
+		organization = providerOptions.Organization
+	}
+	if organization != "" {
+		req.Header.Set("OpenAI-Organization", organization)
+	}
+	setOverrideHeaders(req, providerOptions)
+
+	return req, nil
+}
+
+// Generate produces text from a prompt
+func (p *OpenAIResponsesProvider) Generate(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+	messages := []domain.Message{
+		domain.NewTextMessage(domain.RoleUser, prompt),
+	}
+	response, err := p.GenerateMessage(ctx, messages, options...)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// GenerateMessage produces text from a list of messages
+func (p *OpenAIResponsesProvider) GenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+	// Normalize media and validate content types
+	messages, prepareErr := p.prepareMessages(ctx, messages)
+	if prepareErr != nil {
+		return domain.Response{}, prepareErr
+	}
+
+	req, err := p.newRequest(ctx, messages, options, false)
+	if err != nil {
+		return domain.Response{}, err
+	}
+
+	// Make the request
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return domain.Response{}, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return domain.Response{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return domain.Response{}, ParseJSONError(body, resp.StatusCode, "openai", "GenerateMessage")
+	}
+
+	var result openAIResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return domain.Response{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result.toResponse("GenerateMessage")
+}
+
+// openAIResponse is the response object of the Responses API
+type openAIResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
+	Output []openAIResponseItem `json:"output"`
+}
+
+// openAIResponseItem is an output item, such as a message or a reasoning summary
+type openAIResponseItem struct {
+	Type    string `json:"type"`
+	Content []struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Refusal     string `json:"refusal"`
+		Annotations []struct {
+			Type       string `json:"type"`
+			StartIndex int    `json:"start_index"`
+			EndIndex   int    `json:"end_index"`
+			URL        string `json:"url"`
+			Title      string `json:"title"`
+		} `json:"annotations"`
+	} `json:"content"`
+	Summary []struct {
+		Text string `json:"text"`
+	} `json:"summary"`
+}
+
+// toResponse converts the response object to a domain response, surfacing failures,
+// refusals and content filter blocks as errors
+func (r *openAIResponse) toResponse(operation string) (domain.Response, error) {
+	if r.Status == "failed" {
+		message := "response failed"
+		if r.Error != nil && r.Error.Message != "" {
+			message = r.Error.Message
+		}
+		return domain.Response{}, domain.NewProviderError("openai", operation, 0, message, domain.ErrRequestFailed)
+	}
+
+	var text, reasoning strings.Builder
+	var citations []domain.Citation
+	for _, item := range r.Output {
+		switch item.Type {
+		case "message":
+			for _, content := range item.Content {
+				switch content.Type {
+				case "refusal":
+					return domain.Response{}, domain.NewContentFilteredError("openai", operation,
+						domain.ContentFilterResponse, "refusal", content.Refusal, nil)
+				case "output_text":
+					// Annotation offsets are relative to their text part
+					offset := text.Len()
+					text.WriteString(content.Text)
+					for _, annotation := range content.Annotations {
+						if annotation.Type != "url_citation" {
+							continue
+						}
+						citations = append(citations, domain.Citation{
+							Type:          domain.CitationWebLocation,
+							DocumentIndex: -1,
+							DocumentTitle: annotation.Title,
+							URL:           annotation.URL,
+							ResponseStart: offset + annotation.StartIndex,
+							ResponseEnd:   offset + annotation.EndIndex,
+						})
+					}
+				}
+			}
+		case "reasoning":
+			for _, summary := range item.Summary {
+				if reasoning.Len() > 0 {
+					reasoning.WriteString("\n\n")
+				}
+				reasoning.WriteString(summary.Text)
+			}
+		}
+	}
+
+	if r.IncompleteDetails != nil && r.IncompleteDetails.Reason == "content_filter" {
+		return domain.Response{}, domain.NewContentFilteredError("openai", operation,
+			domain.ContentFilterResponse, "content_filter", text.String(), nil)
+	}
+
+	response := domain.GetResponsePool().NewResponse(text.String())
+	response.ID = r.ID
+	response.Reasoning = reasoning.String()
+	response.Citations = citations
+	return response, nil
+}
+
+// GenerateWithSchema produces structured output conforming to a schema
+func (p *OpenAIResponsesProvider) GenerateWithSchema(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+	// Build a prompt that includes the schema
+	enhancedPrompt := enhancePromptWithSchema(prompt, schema)
+
+	response, err := p.Generate(ctx, enhancedPrompt, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate response: %w", err)
+	}
+
+	jsonStr := processor.ExtractJSON(response)
+	if jsonStr == "" {
+		return nil, fmt.Errorf("response does not contain valid JSON")
+	}
+
+	var result interface{}
+	if err := json.UnmarshalFromString(jsonStr, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response JSON: %w", err)
+	}
+
+	return result, nil
+}
+
+// OpenAIResponseEvent is a typed event of a streamed Responses API call
+type OpenAIResponseEvent struct {
+	// Type is the event type, such as "response.output_text.delta" or "response.completed"
+	Type string
+	// Delta is the text added by delta events (output text, refusal and reasoning summary deltas)
+	Delta string
+	// Response is the complete response, set on the "response.completed" event
+	Response *domain.Response
+	// Err is set when the response failed, was refused or the stream broke off
+	Err error
+	// Data is the raw JSON of the event
+	Data []byte
+}
+
+// Stream streams responses token by token
+func (p *OpenAIResponsesProvider) Stream(ctx context.Context, prompt string, options ...domain.Option) (domain.ResponseStream, error) {
+	messages := []domain.Message{
+		domain.NewTextMessage(domain.RoleUser, prompt),
+	}
+	return p.StreamMessage(ctx, messages, options...)
+}
+
+// StreamMessage streams the output text of a response token by token.
+// Use StreamEvents to also receive reasoning summaries, tool calls and the final response.
+func (p *OpenAIResponsesProvider) StreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
+	events, err := p.StreamEvents(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	responseStream, tokenCh := domain.GetChannelPool().GetResponseStream()
+	go func() {
+		defer close(tokenCh)
+		for event := range events {
+			var token domain.Token
+			switch {
+			case event.Type == "response.output_text.delta" && event.Delta != "":
+				token = domain.GetTokenPool().NewToken(event.Delta, false)
+			case event.Err != nil:
+				// Like the other providers, a failed stream closes without a finished token
+				return
+			case event.Type == "response.completed":
+				token = domain.GetTokenPool().NewToken("", true)
+			default:
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case tokenCh <- token:
+				if token.Finished {
+					return
+				}
+			}
+		}
+	}()
+
+	return responseStream, nil
+}
+
+// StreamEvents streams the typed events of a response. The channel is closed after the
+// "response.completed" event, a failure event, or when the context is canceled. A stream
+// that breaks off ends with an "error" event.
+func (p *OpenAIResponsesProvider) StreamEvents(ctx context.Context, messages []domain.Message, options ...domain.Option) (<-chan OpenAIResponseEvent, error) {
+	// Normalize media and validate content types
+	messages, prepareErr := p.prepareMessages(ctx, messages)
+	if prepareErr != nil {
+		return nil, prepareErr
+	}
+
+	req, err := p.newRequest(ctx, messages, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, ParseJSONError(body, resp.StatusCode, "openai", "StreamMessage")
+	}
+
+	events := make(chan OpenAIResponseEvent)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				// The stream broke off before a final event
+				message := "stream ended before the response completed"
+				if err != io.EOF {
+					message = fmt.Sprintf("failed to read stream: %v", err)
+				}
+				select {
+				case <-ctx.Done():
+				case events <- OpenAIResponseEvent{
+					Type: "error",
+					Err:  domain.NewProviderError("openai", "StreamMessage", 0, message, domain.ErrNetworkConnectivity),
+				}:
+				}
+				return
+			}
+
+			// Every data line carries its event type, so "event:" lines can be skipped
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			data := strings.TrimPrefix(line, "data: ")
+
+			event, done := parseOpenAIResponseEvent(data)
+			if event.Type == "" {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case events <- event:
+			}
+			if done {
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// parseOpenAIResponseEvent parses the data of a stream event and reports whether it ends the stream
+func parseOpenAIResponseEvent(data string) (OpenAIResponseEvent, bool) {
+	var raw struct {
+		Type     string          `json:"type"`
+		Delta    string          `json:"delta"`
+		Message  string          `json:"message"`
+		Response *openAIResponse `json:"response"`
+	}
+	if err := json.UnmarshalFromString(data, &raw); err != nil {
+		// Skip invalid JSON
+		return OpenAIResponseEvent{}, false
+	}
+
+	event := OpenAIResponseEvent{Type: raw.Type, Delta: raw.Delta, Data: []byte(data)}
+	switch raw.Type {
+	case "response.completed", "response.failed", "response.incomplete":
+		if raw.Response == nil {
+			event.Err = domain.NewProviderError("openai", "StreamMessage", 0,
+				"stream event without response", domain.ErrResponseParsing)
+			return event, true
+		}
+		response, err := raw.Response.toResponse("StreamMessage")
+		if err != nil {
+			event.Err = err
+		} else {
+			event.Response = &response
+		}
+		return event, true
+	case "error":
+		event.Err = domain.NewProviderError("openai", "StreamMessage", 0, raw.Message, domain.ErrRequestFailed)
+		return event, true
+	}
+	return event, false
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+func TestOpenAIResponsesProvider(t *testing.T) {
+	reply := `{
+		"id": "resp_123",
+		"status": "completed",
+		"output": [
+			{"type": "reasoning", "summary": [{"type": "summary_text", "text": "Looked up the score."}]},
+			{"type": "web_search_call", "status": "completed"},
+			{"type": "message", "role": "assistant", "content": [
+				{"type": "output_text", "text": "Spain won 2-1.", "annotations": [
+					{"type": "url_citation", "start_index": 0, "end_index": 14, "url": "https://example.com/final", "title": "Final"}
+				]}
+			]}
+		]
+	}`
+	server, requests := newCaptureServer(t, reply)
+	provider := NewOpenAIResponsesProvider("test-key", "o4-mini",
+		domain.NewBaseURLOption(server.URL), domain.NewOpenAIOrganizationOption("org-1"))
+
+	messages := []domain.Message{
+		domain.NewTextMessage(domain.RoleSystem, "Be brief"),
+		domain.NewTextMessage(domain.RoleAssistant, "Hello"),
+		domain.NewImageURLMessage(domain.RoleUser, "https://example.com/pitch.png", "Who won?"),
+	}
+	response, err := provider.GenerateMessage(context.Background(), messages,
+		domain.WithPreviousResponseID("resp_122"),
+		domain.WithReasoningEffort("low"),
+		domain.WithBuiltinTools(map[string]interface{}{"type": "web_search_preview"}),
+		domain.WithMaxTokens(200),
+	)
+	if err != nil {
+		t.Fatalf("GenerateMessage failed: %v", err)
+	}
+
+	t.Run("Request", func(t *testing.T) {
+		request := requests()[0]
+		if request.header.Get("OpenAI-Organization") != "org-1" {
+			t.Errorf("Expected organization header, got %q", request.header.Get("OpenAI-Organization"))
+		}
+		body := request.body
+		if body["previous_response_id"] != "resp_122" || body["max_output_tokens"] != float64(200) {
+			t.Errorf("Unexpected request fields: %v", body)
+		}
+		if reasoning := body["reasoning"].(map[string]interface{}); reasoning["effort"] != "low" {
+			t.Errorf("Unexpected reasoning: %v", reasoning)
+		}
+		if tools := body["tools"].([]interface{}); tools[0].(map[string]interface{})["type"] != "web_search_preview" {
+			t.Errorf("Unexpected tools: %v", tools)
+		}
+
+		input := body["input"].([]interface{})
+		assistant := input[1].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+		if assistant["type"] != "output_text" {
+			t.Errorf("Expected assistant text as output_text, got %v", assistant)
+		}
+		image := input[2].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+		if image["type"] != "input_image" || image["image_url"] != "https://example.com/pitch.png" {
+			t.Errorf("Unexpected image input: %v", image)
+		}
+	})
+
+	t.Run("Response", func(t *testing.T) {
+		if response.Content != "Spain won 2-1." || response.ID != "resp_123" || response.Reasoning != "Looked up the score." {
+			t.Errorf("Unexpected response: %+v", response)
+		}
+		if len(response.Citations) != 1 || response.Citations[0].URL != "https://example.com/final" || response.Citations[0].ResponseEnd != 14 {
+			t.Errorf("Unexpected citations: %+v", response.Citations)
+		}
+	})
+
+	t.Run("UnsupportedContent", func(t *testing.T) {
+		msg := domain.NewAudioMessage(domain.RoleUser, []byte("audio"), "audio/wav", "")
+		_, err := provider.GenerateMessage(context.Background(), []domain.Message{msg})
+		if !domain.IsUnsupportedContentTypeError(err) {
+			t.Errorf("Expected an unsupported content type error, got %v", err)
+		}
+	})
+}
+
+func TestOpenAIResponsesRefusal(t *testing.T) {
+	server, _ := newCaptureServer(t, `{"id": "resp_1", "status": "completed", "output": [
+		{"type": "message", "content": [{"type": "refusal", "refusal": "I can't help with that."}]}
+	]}`)
+	provider := NewOpenAIResponsesProvider("test-key", "gpt-4o", domain.NewBaseURLOption(server.URL))
+
+	_, err := provider.Generate(context.Background(), "Something harmful")
+	if !domain.IsContentFilteredError(err) {
+		t.Errorf("Expected a content filtered error, got %v", err)
+	}
+}
+
+func TestOpenAIResponsesStreaming(t *testing.T) {
+	events := []string{
+		`{"type":"response.created","response":{"id":"resp_9","status":"in_progress"}}`,
+		`{"type":"response.reasoning_summary_text.delta","delta":"Thinking"}`,
+		`{"type":"response.output_text.delta","delta":"Hel"}`,
+		`{"type":"response.output_text.delta","delta":"lo"}`,
+		`{"type":"response.completed","response":{"id":"resp_9","status":"completed","output":[{"type":"message","content":[{"type":"output_text","text":"Hello"}]}]}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/responses" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			eventType := strings.SplitN(strings.TrimPrefix(event, `{"type":"`), `"`, 2)[0]
+			_, _ = w.Write([]byte("event: " + eventType + "\ndata: " + event + "\n\n"))
+		}
+	}))
+	defer server.Close()
+	provider := NewOpenAIResponsesProvider("test-key", "gpt-4o", domain.NewBaseURLOption(server.URL))
+
+	t.Run("Tokens", func(t *testing.T) {
+		stream, err := provider.Stream(context.Background(), "Say hello")
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		var text strings.Builder
+		finished := false
+		for token := range stream {
+			text.WriteString(token.Text)
+			finished = finished || token.Finished
+		}
+		if text.String() != "Hello" || !finished {
+			t.Errorf("Expected \"Hello\" and a finished token, got %q (finished %v)", text.String(), finished)
+		}
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		stream, err := provider.StreamEvents(context.Background(),
+			[]domain.Message{domain.NewTextMessage(domain.RoleUser, "Say hello")})
+		if err != nil {
+			t.Fatalf("StreamEvents failed: %v", err)
+		}
+		var received []OpenAIResponseEvent
+		for event := range stream {
+			received = append(received, event)
+		}
+		if len(received) != len(events) {
+			t.Fatalf("Expected %d events, got %d", len(events), len(received))
+		}
+		if received[1].Type != "response.reasoning_summary_text.delta" || received[1].Delta != "Thinking" {
+			t.Errorf("Unexpected reasoning event: %+v", received[1])
+		}
+		last := received[len(received)-1]
+		if last.Response == nil || last.Response.ID != "resp_9" || last.Response.Content != "Hello" {
+			t.Errorf("Expected the completed response, got %+v", last)
+		}
+	})
+}
+
+func TestOpenAIResponsesStreamBreaksOff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"type":"response.output_text.delta","delta":"Hel"}` + "\n\n"))
+	}))
+	defer server.Close()
+	provider := NewOpenAIResponsesProvider("test-key", "gpt-4o", domain.NewBaseURLOption(server.URL))
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "Say hello")}
+
+	stream, err := provider.StreamEvents(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("StreamEvents failed: %v", err)
+	}
+	var last OpenAIResponseEvent
+	for event := range stream {
+		last = event
+	}
+	if last.Type != "error" || !errors.Is(last.Err, domain.ErrNetworkConnectivity) {
+		t.Errorf("Expected an error event for the broken stream, got %+v", last)
+	}
+
+	tokens, err := provider.StreamMessage(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("StreamMessage failed: %v", err)
+	}
+	for token := range tokens {
+		if token.Finished {
+			t.Error("Expected no finished token for a broken stream")
+		}
+	}
+}
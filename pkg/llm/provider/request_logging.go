@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/lexlapax/go-llms/pkg/util/httplog"
+)
+
+// requestLogging holds a provider's request logging settings, so that a client set
+// after the option with SetHTTPClient is logged too
+type requestLogging struct {
+	logger   *slog.Logger
+	redactor *httplog.Redactor
+}
+
+// newRequestLogging creates request logging settings that also redact the provider's API key
+func newRequestLogging(logger *slog.Logger, redactor *httplog.Redactor, apiKey string) *requestLogging {
+	if redactor == nil {
+		redactor = httplog.NewRedactor()
+	}
+	return &requestLogging{
+		logger:   logger,
+		redactor: redactor.Clone().WithSecret(apiKey),
+	}
+}
+
+// wrap returns the client with request logging, or the client itself if logging is not enabled
+func (l *requestLogging) wrap(client *http.Client) *http.Client {
+	if l == nil {
+		return client
+	}
+	return httplog.WrapClient(client, l.logger, l.redactor)
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/httplog"
+)
+
+// syncBuffer is a buffer safe for concurrent log writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRequestLoggingOption(t *testing.T) {
+	server, requests := newCaptureServer(t, `{"choices":[{"index":0,"message":{"content":"Hi"},"finish_reason":"stop"}]}`)
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	// The HTTP client option comes after the logging option and is still logged
+	p := NewOpenAIProvider("sk-test-0123456789abcdef", "gpt-4o",
+		domain.NewBaseURLOption(server.URL),
+		domain.NewRequestLoggingOption(logger, httplog.EmailPattern),
+		domain.NewHTTPClientOption(&http.Client{}),
+	)
+
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "Reply to bob@example.com")}
+	if _, err := p.GenerateMessage(context.Background(), messages); err != nil {
+		t.Fatalf("GenerateMessage failed: %v", err)
+	}
+
+	if got := requests(); len(got) != 1 || got[0].header.Get("Authorization") != "Bearer sk-test-0123456789abcdef" {
+		t.Errorf("Expected the request to be sent with the API key, got %+v", got)
+	}
+
+	out := logs.String()
+	if !strings.Contains(out, `"msg":"http request"`) || !strings.Contains(out, `"msg":"http response"`) {
+		t.Fatalf("Expected request and response logs, got:\n%s", out)
+	}
+	for _, leaked := range []string{"sk-test", "bob@example.com"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("Expected %q to be redacted, got:\n%s", leaked, out)
+		}
+	}
+	if !strings.Contains(out, "Reply to [REDACTED]") {
+		t.Errorf("Expected the redacted message in the request body, got:\n%s", out)
+	}
+}
+
+func TestRequestLoggingGeminiKey(t *testing.T) {
+	server, _ := newCaptureServer(t, `{"candidates":[{"content":{"parts":[{"text":"Hi"}]}}]}`)
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	p := NewGeminiProvider("gemini-test-key", "gemini-2.0-flash",
+		domain.NewBaseURLOption(server.URL),
+		domain.NewRequestLoggingOption(logger),
+	)
+
+	if _, err := p.Generate(context.Background(), "Hello"); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if out := logs.String(); strings.Contains(out, "gemini-test-key") || !strings.Contains(out, "http request") {
+		t.Errorf("Expected the key in the URL to be redacted, got:\n%s", out)
+	}
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"net/http"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// requestBaseURL returns the per-call base URL if one was given, or the provider's default
+func requestBaseURL(defaultURL string, options *domain.ProviderOptions) string {
+	if options.BaseURL != "" {
+		return options.BaseURL
+	}
+	return defaultURL
+}
+
+// setOverrideHeaders sets the per-call headers on the request. They are applied last so
+// callers can replace headers the provider sets itself.
+func setOverrideHeaders(req *http.Request, options *domain.ProviderOptions) {
+	for key, value := range options.Headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// mergeAnthropicMetadata overlays the per-call metadata and user on the provider's metadata.
+// The provider's map is never modified.
+func mergeAnthropicMetadata(defaults map[string]string, options *domain.ProviderOptions) map[string]string {
+	if len(options.Metadata) == 0 && options.User == "" {
+		return defaults
+	}
+
+	merged := make(map[string]string, len(defaults)+len(options.Metadata)+1)
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range options.Metadata {
+		merged[k] = v
+	}
+	if options.User != "" {
+		merged["user_id"] = options.User
+	}
+	return merged
+}
+
+// mergeGeminiSafetySettings overlays the per-call safety settings on the provider's
+// settings, replacing entries with the same category. The provider's slice is never modified.
+func mergeGeminiSafetySettings(defaults []map[string]interface{}, options *domain.ProviderOptions) []map[string]interface{} {
+	if len(options.SafetySettings) == 0 {
+		return defaults
+	}
+
+	merged := make([]map[string]interface{}, 0, len(defaults)+len(options.SafetySettings))
+	index := make(map[interface{}]int, len(defaults)+len(options.SafetySettings))
+	for _, setting := range append(append([]map[string]interface{}{}, defaults...), options.SafetySettings...) {
+		category, ok := setting["category"]
+		if !ok {
+			merged = append(merged, setting)
+			continue
+		}
+		if i, exists := index[category]; exists {
+			merged[i] = setting
+			continue
+		}
+		index[category] = len(merged)
+		merged = append(merged, setting)
+	}
+	return merged
+}
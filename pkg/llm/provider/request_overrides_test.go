@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+)
+
+// capturedRequest is what a test server saw for one request
+type capturedRequest struct {
+	header http.Header
+	body   map[string]interface{}
+}
+
+// newCaptureServer returns a server that records requests and replies with body
+func newCaptureServer(t *testing.T, reply string) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var captured []capturedRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		captured = append(captured, capturedRequest{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest(nil), captured...)
+	}
+}
+
+func TestPerCallOverrides(t *testing.T) {
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "Hello")}
+
+	t.Run("OpenAI", func(t *testing.T) {
+		server, requests := newCaptureServer(t, `{"choices":[{"message":{"content":"ok"}}]}`)
+		provider := NewOpenAIProvider("test-key", "gpt-4o", domain.NewOpenAIOrganizationOption("org-default"))
+
+		_, err := provider.GenerateMessage(context.Background(), messages,
+			domain.WithBaseURL(server.URL),
+			domain.WithHeaders(map[string]string{"X-Tenant": "acme"}),
+			domain.WithOrganization("org-acme"),
+			domain.WithUser("user-42"))
+		if err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+
+		got := requests()
+		if len(got) != 1 {
+			t.Fatalf("Expected 1 request at the override URL, got %d", len(got))
+		}
+		if got[0].header.Get("X-Tenant") != "acme" {
+			t.Errorf("Expected X-Tenant header, got %q", got[0].header.Get("X-Tenant"))
+		}
+		if got[0].header.Get("OpenAI-Organization") != "org-acme" {
+			t.Errorf("Expected per-call organization, got %q", got[0].header.Get("OpenAI-Organization"))
+		}
+		if got[0].body["user"] != "user-42" {
+			t.Errorf("Expected user in body, got %v", got[0].body["user"])
+		}
+		if provider.baseURL != defaultBaseURL || provider.organization != "org-default" {
+			t.Errorf("Provider was modified: baseURL=%s organization=%s", provider.baseURL, provider.organization)
+		}
+	})
+
+	t.Run("AnthropicMetadataMerge", func(t *testing.T) {
+		server, requests := newCaptureServer(t, `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`)
+		defaults := map[string]string{"user_id": "default-user", "team": "core"}
+		provider := NewAnthropicProvider("test-key", "claude-3-5-sonnet-latest",
+			domain.NewBaseURLOption(server.URL), domain.NewAnthropicMetadataOption(defaults))
+
+		_, err := provider.GenerateMessage(context.Background(), messages,
+			domain.WithUser("tenant-user"),
+			domain.WithMetadata(map[string]string{"request": "r1"}),
+			domain.WithHeaders(map[string]string{"anthropic-beta": "test-beta"}))
+		if err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+
+		got := requests()[0]
+		metadata, _ := got.body["metadata"].(map[string]interface{})
+		if metadata["user_id"] != "tenant-user" || metadata["team"] != "core" || metadata["request"] != "r1" {
+			t.Errorf("Unexpected merged metadata: %v", metadata)
+		}
+		if got.header.Get("anthropic-beta") != "test-beta" {
+			t.Errorf("Expected anthropic-beta header, got %q", got.header.Get("anthropic-beta"))
+		}
+		if len(defaults) != 2 || defaults["user_id"] != "default-user" {
+			t.Errorf("Provider metadata was modified: %v", defaults)
+		}
+
+		// A call without overrides uses the defaults unchanged
+		if _, err := provider.GenerateMessage(context.Background(), messages); err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+		metadata, _ = requests()[1].body["metadata"].(map[string]interface{})
+		if metadata["user_id"] != "default-user" || metadata["request"] != nil {
+			t.Errorf("Overrides leaked into a later call: %v", metadata)
+		}
+	})
+
+	t.Run("GeminiSafetySettingsMerge", func(t *testing.T) {
+		server, requests := newCaptureServer(t, `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}`)
+		provider := NewGeminiProvider("test-key", "gemini-2.0-flash",
+			domain.NewBaseURLOption(server.URL),
+			domain.NewGeminiSafetySettingsOption([]map[string]interface{}{
+				{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_LOW_AND_ABOVE"},
+				{"category": "HARM_CATEGORY_HATE_SPEECH", "threshold": "BLOCK_LOW_AND_ABOVE"},
+			}))
+
+		_, err := provider.GenerateMessage(context.Background(), messages,
+			domain.WithSafetySettings([]map[string]interface{}{
+				{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_NONE"},
+			}))
+		if err != nil {
+			t.Fatalf("GenerateMessage failed: %v", err)
+		}
+
+		settings, _ := requests()[0].body["safetySettings"].([]interface{})
+		if len(settings) != 2 {
+			t.Fatalf("Expected 2 safety settings, got %v", settings)
+		}
+		first, _ := settings[0].(map[string]interface{})
+		second, _ := settings[1].(map[string]interface{})
+		if first["threshold"] != "BLOCK_NONE" || second["threshold"] != "BLOCK_LOW_AND_ABOVE" {
+			t.Errorf("Unexpected merged safety settings: %v", settings)
+		}
+		if provider.safetySettings[0]["threshold"] != "BLOCK_LOW_AND_ABOVE" {
+			t.Errorf("Provider safety settings were modified: %v", provider.safetySettings)
+		}
+	})
+
+	t.Run("ConcurrentTenants", func(t *testing.T) {
+		server, requests := newCaptureServer(t, `{"choices":[{"message":{"content":"ok"}}]}`)
+		provider := NewOpenAIProvider("test-key", "gpt-4o", domain.NewBaseURLOption(server.URL))
+
+		tenants := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+		var wg sync.WaitGroup
+		for _, tenant := range tenants {
+			wg.Add(1)
+			go func(tenant string) {
+				defer wg.Done()
+				_, err := provider.GenerateMessage(context.Background(), messages,
+					domain.WithUser(tenant), domain.WithHeaders(map[string]string{"X-Tenant": tenant}))
+				if err != nil {
+					t.Errorf("GenerateMessage failed: %v", err)
+				}
+			}(tenant)
+		}
+		wg.Wait()
+
+		got := requests()
+		if len(got) != len(tenants) {
+			t.Fatalf("Expected %d requests, got %d", len(tenants), len(got))
+		}
+		for _, req := range got {
+			if req.header.Get("X-Tenant") != req.body["user"] {
+				t.Errorf("Header %q does not match user %v", req.header.Get("X-Tenant"), req.body["user"])
+			}
+		}
+	})
+}
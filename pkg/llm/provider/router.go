@@ -0,0 +1,461 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+	modelDomain "github.com/lexlapax/go-llms/pkg/util/llmutil/modelinfo/domain"
+)
+
+// ErrNoRouteTarget is returned when no configured target can serve a request
+var ErrNoRouteTarget = domain.NewProviderError("router", "route", 0, "no target supports the request", domain.ErrInvalidConfiguration)
+
+// toolsPromptMarker is the phrase agents use to describe available tools in the system prompt
+const toolsPromptMarker = "You have access to the following tools:"
+
+// RouteTarget is a backend the router can send requests to
+type RouteTarget struct {
+	// Name identifies the target in rules, fallback chains and errors
+	Name string
+	// Provider serves the requests
+	Provider domain.Provider
+	// Model describes the capabilities, context window and pricing of the target.
+	// A target without model information (empty Model.Name) is assumed to support everything.
+	Model modelDomain.Model
+}
+
+// RequestProfile describes the content of a request for routing decisions
+type RequestProfile struct {
+	// ContentTypes lists the content types present in the request
+	ContentTypes map[domain.ContentType]bool
+	// EstimatedTokens is a rough estimate of the prompt size in tokens
+	EstimatedTokens int
+	// MaxTokens is the requested maximum number of output tokens
+	MaxTokens int
+	// HasTools reports whether the request involves tool use
+	HasTools bool
+	// Structured reports whether the request asks for schema-conforming output
+	Structured bool
+	// Streaming reports whether the request is streamed
+	Streaming bool
+}
+
+// HasContentType reports whether the request contains the content type
+func (p RequestProfile) HasContentType(contentType domain.ContentType) bool {
+	return p.ContentTypes[contentType]
+}
+
+// RoutePreference orders the targets eligible for a request
+type RoutePreference int
+
+const (
+	// PreferOrder keeps the order in which targets were configured
+	PreferOrder RoutePreference = iota
+	// PreferCheapest orders targets by input and output pricing, cheapest first
+	PreferCheapest
+	// PreferLargestContext orders targets by context window, largest first
+	PreferLargestContext
+)
+
+// RouteCondition declares when a rule applies. All non-zero fields must match.
+type RouteCondition struct {
+	// AnyContentType matches requests containing at least one of these content types
+	AnyContentType []domain.ContentType
+	// MinTokens matches requests with at least this many estimated prompt tokens
+	MinTokens int
+	// MaxTokens matches requests with at most this many estimated prompt tokens
+	MaxTokens int
+	// HasTools matches requests that involve tool use
+	HasTools bool
+	// Structured matches requests that ask for schema-conforming output
+	Structured bool
+	// Match is an optional custom predicate
+	Match func(RequestProfile) bool
+}
+
+// matches reports whether the condition holds for the profile
+func (c RouteCondition) matches(profile RequestProfile) bool {
+	if len(c.AnyContentType) > 0 {
+		found := false
+		for _, contentType := range c.AnyContentType {
+			if profile.HasContentType(contentType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.MinTokens > 0 && profile.EstimatedTokens < c.MinTokens {
+		return false
+	}
+	if c.MaxTokens > 0 && profile.EstimatedTokens > c.MaxTokens {
+		return false
+	}
+	if c.HasTools && !profile.HasTools {
+		return false
+	}
+	if c.Structured && !profile.Structured {
+		return false
+	}
+	if c.Match != nil && !c.Match(profile) {
+		return false
+	}
+	return true
+}
+
+// RouteRule selects and orders targets for requests matching its condition.
+// Rules are evaluated in order and the first matching rule is used.
+type RouteRule struct {
+	// Name identifies the rule in errors
+	Name string
+	// When declares which requests the rule applies to (the zero value matches all)
+	When RouteCondition
+	// Targets restricts the rule to these target names (empty means all targets)
+	Targets []string
+	// Prefer orders the eligible targets
+	Prefer RoutePreference
+}
+
+// DefaultRouteRules returns rules that send long prompts to large-context models
+// and everything else to the cheapest capable model
+func DefaultRouteRules(longPromptTokens int) []RouteRule {
+	return []RouteRule{
+		{Name: "long-context", When: RouteCondition{MinTokens: longPromptTokens}, Prefer: PreferLargestContext},
+		{Name: "cheapest", Prefer: PreferCheapest},
+	}
+}
+
+// RouterProvider implements domain.Provider and routes each request to a backend
+// chosen from the request content, the targets' capabilities and pricing
+type RouterProvider struct {
+	targets      []RouteTarget
+	rules        []RouteRule
+	fallback     []string
+	toolDetector func([]domain.Message) bool
+}
+
+// NewRouterProvider creates a provider that routes requests across the targets using the rules.
+// Targets that cannot handle a request (missing media capability, function calling, streaming,
+// or too small a context window) are never selected.
+func NewRouterProvider(targets []RouteTarget, rules ...RouteRule) *RouterProvider {
+	return &RouterProvider{
+		targets:      targets,
+		rules:        rules,
+		toolDetector: detectToolUse,
+	}
+}
+
+// WithFallback sets the targets tried, in order, after those selected by the matching rule
+func (r *RouterProvider) WithFallback(names ...string) *RouterProvider {
+	r.fallback = names
+	return r
+}
+
+// WithToolDetector overrides how the router decides whether a request involves tool use
+func (r *RouterProvider) WithToolDetector(detector func([]domain.Message) bool) *RouterProvider {
+	if detector != nil {
+		r.toolDetector = detector
+	}
+	return r
+}
+
+// Route returns the ordered chain of targets that would serve the messages
+func (r *RouterProvider) Route(messages []domain.Message, options ...domain.Option) ([]RouteTarget, error) {
+	return r.route(r.profile(messages, false, false, options))
+}
+
+// Generate produces text from a prompt using the routed target
+func (r *RouterProvider) Generate(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)}
+	chain, err := r.route(r.profile(messages, false, false, options))
+	if err != nil {
+		return "", err
+	}
+
+	providerErrors := make(map[string]error)
+	for _, target := range chain {
+		result, err := target.Provider.Generate(ctx, prompt, options...)
+		if err == nil {
+			return result, nil
+		}
+		providerErrors[target.Name] = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return "", NewMultiProviderError(providerErrors, ErrNoSuccessfulCalls.Error())
+}
+
+// GenerateMessage produces text from a list of messages using the routed target
+func (r *RouterProvider) GenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+	chain, err := r.route(r.profile(messages, false, false, options))
+	if err != nil {
+		return domain.Response{}, err
+	}
+
+	providerErrors := make(map[string]error)
+	for _, target := range chain {
+		response, err := target.Provider.GenerateMessage(ctx, messages, options...)
+		if err == nil {
+			return response, nil
+		}
+		providerErrors[target.Name] = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return domain.Response{}, NewMultiProviderError(providerErrors, ErrNoSuccessfulCalls.Error())
+}
+
+// GenerateWithSchema produces structured output using the routed target
+func (r *RouterProvider) GenerateWithSchema(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)}
+	chain, err := r.route(r.profile(messages, true, false, options))
+	if err != nil {
+		return nil, err
+	}
+
+	providerErrors := make(map[string]error)
+	for _, target := range chain {
+		result, err := target.Provider.GenerateWithSchema(ctx, prompt, schema, options...)
+		if err == nil {
+			return result, nil
+		}
+		providerErrors[target.Name] = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, NewMultiProviderError(providerErrors, ErrNoSuccessfulCalls.Error())
+}
+
+// Stream streams responses from the routed target.
+// Fallback applies only to errors returned when the stream is opened.
+func (r *RouterProvider) Stream(ctx context.Context, prompt string, options ...domain.Option) (domain.ResponseStream, error) {
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)}
+	chain, err := r.route(r.profile(messages, false, true, options))
+	if err != nil {
+		return nil, err
+	}
+
+	providerErrors := make(map[string]error)
+	for _, target := range chain {
+		stream, err := target.Provider.Stream(ctx, prompt, options...)
+		if err == nil {
+			return stream, nil
+		}
+		providerErrors[target.Name] = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, NewMultiProviderError(providerErrors, ErrNoSuccessfulCalls.Error())
+}
+
+// StreamMessage streams responses from the routed target.
+// Fallback applies only to errors returned when the stream is opened.
+func (r *RouterProvider) StreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
+	chain, err := r.route(r.profile(messages, false, true, options))
+	if err != nil {
+		return nil, err
+	}
+
+	providerErrors := make(map[string]error)
+	for _, target := range chain {
+		stream, err := target.Provider.StreamMessage(ctx, messages, options...)
+		if err == nil {
+			return stream, nil
+		}
+		providerErrors[target.Name] = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, NewMultiProviderError(providerErrors, ErrNoSuccessfulCalls.Error())
+}
+
+// profile inspects a request for routing
+func (r *RouterProvider) profile(messages []domain.Message, structured, streaming bool, options []domain.Option) RequestProfile {
+	opts := domain.DefaultOptions()
+	for _, option := range options {
+		option(opts)
+	}
+
+	profile := RequestProfile{
+		ContentTypes: make(map[domain.ContentType]bool),
+		MaxTokens:    opts.MaxTokens,
+		HasTools:     r.toolDetector(messages),
+		Structured:   structured,
+		Streaming:    streaming,
+	}
+
+	for _, msg := range messages {
+		for _, part := range msg.Content {
+			profile.ContentTypes[part.Type] = true
+			switch part.Type {
+			case domain.ContentTypeText:
+				// Approximately 4 characters per token for English text
+				profile.EstimatedTokens += len(part.Text)/4 + 5
+			case domain.ContentTypeImage:
+				profile.EstimatedTokens += 1000
+			default:
+				profile.EstimatedTokens += 500
+			}
+		}
+	}
+
+	return profile
+}
+
+// route returns the ordered chain of capable targets for a request profile
+func (r *RouterProvider) route(profile RequestProfile) ([]RouteTarget, error) {
+	if len(r.targets) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	var rule *RouteRule
+	for i := range r.rules {
+		if r.rules[i].When.matches(profile) {
+			rule = &r.rules[i]
+			break
+		}
+	}
+
+	var chain []RouteTarget
+	seen := make(map[string]bool)
+	add := func(target RouteTarget) {
+		if !seen[target.Name] && targetSupports(target, profile) {
+			seen[target.Name] = true
+			chain = append(chain, target)
+		}
+	}
+
+	if rule != nil {
+		candidates := r.targetsNamed(rule.Targets)
+		orderTargets(candidates, rule.Prefer)
+		for _, target := range candidates {
+			add(target)
+		}
+	}
+
+	if len(r.fallback) > 0 {
+		for _, target := range r.targetsNamed(r.fallback) {
+			add(target)
+		}
+	} else if rule == nil {
+		// Without a matching rule or explicit fallback chain, use every capable target in order
+		for _, target := range r.targets {
+			add(target)
+		}
+	}
+
+	if len(chain) == 0 {
+		if rule == nil {
+			return nil, ErrNoRouteTarget
+		}
+		message := fmt.Sprintf("no target for rule %q supports the request", rule.Name)
+		return nil, domain.NewProviderError("router", "route", 0, message, domain.ErrInvalidConfiguration)
+	}
+
+	return chain, nil
+}
+
+// targetsNamed returns the targets with the given names in that order, or all targets if names is empty
+func (r *RouterProvider) targetsNamed(names []string) []RouteTarget {
+	if len(names) == 0 {
+		targets := make([]RouteTarget, len(r.targets))
+		copy(targets, r.targets)
+		return targets
+	}
+
+	targets := make([]RouteTarget, 0, len(names))
+	for _, name := range names {
+		for _, target := range r.targets {
+			if target.Name == name {
+				targets = append(targets, target)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// orderTargets sorts targets according to the preference, keeping configuration order for ties
+func orderTargets(targets []RouteTarget, prefer RoutePreference) {
+	switch prefer {
+	case PreferCheapest:
+		sort.SliceStable(targets, func(i, j int) bool {
+			iKnown, jKnown := targets[i].Model.Name != "", targets[j].Model.Name != ""
+			if iKnown != jKnown {
+				// Targets without pricing information go last
+				return iKnown
+			}
+			return targetCost(targets[i]) < targetCost(targets[j])
+		})
+	case PreferLargestContext:
+		sort.SliceStable(targets, func(i, j int) bool {
+			return targets[i].Model.ContextWindow > targets[j].Model.ContextWindow
+		})
+	}
+}
+
+// targetCost is the combined input and output price used to compare targets
+func targetCost(target RouteTarget) float64 {
+	return target.Model.Pricing.InputPer1kTokens + target.Model.Pricing.OutputPer1kTokens
+}
+
+// targetSupports reports whether the target's capabilities cover the request
+func targetSupports(target RouteTarget, profile RequestProfile) bool {
+	model := target.Model
+	if model.Name == "" {
+		return true
+	}
+
+	capabilities := model.Capabilities
+	if profile.HasContentType(domain.ContentTypeImage) && !capabilities.Image.Read {
+		return false
+	}
+	if profile.HasContentType(domain.ContentTypeAudio) && !capabilities.Audio.Read {
+		return false
+	}
+	if profile.HasContentType(domain.ContentTypeVideo) && !capabilities.Video.Read {
+		return false
+	}
+	if profile.HasContentType(domain.ContentTypeFile) && !capabilities.File.Read {
+		return false
+	}
+	if profile.HasTools && !capabilities.FunctionCalling {
+		return false
+	}
+	if profile.Streaming && !capabilities.Streaming {
+		return false
+	}
+	if model.ContextWindow > 0 && profile.EstimatedTokens+profile.MaxTokens > model.ContextWindow {
+		return false
+	}
+	return true
+}
+
+// detectToolUse reports whether messages contain tool results or agent tool descriptions
+func detectToolUse(messages []domain.Message) bool {
+	for _, msg := range messages {
+		if msg.Role == domain.RoleTool {
+			return true
+		}
+		if msg.Role != domain.RoleSystem {
+			continue
+		}
+		for _, part := range msg.Content {
+			if part.Type == domain.ContentTypeText && strings.Contains(part.Text, toolsPromptMarker) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	modelDomain "github.com/lexlapax/go-llms/pkg/util/llmutil/modelinfo/domain"
+)
+
+func routerTestTargets() []RouteTarget {
+	return []RouteTarget{
+		{
+			Name:     "vision",
+			Provider: textMock("vision"),
+			Model: modelDomain.Model{
+				Name: "vision-model",
+				Capabilities: modelDomain.Capabilities{
+					Text:            modelDomain.MediaTypeCapability{Read: true, Write: true},
+					Image:           modelDomain.MediaTypeCapability{Read: true},
+					FunctionCalling: true,
+					Streaming:       true,
+				},
+				ContextWindow: 128000,
+				Pricing:       modelDomain.Pricing{InputPer1kTokens: 0.01, OutputPer1kTokens: 0.03},
+			},
+		},
+		{
+			Name:     "cheap",
+			Provider: textMock("cheap"),
+			Model: modelDomain.Model{
+				Name: "cheap-model",
+				Capabilities: modelDomain.Capabilities{
+					Text:      modelDomain.MediaTypeCapability{Read: true, Write: true},
+					Streaming: true,
+				},
+				ContextWindow: 16000,
+				Pricing:       modelDomain.Pricing{InputPer1kTokens: 0.0005, OutputPer1kTokens: 0.0015},
+			},
+		},
+		{
+			Name:     "long",
+			Provider: textMock("long"),
+			Model: modelDomain.Model{
+				Name: "long-model",
+				Capabilities: modelDomain.Capabilities{
+					Text:  modelDomain.MediaTypeCapability{Read: true, Write: true},
+					Audio: modelDomain.MediaTypeCapability{Read: true},
+				},
+				ContextWindow: 1000000,
+				Pricing:       modelDomain.Pricing{InputPer1kTokens: 0.002, OutputPer1kTokens: 0.006},
+			},
+		},
+	}
+}
+
+func TestRouterProvider(t *testing.T) {
+	router := NewRouterProvider(routerTestTargets(), DefaultRouteRules(50000)...)
+	ctx := context.Background()
+
+	t.Run("SimplePromptGoesToCheapest", func(t *testing.T) {
+		result, err := router.Generate(ctx, "Say hello")
+		if err != nil || result != "cheap" {
+			t.Errorf("Expected cheap target, got %q (%v)", result, err)
+		}
+	})
+
+	t.Run("ImageGoesToVisionModel", func(t *testing.T) {
+		messages := []domain.Message{domain.NewImageMessage(domain.RoleUser, []byte("png"), "image/png", "Describe this")}
+		response, err := router.GenerateMessage(ctx, messages)
+		if err != nil || response.Content != "vision" {
+			t.Errorf("Expected vision target, got %q (%v)", response.Content, err)
+		}
+	})
+
+	t.Run("AudioGoesToAudioModel", func(t *testing.T) {
+		messages := []domain.Message{domain.NewAudioMessage(domain.RoleUser, []byte("wav"), "audio/wav", "Transcribe")}
+		response, err := router.GenerateMessage(ctx, messages)
+		if err != nil || response.Content != "long" {
+			t.Errorf("Expected long target, got %q (%v)", response.Content, err)
+		}
+	})
+
+	t.Run("LongPromptGoesToLargestContext", func(t *testing.T) {
+		result, err := router.Generate(ctx, strings.Repeat("word ", 60000))
+		if err != nil || result != "long" {
+			t.Errorf("Expected long target, got %q (%v)", result, err)
+		}
+	})
+
+	t.Run("ToolsGoToFunctionCallingModel", func(t *testing.T) {
+		messages := []domain.Message{
+			domain.NewTextMessage(domain.RoleSystem, "You have access to the following tools:\n\nTool: calculator"),
+			domain.NewTextMessage(domain.RoleUser, "What is 2+2?"),
+		}
+		chain, err := router.Route(messages)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(chain) != 1 || chain[0].Name != "vision" {
+			t.Errorf("Expected only the function calling target, got %v", chain)
+		}
+	})
+
+	t.Run("FallsBackOnError", func(t *testing.T) {
+		targets := routerTestTargets()
+		targets[1].Provider = NewMockProvider().WithGenerateFunc(
+			func(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+				return "", errors.New("cheap target down")
+			})
+
+		router := NewRouterProvider(targets, RouteRule{Name: "cheap-only", Targets: []string{"cheap"}}).
+			WithFallback("long", "vision")
+		result, err := router.Generate(ctx, "Say hello")
+		if err != nil || result != "long" {
+			t.Errorf("Expected fallback to long target, got %q (%v)", result, err)
+		}
+	})
+
+	t.Run("NoCapableTarget", func(t *testing.T) {
+		messages := []domain.Message{domain.NewVideoMessage(domain.RoleUser, []byte("mp4"), "video/mp4", "Summarize")}
+		_, err := router.GenerateMessage(ctx, messages)
+		if !errors.Is(err, domain.ErrInvalidConfiguration) {
+			t.Errorf("Expected invalid configuration error, got %v", err)
+		}
+	})
+
+	t.Run("RuleConditions", func(t *testing.T) {
+		router := NewRouterProvider(routerTestTargets(),
+			RouteRule{
+				Name:    "media",
+				When:    RouteCondition{AnyContentType: []domain.ContentType{domain.ContentTypeImage}},
+				Targets: []string{"vision"},
+			},
+			RouteRule{Name: "structured", When: RouteCondition{Structured: true}, Targets: []string{"long"}},
+			RouteRule{Name: "default", Targets: []string{"cheap"}},
+		)
+
+		result, err := router.GenerateWithSchema(ctx, "Extract", extractionSchema())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result == nil {
+			t.Errorf("Expected structured result")
+		}
+
+		chain, err := router.Route([]domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")})
+		if err != nil || chain[0].Name != "cheap" {
+			t.Errorf("Expected default rule to select cheap, got %v (%v)", chain, err)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+func TestSeedAndFingerprint(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requestBody = nil
+		_ = json.Unmarshal(data, &requestBody)
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.URL.Path, "generateContent") {
+			fmt.Fprintln(w, `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}],"modelVersion":"gemini-2.0-flash-lite-001"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"system_fingerprint":"fp_44709d6fcb","choices":[{"index":0,"message":{"content":"ok"}}]}`)
+	}))
+	defer server.Close()
+
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "Hello")}
+
+	t.Run("OpenAI", func(t *testing.T) {
+		openai := NewOpenAIProvider("test-api-key", "gpt-4o-mini")
+		openai.SetBaseURL(server.URL)
+
+		response, err := openai.GenerateMessage(context.Background(), messages, domain.WithSeed(1234))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if requestBody["seed"] != float64(1234) {
+			t.Errorf("Expected seed in request, got %v", requestBody["seed"])
+		}
+		if response.SystemFingerprint != "fp_44709d6fcb" {
+			t.Errorf("Expected system fingerprint, got %q", response.SystemFingerprint)
+		}
+
+		// Seed 0 is a valid seed
+		_, _ = openai.GenerateMessage(context.Background(), messages, domain.WithSeed(0))
+		if seed, ok := requestBody["seed"]; !ok || seed != float64(0) {
+			t.Errorf("Expected seed 0 in request, got %v", requestBody)
+		}
+
+		_, _ = openai.GenerateMessage(context.Background(), messages)
+		if _, ok := requestBody["seed"]; ok {
+			t.Errorf("Expected no seed without the option")
+		}
+	})
+
+	t.Run("Gemini", func(t *testing.T) {
+		gemini := NewGeminiProvider("test-api-key", "")
+		gemini.SetBaseURL(server.URL)
+
+		response, err := gemini.GenerateMessage(context.Background(), messages, domain.WithSeed(1234))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		config := requestBody["generationConfig"].(map[string]interface{})
+		if config["seed"] != float64(1234) {
+			t.Errorf("Expected seed in generationConfig, got %v", config)
+		}
+		if response.SystemFingerprint != "gemini-2.0-flash-lite-001" {
+			t.Errorf("Expected model version as fingerprint, got %q", response.SystemFingerprint)
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+	"github.com/lexlapax/go-llms/pkg/util/metrics"
+)
+
+// Names of the stream metrics in the registry
+const (
+	MetricStreams                = "llm_streams"
+	MetricStreamsCanceled        = "llm_streams_canceled"
+	MetricStreamTimeToFirstToken = "llm_stream_time_to_first_token_seconds"
+	MetricStreamInterTokenTime   = "llm_stream_inter_token_latency_seconds"
+	MetricStreamDuration         = "llm_stream_duration_seconds"
+	MetricStreamOutputTokens     = "llm_stream_output_tokens"
+	MetricStreamTokensPerSecond  = "llm_stream_tokens_per_second"
+)
+
+// Histogram buckets of the stream metrics
+var (
+	timeToFirstTokenBuckets = []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30}
+	interTokenBuckets       = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+	tokensPerSecondBuckets  = []float64{1, 5, 10, 25, 50, 100, 200, 500}
+)
+
+// StreamStats describes a finished stream
+type StreamStats struct {
+	// TimeToFirstToken is the time from the request to the first non-empty token (zero if none arrived)
+	TimeToFirstToken time.Duration
+	// InterTokenLatency is the mean time between tokens after the first
+	InterTokenLatency time.Duration
+	// Duration is the time from the request to the end of the stream
+	Duration time.Duration
+	// OutputTokens counts the non-empty tokens. Providers stream about one token per
+	// chunk, so this is an approximation of the tokens reported by usage.
+	OutputTokens int
+	// TokensPerSecond is the generation rate after the first token
+	TokensPerSecond float64
+	// Canceled is true if the context was canceled or the stream ended without a Finished
+	// token, as failed and blocked streams do
+	Canceled bool
+}
+
+// StreamMetrics records time to first token, inter-token latency, duration and output
+// tokens of streams in a metrics registry, labelled by provider and model
+type StreamMetrics struct {
+	registry   *metrics.Registry
+	onComplete func(labels metrics.Labels, stats StreamStats)
+}
+
+// NewStreamMetrics records stream metrics in a registry, or in metrics.GetRegistry() if it is nil
+func NewStreamMetrics(registry *metrics.Registry) *StreamMetrics {
+	if registry == nil {
+		registry = metrics.GetRegistry()
+	}
+	return &StreamMetrics{registry: registry}
+}
+
+// WithOnComplete sets a function called with the statistics of each finished stream
+func (m *StreamMetrics) WithOnComplete(fn func(labels metrics.Labels, stats StreamStats)) *StreamMetrics {
+	m.onComplete = fn
+	return m
+}
+
+// Instrument forwards a stream, measuring it from now. The statistics are recorded when
+// the stream ends or the context is canceled.
+func (m *StreamMetrics) Instrument(ctx context.Context, stream domain.ResponseStream, labels metrics.Labels) domain.ResponseStream {
+	return m.InstrumentSince(ctx, time.Now(), stream, labels)
+}
+
+// InstrumentSince forwards a stream like Instrument, measuring it from the start of the request
+func (m *StreamMetrics) InstrumentSince(ctx context.Context, start time.Time, stream domain.ResponseStream, labels metrics.Labels) domain.ResponseStream {
+	interToken := m.registry.GetOrCreateLabeledHistogram(MetricStreamInterTokenTime, labels, interTokenBuckets)
+	out := make(chan domain.Token)
+
+	go func() {
+		defer close(out)
+
+		var stats StreamStats
+		var firstToken, lastToken time.Time
+		finished := false
+		defer func() {
+			stats.Canceled = ctx.Err() != nil || !finished
+			stats.Duration = time.Since(start)
+			if stats.OutputTokens > 1 {
+				stats.InterTokenLatency = lastToken.Sub(firstToken) / time.Duration(stats.OutputTokens-1)
+				if generation := lastToken.Sub(firstToken).Seconds(); generation > 0 {
+					stats.TokensPerSecond = float64(stats.OutputTokens-1) / generation
+				}
+			}
+			m.record(labels, stats)
+		}()
+
+		for token := range stream {
+			if token.Text != "" {
+				now := time.Now()
+				if stats.OutputTokens == 0 {
+					firstToken = now
+					stats.TimeToFirstToken = now.Sub(start)
+				} else {
+					interToken.ObserveDuration(now.Sub(lastToken))
+				}
+				lastToken = now
+				stats.OutputTokens++
+			}
+			finished = finished || token.Finished
+
+			select {
+			case out <- token:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// record adds the statistics of a finished stream to the registry
+func (m *StreamMetrics) record(labels metrics.Labels, stats StreamStats) {
+	m.registry.GetOrCreateLabeledCounter(MetricStreams, labels).Increment()
+	if stats.Canceled {
+		m.registry.GetOrCreateLabeledCounter(MetricStreamsCanceled, labels).Increment()
+	}
+	m.registry.GetOrCreateLabeledHistogram(MetricStreamDuration, labels, nil).ObserveDuration(stats.Duration)
+	m.registry.GetOrCreateLabeledCounter(MetricStreamOutputTokens, labels).IncrementBy(int64(stats.OutputTokens))
+	if stats.OutputTokens > 0 {
+		m.registry.GetOrCreateLabeledHistogram(MetricStreamTimeToFirstToken, labels, timeToFirstTokenBuckets).
+			ObserveDuration(stats.TimeToFirstToken)
+	}
+	if stats.TokensPerSecond > 0 {
+		m.registry.GetOrCreateLabeledHistogram(MetricStreamTokensPerSecond, labels, tokensPerSecondBuckets).
+			Observe(stats.TokensPerSecond)
+	}
+
+	if m.onComplete != nil {
+		m.onComplete(labels, stats)
+	}
+}
+
+// StreamMetricsProvider wraps a provider and records metrics for its streams, labelled
+// with the provider and the requested model. Other calls are passed through. Streams are
+// only recorded where a provider is wrapped; llmutil.ProviderPool records its own.
+type StreamMetricsProvider struct {
+	provider domain.Provider
+	metrics  *StreamMetrics
+	system   string
+	model    string
+}
+
+// NewStreamMetricsProvider wraps a provider. With nil stream metrics, streams are recorded
+// in metrics.GetRegistry().
+func NewStreamMetricsProvider(provider domain.Provider, streamMetrics *StreamMetrics) *StreamMetricsProvider {
+	if streamMetrics == nil {
+		streamMetrics = NewStreamMetrics(nil)
+	}
+	system, model := DescribeProvider(provider)
+	return &StreamMetricsProvider{
+		provider: provider,
+		metrics:  streamMetrics,
+		system:   system,
+		model:    model,
+	}
+}
+
+// Unwrap returns the wrapped provider
+func (p *StreamMetricsProvider) Unwrap() domain.Provider {
+	return p.provider
+}
+
+// labels returns the provider and model labels of a request
+func (p *StreamMetricsProvider) labels(options []domain.Option) metrics.Labels {
+	requested := &domain.ProviderOptions{}
+	for _, option := range options {
+		option(requested)
+	}
+	model := p.model
+	if requested.Model != "" {
+		model = requested.Model
+	}
+
+	labels := metrics.Labels{metrics.LabelProvider: p.system}
+	if model != "" {
+		labels[metrics.LabelModel] = model
+	}
+	return labels
+}
+
+// Generate produces text from a prompt
+func (p *StreamMetricsProvider) Generate(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+	return p.provider.Generate(ctx, prompt, options...)
+}
+
+// GenerateMessage produces text from a list of messages
+func (p *StreamMetricsProvider) GenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+	return p.provider.GenerateMessage(ctx, messages, options...)
+}
+
+// GenerateWithSchema produces structured output conforming to a schema
+func (p *StreamMetricsProvider) GenerateWithSchema(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+	return p.provider.GenerateWithSchema(ctx, prompt, schema, options...)
+}
+
+// Stream streams responses token by token, recording the stream's metrics
+func (p *StreamMetricsProvider) Stream(ctx context.Context, prompt string, options ...domain.Option) (domain.ResponseStream, error) {
+	start := time.Now()
+	stream, err := p.provider.Stream(ctx, prompt, options...)
+	if err != nil {
+		return nil, err
+	}
+	return p.metrics.InstrumentSince(ctx, start, stream, p.labels(options)), nil
+}
+
+// StreamMessage streams responses from a list of messages, recording the stream's metrics
+func (p *StreamMetricsProvider) StreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
+	start := time.Now()
+	stream, err := p.provider.StreamMessage(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	return p.metrics.InstrumentSince(ctx, start, stream, p.labels(options)), nil
+}
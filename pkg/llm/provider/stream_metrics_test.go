@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/metrics"
+)
+
+// delayedStream sends tokens after a delay each
+func delayedStream(delay time.Duration, texts ...string) domain.ResponseStream {
+	ch := make(chan domain.Token)
+	go func() {
+		defer close(ch)
+		for i, text := range texts {
+			time.Sleep(delay)
+			ch <- domain.Token{Text: text, Finished: i == len(texts)-1}
+		}
+	}()
+	return ch
+}
+
+func TestStreamMetricsProvider(t *testing.T) {
+	mock := NewMockProvider().WithStreamMessageFunc(
+		func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
+			return delayedStream(20*time.Millisecond, "Hello", ",", " world"), nil
+		})
+
+	registry := metrics.NewRegistry()
+	done := make(chan StreamStats, 1)
+	streamMetrics := NewStreamMetrics(registry).WithOnComplete(func(labels metrics.Labels, stats StreamStats) {
+		done <- stats
+	})
+	metered := NewStreamMetricsProvider(mock, streamMetrics)
+
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "Hi")}
+	stream, err := metered.StreamMessage(context.Background(), messages, domain.WithModel("mock-model"))
+	if err != nil {
+		t.Fatalf("StreamMessage failed: %v", err)
+	}
+	var content string
+	for token := range stream {
+		content += token.Text
+	}
+	if content != "Hello, world" {
+		t.Errorf("Expected the stream to be forwarded, got %q", content)
+	}
+
+	stats := <-done
+	if stats.OutputTokens != 3 || stats.Canceled {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.TimeToFirstToken < 20*time.Millisecond || stats.Duration < 60*time.Millisecond {
+		t.Errorf("Expected TTFT >= 20ms and duration >= 60ms, got %v and %v", stats.TimeToFirstToken, stats.Duration)
+	}
+	if stats.InterTokenLatency < 20*time.Millisecond || stats.TokensPerSecond <= 0 || stats.TokensPerSecond > 50 {
+		t.Errorf("Unexpected rate: %v between tokens, %.1f tokens/s", stats.InterTokenLatency, stats.TokensPerSecond)
+	}
+
+	labels := metrics.Labels{metrics.LabelProvider: "mock", metrics.LabelModel: "mock-model"}
+	if got := registry.GetOrCreateLabeledCounter(MetricStreamOutputTokens, labels).GetValue(); got != 3 {
+		t.Errorf("Expected 3 output tokens, got %d", got)
+	}
+	if got := registry.GetOrCreateLabeledHistogram(MetricStreamTimeToFirstToken, labels, nil).GetCount(); got != 1 {
+		t.Errorf("Expected 1 TTFT observation, got %d", got)
+	}
+	if got := registry.GetOrCreateLabeledHistogram(MetricStreamInterTokenTime, labels, nil).GetCount(); got != 2 {
+		t.Errorf("Expected 2 inter-token observations, got %d", got)
+	}
+}
+
+func TestStreamMetricsCanceled(t *testing.T) {
+	registry := metrics.NewRegistry()
+	done := make(chan StreamStats, 1)
+	streamMetrics := NewStreamMetrics(registry).WithOnComplete(func(labels metrics.Labels, stats StreamStats) {
+		done <- stats
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	labels := metrics.Labels{metrics.LabelProvider: "test"}
+	stream := streamMetrics.Instrument(ctx, delayedStream(time.Millisecond, "a", "b", "c"), labels)
+
+	<-stream
+	cancel()
+	stats := <-done
+	if !stats.Canceled {
+		t.Errorf("Expected a canceled stream, got %+v", stats)
+	}
+	if got := registry.GetOrCreateLabeledCounter(MetricStreamsCanceled, labels).GetValue(); got != 1 {
+		t.Errorf("Expected 1 canceled stream, got %d", got)
+	}
+}
+
+func TestStreamMetricsUnfinished(t *testing.T) {
+	registry := metrics.NewRegistry()
+	done := make(chan StreamStats, 1)
+	streamMetrics := NewStreamMetrics(registry).WithOnComplete(func(labels metrics.Labels, stats StreamStats) {
+		done <- stats
+	})
+
+	// A failed or blocked stream closes without a Finished token
+	blocked := make(chan domain.Token, 1)
+	blocked <- domain.Token{Text: "partial"}
+	close(blocked)
+
+	labels := metrics.Labels{metrics.LabelProvider: "test"}
+	for range streamMetrics.Instrument(context.Background(), blocked, labels) {
+	}
+	if stats := <-done; !stats.Canceled || stats.OutputTokens != 1 {
+		t.Errorf("Expected an unfinished stream to count as canceled, got %+v", stats)
+	}
+	if got := registry.GetOrCreateLabeledCounter(MetricStreamsCanceled, labels).GetValue(); got != 1 {
+		t.Errorf("Expected 1 canceled stream, got %d", got)
+	}
+}
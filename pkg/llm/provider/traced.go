@@ -0,0 +1,196 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+	"github.com/lexlapax/go-llms/pkg/util/tracing"
+)
+
+// TracedProvider wraps a provider and records a span for each call. Spans follow the
+// OpenTelemetry GenAI conventions ("chat gpt-4o" with gen_ai.* attributes) and become
+// children of the span in the call's context, such as an agent iteration.
+type TracedProvider struct {
+	provider domain.Provider
+	tracer   *tracing.Tracer
+	system   string
+	model    string
+}
+
+// NewTracedProvider wraps a provider. With a nil tracer, spans are recorded only when
+// the call's context carries a tracer (see tracing.ContextWithTracer).
+func NewTracedProvider(provider domain.Provider, tracer *tracing.Tracer) *TracedProvider {
+	system, model := DescribeProvider(provider)
+	return &TracedProvider{
+		provider: provider,
+		tracer:   tracer,
+		system:   system,
+		model:    model,
+	}
+}
+
+// DescribeProvider returns the GenAI system name, such as "openai", and the model of the
+// built-in providers, or "unknown" for others
+func DescribeProvider(provider domain.Provider) (string, string) {
+	switch p := provider.(type) {
+	case *OpenAIProvider:
+		return "openai", p.model
+	case *OpenAIResponsesProvider:
+		return "openai", p.model
+	case *AnthropicProvider:
+		return "anthropic", p.model
+	case *GeminiProvider:
+		return "gemini", p.model
+	case *MultiProvider:
+		return "multi", ""
+	case *MockProvider:
+		return "mock", ""
+	case *TracedProvider:
+		return p.system, p.model
+	case *StreamMetricsProvider:
+		return p.system, p.model
+	case interface{ Unwrap() domain.Provider }:
+		// Other wrappers, such as guardrails, describe the provider they wrap
+		return DescribeProvider(p.Unwrap())
+	default:
+		return "unknown", ""
+	}
+}
+
+// Unwrap returns the wrapped provider
+func (p *TracedProvider) Unwrap() domain.Provider {
+	return p.provider
+}
+
+// start starts the span of a call
+func (p *TracedProvider) start(ctx context.Context, operation string, options []domain.Option) (context.Context, *tracing.Span) {
+	// Only explicitly set options are recorded, so defaults are not mistaken for requests
+	requested := &domain.ProviderOptions{}
+	for _, option := range options {
+		option(requested)
+	}
+	model := p.model
+	if requested.Model != "" {
+		model = requested.Model
+	}
+
+	name := operation
+	if model != "" {
+		name += " " + model
+	}
+
+	var span *tracing.Span
+	if p.tracer != nil {
+		ctx, span = p.tracer.Start(ctx, name, tracing.SpanKindClient)
+	} else {
+		ctx, span = tracing.Start(ctx, name, tracing.SpanKindClient)
+	}
+
+	span.SetAttribute("gen_ai.operation.name", operation)
+	span.SetAttribute("gen_ai.system", p.system)
+	if model != "" {
+		span.SetAttribute("gen_ai.request.model", model)
+	}
+	if requested.Temperature != 0 {
+		span.SetAttribute("gen_ai.request.temperature", requested.Temperature)
+	}
+	if requested.MaxTokens != 0 {
+		span.SetAttribute("gen_ai.request.max_tokens", requested.MaxTokens)
+	}
+	if requested.TopP != 0 {
+		span.SetAttribute("gen_ai.request.top_p", requested.TopP)
+	}
+	return ctx, span
+}
+
+// Generate produces text from a prompt
+func (p *TracedProvider) Generate(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+	ctx, span := p.start(ctx, "text_completion", options)
+	defer span.End()
+
+	result, err := p.provider.Generate(ctx, prompt, options...)
+	span.RecordError(err)
+	return result, err
+}
+
+// GenerateMessage produces text from a list of messages
+func (p *TracedProvider) GenerateMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+	ctx, span := p.start(ctx, "chat", options)
+	defer span.End()
+	span.SetAttribute("gen_ai.request.messages", len(messages))
+
+	response, err := p.provider.GenerateMessage(ctx, messages, options...)
+	if err != nil {
+		span.RecordError(err)
+		return response, err
+	}
+	if response.ID != "" {
+		span.SetAttribute("gen_ai.response.id", response.ID)
+	}
+	if len(response.Choices) > 0 {
+		reasons := make([]string, len(response.Choices))
+		for i, choice := range response.Choices {
+			reasons[i] = choice.FinishReason
+		}
+		span.SetAttribute("gen_ai.response.finish_reasons", reasons)
+	}
+	return response, nil
+}
+
+// GenerateWithSchema produces structured output conforming to a schema
+func (p *TracedProvider) GenerateWithSchema(ctx context.Context, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+	ctx, span := p.start(ctx, "text_completion", options)
+	defer span.End()
+	span.SetAttribute("gen_ai.output.type", "json")
+
+	result, err := p.provider.GenerateWithSchema(ctx, prompt, schema, options...)
+	span.RecordError(err)
+	return result, err
+}
+
+// Stream streams responses token by token. The span ends when the stream does.
+func (p *TracedProvider) Stream(ctx context.Context, prompt string, options ...domain.Option) (domain.ResponseStream, error) {
+	ctx, span := p.start(ctx, "text_completion", options)
+	stream, err := p.provider.Stream(ctx, prompt, options...)
+	return p.traceStream(ctx, span, stream, err)
+}
+
+// StreamMessage streams responses from a list of messages. The span ends when the stream does.
+func (p *TracedProvider) StreamMessage(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.ResponseStream, error) {
+	ctx, span := p.start(ctx, "chat", options)
+	span.SetAttribute("gen_ai.request.messages", len(messages))
+	stream, err := p.provider.StreamMessage(ctx, messages, options...)
+	return p.traceStream(ctx, span, stream, err)
+}
+
+// traceStream forwards a stream, ending the span once it is drained
+func (p *TracedProvider) traceStream(ctx context.Context, span *tracing.Span, stream domain.ResponseStream, err error) (domain.ResponseStream, error) {
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	if span == nil {
+		return stream, nil
+	}
+
+	out := make(chan domain.Token)
+	go func() {
+		defer close(out)
+		defer span.End()
+		chunks := 0
+		for token := range stream {
+			chunks++
+			select {
+			case out <- token:
+			case <-ctx.Done():
+				span.RecordError(ctx.Err())
+				span.SetAttribute("gen_ai.response.chunks", chunks)
+				return
+			}
+		}
+		span.SetAttribute("gen_ai.response.chunks", chunks)
+	}()
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/util/tracing"
+)
+
+// spanRecorder keeps exported spans in memory
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) ExportSpans(ctx context.Context, spans []tracing.SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *spanRecorder) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestTracedProvider(t *testing.T) {
+	server, _ := newCaptureServer(t, `{"id":"chatcmpl-1","choices":[{"index":0,"message":{"content":"Hi"},"finish_reason":"stop"}]}`)
+	recorder := &spanRecorder{}
+	tracer := tracing.NewTracer("test", recorder)
+	traced := NewTracedProvider(NewOpenAIProvider("test-key", "gpt-4o", domain.NewBaseURLOption(server.URL)), tracer)
+
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "Hello")}
+	if _, err := traced.GenerateMessage(context.Background(), messages, domain.WithMaxTokens(20)); err != nil {
+		t.Fatalf("GenerateMessage failed: %v", err)
+	}
+
+	stream, err := NewTracedProvider(NewMockProvider(), tracer).Stream(context.Background(), "Hello")
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	for range stream {
+	}
+
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(recorder.spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(recorder.spans))
+	}
+
+	chat := recorder.spans[0]
+	if chat.Name != "chat gpt-4o" || chat.Kind != tracing.SpanKindClient {
+		t.Errorf("Unexpected span: %s (%d)", chat.Name, chat.Kind)
+	}
+	if chat.Attributes["gen_ai.system"] != "openai" || chat.Attributes["gen_ai.request.max_tokens"] != 20 {
+		t.Errorf("Unexpected attributes: %v", chat.Attributes)
+	}
+	if _, ok := chat.Attributes["gen_ai.request.temperature"]; ok {
+		t.Errorf("Default temperature should not be recorded")
+	}
+
+	if streamed := recorder.spans[1]; streamed.Name != "text_completion" || streamed.Attributes["gen_ai.response.chunks"] == 0 {
+		t.Errorf("Unexpected stream span: %+v", streamed)
+	}
+}
@@ -0,0 +1,74 @@
+package processor
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/lexlapax/go-llms/pkg/llm/prompts"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+	"github.com/lexlapax/go-llms/pkg/structured/domain"
+)
+
+//go:embed schema_prompt.md
+var schemaPromptMarkdown []byte
+
+// SchemaPromptTemplate returns the wording of PromptEnhancer as a prompt template, as a
+// starting point for custom wording. It is rendered with the variables prompt, schema,
+// schema_json and options.
+func SchemaPromptTemplate() *prompts.Template {
+	t, err := prompts.ParseMarkdown(schemaPromptMarkdown)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in schema prompt template: %v", err))
+	}
+	return t
+}
+
+// TemplatePromptEnhancer adds schema information to prompts with a prompt template, so the
+// wording can be versioned and changed without code changes
+type TemplatePromptEnhancer struct {
+	template *prompts.Template
+}
+
+// NewTemplatePromptEnhancer creates an enhancer rendering the given template, or
+// SchemaPromptTemplate if it is nil. Templates that declare variables receive only the
+// variables they declare.
+func NewTemplatePromptEnhancer(template *prompts.Template) domain.PromptEnhancer {
+	if template == nil {
+		template = SchemaPromptTemplate()
+	}
+	return &TemplatePromptEnhancer{template: template}
+}
+
+// Enhance adds schema information to a prompt
+func (p *TemplatePromptEnhancer) Enhance(prompt string, schema *schemaDomain.Schema) (string, error) {
+	return p.EnhanceWithOptions(prompt, schema, nil)
+}
+
+// EnhanceWithOptions adds schema information to a prompt, passing the options, such as
+// instructions, format and examples, to the template
+func (p *TemplatePromptEnhancer) EnhanceWithOptions(prompt string, schema *schemaDomain.Schema, options map[string]interface{}) (string, error) {
+	schemaJSON, err := getSchemaJSON(schema)
+	if err != nil {
+		return "", err
+	}
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+
+	vars := prompts.Vars{
+		"prompt":      prompt,
+		"schema":      schema,
+		"schema_json": string(schemaJSON),
+		"options":     options,
+	}
+	if len(p.template.Variables) > 0 {
+		declared := prompts.Vars{}
+		for _, variable := range p.template.Variables {
+			if value, ok := vars[variable.Name]; ok {
+				declared[variable.Name] = value
+			}
+		}
+		vars = declared
+	}
+	return p.template.Render(vars)
+}
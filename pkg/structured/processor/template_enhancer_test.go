@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/llm/prompts"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+)
+
+func TestTemplatePromptEnhancer(t *testing.T) {
+	schema := &schemaDomain.Schema{
+		Type: "object",
+		Properties: map[string]schemaDomain.Property{
+			"mood": {Type: "string", Description: "Overall mood", Enum: []string{"happy", "sad"}},
+		},
+		Required: []string{"mood"},
+	}
+
+	t.Run("default template matches PromptEnhancer", func(t *testing.T) {
+		expected, err := NewPromptEnhancer().Enhance("Describe the mood", schema)
+		if err != nil {
+			t.Fatalf("Enhance failed: %v", err)
+		}
+		enhanced, err := NewTemplatePromptEnhancer(nil).Enhance("Describe the mood", schema)
+		if err != nil {
+			t.Fatalf("Enhance failed: %v", err)
+		}
+		if strings.TrimSpace(enhanced) != strings.TrimSpace(expected) {
+			t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, enhanced)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		enhanced, err := NewTemplatePromptEnhancer(nil).EnhanceWithOptions("Describe the mood", schema, map[string]interface{}{
+			"instructions": "Be brief",
+			"examples":     []map[string]interface{}{{"mood": "happy"}},
+		})
+		if err != nil {
+			t.Fatalf("EnhanceWithOptions failed: %v", err)
+		}
+		for _, expected := range []string{"Additional instructions: Be brief", "examples of valid responses", `{"mood":"happy"}`} {
+			if !strings.Contains(enhanced, expected) {
+				t.Errorf("Expected %q in:\n%s", expected, enhanced)
+			}
+		}
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		custom := prompts.NewTemplate("terse", prompts.System("{{.prompt}}\nReply with JSON matching: {{.schema_json}}")).
+			WithVariable(prompts.Variable{Name: "prompt", Type: prompts.VarString}).
+			WithVariable(prompts.Variable{Name: "schema_json", Type: prompts.VarString})
+		enhanced, err := NewTemplatePromptEnhancer(custom).Enhance("Describe the mood", schema)
+		if err != nil {
+			t.Fatalf("Enhance failed: %v", err)
+		}
+		if !strings.HasPrefix(enhanced, "Describe the mood\nReply with JSON matching: {") {
+			t.Errorf("Unexpected prompt:\n%s", enhanced)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+// Package httplog logs HTTP requests and responses with log/slog, redacting API keys,
+// authentication headers and configurable patterns in message content.
+package httplog
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces redacted values
+const Redacted = "[REDACTED]"
+
+// Common patterns for redacting personal data in message content
+var (
+	// EmailPattern matches email addresses
+	EmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// CardNumberPattern matches payment card numbers of 13 to 19 digits, optionally grouped with spaces or dashes
+	CardNumberPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	// PhonePattern matches international and North American phone numbers
+	PhonePattern = regexp.MustCompile(`\+?\b\d{1,3}?[ .-]?\(?\d{3}\)?[ .-]?\d{3}[ .-]?\d{4}\b`)
+)
+
+// NamedPatterns are the built-in content patterns by name, for configuration and flags
+var NamedPatterns = map[string]*regexp.Regexp{
+	"email": EmailPattern,
+	"card":  CardNumberPattern,
+	"phone": PhonePattern,
+}
+
+// ParsePatterns turns pattern names ("email", "card", "phone") and regular expressions into patterns
+func ParsePatterns(specs []string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(specs))
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if pattern, ok := NamedPatterns[strings.ToLower(spec)]; ok {
+			patterns = append(patterns, pattern)
+			continue
+		}
+		pattern, err := regexp.Compile(spec)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// secretPatterns match API keys that are redacted wherever they appear
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_-]{16,}`), // OpenAI and Anthropic keys
+	regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`), // Google API keys
+}
+
+// defaultSecretHeaders are the headers that carry credentials
+var defaultSecretHeaders = []string{
+	"Authorization", "Proxy-Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key", "Cookie", "Set-Cookie",
+}
+
+// defaultSecretQueryParams are the query parameters that carry credentials, such as Gemini's API key
+var defaultSecretQueryParams = []string{"key", "api_key", "access_token"}
+
+// Redactor removes secrets from headers, URLs and bodies. Credential headers, credential
+// query parameters and well-known API key formats are always redacted; message content
+// is redacted only for the patterns added with WithPattern.
+type Redactor struct {
+	headers     map[string]bool
+	queryParams map[string]bool
+	secrets     []string
+	patterns    []*regexp.Regexp
+}
+
+// NewRedactor creates a redactor for credentials, with the given content patterns
+func NewRedactor(patterns ...*regexp.Regexp) *Redactor {
+	r := &Redactor{
+		headers:     make(map[string]bool),
+		queryParams: make(map[string]bool),
+	}
+	r.WithHeader(defaultSecretHeaders...)
+	r.WithQueryParam(defaultSecretQueryParams...)
+	return r.WithPattern(patterns...)
+}
+
+// WithHeader adds headers whose values are redacted
+func (r *Redactor) WithHeader(names ...string) *Redactor {
+	for _, name := range names {
+		r.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	return r
+}
+
+// WithQueryParam adds query parameters whose values are redacted
+func (r *Redactor) WithQueryParam(names ...string) *Redactor {
+	for _, name := range names {
+		r.queryParams[name] = true
+	}
+	return r
+}
+
+// WithSecret adds values that are redacted wherever they appear, such as an API key
+func (r *Redactor) WithSecret(secrets ...string) *Redactor {
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+	return r
+}
+
+// WithPattern adds patterns redacted in bodies, such as EmailPattern
+func (r *Redactor) WithPattern(patterns ...*regexp.Regexp) *Redactor {
+	for _, pattern := range patterns {
+		if pattern != nil {
+			r.patterns = append(r.patterns, pattern)
+		}
+	}
+	return r
+}
+
+// Clone returns a copy of the redactor that can be extended independently
+func (r *Redactor) Clone() *Redactor {
+	clone := &Redactor{
+		headers:     make(map[string]bool, len(r.headers)),
+		queryParams: make(map[string]bool, len(r.queryParams)),
+		secrets:     append([]string(nil), r.secrets...),
+		patterns:    append([]*regexp.Regexp(nil), r.patterns...),
+	}
+	for name := range r.headers {
+		clone.headers[name] = true
+	}
+	for name := range r.queryParams {
+		clone.queryParams[name] = true
+	}
+	return clone
+}
+
+// RedactHeaders returns the headers as name-value pairs sorted by name, with
+// credentials redacted and multiple values joined by commas
+func (r *Redactor) RedactHeaders(header http.Header) [][2]string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([][2]string, 0, len(names))
+	for _, name := range names {
+		value := Redacted
+		if !r.headers[http.CanonicalHeaderKey(name)] {
+			value = r.redactSecrets(strings.Join(header[name], ", "))
+		}
+		pairs = append(pairs, [2]string{name, value})
+	}
+	return pairs
+}
+
+// RedactURL returns the URL with credential query parameters and secrets redacted
+func (r *Redactor) RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User(redacted.User.Username())
+	}
+	if redacted.RawQuery != "" {
+		query := redacted.Query()
+		for name := range query {
+			if r.queryParams[name] {
+				query.Set(name, Redacted)
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+	return r.redactSecrets(redacted.String())
+}
+
+// RedactBody returns the body with secrets and content patterns redacted
+func (r *Redactor) RedactBody(body string) string {
+	body = r.redactSecrets(body)
+	for _, pattern := range r.patterns {
+		body = pattern.ReplaceAllString(body, Redacted)
+	}
+	return body
+}
+
+// redactSecrets replaces API keys and configured secret values
+func (r *Redactor) redactSecrets(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
@@ -0,0 +1,241 @@
+package httplog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBodySize is the number of body bytes logged before truncation
+const DefaultMaxBodySize = 64 * 1024
+
+// redactionMargin is how many response bytes are captured past the maximum body size, so that
+// a credential crossing the cut is redacted whole before the body is truncated
+const redactionMargin = 1024
+
+// Transport is an http.RoundTripper that logs requests and responses. Responses are
+// logged when their body has been read or closed, so streamed responses are logged
+// once the stream ends.
+type Transport struct {
+	base        http.RoundTripper
+	logger      *slog.Logger
+	redactor    *Redactor
+	level       slog.Level
+	maxBodySize int
+}
+
+// NewTransport logs the requests of a base transport (http.DefaultTransport if nil) to
+// a logger (slog.Default() if nil) at debug level
+func NewTransport(base http.RoundTripper, logger *slog.Logger) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Transport{
+		base:        base,
+		logger:      logger,
+		redactor:    NewRedactor(),
+		level:       slog.LevelDebug,
+		maxBodySize: DefaultMaxBodySize,
+	}
+}
+
+// WithRedactor sets the redactor, which defaults to redacting credentials only
+func (t *Transport) WithRedactor(redactor *Redactor) *Transport {
+	if redactor != nil {
+		t.redactor = redactor
+	}
+	return t
+}
+
+// WithLevel sets the level requests are logged at
+func (t *Transport) WithLevel(level slog.Level) *Transport {
+	t.level = level
+	return t
+}
+
+// WithMaxBodySize sets how many bytes of each body are logged; zero or less omits bodies
+func (t *Transport) WithMaxBodySize(size int) *Transport {
+	t.maxBodySize = size
+	return t
+}
+
+// WrapClient returns a copy of a client (http.DefaultClient if nil) whose requests are logged.
+// A client already wrapped is wrapped again around its original transport.
+func WrapClient(client *http.Client, logger *slog.Logger, redactor *Redactor) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	wrapped := *client
+	base := client.Transport
+	if existing, ok := base.(*Transport); ok {
+		base = existing.base
+	}
+	wrapped.Transport = NewTransport(base, logger).WithRedactor(redactor)
+	return &wrapped
+}
+
+// RoundTrip logs the request, sends it and logs the response
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if !t.logger.Enabled(ctx, t.level) {
+		return t.base.RoundTrip(req)
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", t.redactor.RedactURL(req.URL)),
+		t.headerAttr(req.Header),
+	}
+	if req.Body != nil && req.Body != http.NoBody && t.maxBodySize > 0 {
+		body, restored, err := t.readRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		req = restored
+		attrs = append(attrs, slog.String("body", t.formatBody(req.Header, body, len(body))))
+	}
+	t.logger.LogAttrs(ctx, t.level, "http request", attrs...)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.logger.LogAttrs(ctx, t.level, "http request failed",
+			slog.String("method", req.Method),
+			slog.String("url", t.redactor.RedactURL(req.URL)),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("error", t.redactor.RedactBody(err.Error())),
+		)
+		return nil, err
+	}
+
+	resp.Body = &loggingBody{
+		ReadCloser: resp.Body,
+		transport:  t,
+		ctx:        ctx,
+		resp:       resp,
+		url:        t.redactor.RedactURL(req.URL),
+		start:      start,
+	}
+	return resp, nil
+}
+
+// readRequestBody reads the request body, returning a request whose body can still be sent
+func (t *Transport) readRequestBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			defer body.Close()
+			data, err := io.ReadAll(body)
+			return data, req, err
+		}
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	restored := req.Clone(req.Context())
+	restored.Body = io.NopCloser(bytes.NewReader(data))
+	restored.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return data, restored, nil
+}
+
+// headerAttr groups the redacted headers
+func (t *Transport) headerAttr(header http.Header) slog.Attr {
+	pairs := t.redactor.RedactHeaders(header)
+	attrs := make([]any, 0, len(pairs))
+	for _, pair := range pairs {
+		attrs = append(attrs, slog.String(pair[0], pair[1]))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// formatBody returns the redacted body, truncated to the maximum size. The body is redacted
+// before it is truncated, so a credential crossing the cut is not partly logged. Binary bodies,
+// such as uploaded files, are summarized by size and content type.
+func (t *Transport) formatBody(header http.Header, body []byte, size int) string {
+	contentType := header.Get("Content-Type")
+	if !isTextual(contentType) {
+		return "<" + strconv.Itoa(size) + " bytes of " + contentType + ">"
+	}
+	redacted := t.redactor.RedactBody(string(body))
+	if len(redacted) <= t.maxBodySize && size <= len(body) {
+		return redacted
+	}
+	if len(redacted) > t.maxBodySize {
+		redacted = redacted[:t.maxBodySize]
+	}
+	return redacted + "... (" + strconv.Itoa(size) + " bytes)"
+}
+
+// isTextual reports whether a content type is logged as text
+func isTextual(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	contentType = strings.ToLower(contentType)
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml") ||
+		strings.Contains(contentType, "x-www-form-urlencoded")
+}
+
+// loggingBody captures a response body as it is read and logs the response at EOF or close
+type loggingBody struct {
+	io.ReadCloser
+	transport *Transport
+	ctx       context.Context
+	resp      *http.Response
+	url       string
+	start     time.Time
+
+	captured bytes.Buffer
+	size     int
+	once     sync.Once
+}
+
+func (b *loggingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+	if remaining := b.transport.maxBodySize + redactionMargin - b.captured.Len(); remaining > 0 && n > 0 {
+		b.captured.Write(p[:min(n, remaining)])
+	}
+	if err == io.EOF {
+		b.log()
+	}
+	return n, err
+}
+
+func (b *loggingBody) Close() error {
+	b.log()
+	return b.ReadCloser.Close()
+}
+
+// log logs the response once
+func (b *loggingBody) log() {
+	b.once.Do(func() {
+		t := b.transport
+		attrs := []slog.Attr{
+			slog.Int("status", b.resp.StatusCode),
+			slog.String("url", b.url),
+			slog.Duration("duration", time.Since(b.start)),
+			t.headerAttr(b.resp.Header),
+		}
+		if t.maxBodySize > 0 {
+			attrs = append(attrs, slog.String("body", t.formatBody(b.resp.Header, b.captured.Bytes(), b.size)))
+		}
+		t.logger.LogAttrs(b.ctx, t.level, "http response", attrs...)
+	})
+}
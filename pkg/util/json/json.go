@@ -8,6 +8,9 @@ import (
 	jsoniter "github.com/json-iterator/go"
 )
 
+// RawMessage is a raw encoded JSON value, used to delay decoding
+type RawMessage = jsoniter.RawMessage
+
 var (
 	// Global configuration that matches standard library behavior
 	jsonAPI = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -2,11 +2,13 @@
 package llmutil
 
 import (
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/llm/provider"
 )
 
 // Environment variable constants for common provider options
@@ -22,10 +24,11 @@ const (
 	EnvGeminiUseCase    = "GEMINI_USE_CASE"    // Use case for Gemini
 
 	// OpenAI options
-	EnvOpenAIOrganization = "OPENAI_ORGANIZATION" // Organization ID for OpenAI
-	EnvOpenAIModel        = "OPENAI_MODEL"        // Model to use for OpenAI
-	EnvOpenAIBaseURL      = "OPENAI_BASE_URL"     // Base URL for OpenAI API
-	EnvOpenAIAPIKey       = "OPENAI_API_KEY"      // API key for OpenAI
+	EnvOpenAIOrganization  = "OPENAI_ORGANIZATION"  // Organization ID for OpenAI
+	EnvOpenAIModel         = "OPENAI_MODEL"         // Model to use for OpenAI
+	EnvOpenAIBaseURL       = "OPENAI_BASE_URL"      // Base URL for OpenAI API
+	EnvOpenAIAPIKey        = "OPENAI_API_KEY"       // API key for OpenAI
+	EnvOpenAICompatibility = "OPENAI_COMPATIBILITY" // Compatibility profile for OpenAI-compatible servers (groq, vllm, ...)
 
 	// Anthropic options
 	EnvAnthropicSystemPrompt = "ANTHROPIC_SYSTEM_PROMPT" // System prompt for Anthropic
@@ -93,6 +96,16 @@ func GetOpenAIOptionsFromEnv() []domain.ProviderOption {
 		options = append(options, domain.NewOpenAIOrganizationOption(org))
 	}
 
+	// Compatibility profile option; unknown profiles are reported rather than silently ignored
+	if profile := os.Getenv(EnvOpenAICompatibility); profile != "" {
+		if _, ok := provider.LookupOpenAICompatibilityProfile(profile); ok {
+			options = append(options, domain.NewOpenAICompatibilityOption(profile))
+		} else {
+			slog.Warn("ignoring unknown OpenAI compatibility profile", "env", EnvOpenAICompatibility,
+				"profile", profile, "available", strings.Join(provider.OpenAICompatibilityProfileNames(), ", "))
+		}
+	}
+
 	// Logit bias is more complex and would typically come from a JSON string in an env var
 	// We'll skip it for now as it's less common
 
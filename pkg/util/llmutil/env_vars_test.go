@@ -274,6 +274,7 @@ func TestGetProviderOptionsFromEnv(t *testing.T) {
 	origHTTPTimeout := os.Getenv(EnvHTTPTimeout)
 	origAnthropicSystemPrompt := os.Getenv(EnvAnthropicSystemPrompt)
 	origOpenAIOrganization := os.Getenv(EnvOpenAIOrganization)
+	origOpenAICompatibility := os.Getenv(EnvOpenAICompatibility)
 
 	// Clean up environment after test
 	defer func() {
@@ -283,6 +284,7 @@ func TestGetProviderOptionsFromEnv(t *testing.T) {
 		os.Setenv(EnvHTTPTimeout, origHTTPTimeout)
 		os.Setenv(EnvAnthropicSystemPrompt, origAnthropicSystemPrompt)
 		os.Setenv(EnvOpenAIOrganization, origOpenAIOrganization)
+		os.Setenv(EnvOpenAICompatibility, origOpenAICompatibility)
 	}()
 
 	tests := []struct {
@@ -314,6 +316,23 @@ func TestGetProviderOptionsFromEnv(t *testing.T) {
 			},
 			expectedCount: 2,
 		},
+		{
+			name:     "OpenAI Compatibility Profile",
+			provider: "openai",
+			envVars: map[string]string{
+				EnvOpenAIBaseURL:       "https://api.groq.com/openai",
+				EnvOpenAICompatibility: "groq",
+			},
+			expectedCount: 2,
+		},
+		{
+			name:     "OpenAI Unknown Compatibility Profile",
+			provider: "openai",
+			envVars: map[string]string{
+				EnvOpenAICompatibility: "grok",
+			},
+			expectedCount: 0, // Unknown profiles are reported and skipped
+		},
 		{
 			name:     "Anthropic Base URL and System Prompt",
 			provider: "anthropic",
@@ -355,6 +374,7 @@ func TestGetProviderOptionsFromEnv(t *testing.T) {
 			clearEnvVars := []string{
 				EnvOpenAIBaseURL, EnvAnthropicBaseURL, EnvGeminiBaseURL,
 				EnvHTTPTimeout, EnvAnthropicSystemPrompt, EnvOpenAIOrganization,
+				EnvOpenAICompatibility,
 			}
 			for _, v := range clearEnvVars {
 				os.Unsetenv(v)
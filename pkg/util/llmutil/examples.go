@@ -0,0 +1,448 @@
+package llmutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+)
+
+// Example is an input with the output expected for it. Outputs that are not strings, such as
+// structs for schema-typed generation, are rendered as JSON.
+type Example struct {
+	Input  string
+	Output interface{}
+}
+
+// OutputText returns the output as rendered in prompts
+func (e Example) OutputText() string {
+	switch v := e.Output.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
+
+// SelectionStrategy is how an ExampleSelector ranks examples
+type SelectionStrategy int
+
+const (
+	// SelectLexical ranks examples by TF-IDF similarity of their inputs to the input
+	SelectLexical SelectionStrategy = iota
+	// SelectEmbedding ranks examples by cosine similarity of embeddings, falling back to
+	// lexical similarity without an embedder
+	SelectEmbedding
+	// SelectMMR picks relevant examples that differ from each other (max marginal relevance),
+	// using embeddings when available and lexical similarity otherwise
+	SelectMMR
+)
+
+// Embedder turns texts into embedding vectors, for example by calling an embeddings API
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// EmbedderFunc adapts a function to an Embedder
+type EmbedderFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Embed calls the function
+func (f EmbedderFunc) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	return f(ctx, texts)
+}
+
+// ExampleSelector picks the few-shot examples most relevant to each input from a pool,
+// within a token budget. It is safe for concurrent use; embedder calls are made without
+// holding its lock.
+type ExampleSelector struct {
+	mu         sync.Mutex
+	examples   []Example
+	k          int
+	strategy   SelectionStrategy
+	embedder   Embedder
+	lambda     float64
+	maxTokens  int
+	countToken func(string) int
+
+	// Lazily computed representations of the example inputs
+	embeddings [][]float64
+	termVecs   []map[string]float64
+	idf        map[string]float64
+
+	// generation changes with the pool or embedder, so stale embeddings are not cached
+	generation int
+}
+
+// NewExampleSelector creates a selector picking 3 examples by lexical similarity
+func NewExampleSelector(examples ...Example) *ExampleSelector {
+	return &ExampleSelector{
+		examples:   examples,
+		k:          3,
+		strategy:   SelectLexical,
+		lambda:     0.5,
+		countToken: estimateTokens,
+	}
+}
+
+// WithK sets the maximum number of examples selected
+func (s *ExampleSelector) WithK(k int) *ExampleSelector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.k = k
+	return s
+}
+
+// WithStrategy sets how examples are ranked
+func (s *ExampleSelector) WithStrategy(strategy SelectionStrategy) *ExampleSelector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.strategy = strategy
+	return s
+}
+
+// WithEmbedder sets the embedder used by SelectEmbedding and SelectMMR
+func (s *ExampleSelector) WithEmbedder(embedder Embedder) *ExampleSelector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embedder = embedder
+	s.embeddings = nil
+	s.generation++
+	return s
+}
+
+// WithMMRLambda sets the trade-off of SelectMMR between relevance (1) and diversity (0)
+func (s *ExampleSelector) WithMMRLambda(lambda float64) *ExampleSelector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lambda = lambda
+	return s
+}
+
+// WithTokenBudget limits the estimated tokens of the selected examples; 0 means no limit
+func (s *ExampleSelector) WithTokenBudget(maxTokens int) *ExampleSelector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxTokens = maxTokens
+	return s
+}
+
+// WithTokenCounter replaces the default estimate of about 4 characters per token
+func (s *ExampleSelector) WithTokenCounter(count func(string) int) *ExampleSelector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.countToken = count
+	return s
+}
+
+// Add adds examples to the pool
+func (s *ExampleSelector) Add(examples ...Example) *ExampleSelector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.examples = append(s.examples, examples...)
+	s.embeddings = nil
+	s.generation++
+	s.termVecs = nil
+	s.idf = nil
+	return s
+}
+
+// Select returns up to k examples for an input, most relevant first. Examples that would
+// exceed the token budget are skipped in favour of less relevant ones that fit.
+func (s *ExampleSelector) Select(ctx context.Context, input string) ([]Example, error) {
+	s.mu.Lock()
+	if len(s.examples) == 0 || s.k <= 0 {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	if s.embedder == nil || s.strategy == SelectLexical {
+		defer s.mu.Unlock()
+		relevance, similarity := s.lexicalSimilarities(input)
+		return s.rank(s.examples, relevance, similarity), nil
+	}
+
+	// Embed without holding the lock, which other selections and the setters need
+	examples, embedder, embeddings, generation := s.examples, s.embedder, s.embeddings, s.generation
+	s.mu.Unlock()
+
+	relevance, embeddings, err := embeddingSimilarities(ctx, embedder, examples, embeddings, input)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation == generation {
+		s.embeddings = embeddings
+	}
+	return s.rank(examples, relevance, func(i, j int) float64 { return cosineDense(embeddings[i], embeddings[j]) }), nil
+}
+
+// rank orders examples by relevance, or by MMR, and takes up to k within the token budget
+func (s *ExampleSelector) rank(examples []Example, relevance []float64, similarity func(i, j int) float64) []Example {
+	var ranked []int
+	if s.strategy == SelectMMR {
+		ranked = s.rankMMR(relevance, similarity)
+	} else {
+		ranked = make([]int, len(examples))
+		for i := range ranked {
+			ranked[i] = i
+		}
+		sort.SliceStable(ranked, func(a, b int) bool {
+			return relevance[ranked[a]] > relevance[ranked[b]]
+		})
+	}
+
+	selected := make([]Example, 0, s.k)
+	used := 0
+	for _, i := range ranked {
+		if len(selected) == s.k {
+			break
+		}
+		tokens := s.exampleTokens(examples[i])
+		if s.maxTokens > 0 && used+tokens > s.maxTokens {
+			continue
+		}
+		used += tokens
+		selected = append(selected, examples[i])
+	}
+	return selected
+}
+
+// exampleTokens estimates the tokens an example adds to a prompt
+func (s *ExampleSelector) exampleTokens(example Example) int {
+	return s.countToken(example.Input) + s.countToken(example.OutputText())
+}
+
+// estimateTokens approximates 4 characters per token, plus formatting overhead
+func estimateTokens(text string) int {
+	return len(text)/4 + 5
+}
+
+// embeddingSimilarities returns the relevance of each example to the input by cosine
+// similarity of embeddings, and the example embeddings, computed if not given
+func embeddingSimilarities(ctx context.Context, embedder Embedder, examples []Example, embeddings [][]float64, input string) ([]float64, [][]float64, error) {
+	if embeddings == nil {
+		inputs := make([]string, len(examples))
+		for i, example := range examples {
+			inputs[i] = example.Input
+		}
+		var err error
+		embeddings, err = embedder.Embed(ctx, inputs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to embed examples: %w", err)
+		}
+		if len(embeddings) != len(inputs) {
+			return nil, nil, fmt.Errorf("embedder returned %d embeddings for %d examples", len(embeddings), len(inputs))
+		}
+	}
+	query, err := embedder.Embed(ctx, []string{input})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to embed input: %w", err)
+	}
+	if len(query) != 1 {
+		return nil, nil, fmt.Errorf("embedder returned %d embeddings for 1 input", len(query))
+	}
+
+	relevance := make([]float64, len(embeddings))
+	for i, embedding := range embeddings {
+		relevance[i] = cosineDense(query[0], embedding)
+	}
+	return relevance, embeddings, nil
+}
+
+// lexicalSimilarities returns the TF-IDF relevance of each example to the input and a
+// function for the similarity between two examples
+func (s *ExampleSelector) lexicalSimilarities(input string) ([]float64, func(i, j int) float64) {
+	if s.termVecs == nil {
+		s.buildTermVectors()
+	}
+	termVecs := s.termVecs
+	query := s.termVector(input)
+	relevance := make([]float64, len(termVecs))
+	for i, vec := range termVecs {
+		relevance[i] = cosineSparse(query, vec)
+	}
+	return relevance, func(i, j int) float64 { return cosineSparse(termVecs[i], termVecs[j]) }
+}
+
+// rankMMR orders examples by max marginal relevance: each pick maximises
+// lambda*relevance - (1-lambda)*(similarity to the closest example already picked)
+func (s *ExampleSelector) rankMMR(relevance []float64, similarity func(i, j int) float64) []int {
+	remaining := make([]int, len(relevance))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	ranked := make([]int, 0, len(relevance))
+	for len(remaining) > 0 {
+		best, bestScore := 0, math.Inf(-1)
+		for r, i := range remaining {
+			redundancy := 0.0
+			for _, j := range ranked {
+				redundancy = math.Max(redundancy, similarity(i, j))
+			}
+			score := s.lambda*relevance[i] - (1-s.lambda)*redundancy
+			if score > bestScore {
+				best, bestScore = r, score
+			}
+		}
+		ranked = append(ranked, remaining[best])
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+	return ranked
+}
+
+// buildTermVectors computes the IDF of the pool's terms and the TF-IDF vector of each input
+func (s *ExampleSelector) buildTermVectors() {
+	docFreq := make(map[string]int)
+	for _, example := range s.examples {
+		seen := make(map[string]bool)
+		for _, term := range terms(example.Input) {
+			if !seen[term] {
+				seen[term] = true
+				docFreq[term]++
+			}
+		}
+	}
+
+	// Smoothed IDF, so terms in every example still count a little
+	s.idf = make(map[string]float64, len(docFreq))
+	n := float64(len(s.examples))
+	for term, df := range docFreq {
+		s.idf[term] = math.Log((1+n)/(1+float64(df))) + 1
+	}
+
+	s.termVecs = make([]map[string]float64, len(s.examples))
+	for i, example := range s.examples {
+		s.termVecs[i] = s.termVector(example.Input)
+	}
+}
+
+// termVector returns the TF-IDF vector of a text; terms unknown to the pool are ignored
+func (s *ExampleSelector) termVector(text string) map[string]float64 {
+	vec := make(map[string]float64)
+	for _, term := range terms(text) {
+		if idf, ok := s.idf[term]; ok {
+			vec[term] += idf
+		}
+	}
+	return vec
+}
+
+// terms splits text into lowercase words
+func terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func cosineSparse(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for term, weight := range a {
+		dot += weight * b[term]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+func cosineDense(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// FormatExamples renders examples as input/output pairs for a prompt
+func FormatExamples(examples []Example) string {
+	var b strings.Builder
+	b.WriteString("Here are examples of inputs and the expected outputs:\n")
+	for _, example := range examples {
+		b.WriteString("\nInput: ")
+		b.WriteString(example.Input)
+		b.WriteString("\nOutput: ")
+		b.WriteString(example.OutputText())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ExampleMessages renders examples as user/assistant message pairs
+func ExampleMessages(examples []Example) []domain.Message {
+	messages := make([]domain.Message, 0, len(examples)*2)
+	for _, example := range examples {
+		messages = append(messages,
+			domain.NewTextMessage(domain.RoleUser, example.Input),
+			domain.NewTextMessage(domain.RoleAssistant, example.OutputText()),
+		)
+	}
+	return messages
+}
+
+// EnhancePrompt puts the examples selected for a prompt before it. The result can be used
+// for free-text generation or passed to GenerateWithSchema.
+func (s *ExampleSelector) EnhancePrompt(ctx context.Context, prompt string) (string, error) {
+	examples, err := s.Select(ctx, prompt)
+	if err != nil || len(examples) == 0 {
+		return prompt, err
+	}
+	return FormatExamples(examples) + "\nInput: " + prompt + "\nOutput:", nil
+}
+
+// Messages returns the examples selected for an input as user/assistant pairs, followed by
+// the input as a user message
+func (s *ExampleSelector) Messages(ctx context.Context, input string) ([]domain.Message, error) {
+	examples, err := s.Select(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return append(ExampleMessages(examples), domain.NewTextMessage(domain.RoleUser, input)), nil
+}
+
+// GenerateWithExamples generates a response to a prompt, preceded by the selected examples as
+// message pairs
+func GenerateWithExamples(ctx context.Context, provider domain.Provider, selector *ExampleSelector, prompt string, options ...domain.Option) (string, error) {
+	messages, err := selector.Messages(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	response, err := provider.GenerateMessage(ctx, messages, options...)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// GenerateWithSchemaAndExamples generates structured output for a prompt enhanced with the
+// selected examples, whose outputs should conform to the schema
+func GenerateWithSchemaAndExamples(ctx context.Context, provider domain.Provider, selector *ExampleSelector, prompt string, schema *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+	enhanced, err := selector.EnhancePrompt(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return provider.GenerateWithSchema(ctx, enhanced, schema, options...)
+}
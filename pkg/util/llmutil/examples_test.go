@@ -0,0 +1,288 @@
+package llmutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/llm/provider"
+	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+)
+
+var examplePool = []Example{
+	{Input: "Translate 'good morning' to French", Output: "Bonjour"},
+	{Input: "Translate 'thank you' to French", Output: "Merci"},
+	{Input: "What is the capital of France?", Output: "Paris"},
+	{Input: "What is the capital of Japan?", Output: "Tokyo"},
+	{Input: "Sum 2 and 3", Output: map[string]int{"result": 5}},
+}
+
+func inputs(examples []Example) []string {
+	result := make([]string, len(examples))
+	for i, example := range examples {
+		result[i] = example.Input
+	}
+	return result
+}
+
+func TestExampleSelectorLexical(t *testing.T) {
+	selector := NewExampleSelector(examplePool...).WithK(2)
+
+	selected, err := selector.Select(context.Background(), "What is the capital of Italy?")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if len(selected) != 2 {
+		t.Fatalf("Expected 2 examples, got %d", len(selected))
+	}
+	for _, example := range selected {
+		if !strings.Contains(example.Input, "capital") {
+			t.Errorf("Expected capital examples, got %q", example.Input)
+		}
+	}
+
+	selected, _ = selector.Select(context.Background(), "Translate 'good night' to French")
+	if selected[0].Input != "Translate 'good morning' to French" {
+		t.Errorf("Expected the closest translation first, got %q", selected[0].Input)
+	}
+}
+
+func TestExampleSelectorEmptyPool(t *testing.T) {
+	selected, err := NewExampleSelector().Select(context.Background(), "anything")
+	if err != nil || len(selected) != 0 {
+		t.Errorf("Expected no examples and no error, got %v, %v", selected, err)
+	}
+}
+
+func TestExampleSelectorTokenBudget(t *testing.T) {
+	long := Example{Input: "capital city question " + strings.Repeat("padding ", 100), Output: "long"}
+	selector := NewExampleSelector(append([]Example{long}, examplePool...)...).
+		WithK(3).
+		WithTokenBudget(40)
+
+	selected, err := selector.Select(context.Background(), "capital city question")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	total := 0
+	for _, example := range selected {
+		if example.Input == long.Input {
+			t.Error("Expected the example over budget to be skipped")
+		}
+		total += selector.exampleTokens(example)
+	}
+	if total > 40 {
+		t.Errorf("Expected at most 40 tokens, got %d", total)
+	}
+	if len(selected) == 0 {
+		t.Error("Expected smaller examples to fill the budget")
+	}
+}
+
+// axisEmbedder embeds texts on two axes: greetings and geography
+var axisEmbedder = EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		text = strings.ToLower(text)
+		vec := []float64{0.01, 0.01}
+		if strings.Contains(text, "french") || strings.Contains(text, "hello") {
+			vec[0] = 1
+		}
+		if strings.Contains(text, "capital") || strings.Contains(text, "city") {
+			vec[1] = 1
+		}
+		embeddings[i] = vec
+	}
+	return embeddings, nil
+})
+
+func TestExampleSelectorEmbedding(t *testing.T) {
+	calls := 0
+	embedder := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		calls++
+		return axisEmbedder(ctx, texts)
+	})
+	selector := NewExampleSelector(examplePool...).
+		WithStrategy(SelectEmbedding).
+		WithEmbedder(embedder).
+		WithK(2)
+
+	// No words in common with the pool, so only embeddings can find the matches
+	selected, err := selector.Select(context.Background(), "Say hello")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	for _, example := range selected {
+		if !strings.Contains(example.Input, "French") {
+			t.Errorf("Expected French examples, got %v", inputs(selected))
+		}
+	}
+
+	if _, err := selector.Select(context.Background(), "Which city?"); err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected the pool to be embedded once (3 calls), got %d calls", calls)
+	}
+
+	failing := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		return nil, errors.New("embeddings unavailable")
+	})
+	selector = NewExampleSelector(examplePool...).WithStrategy(SelectEmbedding).WithEmbedder(failing)
+	if _, err := selector.Select(context.Background(), "Say hello"); err == nil {
+		t.Error("Expected embedder errors to be returned")
+	}
+}
+
+func TestExampleSelectorConcurrency(t *testing.T) {
+	// The embedder blocks until the selector is reconfigured, which needs its lock
+	configured := make(chan struct{})
+	embedder := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		select {
+		case <-configured:
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("embedder called with the selector locked")
+		}
+		return axisEmbedder(ctx, texts)
+	})
+	selector := NewExampleSelector(examplePool...).WithStrategy(SelectEmbedding).WithEmbedder(embedder)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := selector.Select(context.Background(), "Say hello")
+		done <- err
+	}()
+	selector.WithK(1)
+	close(configured)
+	if err := <-done; err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if selected, err := selector.Select(context.Background(), "Which city?"); err != nil || len(selected) == 0 {
+				t.Errorf("Select failed: %v, %v", selected, err)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			selector.WithK(i%3 + 1).WithMMRLambda(0.5).WithTokenBudget(0)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestExampleSelectorMMR(t *testing.T) {
+	pool := []Example{
+		{Input: "capital of France", Output: "Paris"},
+		{Input: "capital of France please", Output: "Paris"},
+		{Input: "capital of Japan", Output: "Tokyo"},
+	}
+
+	relevant, _ := NewExampleSelector(pool...).WithK(2).Select(context.Background(), "capital of France")
+	if relevant[1].Input != "capital of France please" {
+		t.Fatalf("Expected lexical selection to pick the near duplicate, got %v", inputs(relevant))
+	}
+
+	diverse, err := NewExampleSelector(pool...).
+		WithStrategy(SelectMMR).
+		WithMMRLambda(0.3).
+		WithK(2).
+		Select(context.Background(), "capital of France")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if diverse[0].Input != "capital of France" || diverse[1].Input != "capital of Japan" {
+		t.Errorf("Expected MMR to skip the near duplicate, got %v", inputs(diverse))
+	}
+}
+
+func TestExampleRendering(t *testing.T) {
+	examples := []Example{
+		{Input: "Sum 2 and 3", Output: map[string]int{"result": 5}},
+		{Input: "Capital of Japan?", Output: "Tokyo"},
+	}
+
+	text := FormatExamples(examples)
+	if !strings.Contains(text, "Input: Sum 2 and 3\nOutput: {\"result\":5}") {
+		t.Errorf("Expected structured outputs rendered as JSON, got %q", text)
+	}
+
+	messages := ExampleMessages(examples)
+	if len(messages) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(messages))
+	}
+	if messages[0].Role != domain.RoleUser || messages[1].Role != domain.RoleAssistant {
+		t.Errorf("Expected user/assistant pairs, got %s/%s", messages[0].Role, messages[1].Role)
+	}
+	if messages[3].Content[0].Text != "Tokyo" {
+		t.Errorf("Expected assistant output 'Tokyo', got %q", messages[3].Content[0].Text)
+	}
+}
+
+func TestGenerateWithExamples(t *testing.T) {
+	selector := NewExampleSelector(examplePool...).WithK(1)
+
+	var received []domain.Message
+	mockProvider := provider.NewMockProvider().WithGenerateMessageFunc(
+		func(ctx context.Context, messages []domain.Message, options ...domain.Option) (domain.Response, error) {
+			received = messages
+			return domain.Response{Content: "Rome"}, nil
+		})
+
+	result, err := GenerateWithExamples(context.Background(), mockProvider, selector, "What is the capital of Italy?")
+	if err != nil {
+		t.Fatalf("GenerateWithExamples failed: %v", err)
+	}
+	if result != "Rome" {
+		t.Errorf("Expected 'Rome', got %q", result)
+	}
+	if len(received) != 3 {
+		t.Fatalf("Expected an example pair and the prompt, got %d messages", len(received))
+	}
+	if !strings.Contains(received[0].Content[0].Text, "capital") {
+		t.Errorf("Expected a capital example, got %q", received[0].Content[0].Text)
+	}
+	if received[2].Content[0].Text != "What is the capital of Italy?" {
+		t.Errorf("Expected the prompt last, got %q", received[2].Content[0].Text)
+	}
+}
+
+func TestGenerateWithSchemaAndExamples(t *testing.T) {
+	selector := NewExampleSelector(examplePool...).WithK(1)
+	schema := &schemaDomain.Schema{
+		Type:       "object",
+		Properties: map[string]schemaDomain.Property{"result": {Type: "integer"}},
+	}
+
+	var receivedPrompt string
+	mockProvider := provider.NewMockProvider().WithGenerateWithSchemaFunc(
+		func(ctx context.Context, prompt string, s *schemaDomain.Schema, options ...domain.Option) (interface{}, error) {
+			receivedPrompt = prompt
+			if s != schema {
+				t.Error("Expected the schema to be passed through")
+			}
+			return map[string]interface{}{"result": 9}, nil
+		})
+
+	result, err := GenerateWithSchemaAndExamples(context.Background(), mockProvider, selector, "Sum 4 and 5", schema)
+	if err != nil {
+		t.Fatalf("GenerateWithSchemaAndExamples failed: %v", err)
+	}
+	if result.(map[string]interface{})["result"] != 9 {
+		t.Errorf("Expected the provider's result, got %v", result)
+	}
+	if !strings.Contains(receivedPrompt, "Output: {\"result\":5}") {
+		t.Errorf("Expected the sum example in the prompt, got %q", receivedPrompt)
+	}
+	if !strings.HasSuffix(receivedPrompt, "Input: Sum 4 and 5\nOutput:") {
+		t.Errorf("Expected the prompt last, got %q", receivedPrompt)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/lexlapax/go-llms/pkg/llm/domain"
@@ -24,8 +25,19 @@ type ModelConfig struct {
 	MaxTokens int                     // Optional max tokens override
 	Options   []domain.ProviderOption // Optional provider-specific options
 	UseCase   string                  // Optional use case identifier (default, performance, reliability, streaming)
+	Transport string                  // Optional OpenAI transport: TransportChatCompletions (default) or TransportResponses
+	// Optional compatibility profile for OpenAI-compatible servers (see provider.OpenAICompatibilityProfileNames)
+	Compatibility string
 }
 
+// OpenAI transports selectable with ModelConfig.Transport
+const (
+	// TransportChatCompletions uses the chat completions API (/v1/chat/completions)
+	TransportChatCompletions = "chat"
+	// TransportResponses uses the Responses API (/v1/responses)
+	TransportResponses = "responses"
+)
+
 // WithProviderOptions creates provider-specific options for initialization
 func WithProviderOptions(config ModelConfig) ([]domain.ProviderOption, error) {
 	var interfaceOptions []domain.ProviderOption
@@ -40,6 +52,11 @@ func WithProviderOptions(config ModelConfig) ([]domain.ProviderOption, error) {
 		interfaceOptions = append(interfaceOptions, config.Options...)
 	}
 
+	// Add compatibility profile option if specified
+	if config.Compatibility != "" && config.Provider == "openai" {
+		interfaceOptions = append(interfaceOptions, domain.NewOpenAICompatibilityOption(config.Compatibility))
+	}
+
 	// Add base URL option if specified
 	if config.BaseURL != "" {
 		// Only add interface options for valid providers
@@ -71,6 +88,14 @@ func CreateProvider(config ModelConfig) (domain.Provider, error) {
 
 	var llmProvider domain.Provider
 
+	// Reject unknown compatibility profiles rather than silently ignoring them
+	if config.Compatibility != "" {
+		if _, ok := provider.LookupOpenAICompatibilityProfile(config.Compatibility); !ok {
+			return nil, fmt.Errorf("unknown OpenAI compatibility profile: %s (available: %s)",
+				config.Compatibility, strings.Join(provider.OpenAICompatibilityProfileNames(), ", "))
+		}
+	}
+
 	// Get options from configuration
 	options, err := WithProviderOptions(config)
 	if err != nil {
@@ -83,7 +108,14 @@ func CreateProvider(config ModelConfig) (domain.Provider, error) {
 
 	switch config.Provider {
 	case "openai":
-		llmProvider = provider.NewOpenAIProvider(config.APIKey, config.Model, options...)
+		switch config.Transport {
+		case "", TransportChatCompletions:
+			llmProvider = provider.NewOpenAIProvider(config.APIKey, config.Model, options...)
+		case TransportResponses:
+			llmProvider = provider.NewOpenAIResponsesProvider(config.APIKey, config.Model, options...)
+		default:
+			return nil, fmt.Errorf("unsupported OpenAI transport: %s", config.Transport)
+		}
 
 	case "anthropic":
 		llmProvider = provider.NewAnthropicProvider(config.APIKey, config.Model, options...)
@@ -121,6 +121,49 @@ func TestCreateProvider(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "OpenAI with Responses transport",
+			config: ModelConfig{
+				Provider:  "openai",
+				Model:     "gpt-4o",
+				APIKey:    "test-api-key",
+				Transport: TransportResponses,
+			},
+			expectError: false,
+		},
+		{
+			name: "Unsupported OpenAI transport",
+			config: ModelConfig{
+				Provider:  "openai",
+				Model:     "gpt-4o",
+				APIKey:    "test-api-key",
+				Transport: "websocket",
+			},
+			expectError:   true,
+			expectedError: "unsupported OpenAI transport",
+		},
+		{
+			name: "OpenAI with compatibility profile",
+			config: ModelConfig{
+				Provider:      "openai",
+				Model:         "llama-3.3-70b-versatile",
+				APIKey:        "test-api-key",
+				BaseURL:       "https://api.groq.com/openai",
+				Compatibility: "groq",
+			},
+			expectError: false,
+		},
+		{
+			name: "Unknown compatibility profile",
+			config: ModelConfig{
+				Provider:      "openai",
+				Model:         "gpt-4o",
+				APIKey:        "test-api-key",
+				Compatibility: "acme",
+			},
+			expectError:   true,
+			expectedError: "unknown OpenAI compatibility profile",
+		},
 		{
 			name: "Missing model with fallback from env",
 			config: ModelConfig{
@@ -2,14 +2,33 @@ package llmutil
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/lexlapax/go-llms/pkg/llm/domain"
+	"github.com/lexlapax/go-llms/pkg/llm/provider"
 	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+	utilMetrics "github.com/lexlapax/go-llms/pkg/util/metrics"
 )
 
+// Default settings for adaptive load balancing
+const (
+	defaultEWMAAlpha              = 0.3
+	defaultEjectionThreshold      = 4
+	defaultEjectionDuration       = 30 * time.Second
+	maxEjectionDurationMultiplier = 10
+
+	// fastestMaxConsecutiveErrors is how many consecutive errors StrategyFastest tolerates
+	// before it skips a provider, even with outlier ejection disabled
+	fastestMaxConsecutiveErrors = 3
+)
+
+// ErrPoolSaturated is returned when every provider in the pool is at its concurrency cap
+var ErrPoolSaturated = domain.NewProviderError("pool", "select", 0, "all providers are at their concurrency limit", domain.ErrProviderUnavailable)
+
 // ProviderPool is a pool of LLM providers for load balancing and fallback
 type ProviderPool struct {
 	providers   []domain.Provider
@@ -17,20 +36,37 @@ type ProviderPool struct {
 	metrics     map[int]*ProviderMetrics
 	mu          sync.RWMutex
 	activeIndex int
+
+	// Adaptive load balancing settings
+	ewmaAlpha         float64
+	ejectionThreshold int
+	ejectionDuration  time.Duration
+	staleAfter        time.Duration
+	weights           []float64
+	rand              *rand.Rand
+
+	// streamMetrics records the pool's streams in the metrics registry
+	streamMetrics *provider.StreamMetrics
 }
 
 // PoolStrategy defines how the provider pool selects a provider
 type PoolStrategy int
 
 const (
-	// StrategyRoundRobin cycles through providers
+	// StrategyRoundRobin cycles through providers, skipping ejected ones
 	StrategyRoundRobin PoolStrategy = iota
 
 	// StrategyFailover uses the first provider until it fails, then moves to the next
 	StrategyFailover
 
-	// StrategyFastest uses the provider with the lowest latency
+	// StrategyFastest uses the provider with the lowest EWMA latency
 	StrategyFastest
+
+	// StrategyLeastOutstanding uses the provider with the fewest in-flight requests
+	StrategyLeastOutstanding
+
+	// StrategyWeightedRandom picks a provider at random, in proportion to its weight
+	StrategyWeightedRandom
 )
 
 // ProviderMetrics tracks performance metrics for a provider
@@ -41,6 +77,27 @@ type ProviderMetrics struct {
 	TotalLatencyMs    int64
 	LastUsed          time.Time
 	ConsecutiveErrors int
+
+	// EWMALatencyMs is the exponentially weighted moving average of successful latencies.
+	// For streams, latency is the time to the first token.
+	EWMALatencyMs float64
+	// Outstanding is the number of in-flight requests, including open streams
+	Outstanding int
+	// MaxConcurrency is the cap on in-flight requests (0 means unlimited)
+	MaxConcurrency int
+	// Ejections counts how many times the provider was ejected as an outlier
+	Ejections int
+	// EjectedUntil is the time until which the provider is skipped (zero if not ejected)
+	EjectedUntil time.Time
+	// P50LatencyMs, P90LatencyMs and P99LatencyMs are streaming estimates of the
+	// latency percentiles of successful requests
+	P50LatencyMs float64
+	P90LatencyMs float64
+	P99LatencyMs float64
+
+	latencies *utilMetrics.Histogram
+	// exported is the provider's series in the metrics registry, shared by pools
+	exported *utilMetrics.Histogram
 }
 
 // NewProviderPool creates a new provider pool
@@ -48,16 +105,104 @@ func NewProviderPool(providers []domain.Provider, strategy PoolStrategy) *Provid
 	metrics := make(map[int]*ProviderMetrics)
 	for i := range providers {
 		metrics[i] = &ProviderMetrics{
-			LastUsed: time.Now(),
+			LastUsed:  time.Now(),
+			latencies: utilMetrics.NewHistogram("provider_latency_seconds", nil),
+		}
+	}
+
+	pool := &ProviderPool{
+		providers:         providers,
+		strategy:          strategy,
+		metrics:           metrics,
+		activeIndex:       0,
+		ewmaAlpha:         defaultEWMAAlpha,
+		ejectionThreshold: defaultEjectionThreshold,
+		ejectionDuration:  defaultEjectionDuration,
+		rand:              rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	return pool.WithMetricsRegistry(utilMetrics.GetRegistry())
+}
+
+// WithMetricsRegistry sets the registry the latencies are exported to, as the
+// provider_latency_seconds histogram labelled by provider and model, along with the
+// stream metrics of provider.StreamMetrics. Pools of the same providers share these
+// series. It defaults to metrics.GetRegistry(); nil stops exporting.
+func (p *ProviderPool) WithMetricsRegistry(registry *utilMetrics.Registry) *ProviderPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamMetrics = nil
+	if registry != nil {
+		p.streamMetrics = provider.NewStreamMetrics(registry)
+	}
+	for i, m := range p.metrics {
+		m.exported = nil
+		if registry != nil {
+			m.exported = registry.GetOrCreateLabeledHistogram("provider_latency_seconds", providerLabels(p.providers[i]), nil)
 		}
 	}
+	return p
+}
+
+// providerLabels labels a provider's metrics with its system and model
+func providerLabels(p domain.Provider) utilMetrics.Labels {
+	system, model := provider.DescribeProvider(p)
+	labels := utilMetrics.Labels{utilMetrics.LabelProvider: system}
+	if model != "" {
+		labels[utilMetrics.LabelModel] = model
+	}
+	return labels
+}
+
+// WithEWMAAlpha sets the smoothing factor (0.0-1.0) for EWMA latency; higher values favor recent requests
+func (p *ProviderPool) WithEWMAAlpha(alpha float64) *ProviderPool {
+	if alpha > 0 && alpha <= 1 {
+		p.ewmaAlpha = alpha
+	}
+	return p
+}
+
+// WithMaxConcurrency caps the number of in-flight requests for every provider (0 means unlimited)
+func (p *ProviderPool) WithMaxConcurrency(limit int) *ProviderPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, m := range p.metrics {
+		m.MaxConcurrency = limit
+	}
+	return p
+}
+
+// WithProviderConcurrency caps the number of in-flight requests for one provider (0 means unlimited)
+func (p *ProviderPool) WithProviderConcurrency(idx int, limit int) *ProviderPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	return &ProviderPool{
-		providers:   providers,
-		strategy:    strategy,
-		metrics:     metrics,
-		activeIndex: 0,
+	if m, ok := p.metrics[idx]; ok {
+		m.MaxConcurrency = limit
 	}
+	return p
+}
+
+// WithOutlierEjection ejects a provider after the given number of consecutive errors.
+// The ejection lasts baseDuration, doubling on each repeated ejection up to ten times baseDuration.
+// A threshold of 0 disables ejection.
+func (p *ProviderPool) WithOutlierEjection(consecutiveErrors int, baseDuration time.Duration) *ProviderPool {
+	p.ejectionThreshold = consecutiveErrors
+	p.ejectionDuration = baseDuration
+	return p
+}
+
+// WithMetricsDecay forgets latency and error history for providers unused for longer than staleAfter,
+// so they are probed again instead of being judged on old data. Zero disables decay.
+func (p *ProviderPool) WithMetricsDecay(staleAfter time.Duration) *ProviderPool {
+	p.staleAfter = staleAfter
+	return p
+}
+
+// WithWeights sets the provider weights used by StrategyWeightedRandom (missing weights default to 1.0)
+func (p *ProviderPool) WithWeights(weights ...float64) *ProviderPool {
+	p.weights = weights
+	return p
 }
 
 // Generate implements the Provider interface for the pool
@@ -200,11 +345,10 @@ func (p *ProviderPool) Stream(ctx context.Context, prompt string, options ...dom
 
 	startTime := time.Now()
 	result, err := provider.Stream(ctx, prompt, options...)
-	duration := time.Since(startTime)
-
-	p.updateMetrics(idx, err, duration)
 
 	if err != nil {
+		p.updateMetrics(idx, err, time.Since(startTime))
+
 		// If the selected provider fails, try to find another one
 		if p.strategy == StrategyFailover {
 			fallbackIdx, fallbackProvider, fallbackErr := p.getFallbackProvider(idx)
@@ -214,21 +358,19 @@ func (p *ProviderPool) Stream(ctx context.Context, prompt string, options ...dom
 
 			fallbackStartTime := time.Now()
 			fallbackResult, fallbackErr := fallbackProvider.Stream(ctx, prompt, options...)
-			fallbackDuration := time.Since(fallbackStartTime)
-
-			p.updateMetrics(fallbackIdx, fallbackErr, fallbackDuration)
 
 			if fallbackErr != nil {
+				p.updateMetrics(fallbackIdx, fallbackErr, time.Since(fallbackStartTime))
 				return nil, err // Return original error if fallback also fails
 			}
 
-			return fallbackResult, nil
+			return p.trackStream(ctx, fallbackIdx, fallbackStartTime, fallbackResult), nil
 		}
 
 		return nil, err
 	}
 
-	return result, nil
+	return p.trackStream(ctx, idx, startTime, result), nil
 }
 
 // StreamMessage implements the Provider interface for the pool
@@ -240,11 +382,10 @@ func (p *ProviderPool) StreamMessage(ctx context.Context, messages []domain.Mess
 
 	startTime := time.Now()
 	result, err := provider.StreamMessage(ctx, messages, options...)
-	duration := time.Since(startTime)
-
-	p.updateMetrics(idx, err, duration)
 
 	if err != nil {
+		p.updateMetrics(idx, err, time.Since(startTime))
+
 		// If the selected provider fails, try to find another one
 		if p.strategy == StrategyFailover {
 			fallbackIdx, fallbackProvider, fallbackErr := p.getFallbackProvider(idx)
@@ -254,24 +395,23 @@ func (p *ProviderPool) StreamMessage(ctx context.Context, messages []domain.Mess
 
 			fallbackStartTime := time.Now()
 			fallbackResult, fallbackErr := fallbackProvider.StreamMessage(ctx, messages, options...)
-			fallbackDuration := time.Since(fallbackStartTime)
-
-			p.updateMetrics(fallbackIdx, fallbackErr, fallbackDuration)
 
 			if fallbackErr != nil {
+				p.updateMetrics(fallbackIdx, fallbackErr, time.Since(fallbackStartTime))
 				return nil, err // Return original error if fallback also fails
 			}
 
-			return fallbackResult, nil
+			return p.trackStream(ctx, fallbackIdx, fallbackStartTime, fallbackResult), nil
 		}
 
 		return nil, err
 	}
 
-	return result, nil
+	return p.trackStream(ctx, idx, startTime, result), nil
 }
 
-// getProvider selects a provider based on the strategy
+// getProvider selects a provider based on the strategy and reserves a concurrency slot for it.
+// The slot is released by updateMetrics.
 func (p *ProviderPool) getProvider() (int, domain.Provider, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -280,81 +420,306 @@ func (p *ProviderPool) getProvider() (int, domain.Provider, error) {
 		return -1, nil, fmt.Errorf("no providers available")
 	}
 
+	now := time.Now()
+	p.decayStaleMetrics(now)
+
+	// Prefer healthy providers with spare capacity; if every provider with capacity
+	// is ejected, fall back to the one whose ejection ends first
+	var candidates, ejected []int
+	for i := range p.providers {
+		m := p.metrics[i]
+		if m.saturated() {
+			continue
+		}
+		if now.Before(m.EjectedUntil) {
+			ejected = append(ejected, i)
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+
+	if len(candidates) == 0 {
+		if len(ejected) == 0 {
+			return -1, nil, ErrPoolSaturated
+		}
+		soonest := ejected[0]
+		for _, i := range ejected[1:] {
+			if p.metrics[i].EjectedUntil.Before(p.metrics[soonest].EjectedUntil) {
+				soonest = i
+			}
+		}
+		candidates = []int{soonest}
+	}
+
+	idx := p.selectCandidate(candidates)
+	p.metrics[idx].Outstanding++
+	return idx, p.providers[idx], nil
+}
+
+// saturated reports whether the provider is at its concurrency cap
+func (m *ProviderMetrics) saturated() bool {
+	return m.MaxConcurrency > 0 && m.Outstanding >= m.MaxConcurrency
+}
+
+// selectCandidate applies the strategy to the available provider indices (in ascending order).
+// Must be called with the lock held.
+func (p *ProviderPool) selectCandidate(candidates []int) int {
 	switch p.strategy {
 	case StrategyRoundRobin:
-		idx := p.activeIndex
-		p.activeIndex = (p.activeIndex + 1) % len(p.providers)
-		return idx, p.providers[idx], nil
+		// Take the first available provider at or after the active index
+		idx := candidates[0]
+		for _, i := range candidates {
+			if i >= p.activeIndex {
+				idx = i
+				break
+			}
+		}
+		p.activeIndex = (idx + 1) % len(p.providers)
+		return idx
 
 	case StrategyFailover:
-		return p.activeIndex, p.providers[p.activeIndex], nil
+		for _, i := range candidates {
+			if i == p.activeIndex {
+				return i
+			}
+		}
+		return candidates[0]
 
 	case StrategyFastest:
-		var fastestIdx int
-		var fastestLatency int64 = -1
+		// Skip providers that keep failing, unless all of them do
+		var healthy []int
+		for _, i := range candidates {
+			if p.metrics[i].ConsecutiveErrors <= fastestMaxConsecutiveErrors {
+				healthy = append(healthy, i)
+			}
+		}
+		if len(healthy) > 0 {
+			candidates = healthy
+		}
 
-		for i, metrics := range p.metrics {
-			// Skip providers with consecutive errors
-			if metrics.ConsecutiveErrors > 3 {
-				continue
+		// Providers without latency data are tried first so they get measured
+		idx := candidates[0]
+		for _, i := range candidates[1:] {
+			latency, best := p.metrics[i].EWMALatencyMs, p.metrics[idx].EWMALatencyMs
+			if best > 0 && (latency == 0 || latency < best) {
+				idx = i
+			}
+		}
+		return idx
+
+	case StrategyLeastOutstanding:
+		// Break ties on outstanding requests by EWMA latency
+		idx := candidates[0]
+		for _, i := range candidates[1:] {
+			m, best := p.metrics[i], p.metrics[idx]
+			if m.Outstanding < best.Outstanding ||
+				(m.Outstanding == best.Outstanding && m.EWMALatencyMs < best.EWMALatencyMs) {
+				idx = i
 			}
+		}
+		return idx
 
-			if fastestLatency == -1 || (metrics.AvgLatencyMs > 0 && metrics.AvgLatencyMs < fastestLatency) {
-				fastestLatency = metrics.AvgLatencyMs
-				fastestIdx = i
+	case StrategyWeightedRandom:
+		total := 0.0
+		for _, i := range candidates {
+			total += p.weight(i)
+		}
+		if total <= 0 {
+			return candidates[p.rand.Intn(len(candidates))]
+		}
+		target := p.rand.Float64() * total
+		for _, i := range candidates {
+			target -= p.weight(i)
+			if target < 0 {
+				return i
 			}
 		}
+		return candidates[len(candidates)-1]
 
-		// If all providers have consecutive errors, use the first one
-		if fastestLatency == -1 {
-			return 0, p.providers[0], nil
+	default:
+		return candidates[0]
+	}
+}
+
+// weight returns the configured weight of a provider for StrategyWeightedRandom
+func (p *ProviderPool) weight(idx int) float64 {
+	if idx < len(p.weights) {
+		if p.weights[idx] < 0 {
+			return 0
 		}
+		return p.weights[idx]
+	}
+	return 1.0
+}
 
-		return fastestIdx, p.providers[fastestIdx], nil
+// decayStaleMetrics forgets latency and error history for providers that have not been used recently.
+// Must be called with the lock held.
+func (p *ProviderPool) decayStaleMetrics(now time.Time) {
+	if p.staleAfter <= 0 {
+		return
+	}
+	for _, m := range p.metrics {
+		if m.Outstanding == 0 && now.Sub(m.LastUsed) > p.staleAfter {
+			m.EWMALatencyMs = 0
+			m.ConsecutiveErrors = 0
+			m.resetLatencies()
+		}
+	}
+}
 
-	default:
-		return 0, p.providers[0], nil
+// trackStream forwards a stream, recording time to first token as latency and
+// releasing the provider's concurrency slot when the stream ends. The stream metrics
+// are recorded as the caller reads the stream.
+func (p *ProviderPool) trackStream(ctx context.Context, idx int, startTime time.Time, stream domain.ResponseStream) domain.ResponseStream {
+	p.mu.RLock()
+	streamMetrics := p.streamMetrics
+	p.mu.RUnlock()
+
+	out := make(chan domain.Token)
+
+	go func() {
+		defer close(out)
+
+		var firstToken time.Duration
+		defer func() {
+			// A stream the caller abandoned says nothing about the provider
+			if ctx.Err() != nil {
+				p.releaseSlot(idx)
+				return
+			}
+			if firstToken == 0 {
+				firstToken = time.Since(startTime)
+			}
+			p.updateMetrics(idx, nil, firstToken)
+		}()
+
+		for token := range stream {
+			if firstToken == 0 {
+				firstToken = time.Since(startTime)
+			}
+			select {
+			case out <- token:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if streamMetrics == nil {
+		return out
 	}
+	return streamMetrics.InstrumentSince(ctx, startTime, out, providerLabels(p.providers[idx]))
 }
 
-// getFallbackProvider finds a fallback provider when the current one fails
+// getFallbackProvider finds a fallback provider when the current one fails and reserves a
+// concurrency slot for it like getProvider. Ejected and saturated providers are skipped.
 func (p *ProviderPool) getFallbackProvider(currentIdx int) (int, domain.Provider, error) {
-	if len(p.providers) <= 1 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Take the first available provider after the failed one
+	now := time.Now()
+	idx := -1
+	for offset := 1; offset < len(p.providers); offset++ {
+		i := (currentIdx + offset) % len(p.providers)
+		m := p.metrics[i]
+		if !m.saturated() && !now.Before(m.EjectedUntil) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
 		return -1, nil, fmt.Errorf("no fallback providers available")
 	}
 
-	// In failover strategy, move to the next provider
+	// In failover strategy, the fallback becomes the active provider
 	if p.strategy == StrategyFailover {
-		p.mu.Lock()
-		p.activeIndex = (currentIdx + 1) % len(p.providers)
-		idx := p.activeIndex
-		p.mu.Unlock()
-
-		return idx, p.providers[idx], nil
+		p.activeIndex = idx
 	}
+	p.metrics[idx].Outstanding++
+	return idx, p.providers[idx], nil
+}
+
+// releaseSlot releases a provider's concurrency slot without recording an outcome, for
+// requests the caller cancelled
+func (p *ProviderPool) releaseSlot(idx int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	// Otherwise just pick a different provider
-	nextIdx := (currentIdx + 1) % len(p.providers)
-	return nextIdx, p.providers[nextIdx], nil
+	metrics := p.metrics[idx]
+	metrics.LastUsed = time.Now()
+	if metrics.Outstanding > 0 {
+		metrics.Outstanding--
+	}
 }
 
-// updateMetrics updates the metrics for a provider
+// updateMetrics updates the metrics for a provider and releases its concurrency slot.
+// Cancellation by the caller is not counted against the provider.
 func (p *ProviderPool) updateMetrics(idx int, err error, duration time.Duration) {
+	if errors.Is(err, context.Canceled) {
+		p.releaseSlot(idx)
+		return
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	now := time.Now()
 	metrics := p.metrics[idx]
 	metrics.Requests++
-	metrics.LastUsed = time.Now()
+	metrics.LastUsed = now
+	if metrics.Outstanding > 0 {
+		metrics.Outstanding--
+	}
 
 	if err != nil {
 		metrics.Failures++
 		metrics.ConsecutiveErrors++
+
+		// Eject the provider as an outlier once it keeps failing
+		if p.ejectionThreshold > 0 && metrics.ConsecutiveErrors >= p.ejectionThreshold && !now.Before(metrics.EjectedUntil) {
+			multiplier := 1 << min(metrics.Ejections, 4)
+			if multiplier > maxEjectionDurationMultiplier {
+				multiplier = maxEjectionDurationMultiplier
+			}
+			metrics.Ejections++
+			metrics.EjectedUntil = now.Add(p.ejectionDuration * time.Duration(multiplier))
+		}
 	} else {
 		metrics.ConsecutiveErrors = 0
+		metrics.EjectedUntil = time.Time{}
 		metrics.TotalLatencyMs += duration.Milliseconds()
 		metrics.AvgLatencyMs = metrics.TotalLatencyMs / int64(metrics.Requests-metrics.Failures)
+
+		latencyMs := float64(duration) / float64(time.Millisecond)
+		if metrics.EWMALatencyMs == 0 {
+			metrics.EWMALatencyMs = latencyMs
+		} else {
+			metrics.EWMALatencyMs = p.ewmaAlpha*latencyMs + (1-p.ewmaAlpha)*metrics.EWMALatencyMs
+		}
+		metrics.observeLatency(duration)
+	}
+}
+
+// observeLatency records a successful request's latency and updates the percentile estimates
+func (m *ProviderMetrics) observeLatency(duration time.Duration) {
+	m.latencies.ObserveDuration(duration)
+	if m.exported != nil {
+		m.exported.ObserveDuration(duration)
+	}
+	m.P50LatencyMs = m.latencies.GetQuantile(0.5) * 1000
+	m.P90LatencyMs = m.latencies.GetQuantile(0.9) * 1000
+	m.P99LatencyMs = m.latencies.GetQuantile(0.99) * 1000
+}
+
+// resetLatencies forgets the latency percentiles
+func (m *ProviderMetrics) resetLatencies() {
+	if m.latencies != nil {
+		m.latencies.Reset()
 	}
+	m.P50LatencyMs = 0
+	m.P90LatencyMs = 0
+	m.P99LatencyMs = 0
 }
 
 // GetMetrics returns metrics for all providers
@@ -372,6 +737,14 @@ func (p *ProviderPool) GetMetrics() map[int]*ProviderMetrics {
 			TotalLatencyMs:    m.TotalLatencyMs,
 			LastUsed:          m.LastUsed,
 			ConsecutiveErrors: m.ConsecutiveErrors,
+			EWMALatencyMs:     m.EWMALatencyMs,
+			Outstanding:       m.Outstanding,
+			MaxConcurrency:    m.MaxConcurrency,
+			Ejections:         m.Ejections,
+			EjectedUntil:      m.EjectedUntil,
+			P50LatencyMs:      m.P50LatencyMs,
+			P90LatencyMs:      m.P90LatencyMs,
+			P99LatencyMs:      m.P99LatencyMs,
 		}
 	}
 
@@ -3,12 +3,14 @@ package llmutil
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/lexlapax/go-llms/pkg/llm/domain"
 	"github.com/lexlapax/go-llms/pkg/llm/provider"
 	schemaDomain "github.com/lexlapax/go-llms/pkg/schema/domain"
+	utilMetrics "github.com/lexlapax/go-llms/pkg/util/metrics"
 )
 
 func TestNewProviderPool(t *testing.T) {
@@ -273,6 +275,26 @@ func TestPoolStreaming(t *testing.T) {
 			t.Errorf("Timed out waiting for stream token")
 		}
 	})
+
+	t.Run("RecordsStreamMetrics", func(t *testing.T) {
+		registry := utilMetrics.NewRegistry()
+		pool := NewProviderPool([]domain.Provider{mockProvider}, StrategyRoundRobin).WithMetricsRegistry(registry)
+
+		stream, err := pool.Stream(context.Background(), "Test prompt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for range stream {
+		}
+
+		labels := utilMetrics.Labels{utilMetrics.LabelProvider: "mock"}
+		if got := registry.GetOrCreateLabeledCounter(provider.MetricStreams, labels).GetValue(); got != 1 {
+			t.Errorf("Expected 1 recorded stream, got %d", got)
+		}
+		if got := registry.GetOrCreateLabeledCounter(provider.MetricStreamsCanceled, labels).GetValue(); got != 0 {
+			t.Errorf("Expected a finished stream, got %d canceled", got)
+		}
+	})
 }
 
 func TestPoolProviderSelection(t *testing.T) {
@@ -381,7 +403,8 @@ func TestPoolProviderSelection(t *testing.T) {
 func TestPoolMetrics(t *testing.T) {
 	mockProvider := provider.NewMockProvider()
 	providers := []domain.Provider{mockProvider}
-	pool := NewProviderPool(providers, StrategyRoundRobin)
+	registry := utilMetrics.NewRegistry()
+	pool := NewProviderPool(providers, StrategyRoundRobin).WithMetricsRegistry(registry)
 
 	// Test initial metrics
 	initialMetrics := pool.GetMetrics()
@@ -425,4 +448,277 @@ func TestPoolMetrics(t *testing.T) {
 	if resetMetrics[0].AvgLatencyMs <= 0 {
 		t.Errorf("Expected positive average latency, got %d", resetMetrics[0].AvgLatencyMs)
 	}
+
+	// Percentiles are exact for the first few successful requests
+	if resetMetrics[0].P50LatencyMs != 50 || resetMetrics[0].P99LatencyMs != 100 {
+		t.Errorf("Expected p50 50ms and p99 100ms, got %f and %f", resetMetrics[0].P50LatencyMs, resetMetrics[0].P99LatencyMs)
+	}
+
+	// The latencies are exported to the metrics registry, labelled by provider
+	var out strings.Builder
+	if err := registry.WritePrometheus(&out, ""); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+	series := `provider_latency_seconds_count{provider="mock"} 2` + "\n"
+	if !strings.Contains(out.String(), series) {
+		t.Errorf("Expected %q in the Prometheus output", series)
+	}
+}
+
+func TestPoolAdaptiveLoadBalancing(t *testing.T) {
+	newPool := func(strategy PoolStrategy) *ProviderPool {
+		return NewProviderPool([]domain.Provider{
+			provider.NewMockProvider(),
+			provider.NewMockProvider(),
+			provider.NewMockProvider(),
+		}, strategy)
+	}
+
+	t.Run("FastestUsesEWMALatency", func(t *testing.T) {
+		pool := newPool(StrategyFastest).WithEWMAAlpha(0.5)
+		pool.updateMetrics(0, nil, 100*time.Millisecond)
+		pool.updateMetrics(1, nil, 40*time.Millisecond)
+		pool.updateMetrics(2, nil, 60*time.Millisecond)
+
+		// A slow response moves provider 1 behind provider 2: 0.5*200 + 0.5*40 = 120
+		pool.updateMetrics(1, nil, 200*time.Millisecond)
+
+		idx, _, err := pool.getProvider()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if idx != 2 {
+			t.Errorf("Expected provider 2, got %d", idx)
+		}
+		if got := pool.GetMetrics()[1].EWMALatencyMs; got != 120 {
+			t.Errorf("Expected EWMA latency 120ms, got %f", got)
+		}
+	})
+
+	t.Run("LeastOutstanding", func(t *testing.T) {
+		pool := newPool(StrategyLeastOutstanding)
+
+		first, _, _ := pool.getProvider()
+		second, _, _ := pool.getProvider()
+		third, _, _ := pool.getProvider()
+		if first == second || second == third || first == third {
+			t.Errorf("Expected in-flight requests to spread across providers, got %d, %d, %d", first, second, third)
+		}
+
+		pool.updateMetrics(second, nil, 10*time.Millisecond)
+		idx, _, _ := pool.getProvider()
+		if idx != second {
+			t.Errorf("Expected the provider with no outstanding requests (%d), got %d", second, idx)
+		}
+	})
+
+	t.Run("WeightedRandom", func(t *testing.T) {
+		pool := newPool(StrategyWeightedRandom).WithWeights(0, 1, 0)
+		for i := 0; i < 20; i++ {
+			idx, _, _ := pool.getProvider()
+			pool.updateMetrics(idx, nil, time.Millisecond)
+			if idx != 1 {
+				t.Fatalf("Expected only the weighted provider to be selected, got %d", idx)
+			}
+		}
+	})
+
+	t.Run("ConcurrencyCap", func(t *testing.T) {
+		pool := newPool(StrategyFailover).WithMaxConcurrency(1)
+
+		for want := 0; want < 3; want++ {
+			idx, _, err := pool.getProvider()
+			if err != nil || idx != want {
+				t.Fatalf("Expected provider %d, got %d (%v)", want, idx, err)
+			}
+		}
+
+		if _, _, err := pool.getProvider(); !errors.Is(err, domain.ErrProviderUnavailable) {
+			t.Errorf("Expected saturation error, got %v", err)
+		}
+
+		pool.updateMetrics(1, nil, time.Millisecond)
+		if idx, _, err := pool.getProvider(); err != nil || idx != 1 {
+			t.Errorf("Expected released provider 1, got %d (%v)", idx, err)
+		}
+	})
+
+	t.Run("OutlierEjection", func(t *testing.T) {
+		pool := newPool(StrategyRoundRobin).WithOutlierEjection(2, time.Minute)
+		testErr := errors.New("test error")
+		pool.updateMetrics(0, testErr, 0)
+		pool.updateMetrics(0, testErr, 0)
+
+		metrics := pool.GetMetrics()[0]
+		if metrics.Ejections != 1 || !metrics.EjectedUntil.After(time.Now()) {
+			t.Fatalf("Expected provider 0 to be ejected, got %+v", metrics)
+		}
+
+		for i := 0; i < 4; i++ {
+			idx, _, _ := pool.getProvider()
+			pool.updateMetrics(idx, nil, time.Millisecond)
+			if idx == 0 {
+				t.Fatalf("Expected ejected provider to be skipped")
+			}
+		}
+	})
+
+	t.Run("AllEjectedStillServes", func(t *testing.T) {
+		pool := NewProviderPool([]domain.Provider{provider.NewMockProvider()}, StrategyRoundRobin).
+			WithOutlierEjection(1, time.Minute)
+		pool.updateMetrics(0, errors.New("test error"), 0)
+
+		if idx, _, err := pool.getProvider(); err != nil || idx != 0 {
+			t.Errorf("Expected the ejected provider as a last resort, got %d (%v)", idx, err)
+		}
+	})
+
+	t.Run("MetricsDecay", func(t *testing.T) {
+		pool := newPool(StrategyFastest).WithMetricsDecay(time.Minute)
+		pool.updateMetrics(0, nil, 500*time.Millisecond)
+		pool.updateMetrics(1, nil, 50*time.Millisecond)
+		pool.updateMetrics(2, nil, 100*time.Millisecond)
+		pool.metrics[0].LastUsed = time.Now().Add(-2 * time.Minute)
+
+		// The stale provider loses its latency history and is probed again
+		idx, _, _ := pool.getProvider()
+		if idx != 0 {
+			t.Errorf("Expected stale provider 0 to be retried, got %d", idx)
+		}
+		if got := pool.GetMetrics()[0].P90LatencyMs; got != 0 {
+			t.Errorf("Expected stale latency percentiles to be reset, got %f", got)
+		}
+	})
+
+	t.Run("StreamReleasesSlot", func(t *testing.T) {
+		pool := NewProviderPool([]domain.Provider{provider.NewMockProvider()}, StrategyRoundRobin).
+			WithMaxConcurrency(1)
+
+		stream, err := pool.Stream(context.Background(), "test")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := pool.GetMetrics()[0].Outstanding; got != 1 {
+			t.Errorf("Expected 1 outstanding stream, got %d", got)
+		}
+
+		for range stream {
+		}
+
+		// The slot is released once the stream has been drained
+		deadline := time.Now().Add(time.Second)
+		for pool.GetMetrics()[0].Outstanding != 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		metrics := pool.GetMetrics()[0]
+		if metrics.Outstanding != 0 || metrics.Requests != 1 || metrics.EWMALatencyMs <= 0 {
+			t.Errorf("Expected stream to be recorded and released, got %+v", metrics)
+		}
+	})
+
+	t.Run("FastestSkipsFailingProviders", func(t *testing.T) {
+		pool := newPool(StrategyFastest).WithOutlierEjection(0, 0)
+		pool.updateMetrics(0, nil, 10*time.Millisecond)
+		pool.updateMetrics(1, nil, 50*time.Millisecond)
+		pool.updateMetrics(2, nil, 90*time.Millisecond)
+		for i := 0; i < 4; i++ {
+			pool.updateMetrics(0, errors.New("test error"), 0)
+		}
+
+		if idx, _, _ := pool.getProvider(); idx != 1 {
+			t.Errorf("Expected the failing provider to be skipped for provider 1, got %d", idx)
+		}
+	})
+
+	t.Run("FallbackReservesSlot", func(t *testing.T) {
+		pool := newPool(StrategyFailover).WithMaxConcurrency(1)
+
+		idx, _, _ := pool.getProvider()
+		fallbackIdx, _, err := pool.getFallbackProvider(idx)
+		if err != nil || fallbackIdx != 1 {
+			t.Fatalf("Expected fallback provider 1, got %d (%v)", fallbackIdx, err)
+		}
+		if got := pool.GetMetrics()[1].Outstanding; got != 1 {
+			t.Errorf("Expected the fallback to reserve a slot, got %d outstanding", got)
+		}
+
+		// Provider 1 is now saturated, so the next fallback skips it
+		if next, _, _ := pool.getFallbackProvider(idx); next != 2 {
+			t.Errorf("Expected saturated provider 1 to be skipped, got %d", next)
+		}
+
+		pool.updateMetrics(1, nil, time.Millisecond)
+		if got := pool.GetMetrics()[1].Outstanding; got != 0 {
+			t.Errorf("Expected the fallback slot to be released, got %d outstanding", got)
+		}
+	})
+
+	t.Run("FallbackSkipsEjected", func(t *testing.T) {
+		pool := newPool(StrategyFailover).WithOutlierEjection(1, time.Minute)
+		pool.updateMetrics(1, errors.New("test error"), 0)
+
+		if idx, _, err := pool.getFallbackProvider(0); err != nil || idx != 2 {
+			t.Errorf("Expected ejected provider 1 to be skipped for provider 2, got %d (%v)", idx, err)
+		}
+
+		pool.updateMetrics(2, errors.New("test error"), 0)
+		if _, _, err := pool.getFallbackProvider(0); err == nil {
+			t.Error("Expected no fallback when all other providers are ejected")
+		}
+	})
+
+	t.Run("FailoverGenerateUsesFallback", func(t *testing.T) {
+		failing := provider.NewMockProvider().WithGenerateFunc(func(ctx context.Context, prompt string, options ...domain.Option) (string, error) {
+			return "", errors.New("test error")
+		})
+		pool := NewProviderPool([]domain.Provider{failing, provider.NewMockProvider()}, StrategyFailover).
+			WithMaxConcurrency(1)
+
+		if _, err := pool.Generate(context.Background(), "test"); err != nil {
+			t.Fatalf("Expected the fallback to answer, got %v", err)
+		}
+		for i, m := range pool.GetMetrics() {
+			if m.Outstanding != 0 || m.Requests != 1 {
+				t.Errorf("Expected provider %d to have 1 request and nothing outstanding, got %+v", i, m)
+			}
+		}
+	})
+
+	t.Run("CancelledStreamIsNotAnError", func(t *testing.T) {
+		slow := provider.NewMockProvider().WithStreamFunc(func(ctx context.Context, prompt string, options ...domain.Option) (domain.ResponseStream, error) {
+			ch := make(chan domain.Token)
+			go func() {
+				defer close(ch)
+				for i := 0; i < 100; i++ {
+					select {
+					case ch <- domain.Token{Text: "x"}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+			return ch, nil
+		})
+		pool := NewProviderPool([]domain.Provider{slow}, StrategyRoundRobin).WithOutlierEjection(1, time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := pool.Stream(ctx, "test")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		<-stream
+		cancel()
+		for range stream {
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for pool.GetMetrics()[0].Outstanding != 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		metrics := pool.GetMetrics()[0]
+		if metrics.Outstanding != 0 || metrics.Failures != 0 || metrics.Ejections != 0 {
+			t.Errorf("Expected the cancelled stream to be released without a failure, got %+v", metrics)
+		}
+	})
+
 }
@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are histogram bucket upper bounds in seconds, sized for LLM calls
+// that take from tens of milliseconds to minutes
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Bucket is a histogram bucket with the cumulative count of observations up to its upper bound
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// Histogram counts observations in buckets and estimates quantiles of the distribution
+type Histogram struct {
+	name      string
+	labels    Labels
+	bounds    []float64
+	counts    []uint64 // per bucket, with a last bucket for +Inf
+	count     uint64
+	sum       float64
+	min       float64
+	max       float64
+	quantiles *QuantileEstimator
+	mu        sync.Mutex
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds, or DefaultLatencyBuckets
+// if none are given. It tracks DefaultQuantiles.
+func NewHistogram(name string, buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+
+	return &Histogram{
+		name:      name,
+		bounds:    bounds,
+		counts:    make([]uint64, len(bounds)+1),
+		quantiles: NewQuantileEstimator(),
+	}
+}
+
+// WithQuantiles sets the quantiles (0-1) the histogram tracks, discarding earlier estimates
+func (h *Histogram) WithQuantiles(quantiles ...float64) *Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.quantiles = NewQuantileEstimator(quantiles...)
+	return h
+}
+
+// Observe records a value
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[sort.SearchFloat64s(h.bounds, value)]++
+	if h.count == 0 || value < h.min {
+		h.min = value
+	}
+	if h.count == 0 || value > h.max {
+		h.max = value
+	}
+	h.count++
+	h.sum += value
+	h.quantiles.Observe(value)
+}
+
+// ObserveDuration records a duration in seconds
+func (h *Histogram) ObserveDuration(duration time.Duration) {
+	h.Observe(duration.Seconds())
+}
+
+// GetCount returns the number of observations
+func (h *Histogram) GetCount() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// GetSum returns the sum of all observations
+func (h *Histogram) GetSum() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sum
+}
+
+// GetMean returns the mean of all observations, or 0 without observations
+func (h *Histogram) GetMean() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / float64(h.count)
+}
+
+// GetMin returns the smallest observation, or 0 without observations
+func (h *Histogram) GetMin() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.min
+}
+
+// GetMax returns the largest observation, or 0 without observations
+func (h *Histogram) GetMax() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.max
+}
+
+// GetBuckets returns the buckets with cumulative counts, ending with the +Inf bucket
+func (h *Histogram) GetBuckets() []Bucket {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets := make([]Bucket, len(h.counts))
+	var cumulative uint64
+	for i, count := range h.counts {
+		cumulative += count
+		bound := math.Inf(1)
+		if i < len(h.bounds) {
+			bound = h.bounds[i]
+		}
+		buckets[i] = Bucket{UpperBound: bound, Count: cumulative}
+	}
+	return buckets
+}
+
+// GetQuantile returns the estimate of a tracked quantile, such as 0.99 for p99.
+// It returns 0 without observations or if the quantile is not tracked.
+func (h *Histogram) GetQuantile(q float64) float64 {
+	h.mu.Lock()
+	quantiles := h.quantiles
+	h.mu.Unlock()
+	return quantiles.Quantile(q)
+}
+
+// GetQuantiles returns the estimates of all tracked quantiles
+func (h *Histogram) GetQuantiles() map[float64]float64 {
+	h.mu.Lock()
+	quantiles := h.quantiles
+	h.mu.Unlock()
+
+	result := make(map[float64]float64)
+	for _, q := range quantiles.Quantiles() {
+		result[q] = quantiles.Quantile(q)
+	}
+	return result
+}
+
+// Reset forgets all observations
+func (h *Histogram) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts = make([]uint64, len(h.bounds)+1)
+	h.count = 0
+	h.sum = 0
+	h.min = 0
+	h.max = 0
+	h.quantiles.Reset()
+}
+
+// Name returns the name of the histogram
+func (h *Histogram) Name() string {
+	return h.name
+}
+
+// Labels returns the labels of the histogram
+func (h *Histogram) Labels() Labels {
+	return h.labels.clone()
+}
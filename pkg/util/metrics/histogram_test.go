@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	h := NewHistogram("latency", []float64{5, 1, 2})
+	for _, v := range []float64{0.5, 1, 1.5, 3, 10} {
+		h.Observe(v)
+	}
+
+	buckets := h.GetBuckets()
+	expected := []Bucket{{1, 2}, {2, 3}, {5, 4}, {math.Inf(1), 5}}
+	if len(buckets) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %d", len(expected), len(buckets))
+	}
+	for i, b := range expected {
+		if buckets[i] != b {
+			t.Errorf("Bucket %d: expected %+v, got %+v", i, b, buckets[i])
+		}
+	}
+
+	if h.GetCount() != 5 || h.GetSum() != 16 || h.GetMin() != 0.5 || h.GetMax() != 10 || h.GetMean() != 3.2 {
+		t.Errorf("Unexpected summary: count %d, sum %v, min %v, max %v", h.GetCount(), h.GetSum(), h.GetMin(), h.GetMax())
+	}
+
+	h.Reset()
+	if h.GetCount() != 0 || h.GetBuckets()[3].Count != 0 || h.GetQuantile(0.5) != 0 {
+		t.Errorf("Expected an empty histogram after reset")
+	}
+}
+
+func TestHistogramDefaultBuckets(t *testing.T) {
+	h := NewHistogram("latency", nil)
+	h.ObserveDuration(300 * time.Millisecond)
+
+	buckets := h.GetBuckets()
+	if len(buckets) != len(DefaultLatencyBuckets)+1 {
+		t.Fatalf("Expected the default buckets, got %d", len(buckets))
+	}
+	if buckets[2].Count != 0 || buckets[3].Count != 1 {
+		t.Errorf("Expected 0.3s in the 0.5s bucket, got %+v", buckets)
+	}
+}
+
+func TestQuantileEstimatorSmallSamples(t *testing.T) {
+	e := NewQuantileEstimator()
+	if e.Quantile(0.5) != 0 {
+		t.Errorf("Expected 0 without observations")
+	}
+	for _, v := range []float64{3, 1, 2} {
+		e.Observe(v)
+	}
+	if e.Quantile(0.5) != 2 || e.Quantile(0.99) != 3 {
+		t.Errorf("Expected exact quantiles, got p50 %v, p99 %v", e.Quantile(0.5), e.Quantile(0.99))
+	}
+	if e.Quantile(0.75) != 0 {
+		t.Errorf("Expected 0 for an untracked quantile")
+	}
+}
+
+func TestQuantileEstimatorAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	e := NewQuantileEstimator(0.5, 0.9, 0.99)
+	values := make([]float64, 20000)
+	for i := range values {
+		// Exponentially distributed, like request latencies with a long tail
+		values[i] = rng.ExpFloat64()
+		e.Observe(values[i])
+	}
+	sort.Float64s(values)
+
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		exact := values[int(q*float64(len(values)))]
+		estimate := e.Quantile(q)
+		if math.Abs(estimate-exact)/exact > 0.05 {
+			t.Errorf("p%v: estimate %.4f differs from %.4f by more than 5%%", q*100, estimate, exact)
+		}
+	}
+}
+
+func TestHistogramConcurrency(t *testing.T) {
+	h := NewHistogram("latency", nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				h.Observe(float64(j))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if h.GetCount() != 1000 {
+		t.Errorf("Expected 1000 observations, got %d", h.GetCount())
+	}
+}
+
+func TestTimerQuantiles(t *testing.T) {
+	timer := NewTimer("latency")
+	for i := 1; i <= 100; i++ {
+		timer.RecordDuration(time.Duration(i) * time.Millisecond)
+	}
+	if p99 := timer.GetQuantile(0.99); p99 < 95*time.Millisecond || p99 > 100*time.Millisecond {
+		t.Errorf("Expected p99 near 99ms, got %v", p99)
+	}
+}
+
+func TestRegistryHistograms(t *testing.T) {
+	registry := NewRegistry()
+	h := registry.GetOrCreateHistogram("latency", nil)
+	if registry.GetOrCreateHistogram("latency", []float64{1}) != h || registry.GetHistogram("latency") != h {
+		t.Errorf("Expected the existing histogram")
+	}
+	labeled := registry.GetOrCreateLabeledHistogram("latency", Labels{LabelProvider: "openai"}, nil)
+	if labeled == h || len(registry.GetAllHistograms()) != 2 {
+		t.Errorf("Expected a separate labeled histogram")
+	}
+	registry.Clear()
+	if len(registry.GetAllHistograms()) != 0 {
+		t.Errorf("Expected no histograms after clear")
+	}
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+)
+
+// Labels break a metric down by dimension, such as provider, model or tool
+type Labels map[string]string
+
+// Common label names
+const (
+	LabelProvider = "provider"
+	LabelModel    = "model"
+	LabelTool     = "tool"
+)
+
+// With returns a copy of the labels with an added label, such as a bucket's "le"
+func (l Labels) With(name, value string) Labels {
+	result := make(Labels, len(l)+1)
+	for k, v := range l {
+		result[k] = v
+	}
+	result[name] = value
+	return result
+}
+
+// clone copies the labels so later changes to the caller's map don't affect the metric
+func (l Labels) clone() Labels {
+	if len(l) == 0 {
+		return nil
+	}
+	result := make(Labels, len(l))
+	for k, v := range l {
+		result[k] = v
+	}
+	return result
+}
+
+// sortedKeys returns the label names in sorted order
+func (l Labels) sortedKeys() []string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String formats the labels as {name="value",...} in name order, or "" without labels
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range l.sortedKeys() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(l[k]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// metricKey identifies a metric in the registry. Unlabeled metrics are keyed by name alone.
+func metricKey(name string, labels Labels) string {
+	return name + labels.String()
+}
+
+// Name returns the name of the counter
+func (c *Counter) Name() string {
+	return c.name
+}
+
+// Labels returns the labels of the counter
+func (c *Counter) Labels() Labels {
+	return c.labels.clone()
+}
+
+// Name returns the name of the gauge
+func (g *Gauge) Name() string {
+	return g.name
+}
+
+// Labels returns the labels of the gauge
+func (g *Gauge) Labels() Labels {
+	return g.labels.clone()
+}
+
+// Name returns the name of the ratio counter
+func (r *RatioCounter) Name() string {
+	return r.name
+}
+
+// Labels returns the labels of the ratio counter
+func (r *RatioCounter) Labels() Labels {
+	return r.labels.clone()
+}
+
+// Name returns the name of the timer
+func (t *Timer) Name() string {
+	return t.name
+}
+
+// Labels returns the labels of the timer
+func (t *Timer) Labels() Labels {
+	return t.labels.clone()
+}
@@ -1,6 +1,6 @@
 // Package metrics provides utilities for collecting and reporting performance metrics
 // in the Go-LLMs project. It supports various metric types including counters, gauges,
-// ratio counters, and timers, all with thread-safe operations.
+// ratio counters, timers, and histograms, all with thread-safe operations.
 package metrics
 
 import (
@@ -11,8 +11,9 @@ import (
 
 // Counter is a monotonically increasing counter
 type Counter struct {
-	name  string
-	value int64
+	name   string
+	labels Labels
+	value  int64
 }
 
 // NewCounter creates a new counter with a given name
@@ -40,9 +41,10 @@ func (c *Counter) GetValue() int64 {
 
 // Gauge is a metric that can go up and down
 type Gauge struct {
-	name  string
-	value float64
-	mu    sync.RWMutex
+	name   string
+	labels Labels
+	value  float64
+	mu     sync.RWMutex
 }
 
 // NewGauge creates a new gauge with a given name
@@ -94,6 +96,7 @@ func (g *Gauge) GetValue() float64 {
 // RatioCounter tracks a ratio between two counters (e.g., cache hit rate)
 type RatioCounter struct {
 	name        string
+	labels      Labels
 	numerator   int64
 	denominator int64
 }
@@ -148,11 +151,13 @@ func (r *RatioCounter) GetValues() (int64, int64) {
 // Timer tracks execution duration of operations
 type Timer struct {
 	name         string
+	labels       Labels
 	startTime    time.Time
 	count        int64
 	totalTime    int64 // nanoseconds
 	lastDuration int64 // nanoseconds
 	running      bool
+	quantiles    *QuantileEstimator
 	mu           sync.Mutex
 }
 
@@ -163,6 +168,7 @@ func NewTimer(name string) *Timer {
 		count:     0,
 		totalTime: 0,
 		running:   false,
+		quantiles: NewQuantileEstimator(),
 	}
 }
 
@@ -206,6 +212,7 @@ func (t *Timer) recordDurationLocked(duration time.Duration) {
 	t.lastDuration = duration.Nanoseconds()
 	t.totalTime += t.lastDuration
 	t.count++
+	t.quantiles.Observe(duration.Seconds())
 }
 
 // TimeFunction times the execution of a function and returns its result
@@ -252,12 +259,19 @@ func (t *Timer) GetAverageDuration() time.Duration {
 	return time.Duration(t.totalTime / t.count)
 }
 
+// GetQuantile returns the estimate of a duration quantile tracked by DefaultQuantiles,
+// such as 0.99 for p99. It returns 0 without timed operations.
+func (t *Timer) GetQuantile(q float64) time.Duration {
+	return time.Duration(t.quantiles.Quantile(q) * float64(time.Second))
+}
+
 // Registry is a central registry for all metrics
 type Registry struct {
 	counters      map[string]*Counter
 	gauges        map[string]*Gauge
 	ratioCounters map[string]*RatioCounter
 	timers        map[string]*Timer
+	histograms    map[string]*Histogram
 	mu            sync.RWMutex
 }
 
@@ -270,20 +284,33 @@ var (
 // GetRegistry returns the singleton global registry
 func GetRegistry() *Registry {
 	registryOnce.Do(func() {
-		globalRegistry = &Registry{
-			counters:      make(map[string]*Counter),
-			gauges:        make(map[string]*Gauge),
-			ratioCounters: make(map[string]*RatioCounter),
-			timers:        make(map[string]*Timer),
-		}
+		globalRegistry = NewRegistry()
 	})
 	return globalRegistry
 }
 
+// NewRegistry creates a registry independent of the global one
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:      make(map[string]*Counter),
+		gauges:        make(map[string]*Gauge),
+		ratioCounters: make(map[string]*RatioCounter),
+		timers:        make(map[string]*Timer),
+		histograms:    make(map[string]*Histogram),
+	}
+}
+
 // GetOrCreateCounter gets or creates a counter with the given name
 func (r *Registry) GetOrCreateCounter(name string) *Counter {
+	return r.GetOrCreateLabeledCounter(name, nil)
+}
+
+// GetOrCreateLabeledCounter gets or creates the counter with the given name and labels
+func (r *Registry) GetOrCreateLabeledCounter(name string, labels Labels) *Counter {
+	key := metricKey(name, labels)
+
 	r.mu.RLock()
-	counter, ok := r.counters[name]
+	counter, ok := r.counters[key]
 	r.mu.RUnlock()
 
 	if ok {
@@ -294,20 +321,28 @@ func (r *Registry) GetOrCreateCounter(name string) *Counter {
 	defer r.mu.Unlock()
 
 	// Check again in case another goroutine created it
-	counter, ok = r.counters[name]
+	counter, ok = r.counters[key]
 	if ok {
 		return counter
 	}
 
 	counter = NewCounter(name)
-	r.counters[name] = counter
+	counter.labels = labels.clone()
+	r.counters[key] = counter
 	return counter
 }
 
 // GetOrCreateGauge gets or creates a gauge with the given name
 func (r *Registry) GetOrCreateGauge(name string) *Gauge {
+	return r.GetOrCreateLabeledGauge(name, nil)
+}
+
+// GetOrCreateLabeledGauge gets or creates the gauge with the given name and labels
+func (r *Registry) GetOrCreateLabeledGauge(name string, labels Labels) *Gauge {
+	key := metricKey(name, labels)
+
 	r.mu.RLock()
-	gauge, ok := r.gauges[name]
+	gauge, ok := r.gauges[key]
 	r.mu.RUnlock()
 
 	if ok {
@@ -318,20 +353,28 @@ func (r *Registry) GetOrCreateGauge(name string) *Gauge {
 	defer r.mu.Unlock()
 
 	// Check again in case another goroutine created it
-	gauge, ok = r.gauges[name]
+	gauge, ok = r.gauges[key]
 	if ok {
 		return gauge
 	}
 
 	gauge = NewGauge(name)
-	r.gauges[name] = gauge
+	gauge.labels = labels.clone()
+	r.gauges[key] = gauge
 	return gauge
 }
 
 // GetOrCreateRatioCounter gets or creates a ratio counter with the given name
 func (r *Registry) GetOrCreateRatioCounter(name string) *RatioCounter {
+	return r.GetOrCreateLabeledRatioCounter(name, nil)
+}
+
+// GetOrCreateLabeledRatioCounter gets or creates the ratio counter with the given name and labels
+func (r *Registry) GetOrCreateLabeledRatioCounter(name string, labels Labels) *RatioCounter {
+	key := metricKey(name, labels)
+
 	r.mu.RLock()
-	ratio, ok := r.ratioCounters[name]
+	ratio, ok := r.ratioCounters[key]
 	r.mu.RUnlock()
 
 	if ok {
@@ -342,20 +385,28 @@ func (r *Registry) GetOrCreateRatioCounter(name string) *RatioCounter {
 	defer r.mu.Unlock()
 
 	// Check again in case another goroutine created it
-	ratio, ok = r.ratioCounters[name]
+	ratio, ok = r.ratioCounters[key]
 	if ok {
 		return ratio
 	}
 
 	ratio = NewRatioCounter(name)
-	r.ratioCounters[name] = ratio
+	ratio.labels = labels.clone()
+	r.ratioCounters[key] = ratio
 	return ratio
 }
 
 // GetOrCreateTimer gets or creates a timer with the given name
 func (r *Registry) GetOrCreateTimer(name string) *Timer {
+	return r.GetOrCreateLabeledTimer(name, nil)
+}
+
+// GetOrCreateLabeledTimer gets or creates the timer with the given name and labels
+func (r *Registry) GetOrCreateLabeledTimer(name string, labels Labels) *Timer {
+	key := metricKey(name, labels)
+
 	r.mu.RLock()
-	timer, ok := r.timers[name]
+	timer, ok := r.timers[key]
 	r.mu.RUnlock()
 
 	if ok {
@@ -366,16 +417,50 @@ func (r *Registry) GetOrCreateTimer(name string) *Timer {
 	defer r.mu.Unlock()
 
 	// Check again in case another goroutine created it
-	timer, ok = r.timers[name]
+	timer, ok = r.timers[key]
 	if ok {
 		return timer
 	}
 
 	timer = NewTimer(name)
-	r.timers[name] = timer
+	timer.labels = labels.clone()
+	r.timers[key] = timer
 	return timer
 }
 
+// GetOrCreateHistogram gets or creates a histogram with the given name.
+// The buckets are only used when the histogram is created; nil uses DefaultLatencyBuckets.
+func (r *Registry) GetOrCreateHistogram(name string, buckets []float64) *Histogram {
+	return r.GetOrCreateLabeledHistogram(name, nil, buckets)
+}
+
+// GetOrCreateLabeledHistogram gets or creates the histogram with the given name and labels
+func (r *Registry) GetOrCreateLabeledHistogram(name string, labels Labels, buckets []float64) *Histogram {
+	key := metricKey(name, labels)
+
+	r.mu.RLock()
+	histogram, ok := r.histograms[key]
+	r.mu.RUnlock()
+
+	if ok {
+		return histogram
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check again in case another goroutine created it
+	histogram, ok = r.histograms[key]
+	if ok {
+		return histogram
+	}
+
+	histogram = NewHistogram(name, buckets)
+	histogram.labels = labels.clone()
+	r.histograms[key] = histogram
+	return histogram
+}
+
 // GetCounter gets a counter by name (or nil if not found)
 func (r *Registry) GetCounter(name string) *Counter {
 	r.mu.RLock()
@@ -404,6 +489,13 @@ func (r *Registry) GetTimer(name string) *Timer {
 	return r.timers[name]
 }
 
+// GetHistogram gets a histogram by name (or nil if not found)
+func (r *Registry) GetHistogram(name string) *Histogram {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.histograms[name]
+}
+
 // GetAllCounters returns all registered counters
 func (r *Registry) GetAllCounters() map[string]*Counter {
 	r.mu.RLock()
@@ -456,6 +548,19 @@ func (r *Registry) GetAllTimers() map[string]*Timer {
 	return result
 }
 
+// GetAllHistograms returns all registered histograms
+func (r *Registry) GetAllHistograms() map[string]*Histogram {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Make a copy to avoid race conditions
+	result := make(map[string]*Histogram, len(r.histograms))
+	for k, v := range r.histograms {
+		result[k] = v
+	}
+	return result
+}
+
 // Clear removes all metrics from the registry
 func (r *Registry) Clear() {
 	r.mu.Lock()
@@ -465,4 +570,5 @@ func (r *Registry) Clear() {
 	r.gauges = make(map[string]*Gauge)
 	r.ratioCounters = make(map[string]*RatioCounter)
 	r.timers = make(map[string]*Timer)
+	r.histograms = make(map[string]*Histogram)
 }
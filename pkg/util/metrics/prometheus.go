@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PrometheusHandler serves the metrics of a registry in the Prometheus text exposition format
+type PrometheusHandler struct {
+	registry  *Registry
+	namespace string
+}
+
+// NewPrometheusHandler creates a handler serving a registry, usually GetRegistry()
+func NewPrometheusHandler(registry *Registry) *PrometheusHandler {
+	return &PrometheusHandler{registry: registry}
+}
+
+// WithNamespace prefixes all metric names, such as "myapp" for "myapp_schema_cache_hit_rate"
+func (h *PrometheusHandler) WithNamespace(namespace string) *PrometheusHandler {
+	h.namespace = namespace
+	return h
+}
+
+// ServeHTTP writes the current metric values
+func (h *PrometheusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	if err := h.registry.WritePrometheus(w, h.namespace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// promSample is a sample of a metric family
+type promSample struct {
+	suffix string
+	labels Labels
+	value  float64
+}
+
+// promFamily is a metric family, written with a single TYPE line
+type promFamily struct {
+	name    string
+	kind    string
+	samples []promSample
+}
+
+// promFamilies collects metric families by name
+type promFamilies map[string]*promFamily
+
+// add adds a sample to a family. Samples of a name already used by a family of
+// another type are dropped, since Prometheus rejects mixed families.
+func (f promFamilies) add(name, kind string, sample promSample) {
+	family, ok := f[name]
+	if !ok {
+		family = &promFamily{name: name, kind: kind}
+		f[name] = family
+	}
+	if family.kind == kind {
+		family.samples = append(family.samples, sample)
+	}
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format.
+// Metric names are sanitized ("schema_cache.hit_rate" becomes "schema_cache_hit_rate") and
+// prefixed with the namespace if it is not empty. Counters get a "_total" suffix, ratio counters
+// are written as a gauge with "_numerator_total" and "_denominator_total" counters, timers
+// as a "_seconds" summary with quantiles, and histograms with cumulative buckets.
+func (r *Registry) WritePrometheus(w io.Writer, namespace string) error {
+	families := make(promFamilies)
+
+	for _, counter := range r.GetAllCounters() {
+		name := withSuffix(prometheusName(namespace, counter.name), "_total")
+		families.add(name, "counter", promSample{labels: counter.labels, value: float64(counter.GetValue())})
+	}
+
+	for _, gauge := range r.GetAllGauges() {
+		name := prometheusName(namespace, gauge.name)
+		families.add(name, "gauge", promSample{labels: gauge.labels, value: gauge.GetValue()})
+	}
+
+	for _, ratio := range r.GetAllRatioCounters() {
+		name := prometheusName(namespace, ratio.name)
+		numerator, denominator := ratio.GetValues()
+		families.add(name, "gauge", promSample{labels: ratio.labels, value: ratio.GetRatio()})
+		families.add(name+"_numerator_total", "counter", promSample{labels: ratio.labels, value: float64(numerator)})
+		families.add(name+"_denominator_total", "counter", promSample{labels: ratio.labels, value: float64(denominator)})
+	}
+
+	for _, timer := range r.GetAllTimers() {
+		name := withSuffix(prometheusName(namespace, timer.name), "_seconds")
+		for _, q := range timer.quantiles.Quantiles() {
+			families.add(name, "summary", promSample{
+				labels: timer.labels.With("quantile", formatPrometheusValue(q)),
+				value:  timer.GetQuantile(q).Seconds(),
+			})
+		}
+		families.add(name, "summary", promSample{suffix: "_sum", labels: timer.labels, value: timer.GetTotalDuration().Seconds()})
+		families.add(name, "summary", promSample{suffix: "_count", labels: timer.labels, value: float64(timer.GetCount())})
+	}
+
+	for _, histogram := range r.GetAllHistograms() {
+		name := prometheusName(namespace, histogram.name)
+		for _, bucket := range histogram.GetBuckets() {
+			families.add(name, "histogram", promSample{
+				suffix: "_bucket",
+				labels: histogram.labels.With("le", formatPrometheusValue(bucket.UpperBound)),
+				value:  float64(bucket.Count),
+			})
+		}
+		families.add(name, "histogram", promSample{suffix: "_sum", labels: histogram.labels, value: histogram.GetSum()})
+		families.add(name, "histogram", promSample{suffix: "_count", labels: histogram.labels, value: float64(histogram.GetCount())})
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := bufio.NewWriter(w)
+	for _, name := range names {
+		family := families[name]
+		// Keep the samples of a labelled series together, in a stable order
+		sort.SliceStable(family.samples, func(i, j int) bool {
+			return seriesKey(family.samples[i].labels) < seriesKey(family.samples[j].labels)
+		})
+
+		out.WriteString("# TYPE " + family.name + " " + family.kind + "\n")
+		for _, sample := range family.samples {
+			out.WriteString(family.name + sample.suffix + formatPrometheusLabels(sample.labels) + " " + formatPrometheusValue(sample.value) + "\n")
+		}
+	}
+	return out.Flush()
+}
+
+// seriesKey formats labels without the "le" and "quantile" labels, which vary within a series
+func seriesKey(labels Labels) string {
+	if _, ok := labels["le"]; !ok {
+		if _, ok := labels["quantile"]; !ok {
+			return formatPrometheusLabels(labels)
+		}
+	}
+	series := make(Labels, len(labels))
+	for k, v := range labels {
+		if k != "le" && k != "quantile" {
+			series[k] = v
+		}
+	}
+	return formatPrometheusLabels(series)
+}
+
+// prometheusName joins the namespace and name and replaces invalid characters with underscores
+func prometheusName(namespace, name string) string {
+	if namespace != "" {
+		name = namespace + "_" + name
+	}
+	return sanitizeName(name, true)
+}
+
+// sanitizeName makes a valid metric name ([a-zA-Z_:][a-zA-Z0-9_:]*) or label name (without colons)
+func sanitizeName(name string, allowColon bool) string {
+	var b strings.Builder
+	for i, c := range name {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9' && i > 0) || (c == ':' && allowColon)
+		if valid {
+			b.WriteRune(c)
+		} else if c >= '0' && c <= '9' {
+			b.WriteByte('_')
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// withSuffix appends a suffix unless the name already ends with it
+func withSuffix(name, suffix string) string {
+	if strings.HasSuffix(name, suffix) {
+		return name
+	}
+	return name + suffix
+}
+
+// formatPrometheusLabels formats labels as {name="value",...} with sanitized names and escaped values
+func formatPrometheusLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range labels.sortedKeys() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(sanitizeName(k, false))
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(labels[k]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelValueEscaper escapes backslashes, double quotes and line feeds in label values
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes a label value for the exposition format
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// formatPrometheusValue formats a sample value, including the special values
+func formatPrometheusValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLabeledMetrics(t *testing.T) {
+	registry := NewRegistry()
+	labels := Labels{LabelProvider: "openai", LabelModel: "gpt-4o"}
+
+	counter := registry.GetOrCreateLabeledCounter("requests", labels)
+	counter.Increment()
+
+	// The same name and labels return the same metric, in any label order
+	same := registry.GetOrCreateLabeledCounter("requests", Labels{LabelModel: "gpt-4o", LabelProvider: "openai"})
+	if same != counter || same.GetValue() != 1 {
+		t.Errorf("Expected the existing labeled counter")
+	}
+
+	// Changing the caller's map doesn't affect the metric
+	labels[LabelModel] = "changed"
+	if counter.Labels()[LabelModel] != "gpt-4o" {
+		t.Errorf("Expected labels to be copied, got %v", counter.Labels())
+	}
+
+	other := registry.GetOrCreateLabeledCounter("requests", Labels{LabelProvider: "anthropic"})
+	if other == counter || registry.GetOrCreateCounter("requests") == counter {
+		t.Errorf("Expected distinct counters per label set")
+	}
+	if len(registry.GetAllCounters()) != 3 {
+		t.Errorf("Expected 3 counters, got %d", len(registry.GetAllCounters()))
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	registry := NewRegistry()
+	registry.GetOrCreateLabeledCounter("llm.requests", Labels{LabelProvider: "openai"}).IncrementBy(3)
+	registry.GetOrCreateLabeledCounter("llm.requests", Labels{LabelProvider: "anthropic"}).Increment()
+	registry.GetOrCreateLabeledGauge("active-streams", Labels{LabelTool: "say \"hi\"\nback\\slash"}).Set(2)
+
+	ratio := registry.GetOrCreateRatioCounter("schema_cache.hit_rate")
+	ratio.IncrementNumerator()
+	ratio.IncrementDenominatorBy(4)
+
+	timer := registry.GetOrCreateLabeledTimer("tool.latency", Labels{LabelTool: "search"})
+	timer.RecordDuration(1500 * time.Millisecond)
+	timer.RecordDuration(500 * time.Millisecond)
+
+	histogram := registry.GetOrCreateLabeledHistogram("llm.latency", Labels{LabelModel: "gpt-4o"}, []float64{1, 5})
+	histogram.Observe(0.5)
+	histogram.Observe(1)
+	histogram.Observe(7)
+
+	var out strings.Builder
+	if err := registry.WritePrometheus(&out, "app"); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+
+	expected := `# TYPE app_active_streams gauge
+app_active_streams{tool="say \"hi\"\nback\\slash"} 2
+# TYPE app_llm_latency histogram
+app_llm_latency_bucket{le="1",model="gpt-4o"} 2
+app_llm_latency_bucket{le="5",model="gpt-4o"} 2
+app_llm_latency_bucket{le="+Inf",model="gpt-4o"} 3
+app_llm_latency_sum{model="gpt-4o"} 8.5
+app_llm_latency_count{model="gpt-4o"} 3
+# TYPE app_llm_requests_total counter
+app_llm_requests_total{provider="anthropic"} 1
+app_llm_requests_total{provider="openai"} 3
+# TYPE app_schema_cache_hit_rate gauge
+app_schema_cache_hit_rate 0.25
+# TYPE app_schema_cache_hit_rate_denominator_total counter
+app_schema_cache_hit_rate_denominator_total 4
+# TYPE app_schema_cache_hit_rate_numerator_total counter
+app_schema_cache_hit_rate_numerator_total 1
+# TYPE app_tool_latency_seconds summary
+app_tool_latency_seconds{quantile="0.5",tool="search"} 0.5
+app_tool_latency_seconds{quantile="0.9",tool="search"} 1.5
+app_tool_latency_seconds{quantile="0.99",tool="search"} 1.5
+app_tool_latency_seconds_sum{tool="search"} 2
+app_tool_latency_seconds_count{tool="search"} 2
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s\nExpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := map[string]string{
+		"cache.hits":   "cache_hits",
+		"9lives":       "_9lives",
+		"ns:metric":    "ns:metric",
+		"latency (ms)": "latency__ms_",
+	}
+	for name, expected := range tests {
+		if got := sanitizeName(name, true); got != expected {
+			t.Errorf("sanitizeName(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+	if got := sanitizeName("a:b", false); got != "a_b" {
+		t.Errorf("Expected colons to be replaced in label names, got %q", got)
+	}
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.GetOrCreateCounter("requests").Increment()
+
+	recorder := httptest.NewRecorder()
+	NewPrometheusHandler(registry).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if recorder.Header().Get("Content-Type") != prometheusContentType {
+		t.Errorf("Unexpected content type %q", recorder.Header().Get("Content-Type"))
+	}
+	body, _ := io.ReadAll(recorder.Body)
+	if !strings.Contains(string(body), "requests_total 1\n") {
+		t.Errorf("Expected the counter in the response, got %q", body)
+	}
+}
@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultQuantiles are the quantiles tracked by histograms and timers: p50, p90 and p99
+var DefaultQuantiles = []float64{0.5, 0.9, 0.99}
+
+// QuantileEstimator estimates quantiles of a stream of observations in constant memory,
+// using the P² algorithm (Jain and Chlamtac, 1985) with five markers per quantile.
+// Estimates are exact for the first five observations and approximate afterwards.
+type QuantileEstimator struct {
+	mu        sync.Mutex
+	quantiles []float64
+	markers   []*p2Markers
+	initial   []float64
+}
+
+// NewQuantileEstimator creates an estimator for the given quantiles (0-1), or DefaultQuantiles if none are given
+func NewQuantileEstimator(quantiles ...float64) *QuantileEstimator {
+	if len(quantiles) == 0 {
+		quantiles = DefaultQuantiles
+	}
+	e := &QuantileEstimator{quantiles: make([]float64, 0, len(quantiles))}
+	for _, q := range quantiles {
+		if q > 0 && q < 1 {
+			e.quantiles = append(e.quantiles, q)
+		}
+	}
+	sort.Float64s(e.quantiles)
+	return e
+}
+
+// Quantiles returns the tracked quantiles in ascending order
+func (e *QuantileEstimator) Quantiles() []float64 {
+	return append([]float64(nil), e.quantiles...)
+}
+
+// Observe adds an observation
+func (e *QuantileEstimator) Observe(value float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.markers != nil {
+		for _, m := range e.markers {
+			m.observe(value)
+		}
+		return
+	}
+
+	e.initial = append(e.initial, value)
+	if len(e.initial) == 5 {
+		sort.Float64s(e.initial)
+		e.markers = make([]*p2Markers, len(e.quantiles))
+		for i, q := range e.quantiles {
+			e.markers[i] = newP2Markers(q, e.initial)
+		}
+		e.initial = nil
+	}
+}
+
+// Quantile returns the estimate of a tracked quantile. It returns 0 without observations
+// or if the quantile is not tracked.
+func (e *QuantileEstimator) Quantile(q float64) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, tracked := range e.quantiles {
+		if tracked != q {
+			continue
+		}
+		if e.markers != nil {
+			return e.markers[i].heights[2]
+		}
+		return exactQuantile(e.initial, q)
+	}
+	return 0
+}
+
+// Reset forgets all observations
+func (e *QuantileEstimator) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.markers = nil
+	e.initial = nil
+}
+
+// exactQuantile returns the nearest-rank quantile of a few observations
+func exactQuantile(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(q*float64(len(sorted)) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// p2Markers are the P² markers of one quantile: the minimum, the quantile estimate
+// with its two neighbours, and the maximum
+type p2Markers struct {
+	heights   [5]float64
+	positions [5]float64
+	desired   [5]float64
+	increment [5]float64
+}
+
+// newP2Markers initializes the markers from the first five sorted observations
+func newP2Markers(q float64, sorted []float64) *p2Markers {
+	m := &p2Markers{
+		positions: [5]float64{1, 2, 3, 4, 5},
+		desired:   [5]float64{1, 1 + 2*q, 1 + 4*q, 3 + 2*q, 5},
+		increment: [5]float64{0, q / 2, q, (1 + q) / 2, 1},
+	}
+	copy(m.heights[:], sorted)
+	return m
+}
+
+// observe adds an observation and adjusts the markers
+func (m *p2Markers) observe(x float64) {
+	// Find the cell containing x, extending the extremes if needed
+	var k int
+	switch {
+	case x < m.heights[0]:
+		m.heights[0] = x
+		k = 0
+	case x >= m.heights[4]:
+		m.heights[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3; k++ {
+			if x < m.heights[k+1] {
+				break
+			}
+		}
+	}
+
+	for i := k + 1; i < 5; i++ {
+		m.positions[i]++
+	}
+	for i := range m.desired {
+		m.desired[i] += m.increment[i]
+	}
+
+	// Move the middle markers towards their desired positions
+	for i := 1; i <= 3; i++ {
+		d := m.desired[i] - m.positions[i]
+		if (d >= 1 && m.positions[i+1]-m.positions[i] > 1) || (d <= -1 && m.positions[i-1]-m.positions[i] < -1) {
+			step := 1.0
+			if d < 0 {
+				step = -1
+			}
+			height := m.parabolic(i, step)
+			if m.heights[i-1] < height && height < m.heights[i+1] {
+				m.heights[i] = height
+			} else {
+				m.heights[i] = m.linear(i, step)
+			}
+			m.positions[i] += step
+		}
+	}
+}
+
+// parabolic returns the piecewise-parabolic prediction of a marker's height after a move
+func (m *p2Markers) parabolic(i int, d float64) float64 {
+	n, q := m.positions, m.heights
+	return q[i] + d/(n[i+1]-n[i-1])*
+		((n[i]-n[i-1]+d)*(q[i+1]-q[i])/(n[i+1]-n[i])+
+			(n[i+1]-n[i]-d)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+// linear returns the linear prediction of a marker's height after a move
+func (m *p2Markers) linear(i int, d float64) float64 {
+	j := i + int(d)
+	return m.heights[i] + d*(m.heights[j]-m.heights[i])/(m.positions[j]-m.positions[i])
+}
@@ -0,0 +1,296 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// instrumentationScope names the instrumentation in exported spans
+const instrumentationScope = "github.com/lexlapax/go-llms"
+
+// The OTLP JSON encoding of spans, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+// MarshalOTLP encodes spans as an OTLP JSON trace export request, grouping them by service
+func MarshalOTLP(spans []SpanData) ([]byte, error) {
+	var traces otlpTraces
+	services := make(map[string]int)
+	for _, span := range spans {
+		index, ok := services[span.ServiceName]
+		if !ok {
+			index = len(traces.ResourceSpans)
+			services[span.ServiceName] = index
+			traces.ResourceSpans = append(traces.ResourceSpans, otlpResourceSpans{
+				Resource:   otlpResource{Attributes: otlpAttributes(map[string]interface{}{"service.name": span.ServiceName})},
+				ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: instrumentationScope}}},
+			})
+		}
+		scope := &traces.ResourceSpans[index].ScopeSpans[0]
+		scope.Spans = append(scope.Spans, toOTLPSpan(span))
+	}
+	return json.Marshal(traces)
+}
+
+// toOTLPSpan converts a span to its OTLP encoding
+func toOTLPSpan(span SpanData) otlpSpan {
+	result := otlpSpan{
+		TraceID:           span.TraceID.String(),
+		SpanID:            span.SpanID.String(),
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: unixNano(span.StartTime),
+		EndTimeUnixNano:   unixNano(span.EndTime),
+		Attributes:        otlpAttributes(span.Attributes),
+		Status:            otlpStatus{Code: span.Status, Message: span.StatusMessage},
+	}
+	if span.ParentSpanID.IsValid() {
+		result.ParentSpanID = span.ParentSpanID.String()
+	}
+	for _, event := range span.Events {
+		result.Events = append(result.Events, otlpEvent{
+			TimeUnixNano: unixNano(event.Time),
+			Name:         event.Name,
+			Attributes:   otlpAttributes(event.Attributes),
+		})
+	}
+	return result
+}
+
+// otlpAttributes converts attributes to OTLP key values in key order
+func otlpAttributes(attributes map[string]interface{}) []otlpKeyValue {
+	if len(attributes) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]otlpKeyValue, len(keys))
+	for i, key := range keys {
+		result[i] = otlpKeyValue{Key: key, Value: otlpValue(attributes[key])}
+	}
+	return result
+}
+
+// otlpValue converts an attribute value. Unsupported types are formatted as strings.
+func otlpValue(value interface{}) otlpAnyValue {
+	switch v := value.(type) {
+	case string:
+		return otlpAnyValue{StringValue: &v}
+	case bool:
+		return otlpAnyValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case float32:
+		f := float64(v)
+		return otlpAnyValue{DoubleValue: &f}
+	case float64:
+		return otlpAnyValue{DoubleValue: &v}
+	case time.Duration:
+		f := v.Seconds()
+		return otlpAnyValue{DoubleValue: &f}
+	case []string:
+		values := make([]otlpAnyValue, len(v))
+		for i, s := range v {
+			values[i] = otlpValue(s)
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	default:
+		s := fmt.Sprint(v)
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+// intValue encodes an integer, which OTLP JSON represents as a string
+func intValue(v int64) otlpAnyValue {
+	s := strconv.FormatInt(v, 10)
+	return otlpAnyValue{IntValue: &s}
+}
+
+// unixNano formats a time as OTLP nanoseconds since the epoch
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// WriterExporter writes each batch of spans as one line of OTLP JSON, the format
+// read by the OpenTelemetry Collector's file receiver
+type WriterExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterExporter creates an exporter writing to w
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+// NewFileExporter creates an exporter appending to a file, creating it if needed
+func NewFileExporter(path string) (*WriterExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return NewWriterExporter(file), nil
+}
+
+// ExportSpans writes the spans as a line of OTLP JSON
+func (e *WriterExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	data, err := MarshalOTLP(spans)
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write spans: %w", err)
+	}
+	return nil
+}
+
+// Shutdown closes the underlying writer if it is an io.Closer
+func (e *WriterExporter) Shutdown(ctx context.Context) error {
+	if closer, ok := e.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// HTTPExporter posts spans as OTLP JSON to a collector,
+// such as "http://localhost:4318/v1/traces"
+type HTTPExporter struct {
+	endpoint   string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// NewHTTPExporter creates an exporter posting to an OTLP/HTTP traces endpoint
+func NewHTTPExporter(endpoint string) *HTTPExporter {
+	return &HTTPExporter{
+		endpoint:   endpoint,
+		headers:    make(map[string]string),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// WithHeader adds a header to export requests, such as an API key of a hosted backend
+func (e *HTTPExporter) WithHeader(key, value string) *HTTPExporter {
+	e.headers[key] = value
+	return e
+}
+
+// WithHTTPClient sets the HTTP client used for export requests
+func (e *HTTPExporter) WithHTTPClient(client *http.Client) *HTTPExporter {
+	e.httpClient = client
+	return e
+}
+
+// ExportSpans posts the spans to the collector
+func (e *HTTPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	data, err := MarshalOTLP(spans)
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to export spans: status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// Shutdown does nothing; HTTPExporter holds no resources
+func (e *HTTPExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,372 @@
+// Package tracing provides lightweight tracing for provider calls, agent runs and tool executions.
+// Spans form a tree through context.Context and are exported as OTLP JSON, so they can be
+// viewed in any OpenTelemetry-compatible backend without depending on the OpenTelemetry SDK.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// SpanKind describes the relationship of a span to its parent, using the OTLP values
+type SpanKind int
+
+const (
+	// SpanKindInternal is an operation within the application
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer is the handling of a remote request
+	SpanKindServer SpanKind = 2
+	// SpanKindClient is a request to a remote service, such as an LLM API
+	SpanKindClient SpanKind = 3
+)
+
+// StatusCode is the status of a span, using the OTLP values
+type StatusCode int
+
+const (
+	// StatusUnset is the default status
+	StatusUnset StatusCode = 0
+	// StatusOK marks a span as successful
+	StatusOK StatusCode = 1
+	// StatusError marks a span as failed
+	StatusError StatusCode = 2
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the hex encoding of the trace ID
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the trace ID is set
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the hex encoding of the span ID
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the span ID is set
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// Event is a timestamped annotation of a span
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+// SpanData is the recorded state of an ended span, as passed to exporters
+type SpanData struct {
+	ServiceName   string
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentSpanID  SpanID
+	Name          string
+	Kind          SpanKind
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]interface{}
+	Events        []Event
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Exporter sends ended spans to a tracing backend
+type Exporter interface {
+	// ExportSpans exports a batch of spans
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	// Shutdown releases the exporter's resources
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer creates spans and exports them in batches
+type Tracer struct {
+	serviceName string
+	exporter    Exporter
+	batchSize   int
+
+	mu       sync.Mutex
+	pending  []SpanData
+	inFlight int        // exports that have not returned yet
+	idle     *sync.Cond // signalled when inFlight drops to zero
+	closed   bool
+	err      error
+}
+
+// NewTracer creates a tracer exporting spans of a service
+func NewTracer(serviceName string, exporter Exporter) *Tracer {
+	t := &Tracer{
+		serviceName: serviceName,
+		exporter:    exporter,
+		batchSize:   64,
+	}
+	t.idle = sync.NewCond(&t.mu)
+	return t
+}
+
+// WithBatchSize sets the number of ended spans that triggers an export (default 64)
+func (t *Tracer) WithBatchSize(size int) *Tracer {
+	if size > 0 {
+		t.batchSize = size
+	}
+	return t
+}
+
+// Start starts a span as a child of the span in ctx, or as the root of a new trace.
+// The returned context carries the span and the tracer.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			ServiceName: t.serviceName,
+			SpanID:      newSpanID(),
+			Name:        name,
+			Kind:        kind,
+			StartTime:   time.Now(),
+			Attributes:  make(map[string]interface{}),
+		},
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.data.TraceID = parent.data.TraceID
+		span.data.ParentSpanID = parent.data.SpanID
+	} else {
+		span.data.TraceID = newTraceID()
+	}
+
+	ctx = context.WithValue(ctx, tracerKey{}, t)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// end queues an ended span and exports the queue once it reaches the batch size.
+// Spans ended after Shutdown are dropped.
+func (t *Tracer) end(data SpanData) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.pending = append(t.pending, data)
+	if len(t.pending) < t.batchSize {
+		t.mu.Unlock()
+		return
+	}
+	batch := t.pending
+	t.pending = nil
+	t.inFlight++
+	t.mu.Unlock()
+
+	// Export in the background so that ending a span never blocks on the backend
+	go t.export(context.Background(), batch)
+}
+
+// export sends a batch counted in inFlight and remembers the first error for Flush
+func (t *Tracer) export(ctx context.Context, batch []SpanData) {
+	err := t.exporter.ExportSpans(ctx, batch)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+	t.inFlight--
+	if t.inFlight == 0 {
+		t.idle.Broadcast()
+	}
+}
+
+// waitForExports blocks until no exports are in flight
+func (t *Tracer) waitForExports() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.inFlight > 0 {
+		t.idle.Wait()
+	}
+}
+
+// Flush exports all ended spans and waits for background exports.
+// It returns the first export error since the previous flush.
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = nil
+	if len(batch) > 0 {
+		t.inFlight++
+	}
+	t.mu.Unlock()
+
+	if len(batch) > 0 {
+		t.export(ctx, batch)
+	}
+	t.waitForExports()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	err := t.err
+	t.err = nil
+	return err
+}
+
+// Shutdown flushes the tracer and shuts down its exporter. Spans ended afterwards are
+// dropped, and only the first call has an effect.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	flushErr := t.Flush(ctx)
+	if err := t.exporter.Shutdown(ctx); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// Span is an operation within a trace. All methods are safe to call on a nil span,
+// which is what Start returns when tracing is not enabled.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// TraceID returns the ID of the span's trace
+func (s *Span) TraceID() TraceID {
+	if s == nil {
+		return TraceID{}
+	}
+	return s.data.TraceID
+}
+
+// SpanID returns the ID of the span
+func (s *Span) SpanID() SpanID {
+	if s == nil {
+		return SpanID{}
+	}
+	return s.data.SpanID
+}
+
+// SetAttribute sets an attribute of the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Attributes[key] = value
+	}
+}
+
+// AddEvent records a timestamped event on the span
+func (s *Span) AddEvent(name string, attributes map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Events = append(s.data.Events, Event{Name: name, Time: time.Now(), Attributes: attributes})
+	}
+}
+
+// SetStatus sets the status of the span
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Status = code
+		s.data.StatusMessage = message
+	}
+}
+
+// RecordError records an "exception" event and marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.AddEvent("exception", map[string]interface{}{"exception.message": err.Error()})
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End ends the span and queues it for export. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.end(data)
+}
+
+// tracerKey and spanKey are the context keys of the current tracer and span
+type tracerKey struct{}
+type spanKey struct{}
+
+// ContextWithTracer enables tracing for the operations run with the returned context
+func ContextWithTracer(ctx context.Context, tracer *Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// TracerFromContext returns the tracer of ctx, or nil if tracing is not enabled
+func TracerFromContext(ctx context.Context) *Tracer {
+	tracer, _ := ctx.Value(tracerKey{}).(*Tracer)
+	return tracer
+}
+
+// SpanFromContext returns the current span of ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start starts a span with the tracer of ctx. Without a tracer it returns ctx and a nil span,
+// so instrumented code costs almost nothing when tracing is not enabled.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	tracer := TracerFromContext(ctx)
+	if tracer == nil {
+		return ctx, nil
+	}
+	return tracer.Start(ctx, name, kind)
+}
+
+// newTraceID returns a random trace ID
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+// newSpanID returns a random span ID
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,230 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lexlapax/go-llms/pkg/util/json"
+)
+
+// recordingExporter keeps exported spans in memory
+type recordingExporter struct {
+	mu       sync.Mutex
+	spans    []SpanData
+	shutdown bool
+}
+
+func (e *recordingExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.shutdown {
+		return errors.New("exporter is shut down")
+	}
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func TestSpanTree(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer("test-service", exporter)
+
+	ctx, root := tracer.Start(context.Background(), "root", SpanKindInternal)
+	childCtx, child := Start(ctx, "child", SpanKindClient)
+	child.SetAttribute("gen_ai.system", "openai")
+	child.AddEvent("retry", map[string]interface{}{"attempt": 1})
+	child.RecordError(errors.New("rate limited"))
+	child.End()
+	child.End() // ending twice has no effect
+	root.End()
+
+	if SpanFromContext(childCtx) != child {
+		t.Errorf("Expected the child span in its context")
+	}
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(exporter.spans))
+	}
+	childData, rootData := exporter.spans[0], exporter.spans[1]
+	if childData.TraceID != rootData.TraceID || childData.ParentSpanID != rootData.SpanID || rootData.ParentSpanID.IsValid() {
+		t.Errorf("Expected child to be parented by root, got %+v and %+v", childData, rootData)
+	}
+	if childData.Status != StatusError || childData.StatusMessage != "rate limited" || len(childData.Events) != 2 {
+		t.Errorf("Unexpected child status or events: %+v", childData)
+	}
+	if childData.Attributes["gen_ai.system"] != "openai" || childData.ServiceName != "test-service" {
+		t.Errorf("Unexpected child attributes: %+v", childData)
+	}
+}
+
+func TestStartWithoutTracer(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := Start(ctx, "noop", SpanKindInternal)
+	if span != nil || spanCtx != ctx {
+		t.Fatalf("Expected no span without a tracer")
+	}
+
+	// A nil span accepts all calls
+	span.SetAttribute("key", "value")
+	span.AddEvent("event", nil)
+	span.RecordError(errors.New("failure"))
+	span.End()
+	if span.TraceID().IsValid() {
+		t.Errorf("Expected an invalid trace ID for a nil span")
+	}
+}
+
+func TestBatchExport(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer("test-service", exporter).WithBatchSize(2)
+
+	for i := 0; i < 3; i++ {
+		_, span := tracer.Start(context.Background(), "span", SpanKindInternal)
+		span.End()
+	}
+	tracer.waitForExports()
+	exporter.mu.Lock()
+	exported := len(exporter.spans)
+	exporter.mu.Unlock()
+	if exported != 2 {
+		t.Errorf("Expected a batch of 2 spans before flushing, got %d", exported)
+	}
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if len(exporter.spans) != 3 {
+		t.Errorf("Expected all 3 spans after shutdown, got %d", len(exporter.spans))
+	}
+}
+
+func TestConcurrentFlushAndShutdown(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer("test-service", exporter).WithBatchSize(3)
+
+	// Spans end while other goroutines flush, which must not race with background exports
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, span := tracer.Start(context.Background(), "span", SpanKindInternal)
+				span.End()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if err := tracer.Flush(context.Background()); err != nil {
+					t.Errorf("Flush failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if len(exporter.spans) != 400 {
+		t.Errorf("Expected all 400 spans to be exported, got %d", len(exporter.spans))
+	}
+
+	// Spans ended after shutdown are not sent to the closed exporter
+	for i := 0; i < 3; i++ {
+		_, span := tracer.Start(context.Background(), "late", SpanKindInternal)
+		span.End()
+	}
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Errorf("Expected no export after shutdown, got %v", err)
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Errorf("Expected a second shutdown to do nothing, got %v", err)
+	}
+}
+
+func TestWriterExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer("test-service", NewWriterExporter(&buf))
+
+	ctx, root := tracer.Start(context.Background(), "root", SpanKindInternal)
+	_, child := Start(ctx, "child", SpanKindClient)
+	child.SetAttribute("count", 3)
+	child.SetAttribute("ratio", 0.5)
+	child.SetAttribute("tags", []string{"a", "b"})
+	child.End()
+	root.End()
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	var traces otlpTraces
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &traces); err != nil {
+		t.Fatalf("Invalid OTLP JSON: %v", err)
+	}
+	resource := traces.ResourceSpans[0]
+	if *resource.Resource.Attributes[0].Value.StringValue != "test-service" {
+		t.Errorf("Expected the service name resource attribute, got %+v", resource.Resource)
+	}
+	spans := resource.ScopeSpans[0].Spans
+	if len(spans) != 2 || spans[0].ParentSpanID != spans[1].SpanID || len(spans[0].TraceID) != 32 {
+		t.Fatalf("Unexpected spans: %+v", spans)
+	}
+	attributes := spans[0].Attributes
+	if attributes[0].Key != "count" || *attributes[0].Value.IntValue != "3" {
+		t.Errorf("Expected integers encoded as strings, got %+v", attributes[0])
+	}
+	if *attributes[1].Value.DoubleValue != 0.5 || len(attributes[2].Value.ArrayValue.Values) != 2 {
+		t.Errorf("Unexpected attribute encoding: %+v", attributes)
+	}
+}
+
+func TestHTTPExporter(t *testing.T) {
+	var body string
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, header = string(data), r.Header
+		if r.Header.Get("X-Api-Key") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter := NewHTTPExporter(server.URL+"/v1/traces").WithHeader("X-Api-Key", "secret")
+	tracer := NewTracer("test-service", exporter)
+	_, span := tracer.Start(context.Background(), "request", SpanKindClient)
+	span.End()
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if header.Get("Content-Type") != "application/json" || !strings.Contains(body, `"name":"request"`) {
+		t.Errorf("Unexpected export request: %s", body)
+	}
+
+	failing := NewTracer("test-service", NewHTTPExporter(server.URL+"/v1/traces"))
+	_, span = failing.Start(context.Background(), "request", SpanKindClient)
+	span.End()
+	if err := failing.Flush(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected the export error from Flush, got %v", err)
+	}
+}